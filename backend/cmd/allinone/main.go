@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"imageprocessor/backend/internal/app/allinone"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/pkg/lib/logger/zaplogger"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	configPath     = "backend/internal/config/config.yaml"
+	dbPasswordPath = "DB_PASSWORD"
+	cloudAccessKey = "CLOUD_ACCESS_KEY"
+	cloudSecretKey = "CLOUD_SECRET_KEY"
+)
+
+func main() {
+	ctx := context.Background()
+
+	log := zaplogger.SetupLoggerWithLevel(zapcore.DebugLevel)
+	log.Info("ALL-IN-ONE SERVICE started")
+
+	config, err := config.LoadServiceConfig(log, configPath, dbPasswordPath, cloudAccessKey, cloudSecretKey)
+	if err != nil {
+		log.Error("Failed to load ALL-IN-ONE SERVICE config", zaplogger.Err(err))
+		os.Exit(1)
+	}
+
+	service, err := allinone.NewAllInOne(ctx, config, log)
+	if err != nil {
+		log.Error("Failed to create ALL-IN-ONE SERVICE", zaplogger.Err(err))
+		os.Exit(1)
+	}
+
+	if err := service.Run(ctx); err != nil {
+		log.Error("Failed to Run ALL-IN-ONE SERVICE", zaplogger.Err(err))
+		os.Exit(1)
+	}
+	log.Info("ALL-IN-ONE SERVICE stopped")
+}
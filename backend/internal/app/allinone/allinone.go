@@ -0,0 +1,93 @@
+package allinone
+
+import (
+	"context"
+	"fmt"
+	"imageprocessor/backend/internal/app"
+	"imageprocessor/backend/internal/app/worker"
+	brokermemory "imageprocessor/backend/internal/broker/memory"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/events/memory"
+	"imageprocessor/backend/internal/repository/postgres"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// AllInOne запускает API и пул воркеров в одном процессе. Задачи передаются
+// через брокер в памяти, поэтому Kafka и ZooKeeper не нужны
+type AllInOne struct {
+	log     *zap.Logger
+	broker  *brokermemory.Broker
+	storage *postgres.Storage
+	api     *app.App
+	worker  *worker.Worker
+}
+
+func NewAllInOne(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger) (*AllInOne, error) {
+	if !cfg.BrokerConfig.IsMemory() {
+		log.Info("All-in-one mode uses memory broker", zap.String("configuredType", cfg.BrokerConfig.Type))
+	}
+
+	// Сообщения, исчерпавшие доставки, сохраняются в dead_letters
+	storage, err := postgres.NewDatabase(ctx, cfg.DbConfig.DBConn)
+	if err != nil {
+		return nil, fmt.Errorf("database connection failed: %w", err)
+	}
+
+	memoryBroker := brokermemory.NewBroker(cfg.BrokerConfig.Memory, postgres.NewDeadLetterRepository(storage.GetPool(), cfg.BrokerConfig.Postgres.JobMaxAttempts()), log)
+	// События хода обработки передаются от воркеров к API в памяти
+	progressHub := memory.NewHub(cfg.EventsConfig.BufferSize, log)
+
+	api, err := app.NewAppWithBroker(ctx, cfg, log, memoryBroker, progressHub)
+	if err != nil {
+		_ = memoryBroker.Close()
+		_ = storage.Close()
+		return nil, fmt.Errorf("failed to create API: %w", err)
+	}
+
+	workers, err := worker.NewWorkerWithBroker(ctx, cfg, log, memoryBroker, memoryBroker, progressHub)
+	if err != nil {
+		_ = memoryBroker.Close()
+		_ = storage.Close()
+		return nil, fmt.Errorf("failed to create workers: %w", err)
+	}
+
+	return &AllInOne{
+		log:     log,
+		broker:  memoryBroker,
+		storage: storage,
+		api:     api,
+		worker:  workers,
+	}, nil
+}
+
+// Run запускает API и воркеры и ждет их остановки. Остановка одного
+// из компонентов останавливает и другой
+func (a *AllInOne) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		if err := a.worker.Run(ctx); err != nil {
+			a.log.Error("Workers stopped with error", zap.Error(err))
+		}
+	}()
+
+	err := a.api.Run(ctx)
+	cancel()
+	wg.Wait()
+
+	// Брокер закрывается после остановки воркеров: задачи, оставшиеся в очереди, теряются
+	_ = a.broker.Close()
+	_ = a.storage.Close()
+
+	if err != nil {
+		return fmt.Errorf("API stopped with error: %w", err)
+	}
+	return nil
+}
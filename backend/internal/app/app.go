@@ -3,19 +3,32 @@ package app
 import (
 	"context"
 	"fmt"
+	"imageprocessor/backend/internal/broker"
 	"imageprocessor/backend/internal/broker/kafka"
+	pgbroker "imageprocessor/backend/internal/broker/postgres"
 	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/events"
+	pgevents "imageprocessor/backend/internal/events/postgres"
 	httpserver "imageprocessor/backend/internal/http-server"
 	"imageprocessor/backend/internal/http-server/handler"
-	"imageprocessor/backend/internal/repository/cloud/s3"
+	"imageprocessor/backend/internal/repository/cloud"
 	"imageprocessor/backend/internal/repository/postgres"
+	backfillservice "imageprocessor/backend/internal/service/backfill_service"
+	deadletterservice "imageprocessor/backend/internal/service/dead_letter_service"
+	"imageprocessor/backend/internal/service/image_processor/overlay"
+	"imageprocessor/backend/internal/service/image_processor/processor"
 	imageservice "imageprocessor/backend/internal/service/image_service"
+	outboxrelay "imageprocessor/backend/internal/service/outbox_relay"
+	presetservice "imageprocessor/backend/internal/service/preset_service"
 	statsservice "imageprocessor/backend/internal/service/stats_service"
+	transformservice "imageprocessor/backend/internal/service/transform_service"
+	webhookservice "imageprocessor/backend/internal/service/webhook_service"
 
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
@@ -23,60 +36,155 @@ type App struct {
 	cfg    *config.ServiceConfig
 	log    *zap.Logger
 	server *httpserver.Server
+	relay  *outboxrelay.Relay
+	// Dispatcher отправляет доставки webhook, сохраненные воркерами
+	dispatcher *webhookservice.Dispatcher
+	// Listener получает события хода обработки от воркеров других процессов
+	listener *pgevents.Notifier
+	// BackfillRunner публикует пачки задач запущенных backfill
+	backfillRunner *backfillservice.Runner
 }
 
 func NewApp(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger) (*App, error) {
+	return newApp(ctx, cfg, log, nil, nil)
+}
+
+// NewAppWithBroker создает API, публикующий задачи через переданный producer
+// и получающий ход обработки из progress. Используется в режиме all-in-one,
+// где брокер и события общие с воркерами
+func NewAppWithBroker(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger, producer broker.ProducerMessageBrokerInterface, progress events.BusInterface) (*App, error) {
+	return newApp(ctx, cfg, log, producer, progress)
+}
 
+// newApp собирает API. Если producer не передан, он создается по broker.type;
+// без progress события хода обработки принимаются через LISTEN/NOTIFY
+func newApp(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger, producer broker.ProducerMessageBrokerInterface, progress events.BusInterface) (*App, error) {
 	storage, err := postgres.NewDatabase(ctx, cfg.DbConfig.DBConn)
 	if err != nil {
 		log.Error("Failed to connect to database", zap.Error(err))
 		return nil, fmt.Errorf("database connection failed: %w", err)
 	}
-	log.Info("Connected to database", zap.String("dsn", cfg.DbConfig.DBConn))
+	log.Info("Connected to database",
+		zap.String("host", cfg.DbConfig.Host),
+		zap.String("database", cfg.DbConfig.DBName),
+	)
 
 	dbPool := storage.GetPool()
 
-	s3Client, err := s3.NewS3CloudStorage(ctx, cfg.CloudStorageConfig)
-	if err != nil {
-		log.Error("Failed to initialize S3 client", zap.Error(err))
-		return nil, fmt.Errorf("S3 client initialization failed: %w", err)
+	if producer == nil {
+		producer, err = newProducer(cfg, dbPool, log)
+		if err != nil {
+			return nil, err
+		}
 	}
-	log.Info("S3 client initialized")
 
-	// Инициализация Kafka producer
-	kafkaProducer := kafka.NewProducer(cfg.BrokerConfig, log)
+	var listener *pgevents.Notifier
+	if progress == nil {
+		listener = pgevents.NewNotifier(dbPool, cfg.EventsConfig, log)
+		progress = listener
+	}
 
-	// Проверка и создание топика Kafka
-	if err := kafka.EnsureTopicExists(cfg.BrokerConfig, log); err != nil {
-		log.Warn("Failed to ensure Kafka topic exists", zap.Error(err))
+	cloudStorage, err := cloud.NewCloudStorage(ctx, cfg.CloudStorageConfig)
+	if err != nil {
+		log.Error("Failed to initialize cloud storage", zap.Error(err))
+		return nil, fmt.Errorf("cloud storage initialization failed: %w", err)
 	}
+	log.Info("Cloud storage initialized", zap.String("backend", cfg.CloudStorageConfig.Backend))
+
+	// Presigned ссылки локального хранилища раздает API
+	fileStorage, _ := cloudStorage.(handler.FileStorageInterface)
 
 	// Инициализация репозиториев
-	imageRepo := postgres.NewImageRepository(dbPool)
+	imageRepo := postgres.NewImageRepository(dbPool, cfg.BrokerConfig.Postgres.JobMaxAttempts())
 	statsRepo := postgres.NewStatisticsRepository(dbPool)
+	presetRepo := postgres.NewPresetRepository(dbPool)
+	outboxRepo := postgres.NewOutboxRepository(dbPool)
+	deadLetterRepo := postgres.NewDeadLetterRepository(dbPool, cfg.BrokerConfig.Postgres.JobMaxAttempts())
+	webhookRepo := postgres.NewWebhookRepository(dbPool)
+	backfillRepo := postgres.NewBackfillRepository(dbPool, cfg.BrokerConfig.Postgres.JobMaxAttempts())
+
+	// Relay публикует задачи, записанные в outbox при загрузке
+	relay := outboxrelay.NewRelay(outboxRepo, producer, cfg.OutboxConfig, log)
+
+	overlayLoader := overlay.NewLoader(imageRepo, cloudStorage, cfg.ProcessingConfig.WatermarkAssets, log)
+	imageProcessor := processor.NewImageProcessor(log, cfg.ProcessingConfig, overlayLoader)
 
 	// Инициализация сервисов
 	imageService := imageservice.NewImageService(
 		imageRepo,
-		s3Client,
-		kafkaProducer,
+		cloudStorage,
+		relay,
+		imageProcessor,
 		log,
 		cfg.CloudStorageConfig.Bucket,
+		imageservice.UploadConfig{
+			MaxSize:              cfg.CloudStorageConfig.MaxUploadSize,
+			AllowContentMismatch: cfg.ProcessingConfig.AllowContentMismatch,
+		},
 	)
 
 	statsService := statsservice.NewStatsService(statsRepo, log)
+	presetService := presetservice.NewPresetService(presetRepo, log)
+	deadLetterService := deadletterservice.NewDeadLetterService(deadLetterRepo, imageRepo, relay, log)
+
+	dispatcher := webhookservice.NewDispatcher(webhookRepo, cfg.WebhookConfig, log)
+	webhookService := webhookservice.NewWebhookService(webhookRepo, imageRepo, dispatcher, cfg.WebhookConfig, log)
+	if cfg.WebhookConfig.Secret == "" {
+		log.Warn("Webhook secret is not configured, callback_url is not accepted")
+	}
+
+	// Backfill публикует задачи напрямую через producer, минуя outbox:
+	// курсор сдвигается только после подтвержденной публикации пачки
+	backfillRunner := backfillservice.NewRunner(backfillRepo, producer, cfg.CloudStorageConfig.Bucket, cfg.BackfillConfig, log)
+	backfillService := backfillservice.NewBackfillService(backfillRepo, backfillRunner, cfg.BackfillConfig, log)
+
+	// Трансформации по URL выполняются синхронно в процессе API
+	if cfg.TransformConfig.SigningKey == "" {
+		log.Warn("Transform signing key is not configured, /transform endpoint is disabled")
+	}
+	transformService := transformservice.NewTransformService(
+		imageRepo,
+		cloudStorage,
+		imageProcessor,
+		log,
+		cfg.TransformConfig,
+	)
 
 	// Инициализация хэндлеров
-	handlers := handler.NewHandler(log, imageService, statsService)
+	handlers := handler.NewHandler(log, imageService, statsService, presetService, deadLetterService, webhookService, backfillService, transformService, overlayLoader, imageProcessor.Fonts(), fileStorage, progress, cfg.ProcessingConfig, cfg.EventsConfig)
 
 	server := httpserver.NewServer(log, cfg, handlers)
 	return &App{
-		cfg:    cfg,
-		log:    log,
-		server: server,
+		cfg:            cfg,
+		log:            log,
+		server:         server,
+		relay:          relay,
+		dispatcher:     dispatcher,
+		listener:       listener,
+		backfillRunner: backfillRunner,
 	}, nil
 }
 
+// newProducer создает producer брокера, выбранного параметром broker.type
+func newProducer(cfg *config.ServiceConfig, dbPool *pgxpool.Pool, log *zap.Logger) (broker.ProducerMessageBrokerInterface, error) {
+	switch {
+	case cfg.BrokerConfig.IsMemory():
+		// Брокер в памяти доступен только API и воркерам одного процесса
+		return nil, fmt.Errorf("memory broker is available only in all-in-one mode")
+	case cfg.BrokerConfig.IsPostgres():
+		return pgbroker.NewQueue(dbPool, cfg.BrokerConfig.Postgres, postgres.NewDeadLetterRepository(dbPool, cfg.BrokerConfig.Postgres.JobMaxAttempts()), log), nil
+	default:
+		// Инициализация Kafka producer
+		kafkaProducer := kafka.NewProducer(cfg.BrokerConfig, log)
+
+		// Проверка и создание топика Kafka
+		if err := kafka.EnsureTopicExists(cfg.BrokerConfig, log); err != nil {
+			log.Warn("Failed to ensure Kafka topic exists", zap.Error(err))
+		}
+		return kafkaProducer, nil
+	}
+}
+
 func (a *App) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -84,6 +192,37 @@ func (a *App) Run(ctx context.Context) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		a.relay.Run(ctx)
+	}()
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		a.dispatcher.Run(ctx)
+	}()
+	listenerDone := make(chan struct{})
+	go func() {
+		defer close(listenerDone)
+		if a.listener != nil {
+			a.listener.Run(ctx)
+		}
+	}()
+	backfillDone := make(chan struct{})
+	go func() {
+		defer close(backfillDone)
+		a.backfillRunner.Run(ctx)
+	}()
+	// Relay, dispatcher, слушатель событий и runner backfill останавливаются вместе с приложением
+	defer func() {
+		cancel()
+		<-relayDone
+		<-dispatcherDone
+		<-listenerDone
+		<-backfillDone
+	}()
+
 	serverDone := make(chan error, 1)
 	go func() {
 		a.log.Info("Starting HTTP server...")
@@ -108,6 +247,15 @@ func (a *App) Run(ctx context.Context) error {
 		a.log.Info("Application gracefully shut down")
 		return nil
 
+	case <-ctx.Done():
+		a.log.Info("Application context cancelled")
+
+		// Контекст уже отменен, поэтому остановка сервера получает свой
+		if err := a.server.Shutdown(context.Background()); err != nil {
+			a.log.Error("Failed to shutdown HTTP server", zap.Error(err))
+		}
+		return nil
+
 	case err := <-serverDone:
 
 		cancel()
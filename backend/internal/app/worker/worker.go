@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"imageprocessor/backend/internal/broker"
 	"imageprocessor/backend/internal/broker/kafka"
+	pgbroker "imageprocessor/backend/internal/broker/postgres"
 	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/events"
+	pgevents "imageprocessor/backend/internal/events/postgres"
 	"imageprocessor/backend/internal/repository/cloud"
-	"imageprocessor/backend/internal/repository/cloud/s3"
 	"imageprocessor/backend/internal/repository/postgres"
+	"imageprocessor/backend/internal/service/image_processor/overlay"
 	"imageprocessor/backend/internal/service/image_processor/processor"
 	imageservice "imageprocessor/backend/internal/service/image_service"
 	statsservice "imageprocessor/backend/internal/service/stats_service"
+	webhookservice "imageprocessor/backend/internal/service/webhook_service"
 	workerservice "imageprocessor/backend/internal/service/worker"
 	"os"
 	"os/signal"
@@ -20,6 +24,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
@@ -27,6 +32,7 @@ type Worker struct {
 	cfg          *config.ServiceConfig
 	log          *zap.Logger
 	consumer     broker.ConsumerMessageBrokerInterface
+	producer     broker.ProducerMessageBrokerInterface
 	processor    *processor.ImageProcessorImpl
 	imageRepo    imageservice.ImageRepositoryInterface
 	statsRepo    statsservice.StatsRepositoryInterface
@@ -36,59 +42,112 @@ type Worker struct {
 }
 
 func NewWorker(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger) (*Worker, error) {
+	return newWorker(ctx, cfg, log, nil, nil, nil)
+}
+
+// NewWorkerWithBroker создает пул воркеров, читающий задачи из переданного consumer
+// и публикующий результаты через producer, а ход обработки - через progress.
+// Используется в режиме all-in-one, где брокер и события общие с API
+func NewWorkerWithBroker(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger, consumer broker.ConsumerMessageBrokerInterface, producer broker.ProducerMessageBrokerInterface, progress events.PublisherInterface) (*Worker, error) {
+	return newWorker(ctx, cfg, log, consumer, producer, progress)
+}
+
+// newWorker собирает пул воркеров. Если consumer не передан, consumer и producer
+// результатов создаются по broker.type; без progress события хода обработки
+// публикуются через LISTEN/NOTIFY
+func newWorker(ctx context.Context, cfg *config.ServiceConfig, log *zap.Logger, consumer broker.ConsumerMessageBrokerInterface, producer broker.ProducerMessageBrokerInterface, progress events.PublisherInterface) (*Worker, error) {
 	storage, err := postgres.NewDatabase(ctx, cfg.DbConfig.DBConn)
 	if err != nil {
 		log.Error("Failed to connect to database", zap.Error(err))
 		return nil, fmt.Errorf("database connection failed: %w", err)
 	}
-	log.Info("Connected to database", zap.String("dsn", cfg.DbConfig.DBConn))
+	log.Info("Connected to database",
+		zap.String("host", cfg.DbConfig.Host),
+		zap.String("database", cfg.DbConfig.DBName),
+	)
 
 	dbpool := storage.GetPool()
 
-	// Инициализация S3 клиента
-	s3Client, err := s3.NewS3CloudStorage(ctx, cfg.CloudStorageConfig)
-	if err != nil {
-		log.Fatal("Failed to initialize S3 client", zap.Error(err))
+	if consumer == nil {
+		consumer, producer, err = newBroker(cfg, dbpool, log)
+		if err != nil {
+			return nil, err
+		}
 	}
-	log.Info("S3 client initialized")
 
-	// Инициализация Kafka consumer
-	kafkaConsumer := kafka.NewConsumer(cfg.BrokerConfig, log)
+	// Инициализация хранилища
+	cloudStorage, err := cloud.NewCloudStorage(ctx, cfg.CloudStorageConfig)
+	if err != nil {
+		log.Fatal("Failed to initialize cloud storage", zap.Error(err))
+	}
+	log.Info("Cloud storage initialized", zap.String("backend", cfg.CloudStorageConfig.Backend))
 
-	// Проверка и создание топика Kafka
-	if err := kafka.EnsureTopicExists(cfg.BrokerConfig, log); err != nil {
-		log.Warn("Failed to ensure Kafka topic exists", zap.Error(err))
+	if progress == nil {
+		progress = pgevents.NewNotifier(dbpool, cfg.EventsConfig, log)
 	}
-	imageRepo := postgres.NewImageRepository(dbpool)
+
+	imageRepo := postgres.NewImageRepository(dbpool, cfg.BrokerConfig.Postgres.JobMaxAttempts())
 	statsRepo := postgres.NewStatisticsRepository(dbpool)
 
 	statsService := statsservice.NewStatsService(statsRepo, log)
 
-	imageProcessor := processor.NewImageProcessor(log)
+	// Доставки webhook сохраняются в БД и отправляются dispatcher процесса API
+	webhookService := webhookservice.NewWebhookService(postgres.NewWebhookRepository(dbpool), imageRepo, nil, cfg.WebhookConfig, log)
+
+	overlayLoader := overlay.NewLoader(imageRepo, cloudStorage, cfg.ProcessingConfig.WatermarkAssets, log)
+	imageProcessor := processor.NewImageProcessor(log, cfg.ProcessingConfig, overlayLoader)
 
 	workerService := workerservice.NewWorkerService(
 		imageProcessor,
-		s3Client,
+		cloudStorage,
 		imageRepo,
 		statsService,
+		producer,
+		webhookService,
+		progress,
 		log,
 		cfg.CloudStorageConfig.Bucket,
+		cfg.WorkerConfig.ProcessingTimeout,
 	)
 
 	return &Worker{
 		cfg:          cfg,
 		log:          log,
-		consumer:     kafkaConsumer,
+		consumer:     consumer,
+		producer:     producer,
 		processor:    imageProcessor,
 		imageRepo:    imageRepo,
 		statsRepo:    statsRepo,
-		cloudStorage: s3Client,
+		cloudStorage: cloudStorage,
 		workService:  workerService,
 		numWorkers:   cfg.WorkerConfig.NumWorkers,
 	}, nil
 
 }
 
+// newBroker создает consumer задач и producer результатов брокера,
+// выбранного параметром broker.type
+func newBroker(cfg *config.ServiceConfig, dbpool *pgxpool.Pool, log *zap.Logger) (broker.ConsumerMessageBrokerInterface, broker.ProducerMessageBrokerInterface, error) {
+	switch {
+	case cfg.BrokerConfig.IsMemory():
+		// Брокер в памяти доступен только API и воркерам одного процесса
+		return nil, nil, fmt.Errorf("memory broker is available only in all-in-one mode")
+	case cfg.BrokerConfig.IsPostgres():
+		queue := pgbroker.NewQueue(dbpool, cfg.BrokerConfig.Postgres, postgres.NewDeadLetterRepository(dbpool, cfg.BrokerConfig.Postgres.JobMaxAttempts()), log)
+		return queue, queue, nil
+	default:
+		// Инициализация Kafka consumer; необработанные сообщения сохраняются в dead_letters
+		kafkaConsumer := kafka.NewConsumer(cfg.BrokerConfig, postgres.NewDeadLetterRepository(dbpool, cfg.BrokerConfig.Postgres.JobMaxAttempts()), log)
+		kafkaProducer := kafka.NewProducer(cfg.BrokerConfig, log)
+
+		// Проверка и создание топиков Kafka
+		if err := kafka.EnsureTopicExists(cfg.BrokerConfig, log); err != nil {
+			log.Warn("Failed to ensure Kafka topic exists", zap.Error(err))
+		}
+		return kafkaConsumer, kafkaProducer, nil
+	}
+}
+
 func (w *Worker) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -96,13 +155,26 @@ func (w *Worker) Run(ctx context.Context) error {
 	wg := &sync.WaitGroup{}
 
 	taskHandler := func(ctx context.Context, task *entity.ProcessingTask) error {
-		w.log.Info("Received task from Kafka",
+		w.log.Info("Received task from broker",
 			zap.String("taskId", task.ID),
 			zap.String("imageId", task.ImageID),
 		)
 
-		// Обрабатываем задачу с повторными попытками
-		err := w.workService.ProcessTaskWithRetry(ctx, task, w.cfg.WorkerConfig.RetryAttempts)
+		// broker.maxProcessingTime ограничивает задачу вместе со всеми повторами
+		if maxProcessingTime := w.cfg.BrokerConfig.MaxProcessingTime; maxProcessingTime > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, maxProcessingTime)
+			defer cancel()
+		}
+
+		// Очередь PostgreSQL сама повторяет задачу через nack, поэтому воркер
+		// выполняет одну попытку; для остальных брокеров повторяет воркер
+		var err error
+		if w.cfg.BrokerConfig.IsPostgres() {
+			err = w.workService.ProcessTask(ctx, task)
+		} else {
+			err = w.workService.ProcessTaskWithRetry(ctx, task, w.cfg.WorkerConfig.RetryAttempts)
+		}
 		if err != nil {
 			w.log.Error("Failed to process task",
 				zap.Error(err),
@@ -136,7 +208,7 @@ func (w *Worker) Run(ctx context.Context) error {
 
 	w.log.Info("All workers started successfully")
 
-	// Мониторинг статистики Kafka consumer
+	// Мониторинг статистики consumer
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -147,9 +219,12 @@ func (w *Worker) Run(ctx context.Context) error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	sig := <-quit
-
-	w.log.Info("Shutting down workers...", zap.String("signal", sig.String()))
+	select {
+	case sig := <-quit:
+		w.log.Info("Shutting down workers...", zap.String("signal", sig.String()))
+	case <-ctx.Done():
+		w.log.Info("Shutting down workers: context cancelled")
+	}
 
 	cancel()
 
@@ -188,7 +263,7 @@ func (w *Worker) monitorStats(ctx context.Context) {
 			return
 		case <-ticker.C:
 			msg, byt := w.consumer.Stats()
-			w.log.Info("Kafka consumer stats",
+			w.log.Info("Consumer stats",
 				zap.Int64("messages", msg),
 				zap.Int64("bytes", byt),
 			)
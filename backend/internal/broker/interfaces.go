@@ -15,5 +15,13 @@ type ConsumerMessageBrokerInterface interface {
 type ProducerMessageBrokerInterface interface {
 	PublishProcessingTask(ctx context.Context, task *entity.ProcessingTask) error
 	PublishBatch(ctx context.Context, tasks []*entity.ProcessingTask) error
+	// PublishProcessingResult публикует итог обработки задачи для внешних подписчиков
+	PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error
 	Close() error
 }
+
+// DeadLetterSinkInterface сохраняет сообщения, которые не удалось обработать
+// после всех попыток или не удалось разобрать
+type DeadLetterSinkInterface interface {
+	StoreDeadLetter(ctx context.Context, letter *entity.DeadLetter) error
+}
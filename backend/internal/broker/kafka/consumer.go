@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"imageprocessor/backend/internal/broker"
 	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
+	"strconv"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -13,12 +15,14 @@ import (
 )
 
 type Consumer struct {
-	reader *kafka.Reader
-	logger *zap.Logger
+	reader      *kafka.Reader
+	logger      *zap.Logger
+	deadLetters broker.DeadLetterSinkInterface
 }
 
-// NewConsumer создает нового Kafka consumer
-func NewConsumer(cfg config.BrokerConfig, logger *zap.Logger) *Consumer {
+// NewConsumer создает нового Kafka consumer. Сообщения, которые не удалось
+// разобрать или обработать, сохраняются в deadLetters и коммитятся
+func NewConsumer(cfg config.BrokerConfig, deadLetters broker.DeadLetterSinkInterface, logger *zap.Logger) *Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:           cfg.Brokers,
 		Topic:             cfg.Topic,
@@ -40,8 +44,9 @@ func NewConsumer(cfg config.BrokerConfig, logger *zap.Logger) *Consumer {
 	)
 
 	return &Consumer{
-		reader: reader,
-		logger: logger,
+		reader:      reader,
+		logger:      logger,
+		deadLetters: deadLetters,
 	}
 }
 
@@ -77,13 +82,25 @@ func (c *Consumer) Start(ctx context.Context, handler func(ctx context.Context,
 
 			// Обрабатываем сообщение
 			if err := c.processMessage(ctx, message, handler); err != nil {
+				if ctx.Err() != nil {
+					// Обработка прервана остановкой: сообщение не коммитится и будет прочитано снова
+					c.logger.Info("Consumer stopped during message processing", zap.Int64("offset", message.Offset))
+					return nil
+				}
 				c.logger.Error("Failed to process message",
 					zap.Error(err),
 					zap.Int64("offset", message.Offset),
 				)
-				continue
+				// Сообщение коммитится только после сохранения в dead-letter очередь
+				if err := c.storeDeadLetter(ctx, message, err); err != nil {
+					c.logger.Error("Failed to store dead letter",
+						zap.Error(err),
+						zap.Int64("offset", message.Offset),
+					)
+					continue
+				}
 			}
-			// Коммитим только после успешной обработки
+			// Коммитим после успешной обработки или сохранения в dead-letter очередь
 			if err := c.reader.CommitMessages(ctx, message); err != nil {
 				c.logger.Error("Failed to commit message",
 					zap.Error(err),
@@ -98,6 +115,44 @@ func (c *Consumer) Start(ctx context.Context, handler func(ctx context.Context,
 	}
 }
 
+// storeDeadLetter сохраняет исходное сообщение с заголовками и ошибкой.
+// Задача и изображение берутся из заголовков, поэтому известны и для
+// сообщений, которые не удалось разобрать. Количество попыток берется
+// из ошибки обработчика, повторявшего задачу в воркере
+func (c *Consumer) storeDeadLetter(ctx context.Context, message kafka.Message, cause error) error {
+	if c.deadLetters == nil {
+		return fmt.Errorf("dead-letter storage is not configured")
+	}
+
+	headers := make(map[string]string, len(message.Headers)+3)
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	headers["topic"] = message.Topic
+	headers["partition"] = strconv.Itoa(message.Partition)
+	headers["offset"] = strconv.FormatInt(message.Offset, 10)
+
+	letter := &entity.DeadLetter{
+		TaskID:   headers["task-id"],
+		ImageID:  headers["image-id"],
+		Source:   config.BrokerTypeKafka,
+		Payload:  message.Value,
+		Headers:  headers,
+		Error:    cause.Error(),
+		Attempts: entity.ProcessingAttempts(cause),
+	}
+	if err := c.deadLetters.StoreDeadLetter(ctx, letter); err != nil {
+		return err
+	}
+
+	c.logger.Warn("Message moved to dead-letter queue",
+		zap.String("deadLetterId", letter.ID),
+		zap.String("taskId", letter.TaskID),
+		zap.Int64("offset", message.Offset),
+	)
+	return nil
+}
+
 // processMessage обрабатывает одно сообщение
 func (c *Consumer) processMessage(ctx context.Context, message kafka.Message, handler func(ctx context.Context, task *entity.ProcessingTask) error) error {
 	// Десериализуем задачу
@@ -159,6 +214,14 @@ func (c *Consumer) ReadBatch(ctx context.Context, maxMessages int) ([]*entity.Pr
 		var task entity.ProcessingTask
 		if err := json.Unmarshal(message.Value, &task); err != nil {
 			c.logger.Error("Failed to unmarshal task", zap.Error(err))
+			// Неразбираемое сообщение убираем из топика, сохранив его в dead-letter очередь
+			if err := c.storeDeadLetter(ctx, message, fmt.Errorf("failed to unmarshal task: %w", err)); err != nil {
+				c.logger.Error("Failed to store dead letter", zap.Error(err), zap.Int64("offset", message.Offset))
+				continue
+			}
+			if err := c.reader.CommitMessages(ctx, message); err != nil {
+				c.logger.Error("Failed to commit message", zap.Error(err))
+			}
 			continue
 		}
 
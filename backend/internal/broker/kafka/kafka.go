@@ -10,9 +10,11 @@ import (
 	"go.uber.org/zap"
 )
 
-// EnsureTopicExists проверяет существование топика и создает его при необходимости
+// EnsureTopicExists проверяет существование топиков задач и результатов
+// и создает их при необходимости
 func EnsureTopicExists(cfg config.BrokerConfig, logger *zap.Logger) error {
-	logger.Info("Checking if Kafka topic exists", zap.String("topic", cfg.Topic))
+	topics := []string{cfg.Topic, cfg.GetResultsTopic()}
+	logger.Info("Checking if Kafka topics exist", zap.Strings("topics", topics))
 
 	conn, err := kafka.Dial("tcp", cfg.Brokers[0])
 	if err != nil {
@@ -50,46 +52,47 @@ func EnsureTopicExists(cfg config.BrokerConfig, logger *zap.Logger) error {
 		return fmt.Errorf("failed to read partitions: %w", err)
 	}
 
-	// Проверяем, существует ли топик
-	topicExists := false
+	// Проверяем, какие топики уже существуют
+	existing := make(map[string]bool)
 	for _, partition := range partitions {
-		if partition.Topic == cfg.Topic {
-			topicExists = true
-			break
-		}
+		existing[partition.Topic] = true
 	}
 
-	if topicExists {
-		logger.Info("Kafka topic already exists", zap.String("topic", cfg.Topic))
-		return nil
-	}
+	for _, topic := range topics {
+		if existing[topic] {
+			logger.Info("Kafka topic already exists", zap.String("topic", topic))
+			continue
+		}
 
-	// Создаем топик
-	logger.Info("Creating Kafka topic", zap.String("topic", cfg.Topic))
-
-	topicConfig := kafka.TopicConfig{
-		Topic:             cfg.Topic,
-		NumPartitions:     3,
-		ReplicationFactor: 1,
-		ConfigEntries: []kafka.ConfigEntry{
-			{
-				ConfigName:  "retention.ms",
-				ConfigValue: "604800000", // 7 дней
+		// Создаем топик
+		logger.Info("Creating Kafka topic", zap.String("topic", topic))
+
+		topicConfig := kafka.TopicConfig{
+			Topic:             topic,
+			NumPartitions:     3,
+			ReplicationFactor: 1,
+			ConfigEntries: []kafka.ConfigEntry{
+				{
+					ConfigName:  "retention.ms",
+					ConfigValue: "604800000", // 7 дней
+				},
+				{
+					ConfigName:  "compression.type",
+					ConfigValue: "snappy",
+				},
 			},
-			{
-				ConfigName:  "compression.type",
-				ConfigValue: "snappy",
-			},
-		},
-	}
+		}
 
-	err = controllerConn.CreateTopics(topicConfig)
-	if err != nil {
-		logger.Error("Failed to create topic", zap.Error(err), zap.String("topic", cfg.Topic))
-		return fmt.Errorf("failed to create topic: %w", err)
+		err = controllerConn.CreateTopics(topicConfig)
+		if err != nil {
+			logger.Error("Failed to create topic", zap.Error(err), zap.String("topic", topic))
+			return fmt.Errorf("failed to create topic: %w", err)
+		}
+		existing[topic] = true
+
+		logger.Info("Kafka topic created successfully", zap.String("topic", topic))
 	}
 
-	logger.Info("Kafka topic created successfully", zap.String("topic", cfg.Topic))
 	return nil
 }
 
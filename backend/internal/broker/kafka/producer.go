@@ -13,9 +13,10 @@ import (
 )
 
 type Producer struct {
-	writer *kafka.Writer
-	logger *zap.Logger
-	topic  string
+	writer       *kafka.Writer
+	resultWriter *kafka.Writer
+	logger       *zap.Logger
+	topic        string
 }
 
 // NewProducer создает нового Kafka producer
@@ -32,15 +33,30 @@ func NewProducer(cfg config.BrokerConfig, logger *zap.Logger) *Producer {
 		BatchSize:    1,                      // Отправлять сразу, без батчинга
 	}
 
+	// Результаты обработки пишутся в отдельный топик с теми же настройками
+	resultWriter := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.GetResultsTopic(),
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireOne,
+		Async:        false,
+		Compression:  kafka.Snappy,
+		WriteTimeout: 100 * time.Millisecond,
+		BatchTimeout: 10 * time.Millisecond,
+		BatchSize:    1,
+	}
+
 	logger.Info("Kafka producer initialized",
 		zap.Strings("brokers", cfg.Brokers),
 		zap.String("topic", cfg.Topic),
+		zap.String("resultsTopic", cfg.GetResultsTopic()),
 	)
 
 	return &Producer{
-		writer: writer,
-		logger: logger,
-		topic:  cfg.Topic,
+		writer:       writer,
+		resultWriter: resultWriter,
+		logger:       logger,
+		topic:        cfg.Topic,
 	}
 }
 
@@ -128,10 +144,51 @@ func (p *Producer) PublishBatch(ctx context.Context, tasks []*entity.ProcessingT
 	return nil
 }
 
+// PublishProcessingResult публикует результат обработки в топик результатов.
+// Ключ сообщения - ID изображения, поэтому результаты одного изображения упорядочены
+func (p *Producer) PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		p.logger.Error("Failed to marshal result", zap.Error(err), zap.String("taskId", result.ID))
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	message := kafka.Message{
+		Key:   []byte(result.ImageID),
+		Value: resultJSON,
+		Headers: []kafka.Header{
+			{Key: "task-id", Value: []byte(result.ID)},
+			{Key: "image-id", Value: []byte(result.ImageID)},
+			{Key: "status", Value: []byte(result.Status)},
+		},
+	}
+
+	if err := p.resultWriter.WriteMessages(ctx, message); err != nil {
+		p.logger.Error("Failed to publish result",
+			zap.Error(err),
+			zap.String("taskId", result.ID),
+			zap.String("imageId", result.ImageID),
+		)
+		return fmt.Errorf("failed to publish result: %w", err)
+	}
+
+	p.logger.Info("Processing result published successfully",
+		zap.String("taskId", result.ID),
+		zap.String("imageId", result.ImageID),
+		zap.String("status", string(result.Status)),
+	)
+
+	return nil
+}
+
 // Close закрывает producer
 func (p *Producer) Close() error {
 	p.logger.Info("Closing Kafka producer")
-	if err := p.writer.Close(); err != nil {
+	err := p.writer.Close()
+	if resultErr := p.resultWriter.Close(); err == nil {
+		err = resultErr
+	}
+	if err != nil {
 		p.logger.Error("Failed to close Kafka producer", zap.Error(err))
 		return err
 	}
@@ -0,0 +1,350 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для незаданных параметров MemoryBrokerConfig
+const (
+	defaultCapacity        = 1000
+	defaultMaxDeliveries   = 3
+	defaultRedeliveryDelay = 5 * time.Second
+)
+
+var (
+	ErrBrokerClosed = errors.New("broker is closed")
+	ErrQueueFull    = errors.New("broker queue is full")
+)
+
+// message - сообщение очереди. Задача хранится сериализованной, как в Kafka,
+// поэтому изменения задачи в обработчике не влияют на повторную доставку
+type message struct {
+	value      []byte
+	taskID     string
+	imageID    string
+	deliveries int
+}
+
+// Broker - брокер в памяти процесса на основе канала ограниченной емкости.
+// Реализует интерфейсы и producer, и consumer, поэтому API и воркеры
+// в режиме all-in-one используют один экземпляр. Сообщение подтверждается
+// после успешной обработки; при ошибке обработчика оно возвращается в очередь
+// с задержкой, пока не исчерпано количество доставок, после чего сохраняется
+// в dead-letter очередь
+type Broker struct {
+	queue           chan *message
+	logger          *zap.Logger
+	deadLetters     broker.DeadLetterSinkInterface
+	maxDeliveries   int
+	redeliveryDelay time.Duration
+
+	done      chan struct{}
+	closeOnce sync.Once
+	pending   sync.WaitGroup
+
+	resultsMu      sync.RWMutex
+	resultHandlers []func(ctx context.Context, result *entity.ProcessingResult)
+
+	messages atomic.Int64
+	bytes    atomic.Int64
+}
+
+// NewBroker создает брокер в памяти процесса
+func NewBroker(cfg config.MemoryBrokerConfig, deadLetters broker.DeadLetterSinkInterface, logger *zap.Logger) *Broker {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = defaultCapacity
+	}
+	if cfg.MaxDeliveries <= 0 {
+		cfg.MaxDeliveries = defaultMaxDeliveries
+	}
+	if cfg.RedeliveryDelay <= 0 {
+		cfg.RedeliveryDelay = defaultRedeliveryDelay
+	}
+
+	logger.Info("Memory broker initialized",
+		zap.Int("capacity", cfg.Capacity),
+		zap.Int("maxDeliveries", cfg.MaxDeliveries),
+		zap.Duration("redeliveryDelay", cfg.RedeliveryDelay),
+	)
+
+	return &Broker{
+		queue:           make(chan *message, cfg.Capacity),
+		logger:          logger,
+		deadLetters:     deadLetters,
+		maxDeliveries:   cfg.MaxDeliveries,
+		redeliveryDelay: cfg.RedeliveryDelay,
+		done:            make(chan struct{}),
+	}
+}
+
+// PublishProcessingTask помещает задачу в очередь. Если очередь заполнена,
+// вызов ждет освобождения места до отмены контекста
+func (b *Broker) PublishProcessingTask(ctx context.Context, task *entity.ProcessingTask) error {
+	taskJSON, err := json.Marshal(task)
+	if err != nil {
+		b.logger.Error("Failed to marshal task", zap.Error(err), zap.String("taskId", task.ID))
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	if err := b.enqueue(ctx, &message{value: taskJSON, taskID: task.ID, imageID: task.ImageID}); err != nil {
+		b.logger.Error("Failed to publish message",
+			zap.Error(err),
+			zap.String("taskId", task.ID),
+			zap.String("imageId", task.ImageID),
+		)
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	b.logger.Info("Processing task published successfully",
+		zap.String("taskId", task.ID),
+		zap.String("imageId", task.ImageID),
+		zap.Int("operationsCount", len(task.Operations)),
+	)
+	return nil
+}
+
+// PublishBatch публикует несколько задач в порядке списка
+func (b *Broker) PublishBatch(ctx context.Context, tasks []*entity.ProcessingTask) error {
+	for i, task := range tasks {
+		if err := b.PublishProcessingTask(ctx, task); err != nil {
+			return fmt.Errorf("failed to publish batch: %d of %d published: %w", i, len(tasks), err)
+		}
+	}
+	return nil
+}
+
+// OnResult подписывает обработчик на результаты обработки. Обработчики
+// вызываются синхронно в горутине воркера, опубликовавшего результат
+func (b *Broker) OnResult(handler func(ctx context.Context, result *entity.ProcessingResult)) {
+	b.resultsMu.Lock()
+	defer b.resultsMu.Unlock()
+	b.resultHandlers = append(b.resultHandlers, handler)
+}
+
+// PublishProcessingResult передает результат обработки подписчикам процесса.
+// Внешних подписчиков у брокера в памяти нет, поэтому без подписчиков
+// результат только записывается в лог
+func (b *Broker) PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error {
+	select {
+	case <-b.done:
+		return ErrBrokerClosed
+	default:
+	}
+
+	b.resultsMu.RLock()
+	handlers := b.resultHandlers
+	b.resultsMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, result)
+	}
+
+	b.logger.Info("Processing result published successfully",
+		zap.String("taskId", result.ID),
+		zap.String("imageId", result.ImageID),
+		zap.String("status", string(result.Status)),
+		zap.Int("subscribers", len(handlers)),
+	)
+	return nil
+}
+
+func (b *Broker) enqueue(ctx context.Context, msg *message) error {
+	select {
+	case <-b.done:
+		return ErrBrokerClosed
+	default:
+	}
+
+	select {
+	case b.queue <- msg:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrQueueFull, ctx.Err())
+	case <-b.done:
+		return ErrBrokerClosed
+	}
+}
+
+// Start читает сообщения из очереди и передает их обработчику до отмены
+// контекста или закрытия брокера. Несколько вызовов Start делят одну очередь
+func (b *Broker) Start(ctx context.Context, handler func(ctx context.Context, task *entity.ProcessingTask) error) error {
+	b.logger.Info("Starting memory broker consumer")
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Info("Consumer stopped by context")
+			return ctx.Err()
+		case <-b.done:
+			b.logger.Info("Consumer stopped: broker closed")
+			return nil
+		case msg := <-b.queue:
+			b.deliver(ctx, msg, handler)
+		}
+	}
+}
+
+// deliver передает сообщение обработчику и подтверждает его или возвращает в очередь
+func (b *Broker) deliver(ctx context.Context, msg *message, handler func(ctx context.Context, task *entity.ProcessingTask) error) {
+	b.messages.Add(1)
+	b.bytes.Add(int64(len(msg.value)))
+	msg.deliveries++
+
+	var task entity.ProcessingTask
+	if err := json.Unmarshal(msg.value, &task); err != nil {
+		// Повторная доставка не исправит сообщение
+		b.logger.Error("Failed to unmarshal task", zap.Error(err), zap.String("taskId", msg.taskID))
+		b.storeDeadLetter(ctx, msg, fmt.Errorf("failed to unmarshal task: %w", err))
+		return
+	}
+
+	startTime := time.Now()
+	err := handler(ctx, &task)
+	duration := time.Since(startTime)
+
+	if err == nil {
+		b.logger.Info("Message processed and acknowledged",
+			zap.String("taskId", task.ID),
+			zap.String("imageId", task.ImageID),
+			zap.Duration("duration", duration),
+		)
+		return
+	}
+
+	b.logger.Error("Task processing failed",
+		zap.Error(err),
+		zap.String("taskId", task.ID),
+		zap.Int("delivery", msg.deliveries),
+		zap.Duration("duration", duration),
+	)
+	if msg.deliveries >= b.maxDeliveries {
+		b.storeDeadLetter(ctx, msg, err)
+		return
+	}
+	b.redeliver(msg)
+}
+
+// storeDeadLetter сохраняет сообщение, которое больше не будет доставлено
+func (b *Broker) storeDeadLetter(ctx context.Context, msg *message, cause error) {
+	if b.deadLetters == nil {
+		b.logger.Error("Message dropped after max deliveries",
+			zap.String("taskId", msg.taskID),
+			zap.Int("deliveries", msg.deliveries),
+		)
+		return
+	}
+
+	letter := &entity.DeadLetter{
+		TaskID:  msg.taskID,
+		ImageID: msg.imageID,
+		Source:  config.BrokerTypeMemory,
+		Payload: msg.value,
+		Headers: map[string]string{
+			"task-id":  msg.taskID,
+			"image-id": msg.imageID,
+		},
+		Error:    cause.Error(),
+		Attempts: msg.deliveries,
+	}
+	if err := b.deadLetters.StoreDeadLetter(ctx, letter); err != nil {
+		b.logger.Error("Failed to store dead letter, message dropped",
+			zap.Error(err),
+			zap.String("taskId", msg.taskID),
+		)
+		return
+	}
+
+	b.logger.Warn("Message moved to dead-letter queue",
+		zap.String("deadLetterId", letter.ID),
+		zap.String("taskId", msg.taskID),
+		zap.Int("deliveries", msg.deliveries),
+	)
+}
+
+// redeliver возвращает сообщение в очередь после задержки
+func (b *Broker) redeliver(msg *message) {
+
+	b.pending.Add(1)
+	go func() {
+		defer b.pending.Done()
+
+		timer := time.NewTimer(b.redeliveryDelay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-b.done:
+			b.logger.Warn("Pending redelivery discarded: broker closed", zap.String("taskId", msg.taskID))
+			return
+		}
+
+		// После отправки сообщением владеет consumer, поэтому поля читаются заранее
+		taskID, delivery := msg.taskID, msg.deliveries+1
+
+		// Ожидание места в очереди ограничено только закрытием брокера:
+		// сообщение уже было принято и не должно теряться из-за нагрузки
+		select {
+		case b.queue <- msg:
+			b.logger.Info("Message redelivered",
+				zap.String("taskId", taskID),
+				zap.Int("delivery", delivery),
+			)
+		case <-b.done:
+			b.logger.Warn("Pending redelivery discarded: broker closed", zap.String("taskId", taskID))
+		}
+	}()
+}
+
+// ReadBatch забирает из очереди до maxMessages сообщений без ожидания.
+// Как и у Kafka consumer, сообщения подтверждаются при чтении
+func (b *Broker) ReadBatch(ctx context.Context, maxMessages int) ([]*entity.ProcessingTask, error) {
+	tasks := make([]*entity.ProcessingTask, 0, maxMessages)
+
+	for len(tasks) < maxMessages {
+		select {
+		case <-ctx.Done():
+			return tasks, ctx.Err()
+		case msg := <-b.queue:
+			b.messages.Add(1)
+			b.bytes.Add(int64(len(msg.value)))
+
+			var task entity.ProcessingTask
+			if err := json.Unmarshal(msg.value, &task); err != nil {
+				b.logger.Error("Failed to unmarshal task", zap.Error(err))
+				continue
+			}
+			tasks = append(tasks, &task)
+		default:
+			return tasks, nil
+		}
+	}
+
+	return tasks, nil
+}
+
+// Stats возвращает количество и объем доставленных сообщений
+func (b *Broker) Stats() (int64, int64) {
+	return b.messages.Load(), b.bytes.Load()
+}
+
+// Close останавливает доставку. Сообщения, оставшиеся в очереди, теряются.
+// Брокер закрывают и API, и воркеры, поэтому повторный вызов безопасен
+func (b *Broker) Close() error {
+	b.closeOnce.Do(func() {
+		b.logger.Info("Closing memory broker", zap.Int("queued", len(b.queue)))
+		close(b.done)
+		b.pending.Wait()
+	})
+	return nil
+}
@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeDeadLetters запоминает сообщения, переданные в dead-letter очередь
+type fakeDeadLetters struct {
+	mu      sync.Mutex
+	letters []*entity.DeadLetter
+}
+
+func (d *fakeDeadLetters) StoreDeadLetter(ctx context.Context, letter *entity.DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.letters = append(d.letters, letter)
+	return nil
+}
+
+func (d *fakeDeadLetters) stored() []*entity.DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*entity.DeadLetter(nil), d.letters...)
+}
+
+func newTestBroker(capacity, maxDeliveries int) *Broker {
+	return newTestBrokerWithDeadLetters(capacity, maxDeliveries, nil)
+}
+
+func newTestBrokerWithDeadLetters(capacity, maxDeliveries int, deadLetters *fakeDeadLetters) *Broker {
+	var sink broker.DeadLetterSinkInterface
+	if deadLetters != nil {
+		sink = deadLetters
+	}
+	return NewBroker(config.MemoryBrokerConfig{
+		Capacity:        capacity,
+		MaxDeliveries:   maxDeliveries,
+		RedeliveryDelay: time.Millisecond,
+	}, sink, zap.NewNop())
+}
+
+// consume запускает consumer и возвращает функцию остановки
+func consume(b *Broker, handler func(ctx context.Context, task *entity.ProcessingTask) error) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = b.Start(ctx, handler)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroker_DeliversAndAcknowledges(t *testing.T) {
+	b := newTestBroker(10, 3)
+	defer b.Close()
+
+	var mu sync.Mutex
+	var received []string
+	stop := consume(b, func(ctx context.Context, task *entity.ProcessingTask) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, task.ID)
+		return nil
+	})
+	defer stop()
+
+	tasks := []*entity.ProcessingTask{{ID: "1", ImageID: "a"}, {ID: "2", ImageID: "b"}}
+	if err := b.PublishBatch(context.Background(), tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	})
+	if messages, _ := b.Stats(); messages != 2 {
+		t.Fatalf("expected 2 delivered messages, got %d", messages)
+	}
+}
+
+func TestBroker_RedeliversOnHandlerError(t *testing.T) {
+	b := newTestBroker(10, 3)
+	defer b.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+	stop := consume(b, func(ctx context.Context, task *entity.ProcessingTask) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// Обработчик может менять задачу, повторная доставка получает исходную
+		task.ImageID = "changed"
+		if attempts < 2 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	defer stop()
+
+	if err := b.PublishProcessingTask(context.Background(), &entity.ProcessingTask{ID: "1", ImageID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts == 2
+	})
+
+	// Подтвержденное сообщение больше не доставляется
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", attempts)
+	}
+}
+
+func TestBroker_DeadLettersAfterMaxDeliveries(t *testing.T) {
+	deadLetters := &fakeDeadLetters{}
+	b := newTestBrokerWithDeadLetters(10, 3, deadLetters)
+	defer b.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+	stop := consume(b, func(ctx context.Context, task *entity.ProcessingTask) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return errors.New("permanent failure")
+	})
+	defer stop()
+
+	if err := b.PublishProcessingTask(context.Background(), &entity.ProcessingTask{ID: "1", ImageID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		return len(deadLetters.stored()) == 1
+	})
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Fatalf("expected 3 deliveries, got %d", attempts)
+	}
+
+	letter := deadLetters.stored()[0]
+	if letter.TaskID != "1" || letter.ImageID != "a" || letter.Attempts != 3 || letter.Error != "permanent failure" {
+		t.Fatalf("unexpected dead letter: %+v", letter)
+	}
+	if len(letter.Payload) == 0 || letter.Source != config.BrokerTypeMemory {
+		t.Fatalf("dead letter must keep original payload and source: %+v", letter)
+	}
+}
+
+func TestBroker_PublishesResultsToSubscribers(t *testing.T) {
+	b := newTestBroker(10, 3)
+	defer b.Close()
+
+	var received []*entity.ProcessingResult
+	b.OnResult(func(ctx context.Context, result *entity.ProcessingResult) {
+		received = append(received, result)
+	})
+
+	result := &entity.ProcessingResult{ID: "1", ImageID: "a", Status: entity.StatusCompleted}
+	if err := b.PublishProcessingResult(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0] != result {
+		t.Fatalf("expected result to be delivered to subscriber, got %v", received)
+	}
+
+	_ = b.Close()
+	if err := b.PublishProcessingResult(context.Background(), result); !errors.Is(err, ErrBrokerClosed) {
+		t.Fatalf("expected ErrBrokerClosed, got %v", err)
+	}
+}
+
+func TestBroker_BoundedCapacity(t *testing.T) {
+	b := newTestBroker(1, 3)
+	defer b.Close()
+
+	if err := b.PublishProcessingTask(context.Background(), &entity.ProcessingTask{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.PublishProcessingTask(ctx, &entity.ProcessingTask{ID: "2"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	tasks, err := b.ReadBatch(context.Background(), 10)
+	if err != nil || len(tasks) != 1 || tasks[0].ID != "1" {
+		t.Fatalf("unexpected batch: %v, %v", tasks, err)
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := newTestBroker(10, 3)
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("repeated close: %v", err)
+	}
+
+	if err := b.PublishProcessingTask(context.Background(), &entity.ProcessingTask{ID: "1"}); !errors.Is(err, ErrBrokerClosed) {
+		t.Fatalf("expected ErrBrokerClosed, got %v", err)
+	}
+	if err := b.Start(context.Background(), nil); err != nil {
+		t.Fatalf("start on closed broker: %v", err)
+	}
+}
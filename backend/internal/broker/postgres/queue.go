@@ -0,0 +1,591 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для незаданных параметров PostgresBrokerConfig
+const (
+	defaultPollInterval      = time.Second
+	defaultVisibilityTimeout = time.Minute
+	defaultMaxAttempts       = config.DefaultJobMaxAttempts
+	defaultRetryBackoff      = 5 * time.Second
+	defaultMaxRetryBackoff   = 5 * time.Minute
+)
+
+// ResultsChannel - канал LISTEN/NOTIFY, в который публикуются результаты обработки
+const ResultsChannel = "image_processed"
+
+// maxNotifyPayload - ограничение PostgreSQL на размер payload в NOTIFY
+const maxNotifyPayload = 8000
+
+// Статусы, в которые очередь переводит задачу после неудачной попытки
+const (
+	jobStatusPending = "pending"
+	jobStatusFailed  = "failed"
+	jobStatusTimeout = entity.JobStatusTimeout
+)
+
+// Queue - очередь задач на таблице processing_jobs. Строка задачи одновременно
+// является сообщением, поэтому состояние задачи и очереди не расходятся.
+// Consumer арендует задачу через SELECT ... FOR UPDATE SKIP LOCKED: статус
+// меняется на processing, а locked_until задает срок видимости. Пока обработчик
+// работает, аренда продлевается; если процесс упал, задача снова становится
+// доступной после истечения срока. Попытки и задержка до следующей попытки
+// хранятся в строке. Задача, исчерпавшая попытки, сохраняется в dead-letter очередь
+type Queue struct {
+	db          *pgxpool.Pool
+	logger      *zap.Logger
+	cfg         config.PostgresBrokerConfig
+	deadLetters broker.DeadLetterSinkInterface
+
+	messages atomic.Int64
+	bytes    atomic.Int64
+}
+
+// lease - задача, арендованная consumer
+type lease struct {
+	task        *entity.ProcessingTask
+	token       string
+	attempts    int
+	maxAttempts int
+	size        int
+}
+
+// NewQueue создает очередь задач в PostgreSQL
+func NewQueue(db *pgxpool.Pool, cfg config.PostgresBrokerConfig, deadLetters broker.DeadLetterSinkInterface, logger *zap.Logger) *Queue {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.MaxRetryBackoff < cfg.RetryBackoff {
+		cfg.MaxRetryBackoff = max(defaultMaxRetryBackoff, cfg.RetryBackoff)
+	}
+
+	logger.Info("Postgres job queue initialized",
+		zap.Duration("pollInterval", cfg.PollInterval),
+		zap.Duration("visibilityTimeout", cfg.VisibilityTimeout),
+		zap.Int("maxAttempts", cfg.MaxAttempts),
+	)
+
+	return &Queue{
+		db:          db,
+		logger:      logger,
+		cfg:         cfg,
+		deadLetters: deadLetters,
+	}
+}
+
+// PublishProcessingTask делает задачу доступной для обработки. Строка задачи
+// создается, если ее еще нет; задача, завершившаяся ошибкой, публикуется
+// повторно со сброшенными попытками. У ожидающей задачи сохраняются попытки
+// и задержка до следующей попытки, назначенная после ошибки. Арендованная, успешно выполненная
+// и отмененная задачи не меняются: relay outbox может опубликовать задачу,
+// которую воркер уже получил из этой же таблицы или которую отменили через API
+func (q *Queue) PublishProcessingTask(ctx context.Context, task *entity.ProcessingTask) error {
+	if err := q.publish(ctx, q.db, task); err != nil {
+		q.logger.Error("Failed to publish task",
+			zap.Error(err),
+			zap.String("taskId", task.ID),
+			zap.String("imageId", task.ImageID),
+		)
+		return err
+	}
+
+	q.logger.Info("Processing task published successfully",
+		zap.String("taskId", task.ID),
+		zap.String("imageId", task.ImageID),
+		zap.Int("operationsCount", len(task.Operations)),
+	)
+	return nil
+}
+
+// PublishBatch публикует несколько задач в одной транзакции
+func (q *Queue) PublishBatch(ctx context.Context, tasks []*entity.ProcessingTask) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	tx, err := q.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, task := range tasks {
+		if err := q.publish(ctx, tx, task); err != nil {
+			return fmt.Errorf("failed to publish batch: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to publish batch: %w", err)
+	}
+
+	q.logger.Info("Batch published successfully", zap.Int("count", len(tasks)))
+	return nil
+}
+
+// PublishProcessingResult отправляет результат обработки в канал ResultsChannel
+// через pg_notify. Уведомление доставляется только подключенным слушателям
+// (LISTEN image_processed) после фиксации транзакции и не хранится
+func (q *Queue) PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		q.logger.Error("Failed to marshal result", zap.Error(err), zap.String("taskId", result.ID))
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	if len(resultJSON) >= maxNotifyPayload {
+		return fmt.Errorf("failed to publish result: payload of %d bytes exceeds NOTIFY limit", len(resultJSON))
+	}
+
+	if _, err := q.db.Exec(ctx, `SELECT pg_notify($1, $2)`, ResultsChannel, string(resultJSON)); err != nil {
+		q.logger.Error("Failed to publish result",
+			zap.Error(err),
+			zap.String("taskId", result.ID),
+			zap.String("imageId", result.ImageID),
+		)
+		return fmt.Errorf("failed to publish result: %w", err)
+	}
+
+	q.logger.Info("Processing result published successfully",
+		zap.String("taskId", result.ID),
+		zap.String("imageId", result.ImageID),
+		zap.String("status", string(result.Status)),
+	)
+	return nil
+}
+
+// Start арендует задачи по одной и передает их обработчику до отмены контекста.
+// Несколько вызовов Start, в том числе из разных процессов, не получают одну задачу
+func (q *Queue) Start(ctx context.Context, handler func(ctx context.Context, task *entity.ProcessingTask) error) error {
+	q.logger.Info("Starting Postgres job queue consumer")
+
+	for {
+		if err := ctx.Err(); err != nil {
+			q.logger.Info("Consumer stopped by context")
+			return err
+		}
+
+		leases, err := q.lease(ctx, 1)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			q.logger.Error("Failed to lease task", zap.Error(err))
+		}
+
+		if len(leases) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(q.cfg.PollInterval):
+			}
+			continue
+		}
+
+		q.process(ctx, leases[0], handler)
+	}
+}
+
+// process выполняет обработчик, продлевая аренду, и фиксирует результат в строке задачи
+func (q *Queue) process(ctx context.Context, l *lease, handler func(ctx context.Context, task *entity.ProcessingTask) error) {
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		q.heartbeat(heartbeatCtx, l)
+	}()
+
+	startTime := time.Now()
+	err := handler(ctx, l.task)
+	duration := time.Since(startTime)
+
+	stopHeartbeat()
+	<-heartbeatDone
+
+	// Результат фиксируется и при остановке воркера, поэтому не зависит от ctx
+	finishCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err == nil {
+		if ackErr := q.ack(finishCtx, l); ackErr != nil {
+			q.logger.Error("Failed to acknowledge task", zap.Error(ackErr), zap.String("taskId", l.task.ID))
+			return
+		}
+		q.logger.Info("Task processed and acknowledged",
+			zap.String("taskId", l.task.ID),
+			zap.String("imageId", l.task.ImageID),
+			zap.Int("attempt", l.attempts),
+			zap.Duration("duration", duration),
+		)
+		return
+	}
+
+	q.logger.Error("Task processing failed",
+		zap.Error(err),
+		zap.String("taskId", l.task.ID),
+		zap.Int("attempt", l.attempts),
+		zap.Int("maxAttempts", l.maxAttempts),
+		zap.Duration("duration", duration),
+	)
+	if nackErr := q.nack(finishCtx, l, err); nackErr != nil {
+		q.logger.Error("Failed to release task", zap.Error(nackErr), zap.String("taskId", l.task.ID))
+	}
+}
+
+// ReadBatch арендует до maxMessages задач без ожидания. Задачи остаются
+// арендованными: если вызывающий не завершит их, они снова станут доступны
+// после истечения срока видимости
+func (q *Queue) ReadBatch(ctx context.Context, maxMessages int) ([]*entity.ProcessingTask, error) {
+	leases, err := q.lease(ctx, maxMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*entity.ProcessingTask, 0, len(leases))
+	for _, l := range leases {
+		tasks = append(tasks, l.task)
+	}
+	return tasks, nil
+}
+
+// Stats возвращает количество и объем арендованных задач
+func (q *Queue) Stats() (int64, int64) {
+	return q.messages.Load(), q.bytes.Load()
+}
+
+// Close ничего не освобождает: пулом соединений владеет вызывающий
+func (q *Queue) Close() error {
+	q.logger.Info("Closing Postgres job queue")
+	return nil
+}
+
+// execer - общий метод пула и транзакции
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func (q *Queue) publish(ctx context.Context, db execer, task *entity.ProcessingTask) error {
+	operationsJSON, err := json.Marshal(task.Operations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	query := `
+		INSERT INTO processing_jobs (id, image_id, operations, preset_name, preset_version, supersede, status, attempts, max_attempts, available_at, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), $6, 'pending', 0, $7, now(), now(), now())
+		ON CONFLICT (id) DO UPDATE SET
+			status = 'pending',
+			attempts = CASE WHEN processing_jobs.status = 'pending' THEN processing_jobs.attempts ELSE 0 END,
+			max_attempts = EXCLUDED.max_attempts,
+			available_at = CASE WHEN processing_jobs.status = 'pending'
+			                    THEN GREATEST(processing_jobs.available_at, EXCLUDED.available_at)
+			                    ELSE EXCLUDED.available_at END,
+			locked_until = NULL,
+			locked_by = NULL,
+			error_message = NULL,
+			completed_at = NULL
+		WHERE processing_jobs.status NOT IN ('processing', 'completed', 'cancelled')
+	`
+
+	_, err = db.Exec(ctx, query, task.ID, task.ImageID, operationsJSON, task.PresetName, task.PresetVersion, task.Supersede, q.cfg.MaxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to publish task: %w", err)
+	}
+	return nil
+}
+
+// lease арендует до limit доступных задач: ожидающих, у которых наступило
+// время попытки, и тех, чья аренда истекла. Задачи с истекшей арендой
+// и исчерпанными попытками сначала переводятся в failed
+func (q *Queue) lease(ctx context.Context, limit int) ([]*lease, error) {
+	if err := q.expireLeases(ctx); err != nil {
+		return nil, err
+	}
+
+	token := uuid.New().String()
+	leaseQuery := `
+		WITH next AS (
+			SELECT id FROM processing_jobs
+			WHERE (status = 'pending' AND available_at <= now())
+			   OR (status = 'processing' AND locked_until < now() AND attempts < max_attempts)
+			ORDER BY available_at, created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE processing_jobs j
+		SET status = 'processing',
+		    attempts = j.attempts + 1,
+		    started_at = now(),
+		    locked_until = now() + make_interval(secs => $2),
+		    locked_by = $3
+		FROM next, images i
+		WHERE j.id = next.id AND i.id = j.image_id
+		RETURNING j.id, j.image_id, j.operations, COALESCE(j.preset_name, ''), COALESCE(j.preset_version, 0), j.supersede,
+		          i.original_path, i.bucket, i.format, j.attempts, j.max_attempts
+	`
+
+	rows, err := q.db.Query(ctx, leaseQuery, limit, q.cfg.VisibilityTimeout.Seconds(), token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var leases []*lease
+	for rows.Next() {
+		var task entity.ProcessingTask
+		var operationsJSON []byte
+		l := &lease{task: &task, token: token}
+
+		if err := rows.Scan(
+			&task.ID,
+			&task.ImageID,
+			&operationsJSON,
+			&task.PresetName,
+			&task.PresetVersion,
+			&task.Supersede,
+			&task.OriginalPath,
+			&task.Bucket,
+			&task.Format,
+			&l.attempts,
+			&l.maxAttempts,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan leased task: %w", err)
+		}
+		if err := json.Unmarshal(operationsJSON, &task.Operations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal operations: %w", err)
+		}
+		l.size = len(operationsJSON)
+		task.Attempt = l.attempts
+		task.MaxAttempts = l.maxAttempts
+
+		q.messages.Add(1)
+		q.bytes.Add(int64(l.size))
+		leases = append(leases, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lease tasks: %w", err)
+	}
+
+	return leases, nil
+}
+
+// expireLeases переводит в failed задачи с истекшей арендой и исчерпанными
+// попытками и сохраняет их в dead-letter очередь
+func (q *Queue) expireLeases(ctx context.Context) error {
+	query := `
+		UPDATE processing_jobs
+		SET status = 'failed',
+		    error_message = 'visibility timeout expired after ' || attempts || ' attempts',
+		    locked_until = NULL, locked_by = NULL, completed_at = now()
+		WHERE status = 'processing' AND locked_until < now() AND attempts >= max_attempts
+		RETURNING id, image_id, operations, COALESCE(preset_name, ''), COALESCE(preset_version, 0), attempts, error_message
+	`
+
+	rows, err := q.db.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to expire leases: %w", err)
+	}
+
+	type expiredTask struct {
+		task     entity.ProcessingTask
+		attempts int
+		errorMsg string
+	}
+	var expired []expiredTask
+	for rows.Next() {
+		var e expiredTask
+		var operationsJSON []byte
+		if err := rows.Scan(&e.task.ID, &e.task.ImageID, &operationsJSON, &e.task.PresetName, &e.task.PresetVersion, &e.attempts, &e.errorMsg); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan expired task: %w", err)
+		}
+		if err := json.Unmarshal(operationsJSON, &e.task.Operations); err != nil {
+			q.logger.Warn("Failed to unmarshal operations of expired task", zap.Error(err), zap.String("taskId", e.task.ID))
+		}
+		expired = append(expired, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to expire leases: %w", err)
+	}
+
+	if len(expired) > 0 {
+		q.logger.Warn("Tasks failed after lease expiry", zap.Int("count", len(expired)))
+	}
+	for _, e := range expired {
+		q.storeDeadLetter(ctx, &e.task, e.attempts, errors.New(e.errorMsg))
+	}
+	return nil
+}
+
+// storeDeadLetter сохраняет задачу, исчерпавшую попытки
+func (q *Queue) storeDeadLetter(ctx context.Context, task *entity.ProcessingTask, attempts int, cause error) {
+	if q.deadLetters == nil {
+		return
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		q.logger.Error("Failed to marshal dead letter", zap.Error(err), zap.String("taskId", task.ID))
+		return
+	}
+
+	letter := &entity.DeadLetter{
+		TaskID:  task.ID,
+		ImageID: task.ImageID,
+		Source:  config.BrokerTypePostgres,
+		Payload: payload,
+		Headers: map[string]string{
+			"task-id":  task.ID,
+			"image-id": task.ImageID,
+		},
+		Error:    cause.Error(),
+		Attempts: attempts,
+	}
+	if err := q.deadLetters.StoreDeadLetter(ctx, letter); err != nil {
+		// Задача уже в статусе failed, поэтому не теряется
+		q.logger.Error("Failed to store dead letter", zap.Error(err), zap.String("taskId", task.ID))
+		return
+	}
+
+	q.logger.Warn("Task moved to dead-letter queue",
+		zap.String("deadLetterId", letter.ID),
+		zap.String("taskId", task.ID),
+		zap.Int("attempts", attempts),
+	)
+}
+
+// heartbeat продлевает аренду каждые полсрока видимости
+func (q *Queue) heartbeat(ctx context.Context, l *lease) {
+	ticker := time.NewTicker(q.cfg.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	query := `
+		UPDATE processing_jobs
+		SET locked_until = now() + make_interval(secs => $1)
+		WHERE id = $2 AND locked_by = $3 AND status = 'processing'
+	`
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := q.db.Exec(ctx, query, q.cfg.VisibilityTimeout.Seconds(), l.task.ID, l.token)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					q.logger.Warn("Failed to extend lease", zap.Error(err), zap.String("taskId", l.task.ID))
+				}
+				continue
+			}
+			if tag.RowsAffected() == 0 {
+				// Задачу отменили через API или аренда потеряна
+				return
+			}
+		}
+	}
+}
+
+// ack завершает аренду успешно обработанной задачи
+func (q *Queue) ack(ctx context.Context, l *lease) error {
+	query := `
+		UPDATE processing_jobs
+		SET status = 'completed', locked_until = NULL, locked_by = NULL,
+		    completed_at = COALESCE(completed_at, now())
+		WHERE id = $1 AND locked_by = $2
+	`
+
+	_, err := q.db.Exec(ctx, query, l.task.ID, l.token)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge task: %w", err)
+	}
+	return nil
+}
+
+// nack возвращает задачу в очередь с задержкой или переводит ее в failed,
+// если попытки исчерпаны. Задача, прерванная по таймауту, не повторяется
+// и получает статус timeout
+func (q *Queue) nack(ctx context.Context, l *lease, cause error) error {
+	status := jobStatusPending
+	switch {
+	case errors.Is(cause, entity.ErrProcessingTimeout):
+		status = jobStatusTimeout
+	case l.attempts >= l.maxAttempts:
+		status = jobStatusFailed
+	}
+	backoff := q.backoff(l.attempts)
+
+	query := `
+		UPDATE processing_jobs
+		SET status = $1::varchar,
+		    error_message = $2,
+		    available_at = now() + make_interval(secs => $3),
+		    locked_until = NULL, locked_by = NULL,
+		    completed_at = CASE WHEN $1::varchar IN ('failed', 'timeout') THEN now() ELSE NULL END
+		WHERE id = $4 AND locked_by = $5
+	`
+
+	_, err := q.db.Exec(ctx, query, status, cause.Error(), backoff.Seconds(), l.task.ID, l.token)
+	if err != nil {
+		return fmt.Errorf("failed to release task: %w", err)
+	}
+
+	switch status {
+	case jobStatusTimeout:
+		q.logger.Error("Task processing timed out",
+			zap.String("taskId", l.task.ID),
+			zap.Int("attempts", l.attempts),
+		)
+		q.storeDeadLetter(ctx, l.task, l.attempts, cause)
+	case jobStatusFailed:
+		q.logger.Error("Task failed after max attempts",
+			zap.String("taskId", l.task.ID),
+			zap.Int("attempts", l.attempts),
+		)
+		q.storeDeadLetter(ctx, l.task, l.attempts, cause)
+	default:
+		q.logger.Info("Task scheduled for retry",
+			zap.String("taskId", l.task.ID),
+			zap.Int("attempt", l.attempts),
+			zap.Duration("backoff", backoff),
+		)
+	}
+	return nil
+}
+
+// backoff возвращает задержку перед следующей попыткой: RetryBackoff,
+// удваиваемая с каждой попыткой, но не больше MaxRetryBackoff
+func (q *Queue) backoff(attempts int) time.Duration {
+	backoff := q.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= q.cfg.MaxRetryBackoff {
+			return q.cfg.MaxRetryBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"imageprocessor/backend/internal/config"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNewQueue_AppliesDefaults(t *testing.T) {
+	q := NewQueue(nil, config.PostgresBrokerConfig{}, nil, zap.NewNop())
+
+	if q.cfg.PollInterval != defaultPollInterval {
+		t.Errorf("pollInterval = %v, want %v", q.cfg.PollInterval, defaultPollInterval)
+	}
+	if q.cfg.VisibilityTimeout != defaultVisibilityTimeout {
+		t.Errorf("visibilityTimeout = %v, want %v", q.cfg.VisibilityTimeout, defaultVisibilityTimeout)
+	}
+	if q.cfg.MaxAttempts != defaultMaxAttempts {
+		t.Errorf("maxAttempts = %d, want %d", q.cfg.MaxAttempts, defaultMaxAttempts)
+	}
+	if q.cfg.RetryBackoff != defaultRetryBackoff || q.cfg.MaxRetryBackoff != defaultMaxRetryBackoff {
+		t.Errorf("backoff = %v..%v, want %v..%v",
+			q.cfg.RetryBackoff, q.cfg.MaxRetryBackoff, defaultRetryBackoff, defaultMaxRetryBackoff)
+	}
+}
+
+func TestQueue_Backoff(t *testing.T) {
+	q := NewQueue(nil, config.PostgresBrokerConfig{
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 5 * time.Second,
+	}, nil, zap.NewNop())
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -2,14 +2,23 @@ package config
 
 import (
 	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
 	"imageprocessor/backend/pkg/lib/logger/zaplogger"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
+const (
+	transformSigningKeyEnv = "TRANSFORM_SIGNING_KEY"
+	storageSigningKeyEnv   = "STORAGE_SIGNING_KEY"
+	adminAPIKeyEnv         = "ADMIN_API_KEY"
+	webhookSecretEnv       = "WEBHOOK_SECRET"
+)
+
 func LoadServiceConfig(log *zap.Logger, configPath, dbPasswordPath, cloudAccessKeyPath, cloudSecretKeyPath string) (*ServiceConfig, error) {
 	v := viper.New()
 	v.SetConfigType("yaml")
@@ -30,20 +39,65 @@ func LoadServiceConfig(log *zap.Logger, configPath, dbPasswordPath, cloudAccessK
 		return &ServiceConfig{}, err
 	}
 
-	CloudAccessKey, CloudSecretKey, err := serviceConfig.GetCloudKeys(cloudAccessKeyPath, cloudSecretKeyPath)
-	if err != nil {
-		log.Error("Error getting cloud storage keys", zaplogger.Err(err))
-		return &ServiceConfig{}, err
+	serviceConfig.DbConfig.DBConn = dbConnStr
+
+	// Ключи доступа нужны только S3; локальному хранилищу нужен ключ подписи ссылок
+	if serviceConfig.CloudStorageConfig.IsFilesystem() {
+		if signingKey := os.Getenv(storageSigningKeyEnv); signingKey != "" {
+			serviceConfig.CloudStorageConfig.Filesystem.SigningKey = signingKey
+		}
+	} else {
+		CloudAccessKey, CloudSecretKey, err := serviceConfig.GetCloudKeys(cloudAccessKeyPath, cloudSecretKeyPath)
+		if err != nil {
+			log.Error("Error getting cloud storage keys", zaplogger.Err(err))
+			return &ServiceConfig{}, err
+		}
+		serviceConfig.CloudStorageConfig.AccessKey = CloudAccessKey
+		serviceConfig.CloudStorageConfig.SecretKey = CloudSecretKey
 	}
+	serviceConfig.ProcessingConfig = serviceConfig.ProcessingConfig.WithDefaults()
 
-	serviceConfig.DbConfig.DBConn = dbConnStr
-	serviceConfig.CloudStorageConfig.AccessKey = CloudAccessKey
-	serviceConfig.CloudStorageConfig.SecretKey = CloudSecretKey
+	// Ключ подписи URL трансформаций можно переопределить через окружение
+	if signingKey := os.Getenv(transformSigningKeyEnv); signingKey != "" {
+		serviceConfig.TransformConfig.SigningKey = signingKey
+	}
+
+	// Ключ административного API не хранится в файле конфигурации
+	if apiKey := os.Getenv(adminAPIKeyEnv); apiKey != "" {
+		serviceConfig.AdminConfig.APIKey = apiKey
+	}
 
-	log.Info("Config", zap.Any("serviceConfig", serviceConfig))
+	// Ключ подписи callback_url не хранится в файле конфигурации
+	if secret := os.Getenv(webhookSecretEnv); secret != "" {
+		serviceConfig.WebhookConfig.Secret = secret
+	}
+
+	log.Info("Config", zap.Any("serviceConfig", serviceConfig.Redacted()))
 	return serviceConfig, nil
 }
 
+// redactedValue заменяет заданные секреты в выводе конфигурации
+const redactedValue = "[REDACTED]"
+
+// Redacted возвращает копию конфигурации для записи в лог: строка подключения
+// к БД, ключи хранилища, ключи подписи и ключ административного API скрыты
+func (d ServiceConfig) Redacted() ServiceConfig {
+	redact := func(value *string) {
+		if *value != "" {
+			*value = redactedValue
+		}
+	}
+
+	redact(&d.DbConfig.DBConn)
+	redact(&d.CloudStorageConfig.AccessKey)
+	redact(&d.CloudStorageConfig.SecretKey)
+	redact(&d.CloudStorageConfig.Filesystem.SigningKey)
+	redact(&d.TransformConfig.SigningKey)
+	redact(&d.AdminConfig.APIKey)
+	redact(&d.WebhookConfig.Secret)
+	return d
+}
+
 func (d *ServiceConfig) DSN(log *zap.Logger, dbPasswordPath string) (string, error) {
 	password := os.Getenv(dbPasswordPath)
 	if password == "" {
@@ -67,9 +121,15 @@ type ServiceConfig struct {
 	Server             ServerConfig       `mapstructure:"server"`
 	DbConfig           DBConfig           `mapstructure:"database"`
 	BrokerConfig       BrokerConfig       `mapstructure:"broker"`
+	OutboxConfig       OutboxConfig       `mapstructure:"outbox"`
+	BackfillConfig     BackfillConfig     `mapstructure:"backfill"`
+	WebhookConfig      WebhookConfig      `mapstructure:"webhooks"`
+	EventsConfig       EventsConfig       `mapstructure:"events"`
 	WorkerConfig       WorkerConfig       `mapstructure:"worker"`
 	CloudStorageConfig CloudStorageConfig `mapstructure:"cloud"`
 	ProcessingConfig   ProcessingConfig   `mapstructure:"processing"`
+	TransformConfig    TransformConfig    `mapstructure:"transform"`
+	AdminConfig        AdminConfig        `mapstructure:"admin"`
 }
 
 type DBConfig struct {
@@ -93,6 +153,8 @@ type ServerConfig struct {
 }
 
 type BrokerConfig struct {
+	// Type выбирает реализацию брокера: kafka (по умолчанию), postgres или memory
+	Type              string        `yaml:"type"`
 	Brokers           []string      `yaml:"brokers"`
 	Topic             string        `yaml:"topic"`
 	ConsumerGroup     string        `yaml:"consumerGroup"`
@@ -103,6 +165,130 @@ type BrokerConfig struct {
 	FetchMinBytes     int           `yaml:"fetchMinBytes"`
 	FetchMaxBytes     int           `yaml:"fetchMaxBytes"`
 	CommitInterval    time.Duration `yaml:"commitInterval"`
+	// ResultsTopic - топик, в который воркеры публикуют результаты обработки
+	ResultsTopic string `yaml:"resultsTopic"`
+	// Memory - настройки брокера в памяти процесса (режим all-in-one)
+	Memory MemoryBrokerConfig `yaml:"memory"`
+	// Postgres - настройки очереди задач в таблице processing_jobs
+	Postgres PostgresBrokerConfig `yaml:"postgres"`
+}
+
+// Реализации брокера, выбираемые параметром broker.type
+const (
+	BrokerTypeKafka    = "kafka"
+	BrokerTypeMemory   = "memory"
+	BrokerTypePostgres = "postgres"
+)
+
+// DefaultJobMaxAttempts - количество попыток задачи, если broker.postgres.maxAttempts не задан
+const DefaultJobMaxAttempts = 3
+
+type MemoryBrokerConfig struct {
+	// Capacity - максимальное количество сообщений в очереди
+	Capacity int `yaml:"capacity"`
+	// MaxDeliveries - сколько раз сообщение доставляется, прежде чем будет отброшено
+	MaxDeliveries int `yaml:"maxDeliveries"`
+	// RedeliveryDelay - задержка перед повторной доставкой после ошибки обработчика
+	RedeliveryDelay time.Duration `yaml:"redeliveryDelay"`
+}
+
+type PostgresBrokerConfig struct {
+	// PollInterval - пауза между опросами таблицы, когда свободных задач нет
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// VisibilityTimeout - срок аренды задачи; аренда продлевается, пока обработчик работает
+	VisibilityTimeout time.Duration `yaml:"visibilityTimeout"`
+	// MaxAttempts - количество попыток для новых задач
+	MaxAttempts int `yaml:"maxAttempts"`
+	// RetryBackoff - задержка после первой неудачной попытки, далее удваивается
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// MaxRetryBackoff ограничивает задержку между попытками
+	MaxRetryBackoff time.Duration `yaml:"maxRetryBackoff"`
+}
+
+// GetResultsTopic возвращает топик результатов обработки; по умолчанию image-processed
+func (c BrokerConfig) GetResultsTopic() string {
+	if c.ResultsTopic == "" {
+		return entity.KafkaTopicResults
+	}
+	return c.ResultsTopic
+}
+
+// IsMemory проверяет, что выбран брокер в памяти процесса
+func (c BrokerConfig) IsMemory() bool {
+	return strings.EqualFold(c.Type, BrokerTypeMemory)
+}
+
+// JobMaxAttempts возвращает количество попыток, с которым создаются новые задачи
+func (c PostgresBrokerConfig) JobMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultJobMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// IsPostgres проверяет, что выбрана очередь задач в PostgreSQL
+func (c BrokerConfig) IsPostgres() bool {
+	return strings.EqualFold(c.Type, BrokerTypePostgres)
+}
+
+type OutboxConfig struct {
+	// PollInterval - пауза между опросами outbox, когда новых записей нет
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// BatchSize - сколько записей relay арендует за один запрос
+	BatchSize int `yaml:"batchSize"`
+	// LeaseTimeout - на сколько запись скрывается от других relay на время публикации
+	LeaseTimeout time.Duration `yaml:"leaseTimeout"`
+	// RetryBackoff - задержка после первой неудачной публикации, далее удваивается
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// MaxRetryBackoff ограничивает задержку между попытками публикации
+	MaxRetryBackoff time.Duration `yaml:"maxRetryBackoff"`
+}
+
+type BackfillConfig struct {
+	// PollInterval - пауза между проверками запущенных backfill, когда пачек к публикации нет
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// BatchSize - размер пачки, если он не указан при создании backfill
+	BatchSize int `yaml:"batchSize"`
+	// MaxBatchSize ограничивает размер пачки, указанный при создании
+	MaxBatchSize int `yaml:"maxBatchSize"`
+	// BatchInterval - пауза между пачками одного backfill, если она не указана при создании
+	BatchInterval time.Duration `yaml:"batchInterval"`
+	// LeaseTimeout - на сколько backfill скрывается от других runner на время публикации пачки
+	LeaseTimeout time.Duration `yaml:"leaseTimeout"`
+	// MaxAttempts - после стольких неудачных публикаций пачки подряд backfill переводится в failed
+	MaxAttempts int `yaml:"maxAttempts"`
+	// RetryBackoff - задержка после первой неудачной публикации, далее удваивается
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// MaxRetryBackoff ограничивает задержку между попытками публикации
+	MaxRetryBackoff time.Duration `yaml:"maxRetryBackoff"`
+}
+
+type WebhookConfig struct {
+	// Secret - ключ HMAC-подписи доставок на callback_url; без ключа callback_url
+	// не принимается. Зарегистрированные endpoint подписываются своими ключами
+	Secret string `yaml:"secret"`
+	// PollInterval - пауза между опросами доставок, когда готовых к отправке нет
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// BatchSize - сколько доставок отправляется параллельно
+	BatchSize int `yaml:"batchSize"`
+	// Timeout - таймаут одного HTTP запроса к получателю
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts - количество попыток доставки события
+	MaxAttempts int `yaml:"maxAttempts"`
+	// RetryBackoff - задержка после первой неудачной попытки, далее удваивается
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// MaxRetryBackoff ограничивает задержку между попытками
+	MaxRetryBackoff time.Duration `yaml:"maxRetryBackoff"`
+}
+
+type EventsConfig struct {
+	// BufferSize - сколько событий хода обработки ждут отправки одному клиенту;
+	// события для клиента, не успевающего их читать, отбрасываются
+	BufferSize int `yaml:"bufferSize"`
+	// KeepAlive - интервал комментариев, поддерживающих SSE соединение открытым
+	KeepAlive time.Duration `yaml:"keepAlive"`
+	// ReconnectBackoff - пауза перед повторным LISTEN после потери соединения с БД
+	ReconnectBackoff time.Duration `yaml:"reconnectBackoff"`
 }
 
 type WorkerConfig struct {
@@ -114,6 +300,8 @@ type WorkerConfig struct {
 }
 
 type CloudStorageConfig struct {
+	// Backend выбирает реализацию хранилища: s3 (по умолчанию) или filesystem
+	Backend            string        `yaml:"backend"`
 	Endpoint           string        `yaml:"endpoint"`
 	Region             string        `yaml:"region"`
 	AccessKey          string        `yaml:"accessKey"`
@@ -123,10 +311,33 @@ type CloudStorageConfig struct {
 	PresignedURLExpiry time.Duration `yaml:"presignedURLExpiry"`
 	UploadPartSize     int64         `yaml:"uploadPartSize"`
 	MaxUploadSize      int64         `yaml:"maxUploadSize"`
+	// Filesystem - настройки хранилища в локальной файловой системе
+	Filesystem FilesystemStorageConfig `yaml:"filesystem"`
+}
+
+// Реализации хранилища, выбираемые параметром cloud.backend
+const (
+	StorageBackendS3         = "s3"
+	StorageBackendFilesystem = "filesystem"
+)
+
+type FilesystemStorageConfig struct {
+	// RootDir - каталог, в котором хранятся объекты
+	RootDir string `yaml:"rootDir"`
+	// PublicURL - внешний адрес маршрута /files API, на который указывают presigned ссылки
+	PublicURL string `yaml:"publicURL"`
+	// SigningKey - ключ HMAC для подписи ссылок
+	SigningKey string `yaml:"signingKey"`
+}
+
+// IsFilesystem проверяет, что выбрано хранилище в локальной файловой системе
+func (c CloudStorageConfig) IsFilesystem() bool {
+	return strings.EqualFold(c.Backend, StorageBackendFilesystem)
 }
 
 type ProcessingConfig struct {
 	DefaultThumbnailSize int      `yaml:"defaultThumbnailSize"`
+	MaxThumbnailSize     int      `yaml:"maxThumbnailSize"`
 	DefaultJpegQuality   int      `yaml:"defaultJpegQuality"`
 	MaxImageWidth        int      `yaml:"maxImageWidth"`
 	MaxImageHeight       int      `yaml:"maxImageHeight"`
@@ -134,4 +345,102 @@ type ProcessingConfig struct {
 	WatermarkFontSize    int      `yaml:"watermarkFontSize"`
 	WatermarkText        string   `yaml:"watermarkText"`
 	SupportedFormats     []string `yaml:"supportedFormats"`
+	// FontsDir - каталог с дополнительными шрифтами .ttf/.otf для водяных знаков
+	FontsDir string `yaml:"fontsDir"`
+	// WatermarkAssets сопоставляет имя ассета ключу объекта в хранилище
+	WatermarkAssets map[string]string `yaml:"watermarkAssets"`
+	// Лимиты исходного изображения, проверяемые по заголовку до полного декодирования
+	MaxSourceWidth    int     `yaml:"maxSourceWidth"`
+	MaxSourceHeight   int     `yaml:"maxSourceHeight"`
+	MaxMegapixels     float64 `yaml:"maxMegapixels"`
+	MaxGIFFrames      int     `yaml:"maxGifFrames"`
+	MaxDecodeMemoryMB int     `yaml:"maxDecodeMemoryMB"`
+	// AllowContentMismatch разрешает загрузку, если MIME тип или расширение
+	// не совпадают с форматом содержимого; сохраняется определенный формат
+	AllowContentMismatch bool `yaml:"allowContentMismatch"`
+}
+
+type TransformConfig struct {
+	SigningKey    string        `yaml:"signingKey"`
+	MaxOperations int           `yaml:"maxOperations"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+type AdminConfig struct {
+	// APIKey - ключ доступа к маршрутам /admin; без ключа они отключены
+	APIKey string `yaml:"apiKey"`
+}
+
+// WithDefaults возвращает копию конфигурации, в которой незаданные значения
+// заменены значениями по умолчанию из entity
+func (c ProcessingConfig) WithDefaults() ProcessingConfig {
+	if c.DefaultThumbnailSize <= 0 {
+		c.DefaultThumbnailSize = entity.DefaultThumbnailSize
+	}
+	if c.MaxThumbnailSize <= 0 {
+		c.MaxThumbnailSize = entity.DefaultMaxThumbnailSize
+	}
+	if c.DefaultJpegQuality <= 0 || c.DefaultJpegQuality > 100 {
+		c.DefaultJpegQuality = entity.DefaultJPEGQuality
+	}
+	if c.MaxImageWidth <= 0 {
+		c.MaxImageWidth = entity.DefaultMaxImageWidth
+	}
+	if c.MaxImageHeight <= 0 {
+		c.MaxImageHeight = entity.DefaultMaxImageHeight
+	}
+	if c.WatermarkOpacity <= 0 || c.WatermarkOpacity > 1 {
+		c.WatermarkOpacity = entity.DefaultWatermarkOpacity
+	}
+	if c.WatermarkFontSize <= 0 {
+		c.WatermarkFontSize = entity.DefaultWatermarkFontSize
+	}
+	if c.WatermarkText == "" {
+		c.WatermarkText = entity.DefaultWatermarkText
+	}
+	if c.MaxSourceWidth <= 0 {
+		c.MaxSourceWidth = entity.DefaultMaxSourceWidth
+	}
+	if c.MaxSourceHeight <= 0 {
+		c.MaxSourceHeight = entity.DefaultMaxSourceHeight
+	}
+	if c.MaxMegapixels <= 0 {
+		c.MaxMegapixels = entity.DefaultMaxMegapixels
+	}
+	if c.MaxGIFFrames <= 0 {
+		c.MaxGIFFrames = entity.DefaultMaxGIFFrames
+	}
+	if c.MaxDecodeMemoryMB <= 0 {
+		c.MaxDecodeMemoryMB = entity.DefaultMaxDecodeMemoryMB
+	}
+	if len(c.SupportedFormats) == 0 {
+		c.SupportedFormats = []string{
+			string(entity.FormatJPEG),
+			string(entity.FormatJPG),
+			string(entity.FormatPNG),
+			string(entity.FormatGIF),
+			string(entity.FormatWebP),
+		}
+	}
+	return c
+}
+
+// IsSupportedFormat проверяет, разрешен ли формат в SupportedFormats.
+// jpeg и jpg считаются одним форматом
+func (c ProcessingConfig) IsSupportedFormat(format entity.ImageFormat) bool {
+	normalize := func(f string) string {
+		f = strings.ToLower(f)
+		if f == string(entity.FormatJPG) {
+			return string(entity.FormatJPEG)
+		}
+		return f
+	}
+
+	want := normalize(string(format))
+	for _, supported := range c.SupportedFormats {
+		if normalize(supported) == want {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,86 @@
+package entity
+
+import "time"
+
+// BackfillStatus - состояние массовой повторной обработки
+type BackfillStatus string
+
+const (
+	// BackfillRunning - runner публикует задачи пачками
+	BackfillRunning BackfillStatus = "running"
+	// BackfillPaused - приостановлен через API; продолжается с сохраненного курсора
+	BackfillPaused BackfillStatus = "paused"
+	// BackfillCompleted - все изображения, подходящие под фильтр, просмотрены
+	BackfillCompleted BackfillStatus = "completed"
+	// BackfillFailed - публикация пачки не удалась backfill.maxAttempts раз подряд.
+	// После устранения причины backfill можно возобновить
+	BackfillFailed BackfillStatus = "failed"
+)
+
+// BackfillFilter отбирает изображения для повторной обработки; пустые поля не фильтруют.
+// Без Statuses удаленные изображения пропускаются
+type BackfillFilter struct {
+	Statuses     []ImageStatus
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	HasVariant   string
+	LacksVariant string
+}
+
+// BackfillCursor - последнее просмотренное изображение; страницы идут по (created_at, id)
+type BackfillCursor struct {
+	CreatedAt time.Time
+	ImageID   string
+}
+
+// Backfill - массовая повторная обработка изображений, подходящих под фильтр
+type Backfill struct {
+	ID            string
+	Status        BackfillStatus
+	Filter        BackfillFilter
+	Operations    []OperationParams
+	PresetName    string
+	PresetVersion int
+	Supersede     bool
+	BatchSize     int
+	// BatchInterval - пауза между публикацией пачек
+	BatchInterval time.Duration
+	// Cursor пуст, пока не обработана первая пачка
+	Cursor *BackfillCursor
+	// TotalImages - сколько изображений подходило под фильтр при создании
+	TotalImages   int64
+	ScannedImages int64
+	QueuedImages  int64
+	// SkippedImages - изображения, у которых уже была ожидающая или выполняющаяся задача
+	SkippedImages int64
+	// Attempts - неудачные публикации текущей пачки подряд
+	Attempts    int
+	LastError   string
+	NextRunAt   time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// BackfillCandidate - изображение очередной страницы backfill
+type BackfillCandidate struct {
+	ImageID      string
+	OriginalPath string
+	Format       ImageFormat
+	CreatedAt    time.Time
+	// ActiveJobID - ожидающая или выполняющаяся задача изображения
+	ActiveJobID string
+	// OwnJob - активная задача ожидает и создана этим же backfill: пачка была
+	// прервана до подтверждения публикации, и задачу нужно опубликовать снова
+	OwnJob bool
+}
+
+// BackfillError - запись журнала неудачных публикаций пачек
+type BackfillError struct {
+	ID         int64
+	BackfillID string
+	Attempt    int
+	BatchSize  int
+	Error      string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// DeadLetterStatus - состояние записи dead-letter очереди
+type DeadLetterStatus string
+
+const (
+	// DeadLetterPending - сообщение ожидает разбора
+	DeadLetterPending DeadLetterStatus = "pending"
+	// DeadLetterReplayed - по сообщению создана новая задача
+	DeadLetterReplayed DeadLetterStatus = "replayed"
+)
+
+// DeadLetter - сообщение, которое брокер не смог доставить: обработчик
+// вернул ошибку после всех попыток или сообщение не удалось разобрать.
+// Payload хранится в исходном виде, даже если это некорректный JSON
+type DeadLetter struct {
+	ID      string
+	TaskID  string
+	ImageID string
+	// Source - брокер, из которого получено сообщение
+	Source       string
+	Payload      []byte
+	Headers      map[string]string
+	Error        string
+	Attempts     int
+	Status       DeadLetterStatus
+	ReplayTaskID string
+	ReplayedAt   *time.Time
+	CreatedAt    time.Time
+}
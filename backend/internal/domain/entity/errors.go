@@ -0,0 +1,83 @@
+package entity
+
+import "errors"
+
+var (
+	ErrImageNotFound   = errors.New("image not found")
+	ErrOverlayNotFound = errors.New("overlay not found")
+
+	// ErrFileTooLarge - размер загружаемого файла превышает лимит
+	ErrFileTooLarge = errors.New("file exceeds upload size limit")
+	// ErrImageTooLarge - размеры изображения превышают лимиты декодирования
+	ErrImageTooLarge = errors.New("image exceeds decoding limits")
+	// ErrInvalidImage - заголовок изображения не удалось прочитать
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrUnsupportedFormat - формат содержимого не разрешен конфигурацией
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrContentMismatch - MIME тип или расширение не совпадают с содержимым
+	ErrContentMismatch = errors.New("declared type does not match image content")
+
+	// ErrProcessingTimeout - обработка задачи не уложилась в worker.processingTimeout
+	ErrProcessingTimeout = errors.New("processing timed out")
+
+	ErrJobNotFound = errors.New("processing job not found")
+	// ErrJobNotCancellable - задача уже завершена и не может быть отменена
+	ErrJobNotCancellable = errors.New("processing job cannot be cancelled")
+	// ErrJobCancelled - причина отмены контекста задачи, отмененной через API
+	ErrJobCancelled = errors.New("processing job cancelled")
+	// ErrJobInProgress - у изображения уже есть ожидающая или выполняющаяся задача
+	ErrJobInProgress = errors.New("image has a pending or processing job")
+
+	ErrPresetNotFound      = errors.New("preset not found")
+	ErrPresetAlreadyExists = errors.New("preset already exists")
+
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterReplayed - по сообщению уже создана новая задача
+	ErrDeadLetterReplayed = errors.New("dead letter already replayed")
+	// ErrDeadLetterNotReplayable - из сообщения нельзя восстановить задачу
+	ErrDeadLetterNotReplayable = errors.New("dead letter cannot be replayed")
+
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrWebhookDeliveryPending - доставка еще не завершена и будет повторена автоматически
+	ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+	// ErrInvalidWebhookURL - URL получателя не является абсолютным http(s) URL
+	ErrInvalidWebhookURL = errors.New("invalid webhook url")
+	// ErrCallbacksDisabled - callback_url не принимается без ключа подписи webhooks.secret
+	ErrCallbacksDisabled = errors.New("callbacks are disabled")
+
+	ErrBackfillNotFound = errors.New("backfill not found")
+	// ErrInvalidBackfill - фильтр или параметры пачек backfill недопустимы
+	ErrInvalidBackfill = errors.New("invalid backfill")
+	// ErrBackfillStateConflict - переход недоступен в текущем состоянии backfill
+	ErrBackfillStateConflict = errors.New("backfill state does not allow this action")
+)
+
+// AttemptsError - ошибка задачи, обработанной воркером за несколько попыток
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *AttemptsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// WithAttempts добавляет к ошибке задачи количество выполненных попыток
+func WithAttempts(err error, attempts int) error {
+	return &AttemptsError{Attempts: attempts, Err: err}
+}
+
+// ProcessingAttempts возвращает количество попыток, после которых задача
+// завершилась ошибкой err; без сведений о попытках считается одна попытка
+func ProcessingAttempts(err error) int {
+	var attemptsErr *AttemptsError
+	if errors.As(err, &attemptsErr) {
+		return attemptsErr.Attempts
+	}
+	return 1
+}
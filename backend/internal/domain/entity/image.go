@@ -8,25 +8,31 @@ type Image struct {
 	ID               string
 	OriginalFilename string
 	OriginalSize     int64
-	MimeType         string
+	MimeType         string      // определяется по содержимому, а не по заголовкам запроса
+	Format           ImageFormat // определяется по содержимому, а не по расширению
+	Checksum         string      // SHA-256 оригинала, вычисляется при загрузке
 	Status           ImageStatus
 	OriginalPath     string
 	Bucket           string
+	CallbackURL      string // URL для webhook о завершении обработки; может быть пустым
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
 
 type ProcessedImage struct {
-	ID         string
-	ImageID    string
-	Operation  OperationType
-	Parameters string
-	Path       string
-	Size       int64
-	MimeType   string
-	Format     ImageFormat
-	Status     string
-	CreatedAt  time.Time
+	ID      string
+	ImageID string
+	// JobID - задача, создавшая вариант
+	JobID       string
+	Operation   OperationType
+	VariantName string
+	Parameters  string
+	Path        string
+	Size        int64
+	MimeType    string
+	Format      ImageFormat
+	Status      string
+	CreatedAt   time.Time
 }
 
 type ImageStatus string
@@ -37,6 +43,8 @@ const (
 	StatusCompleted  ImageStatus = "completed"
 	StatusFailed     ImageStatus = "failed"
 	StatusDeleted    ImageStatus = "deleted"
+	// StatusCancelled - обработка изображения отменена через API
+	StatusCancelled ImageStatus = "cancelled"
 )
 
 type OperationType string
@@ -63,6 +71,56 @@ const (
 	FormatTIFF ImageFormat = "tiff"
 )
 
+// MimeType возвращает MIME тип формата
+func (f ImageFormat) MimeType() string {
+	switch f {
+	case FormatJPEG, FormatJPG:
+		return "image/jpeg"
+	case FormatPNG:
+		return "image/png"
+	case FormatGIF:
+		return "image/gif"
+	case FormatWebP:
+		return "image/webp"
+	case FormatBMP:
+		return "image/bmp"
+	case FormatTIFF:
+		return "image/tiff"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// SupportsAlpha сообщает, сохраняет ли кодировщик формата полупрозрачные пиксели.
+// GIF кодируется без прозрачного цвета в палитре, поэтому прозрачность теряется
+func (f ImageFormat) SupportsAlpha() bool {
+	return f == FormatPNG
+}
+
+// Extension возвращает расширение файла для формата
+func (f ImageFormat) Extension() string {
+	switch f {
+	case FormatJPEG, FormatJPG:
+		return ".jpg"
+	case "":
+		return ""
+	default:
+		return "." + string(f)
+	}
+}
+
+// OriginalVariant - зарезервированное имя варианта для оригинала
+const OriginalVariant = "original"
+
+// ProcessedOutput представляет закодированный результат операции
+type ProcessedOutput struct {
+	Operation OperationType
+	Data      []byte
+	Format    ImageFormat
+	MimeType  string
+	Quality   int
+}
+
 type ImageInfo struct {
 	Width      int
 	Height     int
@@ -70,4 +128,6 @@ type ImageInfo struct {
 	Size       int64
 	ColorSpace string
 	HasAlpha   bool
+	// Frames - количество кадров (больше одного только у анимированных GIF)
+	Frames int
 }
@@ -1,25 +1,96 @@
 package entity
 
+import "time"
+
 type ProcessingTask struct {
-	ID           string
-	ImageID      string
-	OriginalPath string
-	Bucket       string
-	Operations   []OperationParams
-	Format       ImageFormat
+	ID            string
+	ImageID       string
+	OriginalPath  string
+	Bucket        string
+	Operations    []OperationParams
+	Format        ImageFormat
+	PresetName    string
+	PresetVersion int
+	// Supersede - после успешной обработки удалить варианты предыдущих задач
+	Supersede bool
+	// Attempt и MaxAttempts заполняет очередь PostgreSQL: она сама повторяет
+	// задачу и переводит ее статус. У задач других брокеров они нулевые
+	Attempt     int `json:"-"`
+	MaxAttempts int `json:"-"`
+}
+
+// QueueManaged сообщает, что повторами и статусом задачи управляет очередь
+func (t *ProcessingTask) QueueManaged() bool {
+	return t.MaxAttempts > 0
+}
+
+// Статусы задачи в processing_jobs
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+	// JobStatusTimeout - обработка прервана по истечении worker.processingTimeout
+	JobStatusTimeout = "timeout"
+	// JobStatusCancelled - задача отменена через API; воркер прекращает обработку
+	JobStatusCancelled = "cancelled"
+)
+
+// ProcessingJob - состояние задачи в processing_jobs
+type ProcessingJob struct {
+	ID         string
+	ImageID    string
+	Operations []OperationParams
+	Status     string
+	Attempts   int
+	UpdatedAt  time.Time
 }
 
 type OperationParams struct {
-	Type       OperationType
+	Type OperationType
+	Name string
+	// Input - имя варианта, результат которого является входом операции:
+	// "original" или имя другой операции. Пустое значение - предыдущая операция списка
+	Input      string
 	Parameters map[string]interface{}
 }
 
+// VariantName возвращает имя результата операции; для задач без имени - тип операции
+func (o OperationParams) VariantName() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return string(o.Type)
+}
+
+// ProcessingResult - итог обработки задачи, публикуемый воркером в топик
+// image-processed. ID совпадает с ID задачи
 type ProcessingResult struct {
-	ID             string
-	ImageID        string
-	Status         ImageStatus
+	ID            string
+	ImageID       string
+	Status        ImageStatus
+	PresetName    string
+	PresetVersion int
+	// ProcessedPaths - пути сохраненных вариантов по имени варианта
 	ProcessedPaths map[string]string
-	Error          string
+	Variants       []VariantResult
+	// DurationMs - время обработки задачи от скачивания оригинала до сохранения вариантов
+	DurationMs  int64
+	Error       string
+	CompletedAt time.Time
+}
+
+// VariantResult описывает результат одной операции задачи
+type VariantResult struct {
+	Name      string
+	Operation OperationType
+	Path      string
+	Size      int64
+	MimeType  string
+	Format    ImageFormat
+	// DurationMs - время загрузки варианта в хранилище и записи в БД
+	DurationMs int64
+	Error      string
 }
 
 type WatermarkPosition string
@@ -34,6 +105,63 @@ const (
 	WatermarkCenter       WatermarkPosition = "center"
 )
 
+type WatermarkMode string
+
+const (
+	WatermarkModeText  WatermarkMode = "text"
+	WatermarkModeImage WatermarkMode = "image"
+)
+
+// OverlaySource указывает изображение для наложения: ранее загруженное
+// изображение по ID или ассет из конфигурации
+type OverlaySource struct {
+	ImageID string
+	Asset   string
+}
+
+// OverlaySourceFromParams извлекает источник наложения из параметров операции
+// в режиме наложения изображения
+func OverlaySourceFromParams(params map[string]interface{}) (OverlaySource, bool) {
+	if mode, _ := params[ParamMode].(string); mode != string(WatermarkModeImage) {
+		return OverlaySource{}, false
+	}
+	imageID, _ := params[ParamOverlayImageID].(string)
+	asset, _ := params[ParamOverlayAsset].(string)
+	if imageID == "" && asset == "" {
+		return OverlaySource{}, false
+	}
+	return OverlaySource{ImageID: imageID, Asset: asset}, true
+}
+
+type CropMode string
+
+const (
+	CropModeRectangle CropMode = "rectangle"
+	CropModeGravity   CropMode = "gravity"
+)
+
+type CropGravity string
+
+const (
+	GravityTopLeft     CropGravity = "top-left"
+	GravityTop         CropGravity = "top"
+	GravityTopRight    CropGravity = "top-right"
+	GravityLeft        CropGravity = "left"
+	GravityCenter      CropGravity = "center"
+	GravityRight       CropGravity = "right"
+	GravityBottomLeft  CropGravity = "bottom-left"
+	GravityBottom      CropGravity = "bottom"
+	GravityBottomRight CropGravity = "bottom-right"
+)
+
+type FlipMode string
+
+const (
+	FlipHorizontal FlipMode = "horizontal"
+	FlipVertical   FlipMode = "vertical"
+	FlipBoth       FlipMode = "both"
+)
+
 const (
 	KafkaTopicProcessing = "image-processing"
 	KafkaTopicResults    = "image-processed"
@@ -54,9 +182,34 @@ const (
 const (
 	DefaultMaxUploadSize    = 32 << 20
 	DefaultThumbnailSize    = 200
+	DefaultMaxThumbnailSize = 1000
+	DefaultMaxImageWidth    = 4096
+	DefaultMaxImageHeight   = 4096
 	DefaultJPEGQuality      = 85
 	DefaultWatermarkText    = "© ImageProcessor"
 	DefaultWatermarkOpacity = 0.5
+	DefaultRotateBackground = "#00000000"
+	DefaultWatermarkMargin  = 10
+	DefaultWatermarkScale   = 0.2
+
+	// DefaultOpaqueRotateBackground - заливка углов поворота для форматов без прозрачности
+	DefaultOpaqueRotateBackground = "#ffffff"
+
+	DefaultMaxSourceWidth    = 16384
+	DefaultMaxSourceHeight   = 16384
+	DefaultMaxMegapixels     = 50
+	DefaultMaxGIFFrames      = 300
+	DefaultMaxDecodeMemoryMB = 512
+
+	DefaultWatermarkFontSize     = 24
+	DefaultWatermarkFontColor    = "#FFFFFF"
+	DefaultWatermarkStrokeColor  = "#000000"
+	DefaultWatermarkShadowOffset = 2
+
+	MaxWatermarkMargin       = 1000
+	MaxWatermarkFontSize     = 500
+	MaxWatermarkStrokeWidth  = 20
+	MaxWatermarkShadowOffset = 50
 )
 
 const (
@@ -71,4 +224,25 @@ const (
 	ParamKeepAspect = "keep_aspect"
 	ParamCropToFit  = "crop_to_fit"
 	ParamAngle      = "angle"
+	ParamQuality    = "quality"
+	ParamFormat     = "format"
+	ParamX          = "x"
+	ParamY          = "y"
+	ParamMode       = "mode"
+	ParamGravity    = "gravity"
+	ParamBackground = "background"
+
+	ParamOverlayImageID = "overlay_image_id"
+	ParamOverlayAsset   = "overlay_asset"
+	ParamMargin         = "margin"
+	ParamScale          = "scale"
+	ParamTiled          = "tiled"
+	ParamSpacing        = "spacing"
+
+	ParamFont         = "font"
+	ParamStrokeWidth  = "stroke_width"
+	ParamStrokeColor  = "stroke_color"
+	ParamShadowColor  = "shadow_color"
+	ParamShadowOffset = "shadow_offset"
+	ParamMaxWidth     = "max_width"
 )
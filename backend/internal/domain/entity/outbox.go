@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// OutboxMessage - задача на обработку, записанная в outbox вместе с изображением
+// и ожидающая публикации в брокер
+type OutboxMessage struct {
+	ID        int64
+	Task      *ProcessingTask
+	Attempts  int
+	CreatedAt time.Time
+}
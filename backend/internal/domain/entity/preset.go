@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"time"
+)
+
+// Preset представляет именованный версионированный список операций
+type Preset struct {
+	ID          string
+	Name        string
+	Version     int
+	Description string
+	Operations  []OperationParams
+	CreatedAt   time.Time
+}
@@ -0,0 +1,51 @@
+package entity
+
+import "time"
+
+// ProgressEventType определяет этап обработки задачи
+type ProgressEventType string
+
+const (
+	// ProgressStarted - воркер начал обработку задачи
+	ProgressStarted ProgressEventType = "started"
+	// ProgressOperationCompleted - операция конвейера выполнена
+	ProgressOperationCompleted ProgressEventType = "operation_completed"
+	// ProgressVariantUploaded - результат операции сохранен в хранилище
+	ProgressVariantUploaded ProgressEventType = "variant_uploaded"
+	// ProgressCompleted - задача обработана
+	ProgressCompleted ProgressEventType = "completed"
+	// ProgressFailed - попытка обработки задачи завершилась ошибкой
+	ProgressFailed ProgressEventType = "failed"
+	// ProgressCancelled - задача отменена через API
+	ProgressCancelled ProgressEventType = "cancelled"
+)
+
+// IsFinal сообщает, что после события этой попытки новых событий не будет
+func (t ProgressEventType) IsFinal() bool {
+	return t == ProgressCompleted || t == ProgressFailed || t == ProgressCancelled
+}
+
+// ProgressEvent - событие хода обработки задачи. Обработка состоит
+// из выполнения операций и сохранения их результатов, поэтому Progress
+// учитывает оба этапа
+type ProgressEvent struct {
+	Type    ProgressEventType
+	ImageID string
+	TaskID  string
+	// Operation и Variant заданы для событий отдельной операции
+	Operation OperationType
+	Variant   string
+	// Path - ключ сохраненного результата для ProgressVariantUploaded
+	Path                string
+	CompletedOperations int
+	UploadedVariants    int
+	TotalOperations     int
+	// Progress - процент выполнения задачи
+	Progress  int
+	Error     string
+	Timestamp time.Time
+}
+
+// OperationProgressFunc вызывается процессором после выполнения каждой операции
+// конвейера; completed - количество выполненных операций из total
+type OperationProgressFunc func(completed, total int, operation OperationType, variant string)
@@ -12,8 +12,10 @@ type ProcessingStatistics struct {
 	AverageProcessingTimeMs  float64
 	TotalDataProcessedBytes  int64
 	FailedProcessingAttempts int64
-	MostUsedOperationType    string
-	LastStatisticsUpdatedAt  time.Time
+	// TimedOutProcessingAttempts - попытки, прерванные по таймауту обработки
+	TimedOutProcessingAttempts int64
+	MostUsedOperationType      string
+	LastStatisticsUpdatedAt    time.Time
 }
 
 // OperationStat представляет статистику по операции
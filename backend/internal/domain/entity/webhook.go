@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+// WebhookEventType - тип события, о котором уведомляются получатели webhook
+type WebhookEventType string
+
+const (
+	// WebhookEventImageProcessed - обработка изображения завершена
+	WebhookEventImageProcessed WebhookEventType = "image.processed"
+	// WebhookEventImageFailed - обработка изображения завершилась ошибкой
+	WebhookEventImageFailed WebhookEventType = "image.failed"
+)
+
+// WebhookEndpoint - зарегистрированный получатель событий обработки всех изображений
+type WebhookEndpoint struct {
+	ID          string
+	URL         string
+	Secret      string
+	Description string
+	CreatedAt   time.Time
+}
+
+// WebhookDeliveryStatus - состояние доставки события
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending - доставка ожидает очередной попытки
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered - получатель ответил кодом 2xx
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed - попытки доставки исчерпаны
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery - доставка одного события одному получателю: callback_url
+// изображения (EndpointID пуст) или зарегистрированному endpoint
+type WebhookDelivery struct {
+	ID         string
+	EventID    string
+	EventType  WebhookEventType
+	ImageID    string
+	TaskID     string
+	EndpointID string
+	URL        string
+	// Payload - тело запроса; подпись вычисляется по нему при каждой попытке
+	Payload       []byte
+	Status        WebhookDeliveryStatus
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time
+	// LastStatusCode - HTTP код последней попытки; 0, если ответ не получен
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	// Secret - ключ подписи endpoint; заполняется только при аренде доставки
+	Secret string
+}
+
+// WebhookAttempt - запись журнала попыток доставки
+type WebhookAttempt struct {
+	ID         int64
+	DeliveryID string
+	Attempt    int
+	StatusCode int
+	Error      string
+	DurationMs int64
+	CreatedAt  time.Time
+}
+
+// WebhookDeliveryFilter ограничивает список доставок; пустые поля не фильтруют
+type WebhookDeliveryFilter struct {
+	Status     WebhookDeliveryStatus
+	ImageID    string
+	EndpointID string
+}
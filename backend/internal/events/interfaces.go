@@ -0,0 +1,25 @@
+package events
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+// PublisherInterface публикует события хода обработки задач
+type PublisherInterface interface {
+	PublishProgress(ctx context.Context, event *entity.ProgressEvent) error
+}
+
+// SubscriberInterface выдает события хода обработки одного изображения
+type SubscriberInterface interface {
+	// Subscribe возвращает канал событий изображения и функцию отмены подписки.
+	// Канал не закрывается: подписчик перестает читать его после отмены
+	Subscribe(imageID string) (<-chan *entity.ProgressEvent, func())
+}
+
+// BusInterface публикует события и выдает их подписчикам. API публикует
+// события об отмене задач, которые воркер может и не получить
+type BusInterface interface {
+	PublisherInterface
+	SubscriberInterface
+}
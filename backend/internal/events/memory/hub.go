@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// defaultBufferSize - размер буфера подписчика, если EventsConfig.BufferSize не задан
+const defaultBufferSize = 64
+
+// Hub раздает события хода обработки подписчикам изображения в памяти процесса.
+// Публикация не блокируется: если подписчик не успевает читать события,
+// новые события для него отбрасываются
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*subscription]struct{}
+	bufferSize  int
+	logger      *zap.Logger
+}
+
+// subscription - подписка на события одного изображения
+type subscription struct {
+	events chan *entity.ProgressEvent
+}
+
+// NewHub создает хаб событий; bufferSize задает буфер каждого подписчика
+func NewHub(bufferSize int, logger *zap.Logger) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	return &Hub{
+		subscribers: make(map[string]map[*subscription]struct{}),
+		bufferSize:  bufferSize,
+		logger:      logger,
+	}
+}
+
+// Subscribe подписывает на события изображения. Функцию отмены можно вызывать
+// несколько раз
+func (h *Hub) Subscribe(imageID string) (<-chan *entity.ProgressEvent, func()) {
+	sub := &subscription{events: make(chan *entity.ProgressEvent, h.bufferSize)}
+
+	h.mu.Lock()
+	if h.subscribers[imageID] == nil {
+		h.subscribers[imageID] = make(map[*subscription]struct{})
+	}
+	h.subscribers[imageID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[imageID], sub)
+			if len(h.subscribers[imageID]) == 0 {
+				delete(h.subscribers, imageID)
+			}
+		})
+	}
+
+	return sub.events, unsubscribe
+}
+
+// PublishProgress передает событие подписчикам изображения. Подписчики
+// получают один и тот же экземпляр события и не должны его изменять
+func (h *Hub) PublishProgress(ctx context.Context, event *entity.ProgressEvent) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[event.ImageID] {
+		select {
+		case sub.events <- event:
+		default:
+			h.logger.Warn("Progress subscriber is too slow, event dropped",
+				zap.String("imageId", event.ImageID),
+				zap.String("taskId", event.TaskID),
+				zap.String("type", string(event.Type)),
+			)
+		}
+	}
+	return nil
+}
+
+// Subscribers возвращает количество подписчиков изображения
+func (h *Hub) Subscribers(imageID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[imageID])
+}
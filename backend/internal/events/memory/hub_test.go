@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestHub_DeliversEventsToImageSubscribers(t *testing.T) {
+	hub := NewHub(4, zap.NewNop())
+
+	first, unsubscribeFirst := hub.Subscribe("img")
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := hub.Subscribe("img")
+	defer unsubscribeSecond()
+	other, unsubscribeOther := hub.Subscribe("other")
+	defer unsubscribeOther()
+
+	event := &entity.ProgressEvent{Type: entity.ProgressStarted, ImageID: "img", TaskID: "task"}
+	if err := hub.PublishProgress(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, events := range []<-chan *entity.ProgressEvent{first, second} {
+		select {
+		case got := <-events:
+			if got != event {
+				t.Fatalf("unexpected event: %+v", got)
+			}
+		default:
+			t.Fatal("subscriber did not receive event")
+		}
+	}
+	select {
+	case got := <-other:
+		t.Fatalf("subscriber of another image received event: %+v", got)
+	default:
+	}
+}
+
+func TestHub_UnsubscribeStopsDelivery(t *testing.T) {
+	hub := NewHub(4, zap.NewNop())
+
+	events, unsubscribe := hub.Subscribe("img")
+	unsubscribe()
+	unsubscribe()
+
+	if n := hub.Subscribers("img"); n != 0 {
+		t.Fatalf("expected no subscribers, got %d", n)
+	}
+	_ = hub.PublishProgress(context.Background(), &entity.ProgressEvent{ImageID: "img"})
+	if len(events) != 0 {
+		t.Fatal("unsubscribed channel received event")
+	}
+}
+
+func TestHub_DropsEventsForSlowSubscriber(t *testing.T) {
+	hub := NewHub(2, zap.NewNop())
+
+	events, unsubscribe := hub.Subscribe("img")
+	defer unsubscribe()
+
+	// Публикация не блокируется, даже если подписчик не читает события
+	for i := 0; i < 5; i++ {
+		_ = hub.PublishProgress(context.Background(), &entity.ProgressEvent{ImageID: "img", CompletedOperations: i})
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected buffer of 2 events, got %d", len(events))
+	}
+	if got := <-events; got.CompletedOperations != 0 {
+		t.Fatalf("expected oldest event first, got %+v", got)
+	}
+}
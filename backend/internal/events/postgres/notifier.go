@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/events/memory"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ProgressChannel - канал LISTEN/NOTIFY, в который публикуются события хода обработки
+const ProgressChannel = "image_progress"
+
+// maxNotifyPayload - ограничение PostgreSQL на размер payload в NOTIFY
+const maxNotifyPayload = 8000
+
+// defaultReconnectBackoff - пауза перед повторным LISTEN, если EventsConfig.ReconnectBackoff не задан
+const defaultReconnectBackoff = 2 * time.Second
+
+// Notifier передает события хода обработки между процессами через
+// LISTEN/NOTIFY. Воркеры публикуют события через pg_notify, а API слушает
+// канал ProgressChannel одним соединением и раздает события своим
+// подписчикам. Уведомления не хранятся: события, опубликованные, пока
+// соединение слушателя не установлено, теряются
+type Notifier struct {
+	db               *pgxpool.Pool
+	hub              *memory.Hub
+	reconnectBackoff time.Duration
+	logger           *zap.Logger
+}
+
+func NewNotifier(db *pgxpool.Pool, cfg config.EventsConfig, logger *zap.Logger) *Notifier {
+	if cfg.ReconnectBackoff <= 0 {
+		cfg.ReconnectBackoff = defaultReconnectBackoff
+	}
+
+	return &Notifier{
+		db:               db,
+		hub:              memory.NewHub(cfg.BufferSize, logger),
+		reconnectBackoff: cfg.ReconnectBackoff,
+		logger:           logger,
+	}
+}
+
+// PublishProgress отправляет событие в канал ProgressChannel
+func (n *Notifier) PublishProgress(ctx context.Context, event *entity.ProgressEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+	if len(eventJSON) >= maxNotifyPayload {
+		return fmt.Errorf("failed to publish progress event: payload of %d bytes exceeds NOTIFY limit", len(eventJSON))
+	}
+
+	if _, err := n.db.Exec(ctx, `SELECT pg_notify($1, $2)`, ProgressChannel, string(eventJSON)); err != nil {
+		return fmt.Errorf("failed to publish progress event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe подписывает на события изображения, полученные слушателем
+func (n *Notifier) Subscribe(imageID string) (<-chan *entity.ProgressEvent, func()) {
+	return n.hub.Subscribe(imageID)
+}
+
+// Run слушает канал ProgressChannel, пока не отменен контекст.
+// После потери соединения LISTEN повторяется с паузой
+func (n *Notifier) Run(ctx context.Context) {
+	n.logger.Info("Progress listener started", zap.String("channel", ProgressChannel))
+
+	for {
+		err := n.listen(ctx)
+		if ctx.Err() != nil {
+			n.logger.Info("Progress listener stopped")
+			return
+		}
+		n.logger.Warn("Progress listener disconnected, reconnecting",
+			zap.Error(err),
+			zap.Duration("backoff", n.reconnectBackoff),
+		)
+
+		select {
+		case <-ctx.Done():
+			n.logger.Info("Progress listener stopped")
+			return
+		case <-time.After(n.reconnectBackoff):
+		}
+	}
+}
+
+// listen держит соединение с LISTEN и передает уведомления подписчикам
+func (n *Notifier) listen(ctx context.Context) error {
+	pooled, err := n.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// Соединение с активным LISTEN не возвращается в пул
+	conn := pooled.Hijack()
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+ProgressChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		var event entity.ProgressEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			n.logger.Warn("Failed to decode progress event", zap.Error(err))
+			continue
+		}
+		_ = n.hub.PublishProgress(ctx, &event)
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	backfillservice "imageprocessor/backend/internal/service/backfill_service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateBackfill запускает повторную обработку всех изображений, подходящих
+// под фильтр. Задачи публикуются в фоне пачками по batch_size с паузой batch_interval
+func (h *Handler) CreateBackfill(c *gin.Context) {
+	// Подсчет подходящих изображений может занять время на большой библиотеке
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req dto.CreateBackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+
+	var batchInterval time.Duration
+	if req.BatchInterval != "" {
+		var err error
+		batchInterval, err = time.ParseDuration(req.BatchInterval)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_batch_interval",
+				Message: "batch_interval must be a duration such as 500ms or 2s",
+			})
+			return
+		}
+	}
+
+	operations, preset, ok := h.resolveOperations(ctx, c, req.Operations, req.Preset, req.PresetVersion)
+	if !ok {
+		return
+	}
+
+	backfill, err := h.backfillService.CreateBackfill(ctx, backfillservice.BackfillParams{
+		Filter:        req.Filter.ToBackfillFilter(),
+		Operations:    operations,
+		Preset:        preset,
+		Supersede:     req.Supersede,
+		BatchSize:     req.BatchSize,
+		BatchInterval: batchInterval,
+	})
+	if err != nil {
+		h.respondBackfillError(c, err, "")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.FromBackfillEntity(backfill))
+}
+
+// ListBackfills возвращает backfill с пагинацией.
+// ?status=running|paused|completed|failed фильтрует по состоянию
+func (h *Handler) ListBackfills(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	status := entity.BackfillStatus(c.Query("status"))
+	switch status {
+	case "", entity.BackfillRunning, entity.BackfillPaused, entity.BackfillCompleted, entity.BackfillFailed:
+	default:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_status",
+			Message: "status must be running, paused, completed or failed",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	backfills, err := h.backfillService.ListBackfills(ctx, status, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list backfills", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "list_failed",
+			Message: "Failed to list backfills: " + err.Error(),
+		})
+		return
+	}
+
+	response := dto.FromBackfillEntities(backfills)
+	c.JSON(http.StatusOK, gin.H{
+		"backfills": response,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(response),
+	})
+}
+
+// GetBackfill возвращает ход backfill, итоги созданных им задач по статусам
+// и последние неудачные пачки
+func (h *Handler) GetBackfill(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	report, err := h.backfillService.GetBackfill(ctx, id)
+	if err != nil {
+		h.respondBackfillError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromBackfillReport(report.Backfill, report.Jobs, report.RecentErrors))
+}
+
+// PauseBackfill приостанавливает публикацию пачек backfill
+func (h *Handler) PauseBackfill(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	backfill, err := h.backfillService.PauseBackfill(ctx, id)
+	if err != nil {
+		h.respondBackfillError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromBackfillEntity(backfill))
+}
+
+// ResumeBackfill продолжает приостановленный или остановленный ошибками
+// backfill с последнего просмотренного изображения
+func (h *Handler) ResumeBackfill(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	backfill, err := h.backfillService.ResumeBackfill(ctx, id)
+	if err != nil {
+		h.respondBackfillError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromBackfillEntity(backfill))
+}
+
+// respondBackfillError преобразует ошибку сервиса backfill в HTTP ответ
+func (h *Handler) respondBackfillError(c *gin.Context, err error, id string) {
+	switch {
+	case errors.Is(err, entity.ErrBackfillNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "backfill_not_found",
+			Message: "Backfill not found: " + id,
+		})
+	case errors.Is(err, entity.ErrInvalidBackfill):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_backfill",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrBackfillStateConflict):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error:   "invalid_state",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Error("Backfill operation failed", zap.Error(err), zap.String("backfillId", id))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "backfill_error",
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListDeadLetters возвращает сообщения dead-letter очереди с пагинацией.
+// ?status=pending|replayed фильтрует по состоянию
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	status := entity.DeadLetterStatus(c.Query("status"))
+	if status != "" && status != entity.DeadLetterPending && status != entity.DeadLetterReplayed {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_status",
+			Message: "status must be pending or replayed",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	letters, err := h.deadLetterService.ListDeadLetters(ctx, status, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list dead letters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "list_failed",
+			Message: "Failed to list dead letters: " + err.Error(),
+		})
+		return
+	}
+
+	response := dto.FromDeadLetterEntities(letters)
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": response,
+		"limit":        limit,
+		"offset":       offset,
+		"count":        len(response),
+	})
+}
+
+// GetDeadLetter возвращает сообщение dead-letter очереди вместе с исходным сообщением
+func (h *Handler) GetDeadLetter(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	letter, err := h.deadLetterService.GetDeadLetter(ctx, id)
+	if err != nil {
+		h.respondDeadLetterError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromDeadLetterEntity(letter, true))
+}
+
+// ReplayDeadLetter создает по сообщению новую задачу; в теле можно передать
+// исправленные операции
+func (h *Handler) ReplayDeadLetter(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	// Тело необязательно: без него используются операции исходной задачи
+	var req dto.ReplayDeadLetterRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+
+	var operations []entity.OperationParams
+	if len(req.Operations) > 0 {
+		var ok bool
+		operations, ok = h.validateOperations(ctx, c, req.Operations)
+		if !ok {
+			return
+		}
+	}
+
+	task, err := h.deadLetterService.ReplayDeadLetter(ctx, id, operations)
+	if err != nil {
+		h.respondDeadLetterError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.ReplayDeadLetterResponse{
+		DeadLetterID:    id,
+		TaskID:          task.ID,
+		ImageID:         task.ImageID,
+		OperationsCount: len(task.Operations),
+	})
+}
+
+// respondDeadLetterError преобразует ошибку сервиса dead-letter очереди в HTTP ответ
+func (h *Handler) respondDeadLetterError(c *gin.Context, err error, id string) {
+	switch {
+	case errors.Is(err, entity.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "dead_letter_not_found",
+			Message: "Dead letter not found: " + id,
+		})
+	case errors.Is(err, entity.ErrDeadLetterReplayed):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error:   "already_replayed",
+			Message: "Dead letter already replayed: " + id,
+		})
+	case errors.Is(err, entity.ErrDeadLetterNotReplayable):
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+			Error:   "not_replayable",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrImageNotFound):
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+			Error:   "image_not_found",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Error("Dead letter operation failed", zap.Error(err), zap.String("deadLetterId", id))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "dead_letter_error",
+			Message: err.Error(),
+		})
+	}
+}
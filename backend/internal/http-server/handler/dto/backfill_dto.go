@@ -0,0 +1,183 @@
+package dto
+
+import (
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// BackfillFilter отбирает изображения для backfill; пустые поля не фильтруют.
+// Без statuses удаленные изображения пропускаются
+type BackfillFilter struct {
+	Statuses []string `json:"statuses,omitempty"`
+	// CreatedFrom включается в диапазон, CreatedTo - нет
+	CreatedFrom  *time.Time `json:"created_from,omitempty"`
+	CreatedTo    *time.Time `json:"created_to,omitempty"`
+	HasVariant   string     `json:"has_variant,omitempty"`
+	LacksVariant string     `json:"lacks_variant,omitempty"`
+}
+
+// CreateBackfillRequest представляет запрос на массовую повторную обработку.
+// Указываются либо операции, либо пресет
+type CreateBackfillRequest struct {
+	Filter        BackfillFilter     `json:"filter"`
+	Operations    []OperationRequest `json:"operations"`
+	Preset        string             `json:"preset"`
+	PresetVersion int                `json:"preset_version"`
+	// Supersede удаляет варианты предыдущих задач после успешной обработки
+	Supersede bool `json:"supersede"`
+	BatchSize int  `json:"batch_size"`
+	// BatchInterval - пауза между пачками в формате Go duration, например "500ms" или "2s"
+	BatchInterval string `json:"batch_interval"`
+}
+
+// BackfillCursorResponse - последнее просмотренное изображение
+type BackfillCursorResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+	ImageID   string    `json:"image_id"`
+}
+
+// BackfillErrorResponse - неудачная публикация пачки
+type BackfillErrorResponse struct {
+	Attempt   int       `json:"attempt"`
+	BatchSize int       `json:"batch_size"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackfillResponse представляет состояние backfill
+type BackfillResponse struct {
+	ID            string             `json:"id"`
+	Status        string             `json:"status"`
+	Filter        BackfillFilter     `json:"filter"`
+	Operations    []OperationRequest `json:"operations"`
+	Preset        string             `json:"preset,omitempty"`
+	PresetVersion int                `json:"preset_version,omitempty"`
+	Supersede     bool               `json:"supersede"`
+	BatchSize     int                `json:"batch_size"`
+	BatchInterval string             `json:"batch_interval"`
+	// TotalImages - сколько изображений подходило под фильтр при создании
+	TotalImages   int64                   `json:"total_images"`
+	ScannedImages int64                   `json:"scanned_images"`
+	QueuedImages  int64                   `json:"queued_images"`
+	SkippedImages int64                   `json:"skipped_images"`
+	Progress      float64                 `json:"progress"`
+	Cursor        *BackfillCursorResponse `json:"cursor,omitempty"`
+	Attempts      int                     `json:"attempts"`
+	LastError     string                  `json:"last_error,omitempty"`
+	CreatedAt     time.Time               `json:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+	CompletedAt   *time.Time              `json:"completed_at,omitempty"`
+	// Итоги задач и последние ошибки возвращаются только при просмотре одного backfill
+	Jobs         map[string]int64        `json:"jobs,omitempty"`
+	RecentErrors []BackfillErrorResponse `json:"recent_errors,omitempty"`
+}
+
+// ToBackfillFilter конвертирует фильтр запроса в entity.BackfillFilter. Границы
+// дат приводятся к локальному времени сервера, в котором хранится created_at
+func (f BackfillFilter) ToBackfillFilter() entity.BackfillFilter {
+	filter := entity.BackfillFilter{
+		HasVariant:   f.HasVariant,
+		LacksVariant: f.LacksVariant,
+	}
+	for _, status := range f.Statuses {
+		filter.Statuses = append(filter.Statuses, entity.ImageStatus(status))
+	}
+	if f.CreatedFrom != nil {
+		from := f.CreatedFrom.Local()
+		filter.CreatedFrom = &from
+	}
+	if f.CreatedTo != nil {
+		to := f.CreatedTo.Local()
+		filter.CreatedTo = &to
+	}
+	return filter
+}
+
+// FromBackfillEntity конвертирует entity.Backfill в DTO
+func FromBackfillEntity(backfill *entity.Backfill) BackfillResponse {
+	operations := make([]OperationRequest, 0, len(backfill.Operations))
+	for _, op := range backfill.Operations {
+		operations = append(operations, OperationRequest{
+			Type:       string(op.Type),
+			Name:       op.Name,
+			Input:      op.Input,
+			Parameters: op.Parameters,
+		})
+	}
+
+	filter := BackfillFilter{
+		CreatedFrom:  backfill.Filter.CreatedFrom,
+		CreatedTo:    backfill.Filter.CreatedTo,
+		HasVariant:   backfill.Filter.HasVariant,
+		LacksVariant: backfill.Filter.LacksVariant,
+	}
+	for _, status := range backfill.Filter.Statuses {
+		filter.Statuses = append(filter.Statuses, string(status))
+	}
+
+	response := BackfillResponse{
+		ID:            backfill.ID,
+		Status:        string(backfill.Status),
+		Filter:        filter,
+		Operations:    operations,
+		Preset:        backfill.PresetName,
+		PresetVersion: backfill.PresetVersion,
+		Supersede:     backfill.Supersede,
+		BatchSize:     backfill.BatchSize,
+		BatchInterval: backfill.BatchInterval.String(),
+		TotalImages:   backfill.TotalImages,
+		ScannedImages: backfill.ScannedImages,
+		QueuedImages:  backfill.QueuedImages,
+		SkippedImages: backfill.SkippedImages,
+		Attempts:      backfill.Attempts,
+		LastError:     backfill.LastError,
+		CreatedAt:     backfill.CreatedAt,
+		UpdatedAt:     backfill.UpdatedAt,
+		CompletedAt:   backfill.CompletedAt,
+	}
+
+	// Изображения, загруженные после создания, тоже попадают в backfill,
+	// поэтому просмотренных может оказаться больше, чем total_images
+	switch {
+	case backfill.Status == entity.BackfillCompleted:
+		response.Progress = 100
+	case backfill.TotalImages > 0:
+		response.Progress = min(float64(backfill.ScannedImages)/float64(backfill.TotalImages)*100, 100)
+	}
+
+	if backfill.Cursor != nil {
+		response.Cursor = &BackfillCursorResponse{
+			CreatedAt: backfill.Cursor.CreatedAt,
+			ImageID:   backfill.Cursor.ImageID,
+		}
+	}
+
+	return response
+}
+
+// FromBackfillEntities конвертирует список backfill в DTO
+func FromBackfillEntities(backfills []entity.Backfill) []BackfillResponse {
+	result := make([]BackfillResponse, 0, len(backfills))
+	for i := range backfills {
+		result = append(result, FromBackfillEntity(&backfills[i]))
+	}
+	return result
+}
+
+// FromBackfillReport конвертирует состояние backfill вместе с итогами задач и последними ошибками
+func FromBackfillReport(backfill *entity.Backfill, jobs map[string]int64, recentErrors []entity.BackfillError) BackfillResponse {
+	response := FromBackfillEntity(backfill)
+	response.Jobs = jobs
+
+	response.RecentErrors = make([]BackfillErrorResponse, 0, len(recentErrors))
+	for _, backfillError := range recentErrors {
+		response.RecentErrors = append(response.RecentErrors, BackfillErrorResponse{
+			Attempt:   backfillError.Attempt,
+			BatchSize: backfillError.BatchSize,
+			Error:     backfillError.Error,
+			CreatedAt: backfillError.CreatedAt,
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"encoding/json"
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+	"time"
+)
+
+// DeadLetterResponse представляет сообщение dead-letter очереди
+type DeadLetterResponse struct {
+	ID           string     `json:"id"`
+	TaskID       string     `json:"task_id,omitempty"`
+	ImageID      string     `json:"image_id,omitempty"`
+	Source       string     `json:"source"`
+	Error        string     `json:"error"`
+	Attempts     int        `json:"attempts"`
+	Status       string     `json:"status"`
+	ReplayTaskID string     `json:"replay_task_id,omitempty"`
+	ReplayedAt   *time.Time `json:"replayed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// Заголовки и исходное сообщение возвращаются только при просмотре одной записи.
+	// Корректный JSON возвращается в payload, остальное - текстом в raw_payload
+	Headers    map[string]string `json:"headers,omitempty"`
+	Payload    json.RawMessage   `json:"payload,omitempty"`
+	RawPayload string            `json:"raw_payload,omitempty"`
+}
+
+// ReplayDeadLetterRequest представляет запрос на повторную обработку сообщения.
+// Без operations используются операции исходной задачи
+type ReplayDeadLetterRequest struct {
+	Operations []OperationRequest `json:"operations"`
+}
+
+// ReplayDeadLetterResponse представляет задачу, созданную по сообщению
+type ReplayDeadLetterResponse struct {
+	DeadLetterID    string `json:"dead_letter_id"`
+	TaskID          string `json:"task_id"`
+	ImageID         string `json:"image_id"`
+	OperationsCount int    `json:"operations_count"`
+}
+
+// FromDeadLetterEntity конвертирует entity.DeadLetter в DTO; withPayload
+// добавляет заголовки и исходное сообщение
+func FromDeadLetterEntity(letter *entity.DeadLetter, withPayload bool) DeadLetterResponse {
+	response := DeadLetterResponse{
+		ID:           letter.ID,
+		TaskID:       letter.TaskID,
+		ImageID:      letter.ImageID,
+		Source:       letter.Source,
+		Error:        letter.Error,
+		Attempts:     letter.Attempts,
+		Status:       string(letter.Status),
+		ReplayTaskID: letter.ReplayTaskID,
+		ReplayedAt:   letter.ReplayedAt,
+		CreatedAt:    letter.CreatedAt,
+	}
+
+	if withPayload {
+		response.Headers = letter.Headers
+		if json.Valid(letter.Payload) {
+			response.Payload = json.RawMessage(letter.Payload)
+		} else {
+			response.RawPayload = strings.ToValidUTF8(string(letter.Payload), "�")
+		}
+	}
+
+	return response
+}
+
+// FromDeadLetterEntities конвертирует список сообщений в DTO без исходных сообщений
+func FromDeadLetterEntities(letters []entity.DeadLetter) []DeadLetterResponse {
+	result := make([]DeadLetterResponse, 0, len(letters))
+	for i := range letters {
+		result = append(result, FromDeadLetterEntity(&letters[i], false))
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// JobResponse представляет задачу на обработку
+type JobResponse struct {
+	ID        string    `json:"id"`
+	ImageID   string    `json:"image_id"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CancelImageResponse представляет результат отмены обработки изображения
+type CancelImageResponse struct {
+	ImageID string        `json:"image_id"`
+	Status  string        `json:"status"`
+	Jobs    []JobResponse `json:"jobs"`
+}
+
+// ReprocessImageRequest представляет запрос на повторную обработку изображения.
+// Указываются либо операции, либо пресет
+type ReprocessImageRequest struct {
+	Operations    []OperationRequest `json:"operations"`
+	Preset        string             `json:"preset"`
+	PresetVersion int                `json:"preset_version"`
+	// Supersede удаляет варианты предыдущих задач после успешной обработки
+	Supersede bool `json:"supersede"`
+}
+
+// ReprocessImageResponse представляет задачу повторной обработки
+type ReprocessImageResponse struct {
+	ImageID         string `json:"image_id"`
+	JobID           string `json:"job_id"`
+	Status          string `json:"status"`
+	OperationsCount int    `json:"operations_count"`
+	Preset          string `json:"preset,omitempty"`
+	PresetVersion   int    `json:"preset_version,omitempty"`
+	Supersede       bool   `json:"supersede"`
+}
+
+// FromProcessingJob конвертирует entity.ProcessingJob в DTO
+func FromProcessingJob(job *entity.ProcessingJob) JobResponse {
+	return JobResponse{
+		ID:        job.ID,
+		ImageID:   job.ImageID,
+		Status:    job.Status,
+		Attempts:  job.Attempts,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
+
+// FromProcessingJobs конвертирует список задач в DTO
+func FromProcessingJobs(jobs []entity.ProcessingJob) []JobResponse {
+	response := make([]JobResponse, 0, len(jobs))
+	for i := range jobs {
+		response = append(response, FromProcessingJob(&jobs[i]))
+	}
+	return response
+}
@@ -0,0 +1,83 @@
+package dto
+
+import (
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// CreatePresetRequest представляет запрос на создание пресета
+type CreatePresetRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Operations  []OperationRequest `json:"operations" binding:"required"`
+}
+
+// UpdatePresetRequest представляет запрос на создание новой версии пресета
+type UpdatePresetRequest struct {
+	Description string             `json:"description"`
+	Operations  []OperationRequest `json:"operations" binding:"required"`
+}
+
+// PresetResponse представляет версию пресета
+type PresetResponse struct {
+	Name        string             `json:"name"`
+	Version     int                `json:"version"`
+	Description string             `json:"description,omitempty"`
+	Operations  []OperationRequest `json:"operations"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// Validate проверяет имя пресета
+func (r *CreatePresetRequest) Validate() error {
+	if !IsValidPresetName(r.Name) {
+		return fmt.Errorf("invalid preset name %q: use a-z, 0-9, '-' or '_' (max %d chars)", r.Name, maxVariantNameLength)
+	}
+	if len(r.Operations) == 0 {
+		return fmt.Errorf("preset must contain at least one operation")
+	}
+	return nil
+}
+
+// Validate проверяет наличие операций
+func (r *UpdatePresetRequest) Validate() error {
+	if len(r.Operations) == 0 {
+		return fmt.Errorf("preset must contain at least one operation")
+	}
+	return nil
+}
+
+// IsValidPresetName проверяет имя пресета по тем же правилам, что и имена вариантов
+func IsValidPresetName(name string) bool {
+	return name != "" && isValidVariantName(name)
+}
+
+// FromPresetEntity конвертирует entity.Preset в DTO
+func FromPresetEntity(preset *entity.Preset) PresetResponse {
+	operations := make([]OperationRequest, 0, len(preset.Operations))
+	for _, op := range preset.Operations {
+		operations = append(operations, OperationRequest{
+			Type:       string(op.Type),
+			Name:       op.Name,
+			Input:      op.Input,
+			Parameters: op.Parameters,
+		})
+	}
+
+	return PresetResponse{
+		Name:        preset.Name,
+		Version:     preset.Version,
+		Description: preset.Description,
+		Operations:  operations,
+		CreatedAt:   preset.CreatedAt,
+	}
+}
+
+// FromPresetEntities конвертирует список пресетов в DTO
+func FromPresetEntities(presets []entity.Preset) []PresetResponse {
+	result := make([]PresetResponse, 0, len(presets))
+	for i := range presets {
+		result = append(result, FromPresetEntity(&presets[i]))
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// ProgressEventResponse представляет событие хода обработки в SSE потоке
+type ProgressEventResponse struct {
+	Type                string    `json:"type"`
+	ImageID             string    `json:"image_id"`
+	TaskID              string    `json:"task_id"`
+	Operation           string    `json:"operation,omitempty"`
+	Variant             string    `json:"variant,omitempty"`
+	Path                string    `json:"path,omitempty"`
+	CompletedOperations int       `json:"completed_operations"`
+	UploadedVariants    int       `json:"uploaded_variants"`
+	TotalOperations     int       `json:"total_operations"`
+	Progress            int       `json:"progress"`
+	Error               string    `json:"error,omitempty"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// FromProgressEvent конвертирует entity.ProgressEvent в DTO
+func FromProgressEvent(event *entity.ProgressEvent) ProgressEventResponse {
+	return ProgressEventResponse{
+		Type:                string(event.Type),
+		ImageID:             event.ImageID,
+		TaskID:              event.TaskID,
+		Operation:           string(event.Operation),
+		Variant:             event.Variant,
+		Path:                event.Path,
+		CompletedOperations: event.CompletedOperations,
+		UploadedVariants:    event.UploadedVariants,
+		TotalOperations:     event.TotalOperations,
+		Progress:            event.Progress,
+		Error:               event.Error,
+		Timestamp:           event.Timestamp,
+	}
+}
@@ -2,8 +2,10 @@ package dto
 
 import (
 	"fmt"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 	"mime/multipart"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +18,8 @@ type UploadImageRequest struct {
 // OperationRequest представляет операцию обработки изображения
 type OperationRequest struct {
 	Type       string                 `json:"type" binding:"required"`
+	Name       string                 `json:"name,omitempty"`
+	Input      string                 `json:"input,omitempty"` // original или имя операции, объявленной раньше
 	Parameters map[string]interface{} `json:"parameters"`
 }
 
@@ -37,7 +41,7 @@ type GetImageStatusRequest struct {
 }
 
 // Валидация запроса на загрузку изображения
-func (r *UploadImageRequest) Validate() error {
+func (r *UploadImageRequest) Validate(cfg config.ProcessingConfig) error {
 	if r.Image == nil {
 		return fmt.Errorf("image file is required")
 	}
@@ -61,7 +65,7 @@ func (r *UploadImageRequest) Validate() error {
 	// Валидация операций
 	if len(r.Operations) > 0 {
 		for i, op := range r.Operations {
-			if err := op.Validate(); err != nil {
+			if err := op.Validate(cfg); err != nil {
 				return fmt.Errorf("invalid operation at index %d: %w", i, err)
 			}
 		}
@@ -70,8 +74,8 @@ func (r *UploadImageRequest) Validate() error {
 	return nil
 }
 
-// Валидация операции
-func (o *OperationRequest) Validate() error {
+// Валидация операции с лимитами и значениями по умолчанию из конфигурации обработки
+func (o *OperationRequest) Validate(cfg config.ProcessingConfig) error {
 	if o.Type == "" {
 		return fmt.Errorf("operation type is required")
 	}
@@ -91,20 +95,76 @@ func (o *OperationRequest) Validate() error {
 		return fmt.Errorf("invalid operation type: %s", o.Type)
 	}
 
+	if o.Name != "" && !isValidVariantName(o.Name) {
+		return fmt.Errorf("invalid operation name: %s. Use up to %d characters: a-z, 0-9, '-', '_'", o.Name, maxVariantNameLength)
+	}
+
+	if o.Input != "" && !isValidVariantName(o.Input) {
+		return fmt.Errorf("invalid operation input: %s", o.Input)
+	}
+
+	if err := o.validateOutputParams(cfg); err != nil {
+		return err
+	}
+
 	// Валидация параметров в зависимости от типа операции
 	switch entity.OperationType(o.Type) {
 	case entity.OpResize:
-		return o.validateResizeParams()
+		return o.validateResizeParams(cfg)
 	case entity.OpThumbnail:
-		return o.validateThumbnailParams()
+		return o.validateThumbnailParams(cfg)
 	case entity.OpWatermark:
-		return o.validateWatermarkParams()
+		return o.validateWatermarkParams(cfg)
+	case entity.OpCrop:
+		return o.validateCropParams(cfg)
+	case entity.OpRotate:
+		return o.validateRotateParams()
+	case entity.OpFlip:
+		return o.validateFlipParams()
 	}
 
 	return nil
 }
 
-func (o *OperationRequest) validateResizeParams() error {
+// validateOutputParams проверяет формат и качество результата операции
+func (o *OperationRequest) validateOutputParams(cfg config.ProcessingConfig) error {
+	if o.Parameters == nil {
+		return nil
+	}
+
+	if format, hasFormat := o.Parameters[entity.ParamFormat]; hasFormat {
+		f, ok := format.(string)
+		if !ok {
+			return fmt.Errorf("output format must be a string")
+		}
+
+		// WebP поддерживается только на чтение
+		validFormats := map[string]bool{
+			string(entity.FormatJPEG): true,
+			string(entity.FormatJPG):  true,
+			string(entity.FormatPNG):  true,
+			string(entity.FormatGIF):  true,
+		}
+
+		if !validFormats[strings.ToLower(f)] {
+			return fmt.Errorf("unsupported output format: %s. Supported: jpeg, png, gif", f)
+		}
+		if !cfg.IsSupportedFormat(entity.ImageFormat(f)) {
+			return fmt.Errorf("output format %s is disabled. Supported formats: %s", f, strings.Join(cfg.SupportedFormats, ", "))
+		}
+	}
+
+	if quality, hasQuality := o.Parameters[entity.ParamQuality]; hasQuality {
+		q, ok := isNumber(quality)
+		if !ok || q < 1 || q > 100 {
+			return fmt.Errorf("quality must be between 1 and 100")
+		}
+	}
+
+	return nil
+}
+
+func (o *OperationRequest) validateResizeParams(cfg config.ProcessingConfig) error {
 	if o.Parameters == nil {
 		return fmt.Errorf("resize parameters are required")
 	}
@@ -118,52 +178,73 @@ func (o *OperationRequest) validateResizeParams() error {
 
 	if hasWidth {
 		w := getFloat64(width)
-		if w <= 0 || w > 4096 {
-			return fmt.Errorf("width must be between 1 and 4096")
+		if w <= 0 || w > float64(cfg.MaxImageWidth) {
+			return fmt.Errorf("width must be between 1 and %d", cfg.MaxImageWidth)
 		}
 	}
 
 	if hasHeight {
 		h := getFloat64(height)
-		if h <= 0 || h > 4096 {
-			return fmt.Errorf("height must be between 1 and 4096")
+		if h <= 0 || h > float64(cfg.MaxImageHeight) {
+			return fmt.Errorf("height must be between 1 and %d", cfg.MaxImageHeight)
 		}
 	}
 
 	return nil
 }
 
-func (o *OperationRequest) validateThumbnailParams() error {
+func (o *OperationRequest) validateThumbnailParams(cfg config.ProcessingConfig) error {
 	if o.Parameters == nil {
 		o.Parameters = make(map[string]interface{})
-		o.Parameters[entity.ParamSize] = entity.DefaultThumbnailSize
+		o.Parameters[entity.ParamSize] = cfg.DefaultThumbnailSize
 		return nil
 	}
 
 	if size, hasSize := o.Parameters[entity.ParamSize]; hasSize {
 		s := getFloat64(size)
-		if s <= 0 || s > 1000 {
-			return fmt.Errorf("thumbnail size must be between 1 and 1000")
+		if s <= 0 || s > float64(cfg.MaxThumbnailSize) {
+			return fmt.Errorf("thumbnail size must be between 1 and %d", cfg.MaxThumbnailSize)
 		}
 	} else {
-		o.Parameters[entity.ParamSize] = entity.DefaultThumbnailSize
+		o.Parameters[entity.ParamSize] = cfg.DefaultThumbnailSize
 	}
 
 	return nil
 }
 
-func (o *OperationRequest) validateWatermarkParams() error {
+func (o *OperationRequest) validateWatermarkParams(cfg config.ProcessingConfig) error {
 	if o.Parameters == nil {
 		o.Parameters = make(map[string]interface{})
 	}
 
-	// Устанавливаем значения по умолчанию
-	if _, hasText := o.Parameters[entity.ParamText]; !hasText {
-		o.Parameters[entity.ParamText] = entity.DefaultWatermarkText
+	mode, ok := o.Parameters[entity.ParamMode].(string)
+	if !ok {
+		if _, hasMode := o.Parameters[entity.ParamMode]; hasMode {
+			return fmt.Errorf("watermark mode must be a string")
+		}
+		mode = string(entity.WatermarkModeText)
+		o.Parameters[entity.ParamMode] = mode
+	}
+
+	switch entity.WatermarkMode(mode) {
+	case entity.WatermarkModeText:
+		// Устанавливаем значения по умолчанию
+		if _, hasText := o.Parameters[entity.ParamText]; !hasText {
+			o.Parameters[entity.ParamText] = cfg.WatermarkText
+		}
+		if err := o.validateTextWatermarkParams(); err != nil {
+			return err
+		}
+	case entity.WatermarkModeImage:
+		if err := o.validateOverlayParams(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid watermark mode: %s", mode)
 	}
 
 	if _, hasOpacity := o.Parameters[entity.ParamOpacity]; !hasOpacity {
-		o.Parameters[entity.ParamOpacity] = entity.DefaultWatermarkOpacity
+		o.Parameters[entity.ParamOpacity] = cfg.WatermarkOpacity
 	}
 
 	if opacity, hasOpacity := o.Parameters[entity.ParamOpacity]; hasOpacity {
@@ -173,6 +254,21 @@ func (o *OperationRequest) validateWatermarkParams() error {
 		}
 	}
 
+	for _, key := range []string{entity.ParamMargin, entity.ParamSpacing} {
+		if value, exists := o.Parameters[key]; exists {
+			v, ok := isNumber(value)
+			if !ok || v < 0 || v > float64(entity.MaxWatermarkMargin) {
+				return fmt.Errorf("watermark %s must be between 0 and %d", key, entity.MaxWatermarkMargin)
+			}
+		}
+	}
+
+	if tiled, hasTiled := o.Parameters[entity.ParamTiled]; hasTiled {
+		if _, ok := tiled.(bool); !ok {
+			return fmt.Errorf("watermark tiled must be a boolean")
+		}
+	}
+
 	if position, hasPosition := o.Parameters[entity.ParamPosition]; hasPosition {
 		pos, ok := position.(string)
 		if !ok {
@@ -197,16 +293,272 @@ func (o *OperationRequest) validateWatermarkParams() error {
 	return nil
 }
 
+// validateTextWatermarkParams проверяет оформление текстового водяного знака
+func (o *OperationRequest) validateTextWatermarkParams() error {
+	if text, ok := o.Parameters[entity.ParamText].(string); !ok || text == "" {
+		return fmt.Errorf("watermark text must be a non-empty string")
+	}
+
+	// Наличие шрифта проверяет хэндлер по шрифтам процессора
+	if name, hasFont := o.Parameters[entity.ParamFont]; hasFont {
+		if fontName, ok := name.(string); !ok || fontName == "" {
+			return fmt.Errorf("watermark font must be a non-empty string")
+		}
+	}
+
+	for _, key := range []string{entity.ParamFontColor, entity.ParamStrokeColor, entity.ParamShadowColor} {
+		if value, exists := o.Parameters[key]; exists {
+			c, ok := value.(string)
+			if !ok || !isValidColor(c) {
+				return fmt.Errorf("invalid watermark %s: use #RRGGBB, #RRGGBBAA or rgba(r, g, b, a)", key)
+			}
+		}
+	}
+
+	numbers := []struct {
+		key      string
+		min, max float64
+	}{
+		{entity.ParamFontSize, 1, entity.MaxWatermarkFontSize},
+		{entity.ParamStrokeWidth, 0, entity.MaxWatermarkStrokeWidth},
+		{entity.ParamShadowOffset, 0, entity.MaxWatermarkShadowOffset},
+		{entity.ParamMaxWidth, 1, 1 << 16},
+		{entity.ParamAngle, -360, 360},
+	}
+	for _, n := range numbers {
+		if value, exists := o.Parameters[n.key]; exists {
+			v, ok := isNumber(value)
+			if !ok || v < n.min || v > n.max {
+				return fmt.Errorf("watermark %s must be between %g and %g", n.key, n.min, n.max)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateOverlayParams проверяет параметры водяного знака-изображения
+func (o *OperationRequest) validateOverlayParams() error {
+	imageID, hasImageID := o.Parameters[entity.ParamOverlayImageID]
+	asset, hasAsset := o.Parameters[entity.ParamOverlayAsset]
+	if hasImageID == hasAsset {
+		return fmt.Errorf("watermark image mode requires exactly one of %s or %s", entity.ParamOverlayImageID, entity.ParamOverlayAsset)
+	}
+	if hasImageID {
+		if id, ok := imageID.(string); !ok || id == "" {
+			return fmt.Errorf("%s must be a non-empty string", entity.ParamOverlayImageID)
+		}
+	}
+	if hasAsset {
+		if name, ok := asset.(string); !ok || name == "" {
+			return fmt.Errorf("%s must be a non-empty string", entity.ParamOverlayAsset)
+		}
+	}
+
+	if scale, hasScale := o.Parameters[entity.ParamScale]; hasScale {
+		s, ok := isNumber(scale)
+		if !ok || s <= 0 || s > 1 {
+			return fmt.Errorf("watermark scale must be greater than 0 and at most 1")
+		}
+	} else {
+		o.Parameters[entity.ParamScale] = entity.DefaultWatermarkScale
+	}
+
+	return nil
+}
+
+func (o *OperationRequest) validateCropParams(cfg config.ProcessingConfig) error {
+	if o.Parameters == nil {
+		return fmt.Errorf("crop parameters are required")
+	}
+
+	limits := map[string]int{
+		entity.ParamWidth:  cfg.MaxImageWidth,
+		entity.ParamHeight: cfg.MaxImageHeight,
+	}
+	for _, key := range []string{entity.ParamWidth, entity.ParamHeight} {
+		val, exists := o.Parameters[key]
+		if !exists {
+			return fmt.Errorf("%s is required for crop", key)
+		}
+		if v := getFloat64(val); v <= 0 || v > float64(limits[key]) {
+			return fmt.Errorf("crop %s must be between 1 and %d", key, limits[key])
+		}
+	}
+
+	mode := ""
+	if m, hasMode := o.Parameters[entity.ParamMode]; hasMode {
+		str, ok := m.(string)
+		if !ok {
+			return fmt.Errorf("crop mode must be a string")
+		}
+		if str != string(entity.CropModeRectangle) && str != string(entity.CropModeGravity) {
+			return fmt.Errorf("invalid crop mode: %s", str)
+		}
+		mode = str
+	}
+
+	_, hasX := o.Parameters[entity.ParamX]
+	_, hasY := o.Parameters[entity.ParamY]
+	if mode == string(entity.CropModeRectangle) || (mode == "" && (hasX || hasY)) {
+		for _, key := range []string{entity.ParamX, entity.ParamY} {
+			if val, exists := o.Parameters[key]; exists {
+				if _, ok := isNumber(val); !ok || getFloat64(val) < 0 {
+					return fmt.Errorf("crop %s must be a non-negative number", key)
+				}
+			}
+		}
+		return nil
+	}
+
+	if gravity, hasGravity := o.Parameters[entity.ParamGravity]; hasGravity {
+		g, ok := gravity.(string)
+		if !ok {
+			return fmt.Errorf("crop gravity must be a string")
+		}
+
+		validGravities := map[string]bool{
+			string(entity.GravityTopLeft):     true,
+			string(entity.GravityTop):         true,
+			string(entity.GravityTopRight):    true,
+			string(entity.GravityLeft):        true,
+			string(entity.GravityCenter):      true,
+			string(entity.GravityRight):       true,
+			string(entity.GravityBottomLeft):  true,
+			string(entity.GravityBottom):      true,
+			string(entity.GravityBottomRight): true,
+		}
+
+		if !validGravities[g] {
+			return fmt.Errorf("invalid crop gravity: %s", g)
+		}
+	}
+
+	return nil
+}
+
+func (o *OperationRequest) validateRotateParams() error {
+	if o.Parameters == nil {
+		return fmt.Errorf("rotate parameters are required")
+	}
+
+	angle, hasAngle := o.Parameters[entity.ParamAngle]
+	if !hasAngle {
+		return fmt.Errorf("angle is required for rotate")
+	}
+	a, ok := isNumber(angle)
+	if !ok {
+		return fmt.Errorf("rotate angle must be a number")
+	}
+	if a < -360 || a > 360 {
+		return fmt.Errorf("rotate angle must be between -360 and 360")
+	}
+
+	if background, hasBackground := o.Parameters[entity.ParamBackground]; hasBackground {
+		bg, ok := background.(string)
+		if !ok || !isValidColor(bg) {
+			return fmt.Errorf("invalid rotate background color: %v", background)
+		}
+	}
+
+	return nil
+}
+
+func (o *OperationRequest) validateFlipParams() error {
+	if o.Parameters == nil {
+		o.Parameters = make(map[string]interface{})
+	}
+
+	mode, hasMode := o.Parameters[entity.ParamMode]
+	if !hasMode {
+		o.Parameters[entity.ParamMode] = string(entity.FlipHorizontal)
+		return nil
+	}
+
+	m, ok := mode.(string)
+	if !ok {
+		return fmt.Errorf("flip mode must be a string")
+	}
+
+	switch entity.FlipMode(m) {
+	case entity.FlipHorizontal, entity.FlipVertical, entity.FlipBoth:
+		return nil
+	default:
+		return fmt.Errorf("invalid flip mode: %s", m)
+	}
+}
+
 // ToEntity конвертирует DTO в entity
 func (o *OperationRequest) ToEntity() entity.OperationParams {
 	return entity.OperationParams{
 		Type:       entity.OperationType(o.Type),
+		Name:       o.Name,
+		Input:      o.Input,
 		Parameters: o.Parameters,
 	}
 }
 
+// AssignVariantNames проверяет уникальность имен вариантов и назначает имена
+// безымянным операциям: тип операции, а при повторе - тип с порядковым суффиксом.
+// Вход операции может ссылаться только на вариант, объявленный раньше нее
+func AssignVariantNames(operations []entity.OperationParams) error {
+	used := make(map[string]bool, len(operations))
+	for _, op := range operations {
+		if op.Name == "" {
+			continue
+		}
+		if op.Name == entity.OriginalVariant {
+			return fmt.Errorf("operation name %q is reserved", op.Name)
+		}
+		if used[op.Name] {
+			return fmt.Errorf("duplicate operation name: %s", op.Name)
+		}
+		used[op.Name] = true
+	}
+
+	for i := range operations {
+		if operations[i].Name != "" {
+			continue
+		}
+		name := string(operations[i].Type)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", operations[i].Type, n)
+		}
+		operations[i].Name = name
+		used[name] = true
+	}
+
+	// Неизвестные входы отклоняет BuildPipeline, здесь - ссылки на себя и вперед
+	declared := make(map[string]bool, len(operations))
+	for _, op := range operations {
+		if op.Input == op.Name {
+			return fmt.Errorf("operation %s uses itself as input", op.Name)
+		}
+		if used[op.Input] && !declared[op.Input] {
+			return fmt.Errorf("operation %s references input %s declared later", op.Name, op.Input)
+		}
+		declared[op.Name] = true
+	}
+
+	return nil
+}
+
 // Вспомогательные функции
 
+const maxVariantNameLength = 64
+
+func isValidVariantName(name string) bool {
+	if len(name) > maxVariantNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidImageContentType(contentType string) bool {
 	validTypes := map[string]bool{
 		"image/jpeg": true,
@@ -230,6 +582,72 @@ func isValidImageExtension(filename string) bool {
 	return false
 }
 
+// isValidColor проверяет цвет в формате #RGB, #RRGGBB, #RRGGBBAA или "transparent"
+func isValidColor(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "transparent" {
+		return true
+	}
+
+	if strings.HasPrefix(value, "rgb") {
+		return isValidRGBFunc(value)
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 3 && len(hex) != 6 && len(hex) != 8 {
+		return false
+	}
+	for _, r := range hex {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidRGBFunc проверяет rgb(r, g, b) и rgba(r, g, b, a) с альфой 0-1
+func isValidRGBFunc(value string) bool {
+	var args string
+	var channels int
+	switch {
+	case strings.HasPrefix(value, "rgba(") && strings.HasSuffix(value, ")"):
+		args, channels = value[len("rgba("):len(value)-1], 4
+	case strings.HasPrefix(value, "rgb(") && strings.HasSuffix(value, ")"):
+		args, channels = value[len("rgb("):len(value)-1], 3
+	default:
+		return false
+	}
+
+	parts := strings.Split(args, ",")
+	if len(parts) != channels {
+		return false
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i == 3 {
+			a, err := strconv.ParseFloat(part, 64)
+			if err != nil || a < 0 || a > 1 {
+				return false
+			}
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumber(val interface{}) (float64, bool) {
+	switch val.(type) {
+	case float64, float32, int, int64:
+		return getFloat64(val), true
+	default:
+		return 0, false
+	}
+}
+
 func getFloat64(val interface{}) float64 {
 	switch v := val.(type) {
 	case float64:
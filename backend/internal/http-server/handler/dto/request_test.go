@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+	"testing"
+)
+
+func TestAssignVariantNames_Inputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations []entity.OperationParams
+		wantErr    string
+	}{
+		{"earlier named input", []entity.OperationParams{
+			{Type: entity.OpWatermark, Name: "marked"},
+			{Type: entity.OpResize, Input: "marked"},
+		}, ""},
+		{"earlier generated name", []entity.OperationParams{
+			{Type: entity.OpResize},
+			{Type: entity.OpGrayscale, Input: "resize"},
+		}, ""},
+		{"original input", []entity.OperationParams{
+			{Type: entity.OpResize},
+			{Type: entity.OpThumbnail, Input: entity.OriginalVariant},
+		}, ""},
+		{"forward named input", []entity.OperationParams{
+			{Type: entity.OpResize, Input: "marked"},
+			{Type: entity.OpWatermark, Name: "marked"},
+		}, "operation resize references input marked declared later"},
+		{"forward generated name", []entity.OperationParams{
+			{Type: entity.OpResize, Input: "thumbnail"},
+			{Type: entity.OpThumbnail},
+		}, "operation resize references input thumbnail declared later"},
+		{"self input", []entity.OperationParams{
+			{Type: entity.OpResize, Name: "small", Input: "small"},
+		}, "operation small uses itself as input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AssignVariantNames(tt.operations)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -23,6 +23,9 @@ type UploadResponse struct {
 	CreatedAt       time.Time `json:"created_at"`
 	EstimatedTime   int       `json:"estimated_time_seconds,omitempty"`
 	OperationsCount int       `json:"operations_count"`
+	Preset          string    `json:"preset,omitempty"`
+	PresetVersion   int       `json:"preset_version,omitempty"`
+	CallbackURL     string    `json:"callback_url,omitempty"`
 }
 
 // ImageStatusResponse представляет статус обработки изображения
@@ -32,6 +35,8 @@ type ImageStatusResponse struct {
 	Progress            int                  `json:"progress"`
 	ProcessedOperations int                  `json:"processed_operations"`
 	TotalOperations     int                  `json:"total_operations"`
+	JobID               string               `json:"job_id,omitempty"`
+	JobStatus           string               `json:"job_status,omitempty"`
 	Results             []ProcessedImageInfo `json:"results,omitempty"`
 	ErrorMessage        string               `json:"error_message,omitempty"`
 	CreatedAt           time.Time            `json:"created_at"`
@@ -42,9 +47,12 @@ type ImageStatusResponse struct {
 // ProcessedImageInfo представляет информацию об обработанном изображении
 type ProcessedImageInfo struct {
 	Operation string `json:"operation"`
+	Variant   string `json:"variant,omitempty"`
 	URL       string `json:"url,omitempty"`
 	Path      string `json:"path"`
 	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type,omitempty"`
+	Format    string `json:"format,omitempty"`
 	Status    string `json:"status"`
 }
 
@@ -81,6 +89,7 @@ type StatisticsResponse struct {
 	TotalImagesUploaded     int64                `json:"total_images_uploaded"`
 	TotalImagesProcessed    int64                `json:"total_images_processed"`
 	TotalImagesFailed       int64                `json:"total_images_failed"`
+	TotalImagesTimedOut     int64                `json:"total_images_timed_out"`
 	TotalDataProcessedBytes int64                `json:"total_data_processed_bytes"`
 	TotalDataProcessedMB    float64              `json:"total_data_processed_mb"`
 	AverageProcessingTimeMs float64              `json:"average_processing_time_ms"`
@@ -124,8 +133,11 @@ func FromImageEntity(img *entity.Image, versions []entity.ProcessedImage) *Image
 		for _, v := range versions {
 			resp.Versions = append(resp.Versions, ProcessedImageInfo{
 				Operation: string(v.Operation),
+				Variant:   v.VariantName,
 				Path:      v.Path,
 				Size:      v.Size,
+				MimeType:  v.MimeType,
+				Format:    string(v.Format),
 				Status:    v.Status,
 			})
 		}
@@ -140,8 +152,11 @@ func FromProcessedImages(images []entity.ProcessedImage) []ProcessedImageInfo {
 	for _, img := range images {
 		result = append(result, ProcessedImageInfo{
 			Operation: string(img.Operation),
+			Variant:   img.VariantName,
 			Path:      img.Path,
 			Size:      img.Size,
+			MimeType:  img.MimeType,
+			Format:    string(img.Format),
 			Status:    img.Status,
 		})
 	}
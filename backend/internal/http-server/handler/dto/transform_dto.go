@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	transformOperationSeparator = ","
+	transformParamSeparator     = ":"
+	transformValueSeparator     = "="
+)
+
+// ParseTransformOptions разбирает строку опций URL трансформации.
+// Формат: операции через запятую, параметры операции через двоеточие:
+//
+//	resize:width=640:format=png,grayscale
+//
+// Числа и true/false приводятся к соответствующим типам, остальное - строки
+func ParseTransformOptions(options string) ([]OperationRequest, error) {
+	if options == "" {
+		return nil, fmt.Errorf("options are empty")
+	}
+
+	parts := strings.Split(options, transformOperationSeparator)
+	operations := make([]OperationRequest, 0, len(parts))
+
+	for i, part := range parts {
+		fields := strings.Split(part, transformParamSeparator)
+		if fields[0] == "" {
+			return nil, fmt.Errorf("operation %d: type is empty", i)
+		}
+
+		op := OperationRequest{
+			Type:       fields[0],
+			Parameters: make(map[string]interface{}, len(fields)-1),
+		}
+
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, transformValueSeparator)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("operation %d: invalid parameter %q, expected key=value", i, field)
+			}
+			if _, duplicate := op.Parameters[key]; duplicate {
+				return nil, fmt.Errorf("operation %d: duplicate parameter %q", i, key)
+			}
+			op.Parameters[key] = parseTransformValue(value)
+		}
+
+		operations = append(operations, op)
+	}
+
+	return operations, nil
+}
+
+func parseTransformValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+		return number
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package dto
+
+import (
+	"encoding/json"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// CreateWebhookEndpointRequest представляет запрос на регистрацию получателя.
+// Если secret не задан, ключ подписи генерируется и возвращается один раз
+type CreateWebhookEndpointRequest struct {
+	URL         string `json:"url" binding:"required"`
+	Secret      string `json:"secret"`
+	Description string `json:"description"`
+}
+
+// WebhookEndpointResponse представляет получателя событий. Ключ подписи
+// возвращается только при создании
+type WebhookEndpointResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryResponse представляет доставку события одному получателю
+type WebhookDeliveryResponse struct {
+	ID             string     `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	ImageID        string     `json:"image_id"`
+	TaskID         string     `json:"task_id,omitempty"`
+	EndpointID     string     `json:"endpoint_id,omitempty"`
+	URL            string     `json:"url"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// Тело запроса и журнал попыток возвращаются только при просмотре одной доставки
+	Payload    json.RawMessage          `json:"payload,omitempty"`
+	AttemptLog []WebhookAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttemptResponse представляет одну попытку доставки
+type WebhookAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RedeliverWebhookResponse подтверждает постановку доставки в очередь
+type RedeliverWebhookResponse struct {
+	DeliveryID string `json:"delivery_id"`
+	Status     string `json:"status"`
+}
+
+// FromWebhookEndpointEntity конвертирует entity.WebhookEndpoint в DTO;
+// withSecret добавляет ключ подписи
+func FromWebhookEndpointEntity(endpoint *entity.WebhookEndpoint, withSecret bool) WebhookEndpointResponse {
+	response := WebhookEndpointResponse{
+		ID:          endpoint.ID,
+		URL:         endpoint.URL,
+		Description: endpoint.Description,
+		CreatedAt:   endpoint.CreatedAt,
+	}
+	if withSecret {
+		response.Secret = endpoint.Secret
+	}
+	return response
+}
+
+// FromWebhookEndpointEntities конвертирует список получателей в DTO без ключей подписи
+func FromWebhookEndpointEntities(endpoints []entity.WebhookEndpoint) []WebhookEndpointResponse {
+	result := make([]WebhookEndpointResponse, 0, len(endpoints))
+	for i := range endpoints {
+		result = append(result, FromWebhookEndpointEntity(&endpoints[i], false))
+	}
+	return result
+}
+
+// FromWebhookDeliveryEntity конвертирует entity.WebhookDelivery в DTO;
+// attempts != nil добавляет тело запроса и журнал попыток
+func FromWebhookDeliveryEntity(delivery *entity.WebhookDelivery, attempts []entity.WebhookAttempt) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		ImageID:        delivery.ImageID,
+		TaskID:         delivery.TaskID,
+		EndpointID:     delivery.EndpointID,
+		URL:            delivery.URL,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		MaxAttempts:    delivery.MaxAttempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+	// Время следующей попытки имеет смысл только для ожидающей доставки
+	if delivery.Status == entity.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+
+	if attempts != nil {
+		response.Payload = json.RawMessage(delivery.Payload)
+		response.AttemptLog = make([]WebhookAttemptResponse, 0, len(attempts))
+		for _, attempt := range attempts {
+			response.AttemptLog = append(response.AttemptLog, WebhookAttemptResponse{
+				Attempt:    attempt.Attempt,
+				StatusCode: attempt.StatusCode,
+				Error:      attempt.Error,
+				DurationMs: attempt.DurationMs,
+				CreatedAt:  attempt.CreatedAt,
+			})
+		}
+	}
+
+	return response
+}
+
+// FromWebhookDeliveryEntities конвертирует список доставок в DTO без тела запроса
+func FromWebhookDeliveryEntities(deliveries []entity.WebhookDelivery) []WebhookDeliveryResponse {
+	result := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		result = append(result, FromWebhookDeliveryEntity(&deliveries[i], nil))
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultKeepAlive - интервал keep-alive комментариев, если events.keepAlive не задан
+const defaultKeepAlive = 15 * time.Second
+
+// StreamImageEvents передает ход обработки изображения как Server-Sent Events.
+// Первое событие status содержит текущий статус, как GET /images/:id/status,
+// далее передаются события воркеров. Поток закрывается после события
+// completed, failed или cancelled, а если обработка уже завершена - сразу после status
+func (h *Handler) StreamImageEvents(c *gin.Context) {
+	imageID := c.Param("id")
+
+	// Подписка оформляется до чтения статуса, чтобы не пропустить события между ними
+	events, unsubscribe := h.progress.Subscribe(imageID)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	status, err := h.imageService.GetImageStatus(ctx, imageID)
+	cancel()
+	if err != nil {
+		h.logger.Error("Failed to get image status", zap.Error(err), zap.String("imageId", imageID))
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "not_found",
+			Message: "Image not found: " + err.Error(),
+		})
+		return
+	}
+
+	// Поток живет дольше server.writeTimeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Debug("Failed to reset write deadline", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Отключает буферизацию ответа в nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.SSEvent("status", newImageStatusResponse(status))
+	c.Writer.Flush()
+
+	switch status.Status {
+	case entity.StatusCompleted, entity.StatusFailed, entity.StatusCancelled, entity.StatusDeleted:
+		return
+	}
+
+	keepAlive := time.NewTicker(h.eventsConfig.KeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.streamsClosed:
+			return
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event := <-events:
+			c.SSEvent(string(event.Type), dto.FromProgressEvent(event))
+			c.Writer.Flush()
+			if event.Type.IsFinal() {
+				return
+			}
+		}
+	}
+}
+
+// CloseStreams завершает открытые SSE потоки. Вызывается при остановке
+// сервера, который иначе ждал бы отключения клиентов
+func (h *Handler) CloseStreams() {
+	h.closeStreams.Do(func() {
+		close(h.streamsClosed)
+	})
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"imageprocessor/backend/internal/repository/cloud/filesystem"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ServeFile отдает объект локального хранилища по presigned ссылке
+// /files/{objectKey}?expires=...&signature=...
+// Маршрут доступен, только если выбрано хранилище filesystem
+func (h *Handler) ServeFile(c *gin.Context) {
+	if h.fileStorage == nil {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "not_found",
+			Message: "File links are served only by the filesystem storage backend",
+		})
+		return
+	}
+
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+
+	contentType, err := h.fileStorage.VerifySignedURL(objectKey, c.Request.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, filesystem.ErrSigningDisabled):
+			c.JSON(http.StatusServiceUnavailable, dto.ErrorResponse{
+				Error:   "links_disabled",
+				Message: err.Error(),
+			})
+		case errors.Is(err, filesystem.ErrLinkExpired):
+			c.JSON(http.StatusForbidden, dto.ErrorResponse{
+				Error:   "link_expired",
+				Message: "Link has expired",
+			})
+		default:
+			h.logger.Warn("Rejected file request", zap.String("key", objectKey))
+			c.JSON(http.StatusForbidden, dto.ErrorResponse{
+				Error:   "invalid_signature",
+				Message: "Signature does not match link",
+			})
+		}
+		return
+	}
+
+	file, info, err := h.fileStorage.OpenFile(objectKey)
+	if err != nil {
+		if errors.Is(err, filesystem.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error:   "not_found",
+				Message: "File not found",
+			})
+			return
+		}
+		h.logger.Error("Failed to open file", zap.Error(err), zap.String("key", objectKey))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "download_failed",
+			Message: "Failed to open file",
+		})
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// Без заданного при подписи типа ServeContent определяет его по расширению и содержимому
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	http.ServeContent(c.Writer, c.Request, path.Base(objectKey), info.ModTime(), file)
+}
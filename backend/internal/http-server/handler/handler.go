@@ -3,12 +3,18 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 	"imageprocessor/backend/internal/http-server/handler/dto"
+	imageProcessor "imageprocessor/backend/internal/service/image_processor"
+	imageservice "imageprocessor/backend/internal/service/image_service"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,34 +22,80 @@ import (
 )
 
 const (
-	maxFileSize = 32 << 20 // 32 MB
+	// maxFormFieldSize ограничивает текстовые поля формы загрузки
+	maxFormFieldSize = 1 << 20 // 1 MB
+	// maxFormParts ограничивает количество частей формы загрузки
+	maxFormParts = 16
 )
 
 type Handler struct {
 	logger            *zap.Logger
 	imageService      ImageServiceInterface
 	statisticsService StatisticsServiceInterface
+	presetService     PresetServiceInterface
+	deadLetterService DeadLetterServiceInterface
+	webhookService    WebhookServiceInterface
+	backfillService   BackfillServiceInterface
+	transformService  TransformServiceInterface
+	overlayValidator  OverlayValidatorInterface
+	fonts             FontRegistryInterface
+	fileStorage       FileStorageInterface
+	progress          ProgressEventsInterface
+	processingConfig  config.ProcessingConfig
+	eventsConfig      config.EventsConfig
+
+	// streamsClosed закрывается при остановке сервера, завершая SSE потоки
+	streamsClosed chan struct{}
+	closeStreams  sync.Once
 }
 
-func NewHandler(log *zap.Logger, imageService ImageServiceInterface, statisticsService StatisticsServiceInterface) *Handler {
+func NewHandler(
+	log *zap.Logger,
+	imageService ImageServiceInterface,
+	statisticsService StatisticsServiceInterface,
+	presetService PresetServiceInterface,
+	deadLetterService DeadLetterServiceInterface,
+	webhookService WebhookServiceInterface,
+	backfillService BackfillServiceInterface,
+	transformService TransformServiceInterface,
+	overlayValidator OverlayValidatorInterface,
+	fonts FontRegistryInterface,
+	fileStorage FileStorageInterface,
+	progress ProgressEventsInterface,
+	processingConfig config.ProcessingConfig,
+	eventsConfig config.EventsConfig,
+) *Handler {
+	if eventsConfig.KeepAlive <= 0 {
+		eventsConfig.KeepAlive = defaultKeepAlive
+	}
+
 	return &Handler{
 		logger:            log,
 		imageService:      imageService,
 		statisticsService: statisticsService,
+		presetService:     presetService,
+		deadLetterService: deadLetterService,
+		webhookService:    webhookService,
+		backfillService:   backfillService,
+		transformService:  transformService,
+		overlayValidator:  overlayValidator,
+		fonts:             fonts,
+		fileStorage:       fileStorage,
+		progress:          progress,
+		processingConfig:  processingConfig.WithDefaults(),
+		eventsConfig:      eventsConfig,
+		streamsClosed:     make(chan struct{}),
 	}
 }
 
-// UploadImage обрабатывает загрузку изображения с операциями
+// UploadImage обрабатывает загрузку изображения с операциями.
+// Multipart-тело читается потоково: файл передается в хранилище по мере
+// получения, не накапливаясь в памяти, а текстовые поля формы могут идти
+// как до, так и после файла
 func (h *Handler) UploadImage(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
-	defer cancel()
-	// Ограничиваем размер загружаемого файла
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize)
-
-	// Парсим multipart форму (максимум 32MB)
-	err := c.Request.ParseMultipartForm(maxFileSize)
+	reader, err := c.Request.MultipartReader()
 	if err != nil {
-		h.logger.Error("Failed to parse multipart form", zap.Error(err))
+		h.logger.Error("Failed to read multipart form", zap.Error(err))
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
 			Error:   "invalid_request",
 			Message: "Failed to parse form: " + err.Error(),
@@ -51,83 +103,162 @@ func (h *Handler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	// Получаем файл из формы
-	file, header, err := c.Request.FormFile("image")
-	if err != nil {
-		h.logger.Error("Failed to get file from form", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Failed to get image file: " + err.Error(),
-		})
-		return
-	}
-	h.logger.Info("Content-Type of file", zap.String("content_type", c.Request.Header.Get("Content-Type")))
+	var image *entity.Image
+	var filename string
+	fields := make(map[string]string)
+
+	// Оригинал сохраняется до разбора операций, поэтому при любой ошибке
+	// после загрузки файла его нужно удалить
+	completed := false
 	defer func() {
-		err = file.Close()
-		if err != nil {
-			h.logger.Error("Failed to close file", zap.Error(err))
+		if image != nil && !completed {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = h.imageService.DiscardOriginal(ctx, image)
 		}
 	}()
 
-	// Читаем содержимое файла
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		h.logger.Error("Failed to read file", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Error:   "read_error",
-			Message: "Failed to read file: " + err.Error(),
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			h.logger.Error("Failed to parse multipart form", zap.Error(err))
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Failed to parse form: " + err.Error(),
+			})
+			return
+		}
+		if parts >= maxFormParts {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: fmt.Sprintf("Form must not contain more than %d parts", maxFormParts),
+			})
+			return
+		}
+
+		if part.FormName() == "image" {
+			if image != nil || part.FileName() == "" {
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+					Error:   "invalid_request",
+					Message: "Form must contain exactly one image file",
+				})
+				return
+			}
+
+			// Время загрузки зависит от размера файла и ограничено таймаутами сервера
+			filename = part.FileName()
+			image, err = h.imageService.StoreOriginal(c.Request.Context(), part, filename, part.Header.Get("Content-Type"))
+			if err != nil {
+				h.respondUploadError(c, err)
+				return
+			}
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		if err != nil || len(value) > maxFormFieldSize {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: fmt.Sprintf("Form field %q is invalid or exceeds %d bytes", part.FormName(), maxFormFieldSize),
+			})
+			return
+		}
+		fields[part.FormName()] = string(value)
+	}
+
+	if image == nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to get image file: no image part in form",
 		})
 		return
 	}
 
-	// Получаем список операций из формы
-	operationsJSON := c.PostForm("operations")
-	var operations []dto.OperationRequest
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
 
-	if operationsJSON != "" {
-		err = json.Unmarshal([]byte(operationsJSON), &operations)
-		if err != nil {
-			h.logger.Error("Failed to parse operations", zap.Error(err))
+	// Получаем список операций из формы или из пресета
+	operationsJSON := fields["operations"]
+	presetName := fields["preset"]
+
+	var entityOperations []entity.OperationParams
+	var preset *entity.Preset
+
+	if presetName != "" {
+		if operationsJSON != "" {
 			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-				Error:   "invalid_operations",
-				Message: "Failed to parse operations: " + err.Error(),
+				Error:   "invalid_request",
+				Message: "Specify either operations or preset, not both",
 			})
 			return
 		}
+
+		presetVersion := 0
+		if value := fields["preset_version"]; value != "" {
+			presetVersion, err = strconv.Atoi(value)
+			if err != nil || presetVersion < 0 {
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+					Error:   "invalid_preset_version",
+					Message: "preset_version must be a positive integer",
+				})
+				return
+			}
+		}
+
+		preset, err = h.presetService.GetPreset(ctx, presetName, presetVersion)
+		if err != nil {
+			h.respondPresetError(c, err, presetName)
+			return
+		}
+		entityOperations = preset.Operations
 	} else {
-		// Если операции не указаны, используем дефолтные
-		operations = []dto.OperationRequest{
-			{
-				Type: string(entity.OpThumbnail),
-				Parameters: map[string]interface{}{
-					"size": 200,
+		var operations []dto.OperationRequest
+
+		if operationsJSON != "" {
+			err = json.Unmarshal([]byte(operationsJSON), &operations)
+			if err != nil {
+				h.logger.Error("Failed to parse operations", zap.Error(err))
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+					Error:   "invalid_operations",
+					Message: "Failed to parse operations: " + err.Error(),
+				})
+				return
+			}
+		} else {
+			// Если операции не указаны, используем дефолтные
+			operations = []dto.OperationRequest{
+				{
+					Type: string(entity.OpThumbnail),
+					Parameters: map[string]interface{}{
+						entity.ParamSize: h.processingConfig.DefaultThumbnailSize,
+					},
 				},
-			},
+			}
 		}
-	}
 
-	// Валидируем операции
-	entityOperations := make([]entity.OperationParams, 0, len(operations))
-	for i, op := range operations {
-		if err := op.Validate(); err != nil {
-			h.logger.Error("Invalid operation", zap.Error(err), zap.Int("index", i))
-			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-				Error:   "invalid_operation",
-				Message: fmt.Sprintf("Invalid operation at index %d: %s", i, err.Error()),
-			})
+		var ok bool
+		entityOperations, ok = h.validateOperations(ctx, c, operations)
+		if !ok {
 			return
 		}
-		entityOperations = append(entityOperations, op.ToEntity())
 	}
 
-	// Получаем MIME тип
-	mimeType := header.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+	// callback_url получит webhook о завершении обработки
+	if callbackURL := fields["callback_url"]; callbackURL != "" {
+		if err := h.webhookService.ValidateCallbackURL(callbackURL); err != nil {
+			h.respondWebhookError(c, err, "")
+			return
+		}
+		image.CallbackURL = callbackURL
 	}
 
-	// Вызываем сервис для загрузки изображения
-	image, err := h.imageService.UploadImage(ctx, imageData, header.Filename, mimeType, entityOperations)
+	// Сохраняем запись об изображении и публикуем задачу;
+	// при ошибке записи в БД сервис сам удаляет оригинал
+	completed = true
+	image, err = h.imageService.CompleteUpload(ctx, image, entityOperations, preset)
 	if err != nil {
 		h.logger.Error("Failed to upload image", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
@@ -150,17 +281,59 @@ func (h *Handler) UploadImage(c *gin.Context) {
 	response := dto.UploadResponse{
 		ID:              image.ID,
 		Status:          string(image.Status),
-		Filename:        header.Filename,
+		Filename:        filename,
 		Size:            image.OriginalSize,
 		MimeType:        image.MimeType,
 		CreatedAt:       image.CreatedAt,
 		OperationsCount: len(entityOperations),
 		EstimatedTime:   len(entityOperations) * 2, // Примерная оценка в секундах
 	}
+	if preset != nil {
+		response.Preset = preset.Name
+		response.PresetVersion = preset.Version
+	}
+	response.CallbackURL = image.CallbackURL
 
 	c.JSON(http.StatusCreated, response)
 }
 
+// respondUploadError преобразует ошибки проверки загружаемого файла в ответ API
+func (h *Handler) respondUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrUnsupportedFormat):
+		c.JSON(http.StatusUnsupportedMediaType, dto.ErrorResponse{
+			Error:   "unsupported_format",
+			Message: fmt.Sprintf("Unsupported image format. Supported formats: %s", strings.Join(h.processingConfig.SupportedFormats, ", ")),
+		})
+	case errors.Is(err, entity.ErrContentMismatch):
+		c.JSON(http.StatusUnsupportedMediaType, dto.ErrorResponse{
+			Error:   "content_mismatch",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, dto.ErrorResponse{
+			Error:   "file_too_large",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, dto.ErrorResponse{
+			Error:   "image_too_large",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrInvalidImage):
+		c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+			Error:   "invalid_image",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Error("Failed to upload image", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "upload_failed",
+			Message: "Failed to upload image: " + err.Error(),
+		})
+	}
+}
+
 // GetImage возвращает изображение по ID и операции
 func (h *Handler) GetImage(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
@@ -174,17 +347,43 @@ func (h *Handler) GetImage(c *gin.Context) {
 		return
 	}
 
-	// Получаем тип операции из query параметров
+	// Получаем тип операции или имя варианта из query параметров
 	operationStr := c.DefaultQuery("operation", "original")
 	operation := entity.OperationType(operationStr)
+	variant := c.Query("variant")
 
 	h.logger.Debug("Get image request",
 		zap.String("imageId", imageID),
 		zap.String("operation", operationStr),
+		zap.String("variant", variant),
 	)
 
-	// Получаем изображение из сервиса
-	imageData, mimeType, err := h.imageService.GetImage(ctx, imageID, operation)
+	// Находим запрошенную версию изображения
+	object, err := h.imageService.ResolveImage(ctx, imageID, operation, variant)
+	if err != nil {
+		h.logger.Error("Failed to get image", zap.Error(err), zap.String("imageId", imageID))
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "not_found",
+			Message: "Image not found: " + err.Error(),
+		})
+		return
+	}
+
+	// Исходный файл не меняется и кэшируется надолго. Вариант повторная обработка
+	// заменяет под тем же URL, поэтому кэш должен проверять его по ETag
+	etag := object.ETag()
+	c.Header("ETag", etag)
+	if object.Original {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	imageData, err := h.imageService.DownloadImage(ctx, object)
 	if err != nil {
 		h.logger.Error("Failed to get image", zap.Error(err), zap.String("imageId", imageID))
 		c.JSON(http.StatusNotFound, dto.ErrorResponse{
@@ -195,13 +394,11 @@ func (h *Handler) GetImage(c *gin.Context) {
 	}
 
 	// Устанавливаем заголовки
-	c.Header("Content-Type", mimeType)
+	c.Header("Content-Type", object.MimeType)
 	c.Header("Content-Length", strconv.Itoa(len(imageData)))
-	c.Header("Cache-Control", "public, max-age=31536000")
-	c.Header("ETag", fmt.Sprintf("%s-%s", imageID, operationStr))
 
 	// Возвращаем бинарные данные
-	c.Data(http.StatusOK, mimeType, imageData)
+	c.Data(http.StatusOK, object.MimeType, imageData)
 }
 
 // GetImageStatus возвращает статус обработки изображения
@@ -231,18 +428,22 @@ func (h *Handler) GetImageStatus(c *gin.Context) {
 		return
 	}
 
-	// Формируем ответ
-	response := dto.ImageStatusResponse{
+	c.JSON(http.StatusOK, newImageStatusResponse(status))
+}
+
+// newImageStatusResponse формирует ответ со статусом обработки изображения
+func newImageStatusResponse(status *imageservice.ImageStatus) dto.ImageStatusResponse {
+	return dto.ImageStatusResponse{
 		ID:                  status.ID,
 		Status:              string(status.Status),
 		Progress:            status.Progress,
 		ProcessedOperations: status.ProcessedOperations,
 		TotalOperations:     status.TotalOperations,
+		JobID:               status.JobID,
+		JobStatus:           status.JobStatus,
 		CreatedAt:           status.CreatedAt,
 		UpdatedAt:           status.UpdatedAt,
 	}
-
-	c.JSON(http.StatusOK, response)
 }
 
 // DeleteImage удаляет изображение и все его версии
@@ -314,6 +515,7 @@ func (h *Handler) GetStatistics(c *gin.Context) {
 		TotalImagesUploaded:     stats.GeneralStatistics.TotalImagesUploaded,
 		TotalImagesProcessed:    stats.GeneralStatistics.TotalImagesProcessed,
 		TotalImagesFailed:       stats.GeneralStatistics.FailedProcessingAttempts,
+		TotalImagesTimedOut:     stats.GeneralStatistics.TimedOutProcessingAttempts,
 		TotalDataProcessedBytes: stats.GeneralStatistics.TotalDataProcessedBytes,
 		TotalDataProcessedMB:    float64(stats.GeneralStatistics.TotalDataProcessedBytes) / (1024 * 1024),
 		AverageProcessingTimeMs: stats.GeneralStatistics.AverageProcessingTimeMs,
@@ -340,6 +542,7 @@ func (h *Handler) GetImagePresignedURL(c *gin.Context) {
 
 	operationStr := c.DefaultQuery("operation", "original")
 	operation := entity.OperationType(operationStr)
+	variant := c.Query("variant")
 
 	expiryStr := c.DefaultQuery("expiry", "3600")
 	expiry, err := strconv.Atoi(expiryStr)
@@ -350,6 +553,7 @@ func (h *Handler) GetImagePresignedURL(c *gin.Context) {
 	h.logger.Debug("Get presigned URL request",
 		zap.String("imageId", imageID),
 		zap.String("operation", operationStr),
+		zap.String("variant", variant),
 		zap.Int("expiry", expiry),
 	)
 
@@ -358,6 +562,7 @@ func (h *Handler) GetImagePresignedURL(c *gin.Context) {
 		ctx,
 		imageID,
 		operation,
+		variant,
 		time.Duration(expiry)*time.Second,
 	)
 	if err != nil {
@@ -442,3 +647,78 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 		},
 	})
 }
+
+// validateOperations проверяет операции, назначает имена вариантов, проверяет граф конвейера
+// и существование изображений для наложения. При ошибке ответ уже записан в контекст
+func (h *Handler) validateOperations(ctx context.Context, c *gin.Context, operations []dto.OperationRequest) ([]entity.OperationParams, bool) {
+	entityOperations := make([]entity.OperationParams, 0, len(operations))
+	for i, op := range operations {
+		if err := op.Validate(h.processingConfig); err != nil {
+			h.logger.Error("Invalid operation", zap.Error(err), zap.Int("index", i))
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_operation",
+				Message: fmt.Sprintf("Invalid operation at index %d: %s", i, err.Error()),
+			})
+			return nil, false
+		}
+		entityOperations = append(entityOperations, op.ToEntity())
+	}
+
+	// Проверяем и назначаем имена вариантов
+	if err := dto.AssignVariantNames(entityOperations); err != nil {
+		h.logger.Error("Invalid operation names", zap.Error(err))
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_operation",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	// Проверяем граф операций: ссылки на входы и отсутствие циклов
+	if _, err := imageProcessor.BuildPipeline(entityOperations); err != nil {
+		h.logger.Error("Invalid operation pipeline", zap.Error(err))
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_pipeline",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	// Шрифт водяного знака проверяется по тем же шрифтам, что загружают воркеры
+	for i, op := range entityOperations {
+		name, ok := op.Parameters[entity.ParamFont].(string)
+		if !ok || op.Type != entity.OpWatermark || h.fonts.Has(name) {
+			continue
+		}
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_operation",
+			Message: fmt.Sprintf("Invalid operation at index %d: unknown font: %s. Available fonts: %s", i, name, strings.Join(h.fonts.Names(), ", ")),
+		})
+		return nil, false
+	}
+
+	// Проверяем, что изображения для наложения существуют
+	for i, op := range entityOperations {
+		source, ok := entity.OverlaySourceFromParams(op.Parameters)
+		if !ok {
+			continue
+		}
+		if err := h.overlayValidator.ValidateOverlay(ctx, source); err != nil {
+			if errors.Is(err, entity.ErrOverlayNotFound) {
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+					Error:   "invalid_overlay",
+					Message: fmt.Sprintf("Invalid operation at index %d: %s", i, err.Error()),
+				})
+				return nil, false
+			}
+			h.logger.Error("Failed to validate overlay", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "overlay_check_failed",
+				Message: "Failed to check overlay: " + err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	return entityOperations, true
+}
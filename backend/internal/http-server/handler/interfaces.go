@@ -3,18 +3,29 @@ package handler
 import (
 	"context"
 	"imageprocessor/backend/internal/domain/entity"
+	backfillservice "imageprocessor/backend/internal/service/backfill_service"
 	imageservice "imageprocessor/backend/internal/service/image_service"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
 	"time"
 )
 
 // ImageService определяет интерфейс сервиса изображений для хэндлеров
 type ImageServiceInterface interface {
-	UploadImage(ctx context.Context, imageData []byte, filename string, mimeType string, operations []entity.OperationParams) (*entity.Image, error)
-	GetImage(ctx context.Context, imageID string, operation entity.OperationType) ([]byte, string, error)
-	GetImagePresignedURL(ctx context.Context, imageID string, operation entity.OperationType, expiry time.Duration) (string, error)
+	StoreOriginal(ctx context.Context, data io.Reader, filename string, mimeType string) (*entity.Image, error)
+	CompleteUpload(ctx context.Context, image *entity.Image, operations []entity.OperationParams, preset *entity.Preset) (*entity.Image, error)
+	DiscardOriginal(ctx context.Context, image *entity.Image) error
+	ResolveImage(ctx context.Context, imageID string, operation entity.OperationType, variant string) (*imageservice.ImageObject, error)
+	DownloadImage(ctx context.Context, object *imageservice.ImageObject) ([]byte, error)
+	GetImagePresignedURL(ctx context.Context, imageID string, operation entity.OperationType, variant string, expiry time.Duration) (string, error)
 	DeleteImage(ctx context.Context, imageID string) error
 	GetImageStatus(ctx context.Context, imageID string) (*imageservice.ImageStatus, error)
 	ListImages(ctx context.Context, limit, offset int) ([]entity.Image, error)
+	CancelImageProcessing(ctx context.Context, imageID string) ([]entity.ProcessingJob, error)
+	CancelJob(ctx context.Context, jobID string) (*entity.ProcessingJob, error)
+	ReprocessImage(ctx context.Context, imageID string, operations []entity.OperationParams, preset *entity.Preset, supersede bool) (*entity.ProcessingTask, error)
 }
 
 // StatisticsService определяет интерфейс сервиса статистики для хэндлеров
@@ -26,3 +37,70 @@ type StatisticsServiceInterface interface {
 	RecordImageProcessed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error
 	RecordImageFailed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error
 }
+
+// PresetServiceInterface определяет интерфейс сервиса пресетов для хэндлеров
+type PresetServiceInterface interface {
+	CreatePreset(ctx context.Context, name, description string, operations []entity.OperationParams) (*entity.Preset, error)
+	UpdatePreset(ctx context.Context, name, description string, operations []entity.OperationParams) (*entity.Preset, error)
+	GetPreset(ctx context.Context, name string, version int) (*entity.Preset, error)
+	ListPresets(ctx context.Context) ([]entity.Preset, error)
+	ListPresetVersions(ctx context.Context, name string) ([]entity.Preset, error)
+	DeletePreset(ctx context.Context, name string) error
+}
+
+// DeadLetterServiceInterface определяет интерфейс сервиса dead-letter очереди
+type DeadLetterServiceInterface interface {
+	ListDeadLetters(ctx context.Context, status entity.DeadLetterStatus, limit, offset int) ([]entity.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (*entity.DeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, id string, operations []entity.OperationParams) (*entity.ProcessingTask, error)
+}
+
+// WebhookServiceInterface определяет интерфейс сервиса webhook для хэндлеров
+type WebhookServiceInterface interface {
+	ValidateCallbackURL(rawURL string) error
+	CreateEndpoint(ctx context.Context, rawURL, secret, description string) (*entity.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter, limit, offset int) ([]entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error)
+	Redeliver(ctx context.Context, id string) error
+}
+
+// BackfillServiceInterface определяет интерфейс сервиса массовой повторной обработки
+type BackfillServiceInterface interface {
+	CreateBackfill(ctx context.Context, params backfillservice.BackfillParams) (*entity.Backfill, error)
+	ListBackfills(ctx context.Context, status entity.BackfillStatus, limit, offset int) ([]entity.Backfill, error)
+	GetBackfill(ctx context.Context, id string) (*backfillservice.BackfillReport, error)
+	PauseBackfill(ctx context.Context, id string) (*entity.Backfill, error)
+	ResumeBackfill(ctx context.Context, id string) (*entity.Backfill, error)
+}
+
+// TransformServiceInterface определяет интерфейс сервиса трансформаций по URL
+type TransformServiceInterface interface {
+	VerifySignature(signature, options, imageID string) error
+	Transform(ctx context.Context, imageID string, operations []entity.OperationParams) ([]byte, string, error)
+}
+
+// OverlayValidatorInterface проверяет существование изображений для наложения
+type OverlayValidatorInterface interface {
+	ValidateOverlay(ctx context.Context, source entity.OverlaySource) error
+}
+
+// FontRegistryInterface проверяет шрифты водяного знака
+type FontRegistryInterface interface {
+	Has(name string) bool
+	Names() []string
+}
+
+// FileStorageInterface раздает объекты локального хранилища по подписанным ссылкам
+type FileStorageInterface interface {
+	VerifySignedURL(objectKey string, query url.Values) (string, error)
+	OpenFile(objectKey string) (*os.File, fs.FileInfo, error)
+}
+
+// ProgressEventsInterface выдает события хода обработки изображения и
+// публикует события об отмене задач
+type ProgressEventsInterface interface {
+	Subscribe(imageID string) (<-chan *entity.ProgressEvent, func())
+	PublishProgress(ctx context.Context, event *entity.ProgressEvent) error
+}
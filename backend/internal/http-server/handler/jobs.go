@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CancelImageProcessing отменяет ожидающие и выполняющиеся задачи изображения
+func (h *Handler) CancelImageProcessing(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	imageID := c.Param("id")
+
+	jobs, err := h.imageService.CancelImageProcessing(ctx, imageID)
+	if err != nil {
+		h.respondCancelError(c, err, imageID)
+		return
+	}
+
+	for i := range jobs {
+		h.publishCancelled(ctx, &jobs[i])
+	}
+
+	c.JSON(http.StatusOK, dto.CancelImageResponse{
+		ImageID: imageID,
+		Status:  string(entity.StatusCancelled),
+		Jobs:    dto.FromProcessingJobs(jobs),
+	})
+}
+
+// CancelJob отменяет задачу на обработку по ID
+func (h *Handler) CancelJob(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	jobID := c.Param("id")
+
+	job, err := h.imageService.CancelJob(ctx, jobID)
+	if err != nil {
+		h.respondCancelError(c, err, jobID)
+		return
+	}
+
+	h.publishCancelled(ctx, job)
+
+	c.JSON(http.StatusOK, dto.FromProcessingJob(job))
+}
+
+// publishCancelled завершает SSE потоки изображения отмененной задачи.
+// Воркер публикует то же событие, если задача уже выполнялась, но задачу
+// из очереди он может и не получить
+func (h *Handler) publishCancelled(ctx context.Context, job *entity.ProcessingJob) {
+	event := &entity.ProgressEvent{
+		Type:      entity.ProgressCancelled,
+		ImageID:   job.ImageID,
+		TaskID:    job.ID,
+		Timestamp: time.Now(),
+	}
+	if err := h.progress.PublishProgress(ctx, event); err != nil {
+		h.logger.Warn("Failed to publish cancel event", zap.Error(err), zap.String("jobId", job.ID))
+	}
+}
+
+// respondCancelError преобразует ошибку отмены в HTTP ответ
+func (h *Handler) respondCancelError(c *gin.Context, err error, id string) {
+	switch {
+	case errors.Is(err, entity.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "job_not_found",
+			Message: "Processing job not found: " + id,
+		})
+	case errors.Is(err, entity.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "not_found",
+			Message: "Image not found: " + id,
+		})
+	case errors.Is(err, entity.ErrJobNotCancellable):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error:   "not_cancellable",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Error("Failed to cancel processing", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "cancel_failed",
+			Message: "Failed to cancel processing: " + err.Error(),
+		})
+	}
+}
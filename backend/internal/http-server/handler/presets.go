@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreatePreset создает новый пресет (версия 1)
+func (h *Handler) CreatePreset(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req dto.CreatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_preset",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	operations, ok := h.validateOperations(ctx, c, req.Operations)
+	if !ok {
+		return
+	}
+
+	preset, err := h.presetService.CreatePreset(ctx, req.Name, req.Description, operations)
+	if err != nil {
+		h.respondPresetError(c, err, req.Name)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.FromPresetEntity(preset))
+}
+
+// UpdatePreset сохраняет новую версию пресета
+func (h *Handler) UpdatePreset(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+
+	var req dto.UpdatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_preset",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	operations, ok := h.validateOperations(ctx, c, req.Operations)
+	if !ok {
+		return
+	}
+
+	preset, err := h.presetService.UpdatePreset(ctx, name, req.Description, operations)
+	if err != nil {
+		h.respondPresetError(c, err, name)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromPresetEntity(preset))
+}
+
+// GetPreset возвращает последнюю или указанную (?version=) версию пресета
+func (h *Handler) GetPreset(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+
+	version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_version",
+			Message: "version must be a positive integer",
+		})
+		return
+	}
+
+	preset, err := h.presetService.GetPreset(ctx, name, version)
+	if err != nil {
+		h.respondPresetError(c, err, name)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.FromPresetEntity(preset))
+}
+
+// ListPresets возвращает последние версии всех пресетов
+func (h *Handler) ListPresets(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	presets, err := h.presetService.ListPresets(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list presets", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "list_failed",
+			Message: "Failed to list presets: " + err.Error(),
+		})
+		return
+	}
+
+	response := dto.FromPresetEntities(presets)
+	c.JSON(http.StatusOK, gin.H{
+		"presets": response,
+		"count":   len(response),
+	})
+}
+
+// ListPresetVersions возвращает историю версий пресета
+func (h *Handler) ListPresetVersions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+
+	versions, err := h.presetService.ListPresetVersions(ctx, name)
+	if err != nil {
+		h.respondPresetError(c, err, name)
+		return
+	}
+
+	response := dto.FromPresetEntities(versions)
+	c.JSON(http.StatusOK, gin.H{
+		"name":     name,
+		"versions": response,
+		"count":    len(response),
+	})
+}
+
+// DeletePreset удаляет пресет со всеми версиями
+func (h *Handler) DeletePreset(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+
+	if err := h.presetService.DeletePreset(ctx, name); err != nil {
+		h.respondPresetError(c, err, name)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Preset deleted successfully",
+		"name":    name,
+	})
+}
+
+// respondPresetError преобразует ошибку сервиса пресетов в HTTP ответ
+func (h *Handler) respondPresetError(c *gin.Context, err error, name string) {
+	switch {
+	case errors.Is(err, entity.ErrPresetNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "preset_not_found",
+			Message: "Preset not found: " + name,
+		})
+	case errors.Is(err, entity.ErrPresetAlreadyExists):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error:   "preset_exists",
+			Message: "Preset already exists: " + name,
+		})
+	default:
+		h.logger.Error("Preset operation failed", zap.Error(err), zap.String("preset", name))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "preset_error",
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReprocessImage создает новую задачу для загруженного изображения с другими
+// операциями или пресетом, не загружая оригинал повторно
+func (h *Handler) ReprocessImage(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	imageID := c.Param("id")
+
+	var req dto.ReprocessImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+
+	operations, preset, ok := h.resolveOperations(ctx, c, req.Operations, req.Preset, req.PresetVersion)
+	if !ok {
+		return
+	}
+
+	task, err := h.imageService.ReprocessImage(ctx, imageID, operations, preset, req.Supersede)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error:   "not_found",
+				Message: "Image not found: " + imageID,
+			})
+		case errors.Is(err, entity.ErrJobInProgress):
+			c.JSON(http.StatusConflict, dto.ErrorResponse{
+				Error:   "processing_in_progress",
+				Message: "Image has a pending or processing job; cancel it or wait for it to finish",
+			})
+		default:
+			h.logger.Error("Failed to reprocess image", zap.Error(err), zap.String("imageId", imageID))
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "reprocess_failed",
+				Message: "Failed to reprocess image: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.ReprocessImageResponse{
+		ImageID:         imageID,
+		JobID:           task.ID,
+		Status:          entity.JobStatusPending,
+		OperationsCount: len(task.Operations),
+		Preset:          task.PresetName,
+		PresetVersion:   task.PresetVersion,
+		Supersede:       task.Supersede,
+	})
+}
+
+// resolveOperations возвращает операции запроса или операции пресета; указывается
+// что-то одно. При ошибке отправляет ответ и возвращает false
+func (h *Handler) resolveOperations(ctx context.Context, c *gin.Context, requested []dto.OperationRequest, presetName string, presetVersion int) ([]entity.OperationParams, *entity.Preset, bool) {
+	if (presetName == "") == (len(requested) == 0) {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Specify either operations or preset",
+		})
+		return nil, nil, false
+	}
+	if presetVersion < 0 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_preset_version",
+			Message: "preset_version must be a positive integer",
+		})
+		return nil, nil, false
+	}
+
+	if presetName != "" {
+		preset, err := h.presetService.GetPreset(ctx, presetName, presetVersion)
+		if err != nil {
+			h.respondPresetError(c, err, presetName)
+			return nil, nil, false
+		}
+		return preset.Operations, preset, true
+	}
+
+	operations, ok := h.validateOperations(ctx, c, requested)
+	if !ok {
+		return nil, nil, false
+	}
+	return operations, nil, true
+}
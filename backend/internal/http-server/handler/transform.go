@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	transformservice "imageprocessor/backend/internal/service/transform_service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TransformImage выполняет трансформацию по подписанному URL
+// /transform/{signature}/{options}/{imageID} и отдает результат
+func (h *Handler) TransformImage(c *gin.Context) {
+	signature := c.Param("signature")
+	options := c.Param("options")
+	imageID := c.Param("id")
+
+	if err := h.transformService.VerifySignature(signature, options, imageID); err != nil {
+		if errors.Is(err, transformservice.ErrTransformDisabled) {
+			c.JSON(http.StatusServiceUnavailable, dto.ErrorResponse{
+				Error:   "transform_disabled",
+				Message: err.Error(),
+			})
+			return
+		}
+		h.logger.Warn("Rejected transform request", zap.String("imageId", imageID), zap.String("options", options))
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{
+			Error:   "invalid_signature",
+			Message: "Signature does not match options",
+		})
+		return
+	}
+
+	operations, err := dto.ParseTransformOptions(options)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_options",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	entityOperations, ok := h.validateOperations(c.Request.Context(), c, operations)
+	if !ok {
+		return
+	}
+
+	// Таймаут обработки задается в конфигурации сервиса трансформаций
+	imageData, mimeType, err := h.transformService.Transform(c.Request.Context(), imageID, entityOperations)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error:   "not_found",
+				Message: "Image not found: " + imageID,
+			})
+		case errors.Is(err, transformservice.ErrTooManyOperations):
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_options",
+				Message: err.Error(),
+			})
+		case errors.Is(err, entity.ErrImageTooLarge), errors.Is(err, entity.ErrInvalidImage):
+			// Оригинал уже сохранен, поэтому это ошибка обработки, а не размера запроса
+			c.JSON(http.StatusUnprocessableEntity, dto.ErrorResponse{
+				Error:   "unprocessable_image",
+				Message: err.Error(),
+			})
+		default:
+			h.logger.Error("Failed to transform image", zap.Error(err), zap.String("imageId", imageID))
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "transform_failed",
+				Message: "Failed to transform image: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	// Результат однозначно определяется подписанным URL, поэтому кэшируется надолго
+	c.Header("Content-Length", strconv.Itoa(len(imageData)))
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+signature+`"`)
+
+	c.Data(http.StatusOK, mimeType, imageData)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"imageprocessor/backend/internal/service/image_processor/operations"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestValidateOperations_UnknownFont(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fonts, err := operations.LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, fonts, nil, nil, config.ProcessingConfig{}, config.EventsConfig{})
+
+	tests := []struct {
+		font     string
+		wantCode int
+	}{
+		{"gobold", http.StatusOK},
+		{"GoMono", http.StatusOK},
+		{"comic-sans", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+
+		requested := []dto.OperationRequest{{
+			Type:       "watermark",
+			Parameters: map[string]interface{}{"text": "hi", "font": tt.font},
+		}}
+		_, ok := h.validateOperations(context.Background(), c, requested)
+
+		if tt.wantCode == http.StatusOK {
+			if !ok {
+				t.Errorf("font %q: expected operations to be accepted, got %s", tt.font, recorder.Body.String())
+			}
+			continue
+		}
+		if ok || recorder.Code != tt.wantCode || !strings.Contains(recorder.Body.String(), "unknown font: "+tt.font) {
+			t.Errorf("font %q: expected %d with unknown font error, got %d %s", tt.font, tt.wantCode, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateWebhookEndpoint регистрирует получателя событий обработки всех изображений
+func (h *Handler) CreateWebhookEndpoint(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req dto.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request: " + err.Error(),
+		})
+		return
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(ctx, req.URL, req.Secret, req.Description)
+	if err != nil {
+		h.respondWebhookError(c, err, "")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.FromWebhookEndpointEntity(endpoint, true))
+}
+
+// ListWebhookEndpoints возвращает зарегистрированных получателей без ключей подписи
+func (h *Handler) ListWebhookEndpoints(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	endpoints, err := h.webhookService.ListEndpoints(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list webhook endpoints", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "list_failed",
+			Message: "Failed to list webhook endpoints: " + err.Error(),
+		})
+		return
+	}
+
+	response := dto.FromWebhookEndpointEntities(endpoints)
+	c.JSON(http.StatusOK, gin.H{
+		"endpoints": response,
+		"count":     len(response),
+	})
+}
+
+// DeleteWebhookEndpoint удаляет получателя вместе с журналом его доставок
+func (h *Handler) DeleteWebhookEndpoint(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	if err := h.webhookService.DeleteEndpoint(ctx, id); err != nil {
+		h.respondWebhookError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook endpoint deleted successfully",
+		"id":      id,
+	})
+}
+
+// ListWebhookDeliveries возвращает доставки с пагинацией. ?status, ?image_id
+// и ?endpoint_id фильтруют список
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := entity.WebhookDeliveryFilter{
+		Status:     entity.WebhookDeliveryStatus(c.Query("status")),
+		ImageID:    c.Query("image_id"),
+		EndpointID: c.Query("endpoint_id"),
+	}
+	switch filter.Status {
+	case "", entity.WebhookDeliveryPending, entity.WebhookDeliveryDelivered, entity.WebhookDeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_status",
+			Message: "status must be pending, delivered or failed",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(ctx, filter, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "list_failed",
+			Message: "Failed to list webhook deliveries: " + err.Error(),
+		})
+		return
+	}
+
+	response := dto.FromWebhookDeliveryEntities(deliveries)
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": response,
+		"limit":      limit,
+		"offset":     offset,
+		"count":      len(response),
+	})
+}
+
+// GetWebhookDelivery возвращает доставку с телом запроса и журналом попыток
+func (h *Handler) GetWebhookDelivery(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	delivery, attempts, err := h.webhookService.GetDelivery(ctx, id)
+	if err != nil {
+		h.respondWebhookError(c, err, id)
+		return
+	}
+	if attempts == nil {
+		attempts = []entity.WebhookAttempt{}
+	}
+
+	c.JSON(http.StatusOK, dto.FromWebhookDeliveryEntity(delivery, attempts))
+}
+
+// RedeliverWebhookDelivery снова отправляет доставленное или неудавшееся событие
+func (h *Handler) RedeliverWebhookDelivery(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	if err := h.webhookService.Redeliver(ctx, id); err != nil {
+		h.respondWebhookError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.RedeliverWebhookResponse{
+		DeliveryID: id,
+		Status:     string(entity.WebhookDeliveryPending),
+	})
+}
+
+// respondWebhookError преобразует ошибку сервиса webhook в HTTP ответ
+func (h *Handler) respondWebhookError(c *gin.Context, err error, id string) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidWebhookURL):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_url",
+			Message: err.Error(),
+		})
+	case errors.Is(err, entity.ErrCallbacksDisabled):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "callbacks_disabled",
+			Message: "callback_url is not accepted: webhook secret is not configured",
+		})
+	case errors.Is(err, entity.ErrWebhookEndpointNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "endpoint_not_found",
+			Message: "Webhook endpoint not found: " + id,
+		})
+	case errors.Is(err, entity.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error:   "delivery_not_found",
+			Message: "Webhook delivery not found: " + id,
+		})
+	case errors.Is(err, entity.ErrWebhookDeliveryPending):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error:   "delivery_pending",
+			Message: "Webhook delivery is still pending: " + id,
+		})
+	default:
+		h.logger.Error("Webhook operation failed", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "webhook_error",
+			Message: err.Error(),
+		})
+	}
+}
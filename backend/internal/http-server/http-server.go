@@ -35,6 +35,8 @@ func NewServer(logger *zap.Logger, cfg *config.ServiceConfig, handlers *handler.
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	// Shutdown не прерывает обработчики, поэтому SSE потоки завершаются отдельно
+	srv.RegisterOnShutdown(handlers.CloseStreams)
 
 	return &Server{
 		server:   srv,
@@ -92,5 +94,5 @@ func (s *Server) setupRoutes() {
 	// API routes
 	api := s.router.Group("/api/v1")
 	api.Use(middleware.Logger(s.logger))
-	routes.SetupRoutes(api, s.handlers, s.logger)
+	routes.SetupRoutes(api, s.handlers, s.config.AdminConfig.APIKey, s.logger)
 }
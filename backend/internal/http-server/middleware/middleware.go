@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -83,6 +86,31 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// AdminAuth middleware проверяет ключ административного API в заголовке
+// Authorization: Bearer <key>. Без настроенного ключа маршруты отключены
+func AdminAuth(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "admin_disabled",
+				"message": "Admin API key is not configured",
+			})
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "Invalid or missing admin API key",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequestID middleware для добавления уникального ID к каждому запросу
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
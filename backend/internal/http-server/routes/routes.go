@@ -9,7 +9,7 @@ import (
 )
 
 // SetupRoutes настраивает все маршруты API
-func SetupRoutes(router *gin.RouterGroup, h *handler.Handler, logger *zap.Logger) {
+func SetupRoutes(router *gin.RouterGroup, h *handler.Handler, adminAPIKey string, logger *zap.Logger) {
 	// Middleware
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.Recovery(logger))
@@ -23,12 +23,36 @@ func SetupRoutes(router *gin.RouterGroup, h *handler.Handler, logger *zap.Logger
 
 	images := router.Group("/images")
 	{
-		images.POST("", h.UploadImage)                 // Загрузка изображения с операциями
-		images.GET("", h.ListImages)                   // Список изображений
-		images.GET("/:id", h.GetImage)                 // Получение изображения
-		images.GET("/:id/status", h.GetImageStatus)    // Статус обработки
-		images.GET("/:id/url", h.GetImagePresignedURL) // Генерация presigned URL
-		images.DELETE("/:id", h.DeleteImage)           // Удаление изображения
+		images.POST("", h.UploadImage)                      // Загрузка изображения с операциями
+		images.GET("", h.ListImages)                        // Список изображений
+		images.GET("/:id", h.GetImage)                      // Получение изображения
+		images.GET("/:id/status", h.GetImageStatus)         // Статус обработки
+		images.GET("/:id/events", h.StreamImageEvents)      // Ход обработки (Server-Sent Events)
+		images.GET("/:id/url", h.GetImagePresignedURL)      // Генерация presigned URL
+		images.POST("/:id/cancel", h.CancelImageProcessing) // Отмена обработки
+		images.POST("/:id/reprocess", h.ReprocessImage)     // Повторная обработка оригинала
+		images.DELETE("/:id", h.DeleteImage)                // Удаление изображения
+	}
+
+	jobs := router.Group("/jobs")
+	{
+		jobs.POST("/:id/cancel", h.CancelJob) // Отмена задачи
+	}
+
+	// Трансформация по подписанному URL: /transform/{signature}/{options}/{imageID}
+	router.GET("/transform/:signature/:options/:id", h.TransformImage)
+
+	// Раздача файлов локального хранилища по presigned ссылкам: /files/{objectKey}
+	router.GET("/files/*key", h.ServeFile)
+
+	presets := router.Group("/presets")
+	{
+		presets.POST("", h.CreatePreset)                     // Создание пресета
+		presets.GET("", h.ListPresets)                       // Список пресетов (последние версии)
+		presets.GET("/:name", h.GetPreset)                   // Получение пресета (?version=N)
+		presets.GET("/:name/versions", h.ListPresetVersions) // История версий
+		presets.PUT("/:name", h.UpdatePreset)                // Новая версия пресета
+		presets.DELETE("/:name", h.DeletePreset)             // Удаление пресета
 	}
 
 	statistics := router.Group("/statistics")
@@ -36,6 +60,28 @@ func SetupRoutes(router *gin.RouterGroup, h *handler.Handler, logger *zap.Logger
 		statistics.GET("", h.GetStatistics) // Общая статистика
 	}
 
+	// Административные маршруты требуют ключ admin.apiKey
+	admin := router.Group("/admin")
+	admin.Use(middleware.AdminAuth(adminAPIKey))
+	{
+		admin.GET("/dead-letters", h.ListDeadLetters)              // Сообщения dead-letter очереди
+		admin.GET("/dead-letters/:id", h.GetDeadLetter)            // Сообщение с исходным payload
+		admin.POST("/dead-letters/:id/replay", h.ReplayDeadLetter) // Повторная обработка
+
+		admin.POST("/webhook-endpoints", h.CreateWebhookEndpoint)                   // Регистрация получателя
+		admin.GET("/webhook-endpoints", h.ListWebhookEndpoints)                     // Список получателей
+		admin.DELETE("/webhook-endpoints/:id", h.DeleteWebhookEndpoint)             // Удаление получателя
+		admin.GET("/webhook-deliveries", h.ListWebhookDeliveries)                   // Журнал доставок
+		admin.GET("/webhook-deliveries/:id", h.GetWebhookDelivery)                  // Доставка с попытками
+		admin.POST("/webhook-deliveries/:id/redeliver", h.RedeliverWebhookDelivery) // Повторная доставка
+
+		admin.POST("/backfills", h.CreateBackfill)            // Массовая повторная обработка по фильтру
+		admin.GET("/backfills", h.ListBackfills)              // Список backfill
+		admin.GET("/backfills/:id", h.GetBackfill)            // Ход, итоги задач и ошибки
+		admin.POST("/backfills/:id/pause", h.PauseBackfill)   // Приостановка
+		admin.POST("/backfills/:id/resume", h.ResumeBackfill) // Продолжение с курсора
+	}
+
 	// Версия API
 	router.GET("/version", func(c *gin.Context) {
 		c.JSON(200, gin.H{
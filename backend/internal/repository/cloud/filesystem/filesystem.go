@@ -0,0 +1,312 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tmpDir - служебный каталог для незавершенных загрузок. Он находится внутри
+// корня, чтобы переименование готового файла было атомарным
+const tmpDir = ".tmp"
+
+var (
+	ErrInvalidObjectKey = errors.New("invalid object key")
+	ErrObjectNotFound   = errors.New("object not found")
+)
+
+// FileSystemStorage хранит объекты в каталоге локальной файловой системы.
+// Ключ объекта соответствует относительному пути файла внутри корня
+type FileSystemStorage struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewFileSystemStorage создает хранилище в каталоге cfg.Filesystem.RootDir
+func NewFileSystemStorage(cfg config.CloudStorageConfig) (*FileSystemStorage, error) {
+	if cfg.Filesystem.RootDir == "" {
+		return nil, fmt.Errorf("filesystem storage root directory is not configured")
+	}
+
+	root, err := filepath.Abs(cfg.Filesystem.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, tmpDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	return &FileSystemStorage{
+		root:       root,
+		publicURL:  strings.TrimRight(cfg.Filesystem.PublicURL, "/"),
+		signingKey: []byte(cfg.Filesystem.SigningKey),
+	}, nil
+}
+
+// objectPath преобразует ключ объекта в путь внутри корня.
+// Ключи, выходящие за пределы корня или в служебный каталог, отклоняются
+func (s *FileSystemStorage) objectPath(objectKey string) (string, error) {
+	if objectKey == "" || strings.HasPrefix(objectKey, "/") || strings.Contains(objectKey, "\\") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidObjectKey, objectKey)
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(objectKey))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) ||
+		cleaned == tmpDir || strings.HasPrefix(cleaned, tmpDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidObjectKey, objectKey)
+	}
+
+	return filepath.Join(s.root, cleaned), nil
+}
+
+// UploadFile записывает объект во временный файл и переименовывает его
+// после успешного чтения всего потока, поэтому прерванная загрузка
+// не оставляет частично записанных объектов
+func (s *FileSystemStorage) UploadFile(ctx context.Context, objectKey string, data io.Reader, size int64, contentType string) error {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, tmpDir), "upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	written, err := io.Copy(tmp, &contextReader{ctx: ctx, reader: data})
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write file: expected %d bytes, got %d", size, written)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	committed = true
+
+	return nil
+}
+
+// DownloadFile читает объект целиком
+func (s *FileSystemStorage) DownloadFile(ctx context.Context, objectKey string) ([]byte, error) {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", notFound(err, objectKey))
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("object is empty")
+	}
+	return data, nil
+}
+
+// DownloadFileStream открывает объект для чтения
+func (s *FileSystemStorage) DownloadFileStream(ctx context.Context, objectKey string) (io.ReadCloser, error) {
+	file, _, err := s.OpenFile(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// OpenFile открывает объект и возвращает сведения о файле. Используется
+// маршрутом раздачи файлов, которому нужны Seek и время изменения
+func (s *FileSystemStorage) OpenFile(objectKey string) (*os.File, fs.FileInfo, error) {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open object: %w", notFound(err, objectKey))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	if info.IsDir() {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("failed to open object: %w: %s", ErrObjectNotFound, objectKey)
+	}
+	return file, info, nil
+}
+
+// DeleteFile удаляет объект. Как и в S3, удаление отсутствующего объекта не ошибка
+func (s *FileSystemStorage) DeleteFile(ctx context.Context, objectKey string) error {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	s.removeEmptyDirs(filepath.Dir(path))
+	return nil
+}
+
+// DeleteFiles удаляет множество объектов
+func (s *FileSystemStorage) DeleteFiles(ctx context.Context, objectKeys []string) error {
+	failed := 0
+	for _, key := range objectKeys {
+		if err := s.DeleteFile(ctx, key); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d files", failed)
+	}
+	return nil
+}
+
+// FileExists проверяет существование объекта
+func (s *FileSystemStorage) FileExists(ctx context.Context, objectKey string) (bool, error) {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check file existence: %w", err)
+	}
+	return !info.IsDir(), nil
+}
+
+// GetFileSize возвращает размер объекта
+func (s *FileSystemStorage) GetFileSize(ctx context.Context, objectKey string) (int64, error) {
+	path, err := s.objectPath(objectKey)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get file info: %w", notFound(err, objectKey))
+	}
+	return info.Size(), nil
+}
+
+// ListFiles возвращает ключи объектов с заданным префиксом. Как и в S3,
+// префикс сравнивается со строкой ключа, а не только с каталогом
+func (s *FileSystemStorage) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	// Обходим только каталог, в котором может начинаться префикс
+	walkRoot := s.root
+	if dir := filepath.Dir(filepath.FromSlash(prefix)); dir != "." && !strings.HasPrefix(dir, "..") {
+		walkRoot = filepath.Join(s.root, dir)
+	}
+
+	var files []string
+	err := filepath.WalkDir(walkRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if rel == tmpDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			files = append(files, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	return files, nil
+}
+
+// CopyFile копирует объект внутри хранилища
+func (s *FileSystemStorage) CopyFile(ctx context.Context, sourceKey, destKey string) error {
+	source, _, err := s.OpenFile(sourceKey)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	if err := s.UploadFile(ctx, destKey, source, -1, ""); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
+
+// removeEmptyDirs удаляет опустевшие каталоги от dir вверх до корня
+func (s *FileSystemStorage) removeEmptyDirs(dir string) {
+	for dir != s.root && strings.HasPrefix(dir, s.root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// notFound заменяет ошибку отсутствия файла на ErrObjectNotFound
+func notFound(err error, objectKey string) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	return err
+}
+
+// contextReader прерывает чтение при отмене контекста
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
@@ -0,0 +1,188 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/config"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *FileSystemStorage {
+	t.Helper()
+	storage, err := NewFileSystemStorage(config.CloudStorageConfig{
+		Filesystem: config.FilesystemStorageConfig{
+			RootDir:    t.TempDir(),
+			PublicURL:  "http://localhost:8080/api/v1/files/",
+			SigningKey: "secret",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func TestFileSystemStorage_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	data := []byte("image data")
+	if err := storage.UploadFile(ctx, "originals/1/photo.png", bytes.NewReader(data), -1, "image/png"); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if err := storage.CopyFile(ctx, "originals/1/photo.png", "processed/1/photo.png"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	got, err := storage.DownloadFile(ctx, "processed/1/photo.png")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("download: %q, %v", got, err)
+	}
+	if size, err := storage.GetFileSize(ctx, "originals/1/photo.png"); err != nil || size != int64(len(data)) {
+		t.Fatalf("size: %d, %v", size, err)
+	}
+
+	files, err := storage.ListFiles(ctx, "origin")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{"originals/1/photo.png"}) {
+		t.Fatalf("unexpected listing: %v", files)
+	}
+
+	if err := storage.DeleteFiles(ctx, []string{"originals/1/photo.png", "processed/1/photo.png", "missing"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if exists, err := storage.FileExists(ctx, "originals/1/photo.png"); err != nil || exists {
+		t.Fatalf("exists after delete: %v, %v", exists, err)
+	}
+	if _, err := storage.DownloadFile(ctx, "originals/1/photo.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	// Опустевшие каталоги удаляются вместе с объектами
+	entries, err := os.ReadDir(storage.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{tmpDir}) {
+		t.Fatalf("unexpected root entries: %v", names)
+	}
+}
+
+func TestFileSystemStorage_RejectsInvalidKeys(t *testing.T) {
+	storage := newTestStorage(t)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../outside", ".tmp/upload-1", `a\b`} {
+		err := storage.UploadFile(context.Background(), key, strings.NewReader("x"), 1, "")
+		if !errors.Is(err, ErrInvalidObjectKey) {
+			t.Fatalf("%q: expected ErrInvalidObjectKey, got %v", key, err)
+		}
+	}
+}
+
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestFileSystemStorage_AbortedUploadLeavesNothing(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	err := storage.UploadFile(ctx, "originals/1/photo.png", &failingReader{data: []byte("partial")}, -1, "")
+	if err == nil {
+		t.Fatal("expected upload error")
+	}
+
+	if exists, _ := storage.FileExists(ctx, "originals/1/photo.png"); exists {
+		t.Fatal("aborted upload must not create object")
+	}
+	tmp, err := os.ReadDir(filepath.Join(storage.root, tmpDir))
+	if err != nil || len(tmp) != 0 {
+		t.Fatalf("temporary files left: %v, %v", tmp, err)
+	}
+}
+
+func TestFileSystemStorage_PresignedURL(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	link, err := storage.GetPresignedURL(ctx, "processed/1/my photo.png", time.Hour, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(parsed.Path, "/api/v1/files/")
+	if key != "processed/1/my photo.png" {
+		t.Fatalf("unexpected key in link: %q", key)
+	}
+
+	contentType, err := storage.VerifySignedURL(key, parsed.Query())
+	if err != nil || contentType != "image/png" {
+		t.Fatalf("verify: %q, %v", contentType, err)
+	}
+
+	// Подпись покрывает ключ и параметры ссылки
+	if _, err := storage.VerifySignedURL("processed/1/other.png", parsed.Query()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for other key, got %v", err)
+	}
+	tampered := parsed.Query()
+	tampered.Set(queryContentType, "text/html")
+	if _, err := storage.VerifySignedURL(key, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for tampered query, got %v", err)
+	}
+
+	expired := url.Values{}
+	expires := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	expired.Set(queryExpires, expires)
+	expired.Set(querySignature, storage.sign(key, expires, ""))
+	if _, err := storage.VerifySignedURL(key, expired); !errors.Is(err, ErrLinkExpired) {
+		t.Fatalf("expected ErrLinkExpired, got %v", err)
+	}
+}
+
+func TestFileSystemStorage_DownloadStream(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	if err := storage.UploadFile(ctx, "a/b.png", strings.NewReader("stream"), 6, ""); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := storage.DownloadFileStream(ctx, "a/b.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	got, err := io.ReadAll(stream)
+	if err != nil || string(got) != "stream" {
+		t.Fatalf("stream: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,102 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры presigned ссылки
+const (
+	queryExpires     = "expires"
+	queryContentType = "content_type"
+	querySignature   = "signature"
+)
+
+var (
+	ErrSigningDisabled  = errors.New("presigned links are disabled: signing key is not configured")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrLinkExpired      = errors.New("link expired")
+)
+
+// GetPresignedURL возвращает ссылку на маршрут раздачи файлов API:
+// {publicURL}/{objectKey}?expires=...&content_type=...&signature=...
+// Подпись - base64url(HMAC-SHA256(key, objectKey + "\n" + expires + "\n" + contentType))
+func (s *FileSystemStorage) GetPresignedURL(ctx context.Context, objectKey string, expiry time.Duration, contentType string) (string, error) {
+	if len(s.signingKey) == 0 {
+		return "", ErrSigningDisabled
+	}
+	if s.publicURL == "" {
+		return "", fmt.Errorf("failed to generate presigned URL: public URL is not configured")
+	}
+	if _, err := s.objectPath(objectKey); err != nil {
+		return "", err
+	}
+	if expiry <= 0 {
+		return "", fmt.Errorf("failed to generate presigned URL: expiry must be positive")
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set(queryExpires, expires)
+	if contentType != "" {
+		query.Set(queryContentType, contentType)
+	}
+	query.Set(querySignature, s.sign(objectKey, expires, contentType))
+
+	return s.publicURL + "/" + escapeKey(objectKey) + "?" + query.Encode(), nil
+}
+
+// VerifySignedURL проверяет подпись и срок действия ссылки и возвращает
+// Content-Type, заданный при подписи
+func (s *FileSystemStorage) VerifySignedURL(objectKey string, query url.Values) (string, error) {
+	if len(s.signingKey) == 0 {
+		return "", ErrSigningDisabled
+	}
+
+	expires := query.Get(queryExpires)
+	contentType := query.Get(queryContentType)
+
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get(querySignature))
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.sign(objectKey, expires, contentType))
+	if !hmac.Equal(signature, expected) {
+		return "", ErrInvalidSignature
+	}
+
+	// Срок проверяется после подписи, чтобы не раскрывать его для подделанных ссылок
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", ErrLinkExpired
+	}
+
+	return contentType, nil
+}
+
+func (s *FileSystemStorage) sign(objectKey, expires, contentType string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(objectKey + "\n" + expires + "\n" + contentType))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// escapeKey экранирует сегменты ключа, сохраняя разделители каталогов
+func escapeKey(objectKey string) string {
+	segments := strings.Split(objectKey, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
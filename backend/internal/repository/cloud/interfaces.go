@@ -29,8 +29,9 @@ type CloudStorageInterface interface {
 	// GetFileSize возвращает размер файла
 	GetFileSize(ctx context.Context, objectKey string) (int64, error)
 
-	// GetPresignedURL генерирует временную ссылку для скачивания
-	GetPresignedURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error)
+	// GetPresignedURL генерирует временную ссылку для скачивания.
+	// Непустой contentType переопределяет Content-Type ответа
+	GetPresignedURL(ctx context.Context, objectKey string, expiry time.Duration, contentType string) (string, error)
 
 	// ListFiles возвращает список файлов с префиксом
 	ListFiles(ctx context.Context, prefix string) ([]string, error)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"imageprocessor/backend/internal/config"
 	"io"
+	"net/url"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -78,10 +79,11 @@ func (s *S3CloudStorage) UploadFile(ctx context.Context, objectKey string, data
 
 	// Retry logic with exponential backoff
 	maxRetries := 5
+	seeker, seekable := data.(io.Seeker)
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// For retries, we need to seek back if data is a seeker
 		if attempt > 1 {
-			if seeker, ok := data.(io.Seeker); ok {
+			if seekable {
 				_, err := seeker.Seek(0, 0)
 				if err != nil {
 					return fmt.Errorf("failed to seek data for retry: %w", err)
@@ -94,6 +96,10 @@ func (s *S3CloudStorage) UploadFile(ctx context.Context, objectKey string, data
 
 		_, err := s.client.PutObject(ctx, s.bucket, objectKey, data, size, opts)
 		if err != nil {
+			// Поток без Seek нельзя перечитать: повтор загрузил бы только остаток данных
+			if !seekable {
+				return fmt.Errorf("failed to upload stream to S3: %w", err)
+			}
 
 			if attempt == maxRetries {
 				return fmt.Errorf("failed to upload file to S3 after %d attempts: %w", maxRetries, err)
@@ -206,14 +212,19 @@ func (s *S3CloudStorage) GetFileSize(ctx context.Context, objectKey string) (int
 }
 
 // GetPresignedURL генерирует временную ссылку для скачивания
-func (s *S3CloudStorage) GetPresignedURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+func (s *S3CloudStorage) GetPresignedURL(ctx context.Context, objectKey string, expiry time.Duration, contentType string) (string, error) {
+
+	reqParams := make(url.Values)
+	if contentType != "" {
+		reqParams.Set("response-content-type", contentType)
+	}
 
-	url, err := s.client.PresignedGetObject(ctx, s.bucket, objectKey, expiry, nil)
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucket, objectKey, expiry, reqParams)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
 
-	return url.String(), nil
+	return presignedURL.String(), nil
 }
 
 // ListFiles возвращает список файлов с заданным префиксом
@@ -0,0 +1,22 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/repository/cloud/filesystem"
+	"imageprocessor/backend/internal/repository/cloud/s3"
+	"strings"
+)
+
+// NewCloudStorage создает хранилище, выбранное параметром cloud.backend
+func NewCloudStorage(ctx context.Context, cfg config.CloudStorageConfig) (CloudStorageInterface, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", config.StorageBackendS3:
+		return s3.NewS3CloudStorage(ctx, cfg)
+	case config.StorageBackendFilesystem:
+		return filesystem.NewFileSystemStorage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,572 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const backfillColumns = `
+	id, status, filter, operations, COALESCE(preset_name, ''), COALESCE(preset_version, 0), supersede,
+	batch_size, batch_interval_ms, cursor_created_at, cursor_image_id, total_images, scanned_images,
+	queued_images, skipped_images, attempts, COALESCE(last_error, ''), next_run_at, created_at, updated_at,
+	completed_at`
+
+// backfillFilterCondition отбирает изображения по фильтру backfill. Параметры:
+// $1 - статусы, $2 и $3 - границы created_at, $4 - имеющийся вариант, $5 - отсутствующий
+const backfillFilterCondition = `
+	((cardinality($1::varchar[]) = 0 AND i.status <> 'deleted') OR i.status = ANY($1::varchar[]))
+	AND ($2::timestamp IS NULL OR i.created_at >= $2::timestamp)
+	AND ($3::timestamp IS NULL OR i.created_at < $3::timestamp)
+	AND ($4 = '' OR EXISTS (SELECT 1 FROM processed_images p WHERE p.image_id = i.id AND p.variant_name = $4))
+	AND ($5 = '' OR NOT EXISTS (SELECT 1 FROM processed_images p WHERE p.image_id = i.id AND p.variant_name = $5))`
+
+type BackfillRepository struct {
+	db *pgxpool.Pool
+	// jobMaxAttempts - количество попыток задач, созданных backfill
+	jobMaxAttempts int
+}
+
+func NewBackfillRepository(db *pgxpool.Pool, jobMaxAttempts int) *BackfillRepository {
+	return &BackfillRepository{
+		db:             db,
+		jobMaxAttempts: jobMaxAttempts,
+	}
+}
+
+// CreateBackfill сохраняет backfill; runner начинает публикацию при следующем опросе
+func (r *BackfillRepository) CreateBackfill(ctx context.Context, backfill *entity.Backfill) error {
+	filterJSON, err := json.Marshal(backfill.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal filter: %w", err)
+	}
+	operationsJSON, err := json.Marshal(backfill.Operations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operations: %w", err)
+	}
+
+	query := `
+		INSERT INTO backfills (id, status, filter, operations, preset_name, preset_version, supersede,
+		                       batch_size, batch_interval_ms, total_images, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, 0), $7, $8, $9, $10, $11, $11, $11)
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		backfill.ID,
+		backfill.Status,
+		filterJSON,
+		operationsJSON,
+		backfill.PresetName,
+		backfill.PresetVersion,
+		backfill.Supersede,
+		backfill.BatchSize,
+		backfill.BatchInterval.Milliseconds(),
+		backfill.TotalImages,
+		backfill.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create backfill: %w", err)
+	}
+
+	return nil
+}
+
+// CountBackfillImages возвращает количество изображений, подходящих под фильтр
+func (r *BackfillRepository) CountBackfillImages(ctx context.Context, filter entity.BackfillFilter) (int64, error) {
+	query := `SELECT count(*) FROM images i WHERE ` + backfillFilterCondition
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, backfillFilterArgs(filter)...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count backfill images: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetBackfill возвращает backfill по ID
+func (r *BackfillRepository) GetBackfill(ctx context.Context, id string) (*entity.Backfill, error) {
+	query := `SELECT ` + backfillColumns + ` FROM backfills WHERE id = $1`
+
+	backfill, err := scanBackfill(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", entity.ErrBackfillNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get backfill: %w", err)
+	}
+
+	return backfill, nil
+}
+
+// ListBackfills возвращает backfill, начиная с последних созданных; пустой status не фильтрует
+func (r *BackfillRepository) ListBackfills(ctx context.Context, status entity.BackfillStatus, limit, offset int) ([]entity.Backfill, error) {
+	query := `
+		SELECT ` + backfillColumns + `
+		FROM backfills
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, string(status), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfills: %w", err)
+	}
+	defer rows.Close()
+
+	var backfills []entity.Backfill
+	for rows.Next() {
+		backfill, err := scanBackfill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill: %w", err)
+		}
+		backfills = append(backfills, *backfill)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list backfills: %w", err)
+	}
+
+	return backfills, nil
+}
+
+// ListBackfillErrors возвращает последние записи журнала ошибок backfill
+func (r *BackfillRepository) ListBackfillErrors(ctx context.Context, backfillID string, limit int) ([]entity.BackfillError, error) {
+	query := `
+		SELECT id, backfill_id, attempt, batch_size, error_message, created_at
+		FROM backfill_errors
+		WHERE backfill_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, backfillID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfill errors: %w", err)
+	}
+	defer rows.Close()
+
+	var backfillErrors []entity.BackfillError
+	for rows.Next() {
+		var backfillError entity.BackfillError
+		err := rows.Scan(
+			&backfillError.ID,
+			&backfillError.BackfillID,
+			&backfillError.Attempt,
+			&backfillError.BatchSize,
+			&backfillError.Error,
+			&backfillError.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill error: %w", err)
+		}
+		backfillErrors = append(backfillErrors, backfillError)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list backfill errors: %w", err)
+	}
+
+	return backfillErrors, nil
+}
+
+// CountBackfillJobs возвращает количество задач backfill по статусам
+func (r *BackfillRepository) CountBackfillJobs(ctx context.Context, backfillID string) (map[string]int64, error) {
+	query := `
+		SELECT status, count(*)
+		FROM processing_jobs
+		WHERE backfill_id = $1
+		GROUP BY status
+	`
+
+	rows, err := r.db.Query(ctx, query, backfillID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count backfill jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan backfill job count: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count backfill jobs: %w", err)
+	}
+
+	return counts, nil
+}
+
+// PauseBackfill приостанавливает запущенный backfill. Пачка, которую runner
+// публикует в этот момент, завершается
+func (r *BackfillRepository) PauseBackfill(ctx context.Context, id string) (*entity.Backfill, error) {
+	query := `
+		UPDATE backfills
+		SET status = $2, updated_at = now()
+		WHERE id = $1 AND status = $3
+		RETURNING ` + backfillColumns
+
+	return r.changeBackfillStatus(ctx, id, query, entity.BackfillPaused, entity.BackfillRunning)
+}
+
+// ResumeBackfill продолжает приостановленный или остановленный ошибками backfill
+// с сохраненного курсора
+func (r *BackfillRepository) ResumeBackfill(ctx context.Context, id string) (*entity.Backfill, error) {
+	query := `
+		UPDATE backfills
+		SET status = $2, attempts = 0, next_run_at = now(), updated_at = now()
+		WHERE id = $1 AND status IN ($3, $4)
+		RETURNING ` + backfillColumns
+
+	return r.changeBackfillStatus(ctx, id, query, entity.BackfillRunning, entity.BackfillPaused, entity.BackfillFailed)
+}
+
+// changeBackfillStatus выполняет переход состояния; если backfill существует,
+// но переход недоступен, возвращает ErrBackfillStateConflict
+func (r *BackfillRepository) changeBackfillStatus(ctx context.Context, id, query string, args ...any) (*entity.Backfill, error) {
+	backfill, err := scanBackfill(r.db.QueryRow(ctx, query, append([]any{id}, args...)...))
+	if err == nil {
+		return backfill, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to update backfill status: %w", err)
+	}
+
+	current, err := r.GetBackfill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: backfill is %s", entity.ErrBackfillStateConflict, current.Status)
+}
+
+// ClaimBackfill арендует запущенный backfill, для которого наступило время
+// следующей пачки, скрывая его от других runner на время lease.
+// Возвращает nil, если таких backfill нет
+func (r *BackfillRepository) ClaimBackfill(ctx context.Context, lease time.Duration) (*entity.Backfill, error) {
+	query := `
+		WITH next AS (
+			SELECT id FROM backfills
+			WHERE status = $1 AND next_run_at <= now()
+			ORDER BY next_run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE backfills
+		SET next_run_at = now() + make_interval(secs => $2)
+		WHERE id = (SELECT id FROM next)
+		RETURNING ` + backfillColumns
+
+	backfill, err := scanBackfill(r.db.QueryRow(ctx, query, entity.BackfillRunning, lease.Seconds()))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim backfill: %w", err)
+	}
+
+	return backfill, nil
+}
+
+// ListBackfillCandidates возвращает следующую после курсора страницу изображений,
+// подходящих под фильтр, вместе с их активными задачами
+func (r *BackfillRepository) ListBackfillCandidates(ctx context.Context, backfill *entity.Backfill, limit int) ([]entity.BackfillCandidate, error) {
+	query := `
+		SELECT i.id, i.original_path, i.format, i.created_at,
+		       COALESCE(j.id, ''), COALESCE(j.status = 'pending' AND j.backfill_id = $8, false)
+		FROM images i
+		LEFT JOIN LATERAL (
+			SELECT id, status, backfill_id FROM processing_jobs
+			WHERE image_id = i.id AND status IN ('pending', 'processing')
+			ORDER BY created_at DESC
+			LIMIT 1
+		) j ON true
+		WHERE ` + backfillFilterCondition + `
+		  AND ($6::timestamp IS NULL OR (i.created_at, i.id) > ($6::timestamp, $7::varchar))
+		ORDER BY i.created_at, i.id
+		LIMIT $9
+	`
+
+	var cursorCreatedAt *time.Time
+	var cursorImageID string
+	if backfill.Cursor != nil {
+		cursorCreatedAt = &backfill.Cursor.CreatedAt
+		cursorImageID = backfill.Cursor.ImageID
+	}
+
+	args := append(backfillFilterArgs(backfill.Filter), cursorCreatedAt, cursorImageID, backfill.ID, limit)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfill candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []entity.BackfillCandidate
+	for rows.Next() {
+		var candidate entity.BackfillCandidate
+		err := rows.Scan(
+			&candidate.ImageID,
+			&candidate.OriginalPath,
+			&candidate.Format,
+			&candidate.CreatedAt,
+			&candidate.ActiveJobID,
+			&candidate.OwnJob,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill candidate: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list backfill candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// CreateBackfillJobs в одной транзакции создает задачи backfill и переводит их
+// изображения в статус uploaded. Задачи для изображений, которые были удалены
+// или получили активную задачу после выборки страницы, не создаются.
+// Возвращает созданные задачи
+func (r *BackfillRepository) CreateBackfillJobs(ctx context.Context, backfillID string, tasks []*entity.ProcessingTask) ([]*entity.ProcessingTask, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	imageIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		imageIDs = append(imageIDs, task.ImageID)
+	}
+
+	// Блокировка строк изображений не дает повторной обработке через API
+	// создать задачу одновременно с backfill. Активные задачи проверяются
+	// после блокировки, чтобы увидеть задачи, созданные до ее получения
+	lockQuery := `SELECT id FROM images WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+	available, err := queryImageIDs(ctx, tx, lockQuery, imageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock images: %w", err)
+	}
+
+	activeQuery := `SELECT DISTINCT image_id FROM processing_jobs WHERE image_id = ANY($1) AND status IN ($2, $3)`
+	active, err := queryImageIDs(ctx, tx, activeQuery, imageIDs, entity.JobStatusPending, entity.JobStatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check active jobs: %w", err)
+	}
+	for imageID := range active {
+		delete(available, imageID)
+	}
+
+	created := make([]*entity.ProcessingTask, 0, len(available))
+	jobIDs := make([]string, 0, len(available))
+	createdImageIDs := make([]string, 0, len(available))
+	for _, task := range tasks {
+		if !available[task.ImageID] {
+			continue
+		}
+		if err := createProcessingJob(ctx, tx, task, r.jobMaxAttempts); err != nil {
+			return nil, err
+		}
+		created = append(created, task)
+		jobIDs = append(jobIDs, task.ID)
+		createdImageIDs = append(createdImageIDs, task.ImageID)
+	}
+
+	if len(created) > 0 {
+		if _, err := tx.Exec(ctx, `UPDATE processing_jobs SET backfill_id = $1 WHERE id = ANY($2)`, backfillID, jobIDs); err != nil {
+			return nil, fmt.Errorf("failed to link jobs to backfill: %w", err)
+		}
+
+		imageQuery := `
+			UPDATE images
+			SET status = $2, updated_at = now()
+			WHERE id = ANY($1)
+		`
+
+		if _, err := tx.Exec(ctx, imageQuery, createdImageIDs, entity.StatusUploaded); err != nil {
+			return nil, fmt.Errorf("failed to update image status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// AdvanceBackfill сохраняет курсор после опубликованной пачки, увеличивает
+// счетчики и назначает следующую пачку через delay
+func (r *BackfillRepository) AdvanceBackfill(ctx context.Context, id string, cursor entity.BackfillCursor, scanned, queued, skipped int64, delay time.Duration) error {
+	query := `
+		UPDATE backfills
+		SET cursor_created_at = $2, cursor_image_id = $3,
+		    scanned_images = scanned_images + $4,
+		    queued_images = queued_images + $5,
+		    skipped_images = skipped_images + $6,
+		    attempts = 0,
+		    next_run_at = now() + make_interval(secs => $7),
+		    updated_at = now()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id, cursor.CreatedAt, cursor.ImageID, scanned, queued, skipped, delay.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to advance backfill: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteBackfill завершает backfill, просмотревший все изображения.
+// Приостановленный за это время backfill остается приостановленным
+func (r *BackfillRepository) CompleteBackfill(ctx context.Context, id string) error {
+	query := `
+		UPDATE backfills
+		SET status = $2, attempts = 0, completed_at = now(), updated_at = now()
+		WHERE id = $1 AND status = $3
+	`
+
+	_, err := r.db.Exec(ctx, query, id, entity.BackfillCompleted, entity.BackfillRunning)
+	if err != nil {
+		return fmt.Errorf("failed to complete backfill: %w", err)
+	}
+
+	return nil
+}
+
+// RecordBackfillFailure записывает неудачную публикацию пачки в журнал и
+// назначает повтор через delay. Когда неудачи подряд достигают maxAttempts,
+// запущенный backfill переводится в failed. Возвращает новое состояние
+func (r *BackfillRepository) RecordBackfillFailure(ctx context.Context, id string, batchSize int, errorMsg string, delay time.Duration, maxAttempts int) (entity.BackfillStatus, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE backfills
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    status = CASE WHEN status = $3 AND attempts + 1 >= $4 THEN $5 ELSE status END,
+		    next_run_at = now() + make_interval(secs => $6),
+		    updated_at = now()
+		WHERE id = $1
+		RETURNING status, attempts
+	`
+
+	var status entity.BackfillStatus
+	var attempt int
+	err = tx.QueryRow(ctx, query, id, errorMsg, entity.BackfillRunning, maxAttempts, entity.BackfillFailed, delay.Seconds()).Scan(&status, &attempt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", fmt.Errorf("%w: %s", entity.ErrBackfillNotFound, id)
+		}
+		return "", fmt.Errorf("failed to record backfill failure: %w", err)
+	}
+
+	errorQuery := `
+		INSERT INTO backfill_errors (backfill_id, attempt, batch_size, error_message, created_at)
+		VALUES ($1, $2, $3, $4, now())
+	`
+
+	if _, err := tx.Exec(ctx, errorQuery, id, attempt, batchSize, errorMsg); err != nil {
+		return "", fmt.Errorf("failed to record backfill error: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return status, nil
+}
+
+// queryImageIDs выполняет запрос, возвращающий ID изображений, и собирает их во множество
+func queryImageIDs(ctx context.Context, tx pgx.Tx, query string, args ...any) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// backfillFilterArgs возвращает параметры $1-$5 условия backfillFilterCondition
+func backfillFilterArgs(filter entity.BackfillFilter) []any {
+	statuses := make([]string, 0, len(filter.Statuses))
+	for _, status := range filter.Statuses {
+		statuses = append(statuses, string(status))
+	}
+	return []any{statuses, filter.CreatedFrom, filter.CreatedTo, filter.HasVariant, filter.LacksVariant}
+}
+
+func scanBackfill(row pgx.Row) (*entity.Backfill, error) {
+	var backfill entity.Backfill
+	var filterJSON, operationsJSON []byte
+	var batchIntervalMs int64
+	var cursorCreatedAt *time.Time
+	var cursorImageID *string
+
+	err := row.Scan(
+		&backfill.ID,
+		&backfill.Status,
+		&filterJSON,
+		&operationsJSON,
+		&backfill.PresetName,
+		&backfill.PresetVersion,
+		&backfill.Supersede,
+		&backfill.BatchSize,
+		&batchIntervalMs,
+		&cursorCreatedAt,
+		&cursorImageID,
+		&backfill.TotalImages,
+		&backfill.ScannedImages,
+		&backfill.QueuedImages,
+		&backfill.SkippedImages,
+		&backfill.Attempts,
+		&backfill.LastError,
+		&backfill.NextRunAt,
+		&backfill.CreatedAt,
+		&backfill.UpdatedAt,
+		&backfill.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filterJSON, &backfill.Filter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal filter: %w", err)
+	}
+	if err := json.Unmarshal(operationsJSON, &backfill.Operations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operations: %w", err)
+	}
+	backfill.BatchInterval = time.Duration(batchIntervalMs) * time.Millisecond
+	if cursorCreatedAt != nil && cursorImageID != nil {
+		backfill.Cursor = &entity.BackfillCursor{CreatedAt: *cursorCreatedAt, ImageID: *cursorImageID}
+	}
+
+	return &backfill, nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeadLetterRepository struct {
+	db *pgxpool.Pool
+	// jobMaxAttempts - количество попыток задач, созданных повтором сообщения
+	jobMaxAttempts int
+}
+
+func NewDeadLetterRepository(db *pgxpool.Pool, jobMaxAttempts int) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		db:             db,
+		jobMaxAttempts: jobMaxAttempts,
+	}
+}
+
+// StoreDeadLetter сохраняет сообщение в dead-letter очередь; ID и время
+// создания назначаются, если не заданы
+func (r *DeadLetterRepository) StoreDeadLetter(ctx context.Context, letter *entity.DeadLetter) error {
+	if letter.ID == "" {
+		letter.ID = uuid.New().String()
+	}
+	if letter.CreatedAt.IsZero() {
+		letter.CreatedAt = time.Now()
+	}
+	letter.Status = entity.DeadLetterPending
+
+	headersJSON, err := json.Marshal(letter.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+
+	query := `
+		INSERT INTO dead_letters (id, task_id, image_id, source, payload, headers, error_message, attempts, status, created_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		letter.ID,
+		letter.TaskID,
+		letter.ImageID,
+		letter.Source,
+		letter.Payload,
+		headersJSON,
+		letter.Error,
+		letter.Attempts,
+		letter.Status,
+		letter.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetter получает сообщение dead-letter очереди по ID
+func (r *DeadLetterRepository) GetDeadLetter(ctx context.Context, id string) (*entity.DeadLetter, error) {
+	query := `
+		SELECT id, COALESCE(task_id, ''), COALESCE(image_id, ''), source, payload, headers, error_message,
+		       attempts, status, COALESCE(replay_task_id, ''), replayed_at, created_at
+		FROM dead_letters
+		WHERE id = $1
+	`
+
+	letter, err := scanDeadLetter(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", entity.ErrDeadLetterNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	return letter, nil
+}
+
+// ListDeadLetters возвращает сообщения dead-letter очереди, новые первыми.
+// Пустой status означает сообщения в любом состоянии
+func (r *DeadLetterRepository) ListDeadLetters(ctx context.Context, status entity.DeadLetterStatus, limit, offset int) ([]entity.DeadLetter, error) {
+	query := `
+		SELECT id, COALESCE(task_id, ''), COALESCE(image_id, ''), source, payload, headers, error_message,
+		       attempts, status, COALESCE(replay_task_id, ''), replayed_at, created_at
+		FROM dead_letters
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, string(status), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []entity.DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, *letter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetter в одной транзакции отмечает сообщение обработанным, создает
+// по нему новую задачу и запись outbox. Изображение, обработка которого
+// завершилась ошибкой, снова ожидает обработки
+func (r *DeadLetterRepository) ReplayDeadLetter(ctx context.Context, id string, task *entity.ProcessingTask) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE dead_letters
+		SET status = $2, replay_task_id = $3, replayed_at = now()
+		WHERE id = $1 AND status = $4
+	`
+
+	tag, err := tx.Exec(ctx, query, id, entity.DeadLetterReplayed, task.ID, entity.DeadLetterPending)
+	if err != nil {
+		return fmt.Errorf("failed to mark dead letter replayed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", entity.ErrDeadLetterReplayed, id)
+	}
+
+	if err := createProcessingJob(ctx, tx, task, r.jobMaxAttempts); err != nil {
+		return err
+	}
+	if err := insertOutboxMessage(ctx, tx, task); err != nil {
+		return err
+	}
+
+	imageQuery := `
+		UPDATE images
+		SET status = $2
+		WHERE id = $1 AND status = $3
+	`
+
+	if _, err := tx.Exec(ctx, imageQuery, task.ImageID, entity.StatusUploaded, entity.StatusFailed); err != nil {
+		return fmt.Errorf("failed to update image status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// scanDeadLetter сканирует строку dead_letters
+func scanDeadLetter(row pgx.Row) (*entity.DeadLetter, error) {
+	var letter entity.DeadLetter
+	var headersJSON []byte
+
+	err := row.Scan(
+		&letter.ID,
+		&letter.TaskID,
+		&letter.ImageID,
+		&letter.Source,
+		&letter.Payload,
+		&headersJSON,
+		&letter.Error,
+		&letter.Attempts,
+		&letter.Status,
+		&letter.ReplayTaskID,
+		&letter.ReplayedAt,
+		&letter.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headersJSON) > 0 {
+		if err := json.Unmarshal(headersJSON, &letter.Headers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal headers: %w", err)
+		}
+	}
+
+	return &letter, nil
+}
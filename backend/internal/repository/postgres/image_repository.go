@@ -13,29 +13,66 @@ import (
 
 type ImageRepository struct {
 	db *pgxpool.Pool
+	// jobMaxAttempts - количество попыток новых задач в очереди PostgreSQL
+	jobMaxAttempts int
 }
 
-func NewImageRepository(db *pgxpool.Pool) *ImageRepository {
+func NewImageRepository(db *pgxpool.Pool, jobMaxAttempts int) *ImageRepository {
 	return &ImageRepository{
-		db: db,
+		db:             db,
+		jobMaxAttempts: jobMaxAttempts,
 	}
 }
 
 // CreateImage создает запись об изображении в БД
 func (r *ImageRepository) CreateImage(ctx context.Context, image *entity.Image) error {
+	return createImage(ctx, r.db, image)
+}
+
+// CreateImageWithTask в одной транзакции создает запись об изображении, задачу
+// на обработку и запись outbox, из которой задача публикуется в брокер
+func (r *ImageRepository) CreateImageWithTask(ctx context.Context, image *entity.Image, task *entity.ProcessingTask) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := createImage(ctx, tx, image); err != nil {
+		return err
+	}
+	if err := createProcessingJob(ctx, tx, task, r.jobMaxAttempts); err != nil {
+		return err
+	}
+	if err := insertOutboxMessage(ctx, tx, task); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func createImage(ctx context.Context, db execer, image *entity.Image) error {
 	query := `
-		INSERT INTO images (id, original_filename, original_size, mime_type, status, original_path, bucket, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO images (id, original_filename, original_size, mime_type, format, checksum, status, original_path, bucket, callback_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
 	`
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := db.Exec(ctx, query,
 		image.ID,
 		image.OriginalFilename,
 		image.OriginalSize,
 		image.MimeType,
+		image.Format,
+		image.Checksum,
 		image.Status,
 		image.OriginalPath,
 		image.Bucket,
+		image.CallbackURL,
 		image.CreatedAt,
 		image.UpdatedAt,
 	)
@@ -50,7 +87,8 @@ func (r *ImageRepository) CreateImage(ctx context.Context, image *entity.Image)
 // GetImageByID получает изображение по ID
 func (r *ImageRepository) GetImageByID(ctx context.Context, imageID string) (*entity.Image, error) {
 	query := `
-		SELECT id, original_filename, original_size, mime_type, status, original_path, bucket, created_at, updated_at
+		SELECT id, original_filename, original_size, mime_type, format, checksum, status, original_path, bucket,
+		       COALESCE(callback_url, ''), created_at, updated_at
 		FROM images
 		WHERE id = $1
 	`
@@ -61,16 +99,19 @@ func (r *ImageRepository) GetImageByID(ctx context.Context, imageID string) (*en
 		&image.OriginalFilename,
 		&image.OriginalSize,
 		&image.MimeType,
+		&image.Format,
+		&image.Checksum,
 		&image.Status,
 		&image.OriginalPath,
 		&image.Bucket,
+		&image.CallbackURL,
 		&image.CreatedAt,
 		&image.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("image not found: %s", imageID)
+			return nil, fmt.Errorf("%w: %s", entity.ErrImageNotFound, imageID)
 		}
 		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
@@ -98,6 +139,24 @@ func (r *ImageRepository) UpdateImageStatus(ctx context.Context, imageID string,
 	return nil
 }
 
+// UpdateImageResultStatus записывает итог обработки изображения. Как и статус
+// задачи, статус отмененного изображения не меняется. Возвращает false,
+// если изображение отменено или удалено
+func (r *ImageRepository) UpdateImageResultStatus(ctx context.Context, imageID string, status entity.ImageStatus) (bool, error) {
+	query := `
+		UPDATE images
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status <> $4
+	`
+
+	result, err := r.db.Exec(ctx, query, status, time.Now(), imageID, entity.StatusCancelled)
+	if err != nil {
+		return false, fmt.Errorf("failed to update image status: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
 // DeleteImage удаляет изображение из БД
 func (r *ImageRepository) DeleteImage(ctx context.Context, imageID string) error {
 	query := `DELETE FROM images WHERE id = $1`
@@ -117,7 +176,7 @@ func (r *ImageRepository) DeleteImage(ctx context.Context, imageID string) error
 // ListImages возвращает список изображений с пагинацией
 func (r *ImageRepository) ListImages(ctx context.Context, limit, offset int) ([]entity.Image, error) {
 	query := `
-		SELECT id, original_filename, original_size, mime_type, status, original_path, bucket, created_at, updated_at
+		SELECT id, original_filename, original_size, mime_type, format, checksum, status, original_path, bucket, created_at, updated_at
 		FROM images
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -137,6 +196,8 @@ func (r *ImageRepository) ListImages(ctx context.Context, limit, offset int) ([]
 			&image.OriginalFilename,
 			&image.OriginalSize,
 			&image.MimeType,
+			&image.Format,
+			&image.Checksum,
 			&image.Status,
 			&image.OriginalPath,
 			&image.Bucket,
@@ -160,14 +221,16 @@ func (r *ImageRepository) CreateProcessedImage(ctx context.Context, processed *e
 	}
 
 	query := `
-		INSERT INTO processed_images (id, image_id, operation, parameters, path, size, mime_type, format, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO processed_images (id, image_id, job_id, operation, variant_name, parameters, path, size, mime_type, format, status, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
 	_, err = r.db.Exec(ctx, query,
 		processed.ID,
 		processed.ImageID,
+		processed.JobID,
 		processed.Operation,
+		processed.VariantName,
 		paramsJSON,
 		processed.Path,
 		processed.Size,
@@ -187,7 +250,7 @@ func (r *ImageRepository) CreateProcessedImage(ctx context.Context, processed *e
 // GetProcessedImagesByImageID получает все обработанные версии изображения
 func (r *ImageRepository) GetProcessedImagesByImageID(ctx context.Context, imageID string) ([]entity.ProcessedImage, error) {
 	query := `
-		SELECT id, image_id, operation, parameters, path, size, mime_type, format, status, created_at
+		SELECT id, image_id, COALESCE(job_id, ''), operation, variant_name, parameters, path, size, mime_type, format, status, created_at
 		FROM processed_images
 		WHERE image_id = $1
 		ORDER BY created_at DESC
@@ -207,7 +270,9 @@ func (r *ImageRepository) GetProcessedImagesByImageID(ctx context.Context, image
 		err := rows.Scan(
 			&processed.ID,
 			&processed.ImageID,
+			&processed.JobID,
 			&processed.Operation,
+			&processed.VariantName,
 			&paramsJSON,
 			&processed.Path,
 			&processed.Size,
@@ -233,7 +298,7 @@ func (r *ImageRepository) GetProcessedImagesByImageID(ctx context.Context, image
 // GetProcessedImageByOperation получает обработанное изображение по типу операции
 func (r *ImageRepository) GetProcessedImageByOperation(ctx context.Context, imageID string, operation entity.OperationType) (*entity.ProcessedImage, error) {
 	query := `
-		SELECT id, image_id, operation, parameters, path, size, mime_type, format, status, created_at
+		SELECT id, image_id, COALESCE(job_id, ''), operation, variant_name, parameters, path, size, mime_type, format, status, created_at
 		FROM processed_images
 		WHERE image_id = $1 AND operation = $2
 		ORDER BY created_at DESC
@@ -246,7 +311,51 @@ func (r *ImageRepository) GetProcessedImageByOperation(ctx context.Context, imag
 	err := r.db.QueryRow(ctx, query, imageID, operation).Scan(
 		&processed.ID,
 		&processed.ImageID,
+		&processed.JobID,
+		&processed.Operation,
+		&processed.VariantName,
+		&paramsJSON,
+		&processed.Path,
+		&processed.Size,
+		&processed.MimeType,
+		&processed.Format,
+		&processed.Status,
+		&processed.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("processed image not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get processed image: %w", err)
+	}
+
+	if err := json.Unmarshal(paramsJSON, &processed.Parameters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal images: %w", err)
+	}
+
+	return &processed, nil
+}
+
+// GetProcessedImageByVariant получает обработанное изображение по имени варианта
+func (r *ImageRepository) GetProcessedImageByVariant(ctx context.Context, imageID string, variantName string) (*entity.ProcessedImage, error) {
+	query := `
+		SELECT id, image_id, COALESCE(job_id, ''), operation, variant_name, parameters, path, size, mime_type, format, status, created_at
+		FROM processed_images
+		WHERE image_id = $1 AND variant_name = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var processed entity.ProcessedImage
+	var paramsJSON []byte
+
+	err := r.db.QueryRow(ctx, query, imageID, variantName).Scan(
+		&processed.ID,
+		&processed.ImageID,
+		&processed.JobID,
 		&processed.Operation,
+		&processed.VariantName,
 		&paramsJSON,
 		&processed.Path,
 		&processed.Size,
@@ -272,18 +381,23 @@ func (r *ImageRepository) GetProcessedImageByOperation(ctx context.Context, imag
 
 // CreateProcessingJob создает задачу на обработку
 func (r *ImageRepository) CreateProcessingJob(ctx context.Context, job *entity.ProcessingTask) error {
+	return createProcessingJob(ctx, r.db, job, r.jobMaxAttempts)
+}
+
+// createProcessingJob создает задачу в статусе pending с maxAttempts попытками
+func createProcessingJob(ctx context.Context, db execer, job *entity.ProcessingTask, maxAttempts int) error {
 	operationsJSON, err := json.Marshal(job.Operations)
 	if err != nil {
 		return fmt.Errorf("failed to marshal operations: %w", err)
 	}
 
 	query := `
-		INSERT INTO processing_jobs (id, image_id, operations, status, attempts, max_attempts, created_at, updated_at)
-		VALUES ($1, $2, $3, 'pending', 0, 3, $4, $5)
+		INSERT INTO processing_jobs (id, image_id, operations, preset_name, preset_version, supersede, status, attempts, max_attempts, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), $6, 'pending', 0, $7, $8, $9)
 	`
 
 	now := time.Now()
-	_, err = r.db.Exec(ctx, query, job.ID, job.ImageID, operationsJSON, now, now)
+	_, err = db.Exec(ctx, query, job.ID, job.ImageID, operationsJSON, job.PresetName, job.PresetVersion, job.Supersede, maxAttempts, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to create processing job: %w", err)
 	}
@@ -291,50 +405,50 @@ func (r *ImageRepository) CreateProcessingJob(ctx context.Context, job *entity.P
 	return nil
 }
 
-// UpdateProcessingJobStatus обновляет статус задачи
-func (r *ImageRepository) UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) error {
+// UpdateProcessingJobStatus обновляет статус задачи. Статус отмененной
+// задачи не меняется: отмена через API имеет приоритет над итогом воркера.
+// Возвращает false, если задача не обновлена
+func (r *ImageRepository) UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) (bool, error) {
 	query := `
 		UPDATE processing_jobs
 		SET status = $1::varchar, error_message = $2, updated_at = $3,
-		    completed_at = CASE WHEN $1::varchar IN ('completed', 'failed') THEN $3 ELSE completed_at END
-		WHERE id = $4
+		    completed_at = CASE WHEN $1::varchar IN ('completed', 'failed', 'timeout') THEN $3 ELSE completed_at END
+		WHERE id = $4 AND status <> 'cancelled'
 	`
 
-	_, err := r.db.Exec(ctx, query, status, errorMsg, time.Now(), jobID)
+	result, err := r.db.Exec(ctx, query, status, errorMsg, time.Now(), jobID)
 	if err != nil {
-		return fmt.Errorf("failed to update processing job status: %w", err)
+		return false, fmt.Errorf("failed to update processing job status: %w", err)
 	}
 
-	return nil
+	return result.RowsAffected() > 0, nil
 }
 
-// GetProcessingJobByImageID получает задачу по ID изображения
-func (r *ImageRepository) GetProcessingJobByImageID(ctx context.Context, imageID string) (*entity.ProcessingTask, error) {
+// GetProcessingJobByImageID получает последнюю задачу изображения
+func (r *ImageRepository) GetProcessingJobByImageID(ctx context.Context, imageID string) (*entity.ProcessingJob, error) {
 	query := `
-		SELECT id, image_id, operations, status, attempts, max_attempts
+		SELECT id, image_id, operations, status, attempts, updated_at
 		FROM processing_jobs
 		WHERE image_id = $1
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 
-	var job entity.ProcessingTask
+	var job entity.ProcessingJob
 	var operationsJSON []byte
-	var status string
-	var attempts, maxAttempts int
 
 	err := r.db.QueryRow(ctx, query, imageID).Scan(
 		&job.ID,
 		&job.ImageID,
 		&operationsJSON,
-		&status,
-		&attempts,
-		&maxAttempts,
+		&job.Status,
+		&job.Attempts,
+		&job.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("processing job not found")
+			return nil, entity.ErrJobNotFound
 		}
 		return nil, fmt.Errorf("failed to get processing job: %w", err)
 	}
@@ -345,3 +459,205 @@ func (r *ImageRepository) GetProcessingJobByImageID(ctx context.Context, imageID
 
 	return &job, nil
 }
+
+// CreateReprocessTask в одной транзакции создает новую задачу для загруженного
+// изображения и запись outbox и переводит изображение в статус uploaded.
+// Если у изображения есть ожидающая или выполняющаяся задача, возвращает ErrJobInProgress
+func (r *ImageRepository) CreateReprocessTask(ctx context.Context, task *entity.ProcessingTask) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Блокировка строки изображения не дает двум запросам создать задачи одновременно
+	lockQuery := `SELECT id FROM images WHERE id = $1 FOR UPDATE`
+	var imageID string
+	if err := tx.QueryRow(ctx, lockQuery, task.ImageID).Scan(&imageID); err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %s", entity.ErrImageNotFound, task.ImageID)
+		}
+		return fmt.Errorf("failed to lock image: %w", err)
+	}
+
+	activeQuery := `SELECT EXISTS (SELECT 1 FROM processing_jobs WHERE image_id = $1 AND status IN ($2, $3))`
+	var active bool
+	if err := tx.QueryRow(ctx, activeQuery, task.ImageID, entity.JobStatusPending, entity.JobStatusProcessing).Scan(&active); err != nil {
+		return fmt.Errorf("failed to check active jobs: %w", err)
+	}
+	if active {
+		return fmt.Errorf("%w: %s", entity.ErrJobInProgress, task.ImageID)
+	}
+
+	if err := createProcessingJob(ctx, tx, task, r.jobMaxAttempts); err != nil {
+		return err
+	}
+	if err := insertOutboxMessage(ctx, tx, task); err != nil {
+		return err
+	}
+
+	imageQuery := `
+		UPDATE images
+		SET status = $2, updated_at = now()
+		WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, imageQuery, task.ImageID, entity.StatusUploaded); err != nil {
+		return fmt.Errorf("failed to update image status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteSupersededImages удаляет записи вариантов изображения, созданные
+// другими задачами, и возвращает пути их файлов
+func (r *ImageRepository) DeleteSupersededImages(ctx context.Context, imageID, jobID string) ([]string, error) {
+	query := `
+		DELETE FROM processed_images
+		WHERE image_id = $1 AND (job_id IS NULL OR job_id <> $2)
+		RETURNING path
+	`
+
+	rows, err := r.db.Query(ctx, query, imageID, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete superseded images: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan superseded image: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete superseded images: %w", err)
+	}
+
+	return paths, nil
+}
+
+// GetProcessingJobStatus возвращает текущий статус задачи
+func (r *ImageRepository) GetProcessingJobStatus(ctx context.Context, jobID string) (string, error) {
+	query := `SELECT status FROM processing_jobs WHERE id = $1`
+
+	var status string
+	err := r.db.QueryRow(ctx, query, jobID).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", fmt.Errorf("%w: %s", entity.ErrJobNotFound, jobID)
+		}
+		return "", fmt.Errorf("failed to get processing job status: %w", err)
+	}
+
+	return status, nil
+}
+
+// CancelProcessingJob отменяет ожидающую или выполняющуюся задачу и переводит
+// изображение в статус cancelled. Аренда задачи снимается, поэтому очередь
+// PostgreSQL не вернет ее в обработку
+func (r *ImageRepository) CancelProcessingJob(ctx context.Context, jobID string) (*entity.ProcessingJob, error) {
+	jobs, err := r.cancelProcessingJobs(ctx, "id = $1", jobID)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) > 0 {
+		return &jobs[0], nil
+	}
+
+	status, err := r.GetProcessingJobStatus(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: job is %s", entity.ErrJobNotCancellable, status)
+}
+
+// CancelImageProcessingJobs отменяет все ожидающие и выполняющиеся задачи изображения
+func (r *ImageRepository) CancelImageProcessingJobs(ctx context.Context, imageID string) ([]entity.ProcessingJob, error) {
+	jobs, err := r.cancelProcessingJobs(ctx, "image_id = $1", imageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) > 0 {
+		return jobs, nil
+	}
+
+	if _, err := r.GetImageByID(ctx, imageID); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: image has no pending or processing jobs", entity.ErrJobNotCancellable)
+}
+
+// cancelProcessingJobs в одной транзакции отменяет задачи по условию и
+// обновляет статус их изображений
+func (r *ImageRepository) cancelProcessingJobs(ctx context.Context, condition string, arg string) ([]entity.ProcessingJob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE processing_jobs
+		SET status = $2, error_message = NULL, locked_until = NULL, locked_by = NULL,
+		    updated_at = now(), completed_at = now()
+		WHERE ` + condition + ` AND status IN ($3, $4)
+		RETURNING id, image_id, status, attempts, updated_at
+	`
+
+	rows, err := tx.Query(ctx, query, arg, entity.JobStatusCancelled, entity.JobStatusPending, entity.JobStatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel processing jobs: %w", err)
+	}
+	var jobs []entity.ProcessingJob
+	for rows.Next() {
+		var job entity.ProcessingJob
+		if err := rows.Scan(&job.ID, &job.ImageID, &job.Status, &job.Attempts, &job.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan cancelled job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to cancel processing jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	imageQuery := `
+		UPDATE images
+		SET status = $2, updated_at = now()
+		WHERE id = $1 AND status IN ($3, $4)
+	`
+
+	if _, err := tx.Exec(ctx, imageQuery, jobs[0].ImageID, entity.StatusCancelled, entity.StatusUploaded, entity.StatusProcessing); err != nil {
+		return nil, fmt.Errorf("failed to update image status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return jobs, nil
+}
+
+// DeleteProcessedImage удаляет запись об обработанном изображении
+func (r *ImageRepository) DeleteProcessedImage(ctx context.Context, id string) error {
+	query := `DELETE FROM processed_images WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete processed image: %w", err)
+	}
+
+	return nil
+}
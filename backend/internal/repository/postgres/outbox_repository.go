@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Статусы записей outbox
+const (
+	outboxStatusPending = "pending"
+	outboxStatusSent    = "sent"
+)
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// insertOutboxMessage записывает задачу в outbox; вызывается в транзакции,
+// создающей задачу
+func insertOutboxMessage(ctx context.Context, db execer, task *entity.ProcessingTask) error {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_outbox (task_id, image_id, payload, status, attempts, available_at, created_at)
+		VALUES ($1, $2, $3, $4, 0, now(), now())
+	`
+
+	_, err = db.Exec(ctx, query, task.ID, task.ImageID, payload, outboxStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimOutboxMessages арендует до limit записей, готовых к публикации.
+// Арендованные записи скрываются от других relay на время lease: если
+// публикация не подтверждена за это время, запись будет отправлена повторно
+func (r *OutboxRepository) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error) {
+	query := `
+		WITH next AS (
+			SELECT id FROM task_outbox
+			WHERE status = $1 AND available_at <= now()
+			ORDER BY available_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE task_outbox o
+		SET attempts = o.attempts + 1,
+		    available_at = now() + make_interval(secs => $3)
+		FROM next
+		WHERE o.id = next.id
+		RETURNING o.id, o.payload, o.attempts, o.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, outboxStatusPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []entity.OutboxMessage
+	for rows.Next() {
+		var message entity.OutboxMessage
+		var payload []byte
+
+		if err := rows.Scan(&message.ID, &payload, &message.Attempts, &message.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		if err := json.Unmarshal(payload, &message.Task); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal outbox message %d: %w", message.ID, err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// MarkOutboxMessageSent отмечает запись отправленной и переводит изображение,
+// ожидающее обработки, в статус processing
+func (r *OutboxRepository) MarkOutboxMessageSent(ctx context.Context, id int64) error {
+	query := `
+		WITH sent AS (
+			UPDATE task_outbox
+			SET status = $2, sent_at = now(), last_error = NULL
+			WHERE id = $1
+			RETURNING image_id
+		)
+		UPDATE images
+		SET status = $3
+		FROM sent
+		WHERE images.id = sent.image_id AND images.status = $4
+	`
+
+	_, err := r.db.Exec(ctx, query, id, outboxStatusSent, entity.StatusProcessing, entity.StatusUploaded)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message sent: %w", err)
+	}
+
+	return nil
+}
+
+// RescheduleOutboxMessage откладывает повторную публикацию записи на delay
+func (r *OutboxRepository) RescheduleOutboxMessage(ctx context.Context, id int64, errorMsg string, delay time.Duration) error {
+	query := `
+		UPDATE task_outbox
+		SET available_at = now() + make_interval(secs => $2), last_error = $3
+		WHERE id = $1 AND status = $4
+	`
+
+	_, err := r.db.Exec(ctx, query, id, delay.Seconds(), errorMsg, outboxStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbox message: %w", err)
+	}
+
+	return nil
+}
@@ -5,9 +5,16 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// execer - общий метод пула и транзакции; позволяет выполнять запросы
+// репозиториев как отдельно, так и внутри транзакции
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
 type Storage struct {
 	pool *pgxpool.Pool
 }
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PresetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPresetRepository(db *pgxpool.Pool) *PresetRepository {
+	return &PresetRepository{
+		db: db,
+	}
+}
+
+// CreatePresetVersion сохраняет новую версию пресета; номер версии назначается БД
+func (r *PresetRepository) CreatePresetVersion(ctx context.Context, preset *entity.Preset) error {
+	operationsJSON, err := json.Marshal(preset.Operations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operations: %w", err)
+	}
+
+	query := `
+		INSERT INTO presets (id, name, version, description, operations, created_at)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(version), 0) + 1 FROM presets WHERE name = $2), $3, $4, $5)
+		RETURNING version
+	`
+
+	err = r.db.QueryRow(ctx, query,
+		preset.ID,
+		preset.Name,
+		preset.Description,
+		operationsJSON,
+		preset.CreatedAt,
+	).Scan(&preset.Version)
+
+	if err != nil {
+		return fmt.Errorf("failed to create preset: %w", err)
+	}
+
+	return nil
+}
+
+// GetPreset получает версию пресета; version = 0 означает последнюю версию
+func (r *PresetRepository) GetPreset(ctx context.Context, name string, version int) (*entity.Preset, error) {
+	query := `
+		SELECT id, name, version, COALESCE(description, ''), operations, created_at
+		FROM presets
+		WHERE name = $1 AND ($2 = 0 OR version = $2)
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	preset, err := scanPreset(r.db.QueryRow(ctx, query, name, version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", entity.ErrPresetNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+
+	return preset, nil
+}
+
+// ListPresets возвращает последние версии всех пресетов
+func (r *PresetRepository) ListPresets(ctx context.Context) ([]entity.Preset, error) {
+	query := `
+		SELECT DISTINCT ON (name) id, name, version, COALESCE(description, ''), operations, created_at
+		FROM presets
+		ORDER BY name, version DESC
+	`
+
+	return r.queryPresets(ctx, query)
+}
+
+// ListPresetVersions возвращает все версии пресета, начиная с последней
+func (r *PresetRepository) ListPresetVersions(ctx context.Context, name string) ([]entity.Preset, error) {
+	query := `
+		SELECT id, name, version, COALESCE(description, ''), operations, created_at
+		FROM presets
+		WHERE name = $1
+		ORDER BY version DESC
+	`
+
+	return r.queryPresets(ctx, query, name)
+}
+
+// DeletePreset удаляет все версии пресета
+func (r *PresetRepository) DeletePreset(ctx context.Context, name string) error {
+	query := `DELETE FROM presets WHERE name = $1`
+
+	result, err := r.db.Exec(ctx, query, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete preset: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", entity.ErrPresetNotFound, name)
+	}
+
+	return nil
+}
+
+func (r *PresetRepository) queryPresets(ctx context.Context, query string, args ...interface{}) ([]entity.Preset, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+	defer rows.Close()
+
+	var presets []entity.Preset
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan preset: %w", err)
+		}
+		presets = append(presets, *preset)
+	}
+
+	return presets, nil
+}
+
+func scanPreset(row pgx.Row) (*entity.Preset, error) {
+	var preset entity.Preset
+	var operationsJSON []byte
+
+	err := row.Scan(
+		&preset.ID,
+		&preset.Name,
+		&preset.Version,
+		&preset.Description,
+		&operationsJSON,
+		&preset.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(operationsJSON, &preset.Operations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operations: %w", err)
+	}
+
+	return &preset, nil
+}
@@ -22,7 +22,7 @@ func NewStatisticsRepository(db *pgxpool.Pool) *StatisticsRepository {
 func (r *StatisticsRepository) GetStatistics(ctx context.Context) (*entity.ProcessingStatistics, error) {
 	query := `
 		SELECT id, total_images_uploaded, total_images_processed, total_images_failed,
-		       total_data_processed_bytes, average_processing_time_ms, updated_at
+		       total_images_timed_out, total_data_processed_bytes, average_processing_time_ms, updated_at
 		FROM statistics
 		WHERE id = 'default'
 	`
@@ -33,6 +33,7 @@ func (r *StatisticsRepository) GetStatistics(ctx context.Context) (*entity.Proce
 		&stats.TotalImagesUploaded,
 		&stats.TotalImagesProcessed,
 		&stats.FailedProcessingAttempts,
+		&stats.TimedOutProcessingAttempts,
 		&stats.TotalDataProcessedBytes,
 		&stats.AverageProcessingTimeMs,
 		&stats.LastStatisticsUpdatedAt,
@@ -95,6 +96,22 @@ func (r *StatisticsRepository) IncrementImageFailed(ctx context.Context) error {
 	return nil
 }
 
+// IncrementImageTimedOut увеличивает счетчик обработок, прерванных по таймауту
+func (r *StatisticsRepository) IncrementImageTimedOut(ctx context.Context) error {
+	query := `
+		UPDATE statistics
+		SET total_images_timed_out = total_images_timed_out + 1
+		WHERE id = 'default'
+	`
+
+	_, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to increment image timed out: %w", err)
+	}
+
+	return nil
+}
+
 // GetOperationStatistics получает статистику по операциям
 func (r *StatisticsRepository) GetOperationStatistics(ctx context.Context) ([]entity.OperationStat, error) {
 	query := `
@@ -0,0 +1,385 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// webhookDeliveryColumns - колонки webhook_deliveries в порядке scanWebhookDelivery
+const webhookDeliveryColumns = `
+	id, event_id, event_type, image_id, COALESCE(task_id, ''), COALESCE(endpoint_id, ''), url, payload,
+	status, attempts, max_attempts, next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''),
+	delivered_at, created_at, updated_at`
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+// CreateWebhookEndpoint регистрирует получателя событий
+func (r *WebhookRepository) CreateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (id, url, secret, description, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(ctx, query, endpoint.ID, endpoint.URL, endpoint.Secret, endpoint.Description, endpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhookEndpoints возвращает зарегистрированных получателей в порядке создания
+func (r *WebhookRepository) ListWebhookEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error) {
+	query := `
+		SELECT id, url, secret, description, created_at
+		FROM webhook_endpoints
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []entity.WebhookEndpoint
+	for rows.Next() {
+		var endpoint entity.WebhookEndpoint
+		if err := rows.Scan(&endpoint.ID, &endpoint.URL, &endpoint.Secret, &endpoint.Description, &endpoint.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// DeleteWebhookEndpoint удаляет получателя вместе с его доставками
+func (r *WebhookRepository) DeleteWebhookEndpoint(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", entity.ErrWebhookEndpointNotFound, id)
+	}
+
+	return nil
+}
+
+// CreateWebhookDeliveries сохраняет доставки одного события в одной транзакции
+func (r *WebhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, event_id, event_type, image_id, task_id, endpoint_id, url, payload,
+		                                status, attempts, max_attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, 0, $10, $11, $12, $12)
+	`
+
+	for _, delivery := range deliveries {
+		_, err := tx.Exec(ctx, query,
+			delivery.ID,
+			delivery.EventID,
+			delivery.EventType,
+			delivery.ImageID,
+			delivery.TaskID,
+			delivery.EndpointID,
+			delivery.URL,
+			delivery.Payload,
+			delivery.Status,
+			delivery.MaxAttempts,
+			delivery.NextAttemptAt,
+			delivery.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries арендует до limit доставок, готовых к отправке, и
+// засчитывает им попытку. Арендованные доставки скрыты от других dispatcher
+// на время lease; ключ подписи endpoint возвращается в поле Secret
+func (r *WebhookRepository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	query := `
+		WITH next AS (
+			SELECT d.id, COALESCE(e.secret, '') AS secret
+			FROM webhook_deliveries d
+			LEFT JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status = $1 AND d.next_attempt_at <= now()
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+		    next_attempt_at = now() + make_interval(secs => $3),
+		    updated_at = now()
+		FROM next
+		WHERE d.id = next.id
+		RETURNING d.id, d.event_id, d.event_type, d.image_id, COALESCE(d.task_id, ''), COALESCE(d.endpoint_id, ''),
+		          d.url, d.payload, d.attempts, d.max_attempts, next.secret
+	`
+
+	rows, err := r.db.Query(ctx, query, entity.WebhookDeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []entity.WebhookDelivery
+	for rows.Next() {
+		delivery := entity.WebhookDelivery{Status: entity.WebhookDeliveryPending}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.ImageID,
+			&delivery.TaskID,
+			&delivery.EndpointID,
+			&delivery.URL,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.MaxAttempts,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt записывает попытку в журнал и переводит доставку в status.
+// Для pending следующая попытка назначается через retryDelay. Доставка,
+// повторно арендованная после истечения аренды, не меняется
+func (r *WebhookRepository) RecordWebhookAttempt(ctx context.Context, attempt *entity.WebhookAttempt, status entity.WebhookDeliveryStatus, retryDelay time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	attemptQuery := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error_message, duration_ms, created_at)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5, now())
+	`
+
+	_, err = tx.Exec(ctx, attemptQuery, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	deliveryQuery := `
+		UPDATE webhook_deliveries
+		SET status = $3,
+		    last_status_code = NULLIF($4, 0),
+		    last_error = NULLIF($5, ''),
+		    next_attempt_at = now() + make_interval(secs => $6),
+		    delivered_at = CASE WHEN $7 THEN now() ELSE delivered_at END,
+		    updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = $8
+	`
+
+	_, err = tx.Exec(ctx, deliveryQuery,
+		attempt.DeliveryID,
+		attempt.Attempt,
+		status,
+		attempt.StatusCode,
+		attempt.Error,
+		retryDelay.Seconds(),
+		status == entity.WebhookDeliveryDelivered,
+		entity.WebhookDeliveryPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDelivery получает доставку по ID
+func (r *WebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", entity.ErrWebhookDeliveryNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// ListWebhookDeliveries возвращает доставки, новые первыми
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter, limit, offset int) ([]entity.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE ($1 = '' OR status = $1)
+		  AND ($2 = '' OR image_id = $2)
+		  AND ($3 = '' OR endpoint_id = $3)
+		ORDER BY created_at DESC, id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(ctx, query, string(filter.Status), filter.ImageID, filter.EndpointID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []entity.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ListWebhookAttempts возвращает журнал попыток доставки в порядке выполнения
+func (r *WebhookRepository) ListWebhookAttempts(ctx context.Context, deliveryID string) ([]entity.WebhookAttempt, error) {
+	query := `
+		SELECT id, delivery_id, attempt, COALESCE(status_code, 0), COALESCE(error_message, ''), duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []entity.WebhookAttempt
+	for rows.Next() {
+		var attempt entity.WebhookAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RedeliverWebhookDelivery снова ставит завершенную доставку в очередь: счетчик
+// попыток сбрасывается, журнал прежних попыток сохраняется
+func (r *WebhookRepository) RedeliverWebhookDelivery(ctx context.Context, id string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = 0, next_attempt_at = now(), updated_at = now()
+		WHERE id = $1 AND status <> $2
+	`
+
+	result, err := r.db.Exec(ctx, query, id, entity.WebhookDeliveryPending)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		// Доставка не найдена или еще ожидает попытки
+		if _, err := r.GetWebhookDelivery(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", entity.ErrWebhookDeliveryPending, id)
+	}
+
+	return nil
+}
+
+// scanWebhookDelivery сканирует строку webhook_deliveries в порядке webhookDeliveryColumns
+func scanWebhookDelivery(row pgx.Row) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.ImageID,
+		&delivery.TaskID,
+		&delivery.EndpointID,
+		&delivery.URL,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.MaxAttempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
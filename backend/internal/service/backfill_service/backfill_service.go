@@ -0,0 +1,195 @@
+package backfillservice
+
+import (
+	"context"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// recentErrorsLimit - сколько последних ошибок возвращается вместе с состоянием backfill
+const recentErrorsLimit = 20
+
+// BackfillParams - параметры нового backfill. Нулевые BatchSize и
+// BatchInterval заменяются значениями из конфигурации
+type BackfillParams struct {
+	Filter        entity.BackfillFilter
+	Operations    []entity.OperationParams
+	Preset        *entity.Preset
+	Supersede     bool
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+// BackfillReport - состояние backfill с итогами созданных им задач
+type BackfillReport struct {
+	Backfill *entity.Backfill
+	// Jobs - количество задач backfill по статусам processing_jobs
+	Jobs map[string]int64
+	// RecentErrors - последние неудачные пачки, новые первыми
+	RecentErrors []entity.BackfillError
+}
+
+type BackfillService struct {
+	repo   BackfillRepositoryInterface
+	runner RunnerInterface
+	cfg    config.BackfillConfig
+	logger *zap.Logger
+}
+
+// NewBackfillService создает сервис backfill. runner может быть nil, если
+// пачки публикует другой процесс: тогда backfill будет найден при опросе
+func NewBackfillService(repo BackfillRepositoryInterface, runner RunnerInterface, cfg config.BackfillConfig, logger *zap.Logger) *BackfillService {
+	return &BackfillService{
+		repo:   repo,
+		runner: runner,
+		cfg:    withDefaults(cfg),
+		logger: logger,
+	}
+}
+
+// CreateBackfill запускает повторную обработку изображений, подходящих под фильтр
+func (s *BackfillService) CreateBackfill(ctx context.Context, params BackfillParams) (*entity.Backfill, error) {
+	if err := validateFilter(params.Filter); err != nil {
+		return nil, err
+	}
+	if len(params.Operations) == 0 {
+		return nil, fmt.Errorf("%w: operations are required", entity.ErrInvalidBackfill)
+	}
+
+	batchSize := params.BatchSize
+	if batchSize == 0 {
+		batchSize = s.cfg.BatchSize
+	}
+	if batchSize < 0 || batchSize > s.cfg.MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch_size must be between 1 and %d", entity.ErrInvalidBackfill, s.cfg.MaxBatchSize)
+	}
+
+	batchInterval := params.BatchInterval
+	if batchInterval == 0 {
+		batchInterval = s.cfg.BatchInterval
+	}
+	if batchInterval < 0 {
+		return nil, fmt.Errorf("%w: batch_interval must not be negative", entity.ErrInvalidBackfill)
+	}
+
+	total, err := s.repo.CountBackfillImages(ctx, params.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	backfill := &entity.Backfill{
+		ID:            uuid.New().String(),
+		Status:        entity.BackfillRunning,
+		Filter:        params.Filter,
+		Operations:    params.Operations,
+		Supersede:     params.Supersede,
+		BatchSize:     batchSize,
+		BatchInterval: batchInterval,
+		TotalImages:   total,
+		NextRunAt:     now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if params.Preset != nil {
+		backfill.PresetName = params.Preset.Name
+		backfill.PresetVersion = params.Preset.Version
+	}
+
+	if err := s.repo.CreateBackfill(ctx, backfill); err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	s.logger.Info("Backfill created",
+		zap.String("backfillId", backfill.ID),
+		zap.Int64("totalImages", total),
+		zap.Int("batchSize", batchSize),
+		zap.Duration("batchInterval", batchInterval),
+	)
+	return backfill, nil
+}
+
+// ListBackfills возвращает backfill, новые первыми
+func (s *BackfillService) ListBackfills(ctx context.Context, status entity.BackfillStatus, limit, offset int) ([]entity.Backfill, error) {
+	return s.repo.ListBackfills(ctx, status, limit, offset)
+}
+
+// GetBackfill возвращает состояние backfill, итоги его задач и последние ошибки
+func (s *BackfillService) GetBackfill(ctx context.Context, id string) (*BackfillReport, error) {
+	backfill, err := s.repo.GetBackfill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.repo.CountBackfillJobs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	recentErrors, err := s.repo.ListBackfillErrors(ctx, id, recentErrorsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackfillReport{
+		Backfill:     backfill,
+		Jobs:         jobs,
+		RecentErrors: recentErrors,
+	}, nil
+}
+
+// PauseBackfill приостанавливает публикацию пачек; уже опубликованные задачи обрабатываются
+func (s *BackfillService) PauseBackfill(ctx context.Context, id string) (*entity.Backfill, error) {
+	backfill, err := s.repo.PauseBackfill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Backfill paused", zap.String("backfillId", id))
+	return backfill, nil
+}
+
+// ResumeBackfill продолжает приостановленный или остановленный ошибками backfill
+func (s *BackfillService) ResumeBackfill(ctx context.Context, id string) (*entity.Backfill, error) {
+	backfill, err := s.repo.ResumeBackfill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	s.logger.Info("Backfill resumed", zap.String("backfillId", id))
+	return backfill, nil
+}
+
+func (s *BackfillService) notify() {
+	if s.runner != nil {
+		s.runner.Notify()
+	}
+}
+
+// validateFilter проверяет статусы, диапазон дат и варианты фильтра
+func validateFilter(filter entity.BackfillFilter) error {
+	for _, status := range filter.Statuses {
+		switch status {
+		case entity.StatusUploaded, entity.StatusProcessing, entity.StatusCompleted, entity.StatusFailed, entity.StatusCancelled:
+		default:
+			return fmt.Errorf("%w: unsupported status %q", entity.ErrInvalidBackfill, status)
+		}
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return fmt.Errorf("%w: created_from must be before created_to", entity.ErrInvalidBackfill)
+	}
+
+	if filter.HasVariant != "" && filter.HasVariant == filter.LacksVariant {
+		return fmt.Errorf("%w: has_variant and lacks_variant must differ", entity.ErrInvalidBackfill)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package backfillservice
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeBackfillRepo запоминает созданные backfill; остальные методы в тесте не вызываются
+type fakeBackfillRepo struct {
+	BackfillRepositoryInterface
+	created []*entity.Backfill
+}
+
+func (r *fakeBackfillRepo) CountBackfillImages(ctx context.Context, filter entity.BackfillFilter) (int64, error) {
+	return 42, nil
+}
+
+func (r *fakeBackfillRepo) CreateBackfill(ctx context.Context, backfill *entity.Backfill) error {
+	r.created = append(r.created, backfill)
+	return nil
+}
+
+type fakeRunner struct {
+	notified int
+}
+
+func (r *fakeRunner) Notify() {
+	r.notified++
+}
+
+func TestCreateBackfill_AppliesDefaultsAndNotifiesRunner(t *testing.T) {
+	repo := &fakeBackfillRepo{}
+	runner := &fakeRunner{}
+	service := NewBackfillService(repo, runner, config.BackfillConfig{BatchSize: 50, BatchInterval: 2 * time.Second}, zap.NewNop())
+
+	backfill, err := service.CreateBackfill(context.Background(), BackfillParams{
+		Filter:     entity.BackfillFilter{LacksVariant: "thumbnail"},
+		Operations: []entity.OperationParams{{Type: entity.OpThumbnail}},
+		Preset:     &entity.Preset{Name: "thumbs", Version: 3},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if backfill.Status != entity.BackfillRunning || backfill.BatchSize != 50 || backfill.BatchInterval != 2*time.Second {
+		t.Fatalf("defaults not applied: %+v", backfill)
+	}
+	if backfill.TotalImages != 42 || backfill.PresetName != "thumbs" || backfill.PresetVersion != 3 {
+		t.Fatalf("unexpected backfill: %+v", backfill)
+	}
+	if len(repo.created) != 1 || runner.notified != 1 {
+		t.Fatalf("expected backfill stored and runner notified, got %d stored, %d notifications", len(repo.created), runner.notified)
+	}
+}
+
+func TestCreateBackfill_RejectsInvalidParams(t *testing.T) {
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+	operations := []entity.OperationParams{{Type: entity.OpThumbnail}}
+
+	tests := map[string]BackfillParams{
+		"unknown status":    {Filter: entity.BackfillFilter{Statuses: []entity.ImageStatus{"archived"}}, Operations: operations},
+		"deleted status":    {Filter: entity.BackfillFilter{Statuses: []entity.ImageStatus{entity.StatusDeleted}}, Operations: operations},
+		"inverted range":    {Filter: entity.BackfillFilter{CreatedFrom: &from, CreatedTo: &to}, Operations: operations},
+		"same variant":      {Filter: entity.BackfillFilter{HasVariant: "thumb", LacksVariant: "thumb"}, Operations: operations},
+		"no operations":     {},
+		"batch too large":   {Operations: operations, BatchSize: 1001},
+		"negative interval": {Operations: operations, BatchInterval: -time.Second},
+	}
+
+	for name, params := range tests {
+		repo := &fakeBackfillRepo{}
+		service := NewBackfillService(repo, nil, config.BackfillConfig{}, zap.NewNop())
+
+		_, err := service.CreateBackfill(context.Background(), params)
+		if !errors.Is(err, entity.ErrInvalidBackfill) {
+			t.Fatalf("%s: expected ErrInvalidBackfill, got %v", name, err)
+		}
+		if len(repo.created) != 0 {
+			t.Fatalf("%s: invalid backfill stored", name)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package backfillservice
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// BackfillRepositoryInterface определяет методы репозитория, нужные API backfill
+type BackfillRepositoryInterface interface {
+	CreateBackfill(ctx context.Context, backfill *entity.Backfill) error
+	CountBackfillImages(ctx context.Context, filter entity.BackfillFilter) (int64, error)
+	GetBackfill(ctx context.Context, id string) (*entity.Backfill, error)
+	ListBackfills(ctx context.Context, status entity.BackfillStatus, limit, offset int) ([]entity.Backfill, error)
+	ListBackfillErrors(ctx context.Context, backfillID string, limit int) ([]entity.BackfillError, error)
+	CountBackfillJobs(ctx context.Context, backfillID string) (map[string]int64, error)
+	PauseBackfill(ctx context.Context, id string) (*entity.Backfill, error)
+	ResumeBackfill(ctx context.Context, id string) (*entity.Backfill, error)
+}
+
+// RunnerRepositoryInterface определяет методы репозитория, нужные runner
+type RunnerRepositoryInterface interface {
+	// ClaimBackfill арендует backfill, для которого наступило время следующей пачки
+	ClaimBackfill(ctx context.Context, lease time.Duration) (*entity.Backfill, error)
+	ListBackfillCandidates(ctx context.Context, backfill *entity.Backfill, limit int) ([]entity.BackfillCandidate, error)
+	// CreateBackfillJobs создает задачи для изображений без активных задач и возвращает созданные
+	CreateBackfillJobs(ctx context.Context, backfillID string, tasks []*entity.ProcessingTask) ([]*entity.ProcessingTask, error)
+	AdvanceBackfill(ctx context.Context, id string, cursor entity.BackfillCursor, scanned, queued, skipped int64, delay time.Duration) error
+	CompleteBackfill(ctx context.Context, id string) error
+	RecordBackfillFailure(ctx context.Context, id string, batchSize int, errorMsg string, delay time.Duration, maxAttempts int) (entity.BackfillStatus, error)
+}
+
+// RunnerInterface публикует пачки запущенных backfill
+type RunnerInterface interface {
+	// Notify сообщает о запущенном backfill, чтобы не ждать следующего опроса
+	Notify()
+}
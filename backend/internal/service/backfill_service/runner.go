@@ -0,0 +1,256 @@
+package backfillservice
+
+import (
+	"context"
+	"fmt"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для незаданных параметров BackfillConfig
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultMaxBatchSize    = 1000
+	defaultBatchInterval   = time.Second
+	defaultLeaseTimeout    = time.Minute
+	defaultMaxAttempts     = 5
+	defaultRetryBackoff    = 5 * time.Second
+	defaultMaxRetryBackoff = 5 * time.Minute
+)
+
+// withDefaults заменяет незаданные параметры значениями по умолчанию
+func withDefaults(cfg config.BackfillConfig) config.BackfillConfig {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultMaxBatchSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = min(defaultBatchSize, cfg.MaxBatchSize)
+	}
+	if cfg.BatchInterval <= 0 {
+		cfg.BatchInterval = defaultBatchInterval
+	}
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = defaultLeaseTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.MaxRetryBackoff < cfg.RetryBackoff {
+		cfg.MaxRetryBackoff = max(defaultMaxRetryBackoff, cfg.RetryBackoff)
+	}
+	return cfg
+}
+
+// Runner публикует задачи запущенных backfill. За один проход он арендует
+// backfill, выбирает следующую после курсора страницу изображений, создает
+// задачи и публикует их одной пачкой через PublishBatch, после чего сдвигает
+// курсор и откладывает следующую пачку на BatchInterval. Задачи привязаны
+// к backfill, поэтому пачка, прерванная до сдвига курсора, публикуется снова
+type Runner struct {
+	repo     RunnerRepositoryInterface
+	producer broker.ProducerMessageBrokerInterface
+	bucket   string
+	cfg      config.BackfillConfig
+	logger   *zap.Logger
+	wake     chan struct{}
+}
+
+func NewRunner(repo RunnerRepositoryInterface, producer broker.ProducerMessageBrokerInterface, bucket string, cfg config.BackfillConfig, logger *zap.Logger) *Runner {
+	return &Runner{
+		repo:     repo,
+		producer: producer,
+		bucket:   bucket,
+		cfg:      withDefaults(cfg),
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает о запущенном backfill, чтобы runner не ждал следующего опроса
+func (r *Runner) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run публикует пачки backfill, пока не отменен контекст
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	r.logger.Info("Backfill runner started", zap.Duration("pollInterval", r.cfg.PollInterval))
+
+	for {
+		// Несколько запущенных backfill получают пачки по очереди
+		for {
+			ran, err := r.RunBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("Failed to run backfill batch", zap.Error(err))
+				}
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Backfill runner stopped")
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RunBatch арендует backfill и публикует его следующую пачку. Возвращает
+// false, если готовых к публикации backfill нет
+func (r *Runner) RunBatch(ctx context.Context) (bool, error) {
+	backfill, err := r.repo.ClaimBackfill(ctx, r.cfg.LeaseTimeout)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim backfill: %w", err)
+	}
+	if backfill == nil {
+		return false, nil
+	}
+
+	candidates, err := r.repo.ListBackfillCandidates(ctx, backfill, backfill.BatchSize)
+	if err != nil {
+		r.recordFailure(ctx, backfill, 0, err)
+		return true, nil
+	}
+
+	if len(candidates) == 0 {
+		if err := r.repo.CompleteBackfill(ctx, backfill.ID); err != nil {
+			return true, fmt.Errorf("failed to complete backfill: %w", err)
+		}
+		r.logger.Info("Backfill completed",
+			zap.String("backfillId", backfill.ID),
+			zap.Int64("scanned", backfill.ScannedImages),
+			zap.Int64("queued", backfill.QueuedImages),
+			zap.Int64("skipped", backfill.SkippedImages),
+		)
+		return true, nil
+	}
+
+	var tasks, fresh []*entity.ProcessingTask
+	var skipped int64
+	for i := range candidates {
+		candidate := &candidates[i]
+		switch {
+		case candidate.OwnJob:
+			// Задача создана прерванной пачкой: публикуется с тем же ID
+			tasks = append(tasks, r.newTask(backfill, candidate, candidate.ActiveJobID))
+		case candidate.ActiveJobID != "":
+			skipped++
+		default:
+			fresh = append(fresh, r.newTask(backfill, candidate, uuid.New().String()))
+		}
+	}
+
+	created, err := r.repo.CreateBackfillJobs(ctx, backfill.ID, fresh)
+	if err != nil {
+		r.recordFailure(ctx, backfill, len(candidates), err)
+		return true, nil
+	}
+	skipped += int64(len(fresh) - len(created))
+	tasks = append(tasks, created...)
+
+	if err := r.producer.PublishBatch(ctx, tasks); err != nil {
+		if ctx.Err() != nil {
+			// Пачка будет опубликована снова после истечения аренды
+			return true, ctx.Err()
+		}
+		r.recordFailure(ctx, backfill, len(tasks), fmt.Errorf("failed to publish batch: %w", err))
+		return true, nil
+	}
+
+	last := candidates[len(candidates)-1]
+	cursor := entity.BackfillCursor{CreatedAt: last.CreatedAt, ImageID: last.ImageID}
+	if err := r.repo.AdvanceBackfill(ctx, backfill.ID, cursor, int64(len(candidates)), int64(len(tasks)), skipped, backfill.BatchInterval); err != nil {
+		// Курсор не сдвинут: после истечения аренды пачка будет опубликована повторно
+		return true, fmt.Errorf("failed to advance backfill: %w", err)
+	}
+
+	r.logger.Info("Backfill batch published",
+		zap.String("backfillId", backfill.ID),
+		zap.Int("scanned", len(candidates)),
+		zap.Int("queued", len(tasks)),
+		zap.Int64("skipped", skipped),
+	)
+	return true, nil
+}
+
+// newTask создает задачу backfill для изображения страницы
+func (r *Runner) newTask(backfill *entity.Backfill, candidate *entity.BackfillCandidate, taskID string) *entity.ProcessingTask {
+	return &entity.ProcessingTask{
+		ID:            taskID,
+		ImageID:       candidate.ImageID,
+		OriginalPath:  candidate.OriginalPath,
+		Bucket:        r.bucket,
+		Operations:    backfill.Operations,
+		Format:        candidate.Format,
+		PresetName:    backfill.PresetName,
+		PresetVersion: backfill.PresetVersion,
+		Supersede:     backfill.Supersede,
+	}
+}
+
+// recordFailure записывает неудачную пачку и назначает повтор с растущей задержкой
+func (r *Runner) recordFailure(ctx context.Context, backfill *entity.Backfill, batchSize int, cause error) {
+	attempt := backfill.Attempts + 1
+	delay := r.backoff(attempt)
+
+	status, err := r.repo.RecordBackfillFailure(ctx, backfill.ID, batchSize, cause.Error(), delay, r.cfg.MaxAttempts)
+	if err != nil {
+		// Backfill снова станет доступен после истечения аренды
+		r.logger.Error("Failed to record backfill failure",
+			zap.Error(err),
+			zap.NamedError("cause", cause),
+			zap.String("backfillId", backfill.ID),
+		)
+		return
+	}
+
+	if status == entity.BackfillFailed {
+		r.logger.Error("Backfill failed after all attempts",
+			zap.Error(cause),
+			zap.String("backfillId", backfill.ID),
+			zap.Int("attempts", attempt),
+		)
+		return
+	}
+	r.logger.Warn("Backfill batch failed, will retry",
+		zap.Error(cause),
+		zap.String("backfillId", backfill.ID),
+		zap.Int("attempt", attempt),
+		zap.Duration("backoff", delay),
+	)
+}
+
+// backoff возвращает задержку перед повтором пачки после attempts неудач подряд
+func (r *Runner) backoff(attempts int) time.Duration {
+	backoff := r.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= r.cfg.MaxRetryBackoff {
+			return r.cfg.MaxRetryBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,231 @@
+package backfillservice
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// advance - сдвиг курсора, записанный runner
+type advance struct {
+	cursor                   entity.BackfillCursor
+	scanned, queued, skipped int64
+	delay                    time.Duration
+}
+
+// fakeRunnerRepo выдает backfill один раз и одну страницу кандидатов.
+// Задачи для изображений из taken не создаются, как если бы их заняла
+// повторная обработка через API
+type fakeRunnerRepo struct {
+	backfill   *entity.Backfill
+	candidates []entity.BackfillCandidate
+	taken      map[string]bool
+
+	created   []*entity.ProcessingTask
+	advanced  []advance
+	completed []string
+	failures  []time.Duration
+	maxSeen   int
+}
+
+func (r *fakeRunnerRepo) ClaimBackfill(ctx context.Context, lease time.Duration) (*entity.Backfill, error) {
+	backfill := r.backfill
+	r.backfill = nil
+	return backfill, nil
+}
+
+func (r *fakeRunnerRepo) ListBackfillCandidates(ctx context.Context, backfill *entity.Backfill, limit int) ([]entity.BackfillCandidate, error) {
+	return r.candidates, nil
+}
+
+func (r *fakeRunnerRepo) CreateBackfillJobs(ctx context.Context, backfillID string, tasks []*entity.ProcessingTask) ([]*entity.ProcessingTask, error) {
+	var created []*entity.ProcessingTask
+	for _, task := range tasks {
+		if !r.taken[task.ImageID] {
+			created = append(created, task)
+		}
+	}
+	r.created = append(r.created, created...)
+	return created, nil
+}
+
+func (r *fakeRunnerRepo) AdvanceBackfill(ctx context.Context, id string, cursor entity.BackfillCursor, scanned, queued, skipped int64, delay time.Duration) error {
+	r.advanced = append(r.advanced, advance{cursor: cursor, scanned: scanned, queued: queued, skipped: skipped, delay: delay})
+	return nil
+}
+
+func (r *fakeRunnerRepo) CompleteBackfill(ctx context.Context, id string) error {
+	r.completed = append(r.completed, id)
+	return nil
+}
+
+func (r *fakeRunnerRepo) RecordBackfillFailure(ctx context.Context, id string, batchSize int, errorMsg string, delay time.Duration, maxAttempts int) (entity.BackfillStatus, error) {
+	r.failures = append(r.failures, delay)
+	r.maxSeen = maxAttempts
+	return entity.BackfillRunning, nil
+}
+
+// fakeProducer запоминает опубликованные пачки; с err отклоняет их
+type fakeProducer struct {
+	broker.ProducerMessageBrokerInterface
+	err     error
+	batches [][]*entity.ProcessingTask
+}
+
+func (p *fakeProducer) PublishBatch(ctx context.Context, tasks []*entity.ProcessingTask) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.batches = append(p.batches, tasks)
+	return nil
+}
+
+func newTestRunner(repo *fakeRunnerRepo, producer *fakeProducer) *Runner {
+	return NewRunner(repo, producer, "bucket", config.BackfillConfig{
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 5 * time.Second,
+		MaxAttempts:     3,
+	}, zap.NewNop())
+}
+
+func newTestBackfill() *entity.Backfill {
+	return &entity.Backfill{
+		ID:            "backfill",
+		Status:        entity.BackfillRunning,
+		Operations:    []entity.OperationParams{{Type: entity.OpThumbnail}},
+		PresetName:    "thumbs",
+		PresetVersion: 2,
+		Supersede:     true,
+		BatchSize:     3,
+		BatchInterval: 500 * time.Millisecond,
+	}
+}
+
+func TestRunBatch_PublishesPageAndAdvancesCursor(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRunnerRepo{
+		backfill: newTestBackfill(),
+		candidates: []entity.BackfillCandidate{
+			{ImageID: "fresh", OriginalPath: "original/fresh.png", Format: entity.FormatPNG, CreatedAt: created},
+			{ImageID: "busy", CreatedAt: created, ActiveJobID: "other-job"},
+			{ImageID: "interrupted", CreatedAt: created.Add(time.Second), ActiveJobID: "own-job", OwnJob: true},
+		},
+	}
+	producer := &fakeProducer{}
+
+	ran, err := newTestRunner(repo, producer).RunBatch(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("expected batch to run, got ran=%v err=%v", ran, err)
+	}
+
+	if len(producer.batches) != 1 || len(producer.batches[0]) != 2 {
+		t.Fatalf("expected one batch of 2 tasks, got %v", producer.batches)
+	}
+	published := make(map[string]*entity.ProcessingTask)
+	for _, task := range producer.batches[0] {
+		published[task.ImageID] = task
+	}
+	if task := published["interrupted"]; task == nil || task.ID != "own-job" {
+		t.Fatalf("interrupted job must be republished with its ID, got %+v", task)
+	}
+	fresh := published["fresh"]
+	if fresh == nil || len(repo.created) != 1 || repo.created[0].ID != fresh.ID {
+		t.Fatalf("fresh image must get a new job, got %+v, created %v", fresh, repo.created)
+	}
+	if fresh.Bucket != "bucket" || fresh.OriginalPath != "original/fresh.png" || fresh.Format != entity.FormatPNG ||
+		fresh.PresetName != "thumbs" || fresh.PresetVersion != 2 || !fresh.Supersede || len(fresh.Operations) != 1 {
+		t.Fatalf("unexpected task: %+v", fresh)
+	}
+	if _, ok := published["busy"]; ok {
+		t.Fatal("image with an active job must be skipped")
+	}
+
+	want := advance{
+		cursor:  entity.BackfillCursor{CreatedAt: created.Add(time.Second), ImageID: "interrupted"},
+		scanned: 3,
+		queued:  2,
+		skipped: 1,
+		delay:   500 * time.Millisecond,
+	}
+	if len(repo.advanced) != 1 || repo.advanced[0] != want {
+		t.Fatalf("expected advance %+v, got %+v", want, repo.advanced)
+	}
+}
+
+func TestRunBatch_CountsImagesTakenConcurrentlyAsSkipped(t *testing.T) {
+	repo := &fakeRunnerRepo{
+		backfill: newTestBackfill(),
+		candidates: []entity.BackfillCandidate{
+			{ImageID: "first"},
+			{ImageID: "second"},
+		},
+		taken: map[string]bool{"second": true},
+	}
+	producer := &fakeProducer{}
+
+	if _, err := newTestRunner(repo, producer).RunBatch(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(repo.advanced) != 1 || repo.advanced[0].queued != 1 || repo.advanced[0].skipped != 1 {
+		t.Fatalf("expected 1 queued and 1 skipped, got %+v", repo.advanced)
+	}
+}
+
+func TestRunBatch_CompletesWhenNoImagesLeft(t *testing.T) {
+	repo := &fakeRunnerRepo{backfill: newTestBackfill()}
+	producer := &fakeProducer{}
+	runner := newTestRunner(repo, producer)
+
+	ran, err := runner.RunBatch(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("expected backfill to be processed, got ran=%v err=%v", ran, err)
+	}
+	if len(repo.completed) != 1 || len(producer.batches) != 0 {
+		t.Fatalf("expected completion without publishing, got completed %v, batches %d", repo.completed, len(producer.batches))
+	}
+
+	ran, err = runner.RunBatch(context.Background())
+	if err != nil || ran {
+		t.Fatalf("expected no backfill to run, got ran=%v err=%v", ran, err)
+	}
+}
+
+func TestRunBatch_PublishFailureKeepsCursorAndBacksOff(t *testing.T) {
+	for _, tt := range []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{attempts: 0, delay: time.Second},
+		{attempts: 2, delay: 4 * time.Second},
+		{attempts: 5, delay: 5 * time.Second},
+	} {
+		backfill := newTestBackfill()
+		backfill.Attempts = tt.attempts
+		repo := &fakeRunnerRepo{
+			backfill:   backfill,
+			candidates: []entity.BackfillCandidate{{ImageID: "img"}},
+		}
+		producer := &fakeProducer{err: errors.New("broker unavailable")}
+
+		if _, err := newTestRunner(repo, producer).RunBatch(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(repo.advanced) != 0 {
+			t.Fatalf("cursor advanced after failed publish: %+v", repo.advanced)
+		}
+		if len(repo.failures) != 1 || repo.failures[0] != tt.delay {
+			t.Fatalf("attempts=%d: expected retry in %v, got %v", tt.attempts, tt.delay, repo.failures)
+		}
+		if repo.maxSeen != 3 {
+			t.Fatalf("expected maxAttempts 3, got %d", repo.maxSeen)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package deadletterservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type DeadLetterService struct {
+	repo      DeadLetterRepositoryInterface
+	imageRepo ImageRepositoryInterface
+	taskRelay TaskRelayInterface
+	logger    *zap.Logger
+}
+
+func NewDeadLetterService(
+	repo DeadLetterRepositoryInterface,
+	imageRepo ImageRepositoryInterface,
+	taskRelay TaskRelayInterface,
+	logger *zap.Logger,
+) *DeadLetterService {
+	return &DeadLetterService{
+		repo:      repo,
+		imageRepo: imageRepo,
+		taskRelay: taskRelay,
+		logger:    logger,
+	}
+}
+
+// ListDeadLetters возвращает сообщения dead-letter очереди; пустой status - все сообщения
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, status entity.DeadLetterStatus, limit, offset int) ([]entity.DeadLetter, error) {
+	return s.repo.ListDeadLetters(ctx, status, limit, offset)
+}
+
+// GetDeadLetter возвращает сообщение dead-letter очереди
+func (s *DeadLetterService) GetDeadLetter(ctx context.Context, id string) (*entity.DeadLetter, error) {
+	return s.repo.GetDeadLetter(ctx, id)
+}
+
+// ReplayDeadLetter создает по сообщению новую задачу и публикует ее через outbox.
+// Если operations не заданы, используются операции исходной задачи. Путь
+// к оригиналу и формат берутся из текущей записи об изображении
+func (s *DeadLetterService) ReplayDeadLetter(ctx context.Context, id string, operations []entity.OperationParams) (*entity.ProcessingTask, error) {
+	letter, err := s.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status == entity.DeadLetterReplayed {
+		return nil, fmt.Errorf("%w: %s", entity.ErrDeadLetterReplayed, id)
+	}
+
+	// Payload может оказаться некорректным - тогда нужны операции из запроса
+	var original entity.ProcessingTask
+	decodeErr := json.Unmarshal(letter.Payload, &original)
+
+	imageID := letter.ImageID
+	if imageID == "" && decodeErr == nil {
+		imageID = original.ImageID
+	}
+	if imageID == "" {
+		return nil, fmt.Errorf("%w: image is unknown", entity.ErrDeadLetterNotReplayable)
+	}
+
+	edited := len(operations) > 0
+	if !edited {
+		if decodeErr != nil {
+			return nil, fmt.Errorf("%w: payload is not a valid task, operations are required: %v", entity.ErrDeadLetterNotReplayable, decodeErr)
+		}
+		if len(original.Operations) == 0 {
+			return nil, fmt.Errorf("%w: original task has no operations", entity.ErrDeadLetterNotReplayable)
+		}
+		operations = original.Operations
+	}
+
+	image, err := s.imageRepo.GetImageByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &entity.ProcessingTask{
+		ID:           uuid.New().String(),
+		ImageID:      image.ID,
+		OriginalPath: image.OriginalPath,
+		Bucket:       image.Bucket,
+		Operations:   operations,
+		Format:       image.Format,
+	}
+	// Пресет сохраняется, только если операции не изменены
+	if !edited {
+		task.PresetName = original.PresetName
+		task.PresetVersion = original.PresetVersion
+	}
+
+	if err := s.repo.ReplayDeadLetter(ctx, id, task); err != nil {
+		s.logger.Error("Failed to replay dead letter", zap.Error(err), zap.String("deadLetterId", id))
+		return nil, err
+	}
+	s.taskRelay.Notify()
+
+	s.logger.Info("Dead letter replayed",
+		zap.String("deadLetterId", id),
+		zap.String("taskId", task.ID),
+		zap.String("imageId", task.ImageID),
+		zap.Bool("editedOperations", edited),
+	)
+
+	return task, nil
+}
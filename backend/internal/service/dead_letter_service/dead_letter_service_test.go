@@ -0,0 +1,136 @@
+package deadletterservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type fakeDeadLetterRepo struct {
+	DeadLetterRepositoryInterface
+	letters  map[string]*entity.DeadLetter
+	replayed []*entity.ProcessingTask
+}
+
+func (r *fakeDeadLetterRepo) GetDeadLetter(ctx context.Context, id string) (*entity.DeadLetter, error) {
+	letter, ok := r.letters[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", entity.ErrDeadLetterNotFound, id)
+	}
+	return letter, nil
+}
+
+func (r *fakeDeadLetterRepo) ReplayDeadLetter(ctx context.Context, id string, task *entity.ProcessingTask) error {
+	r.letters[id].Status = entity.DeadLetterReplayed
+	r.replayed = append(r.replayed, task)
+	return nil
+}
+
+type fakeImageRepo struct{}
+
+func (fakeImageRepo) GetImageByID(ctx context.Context, imageID string) (*entity.Image, error) {
+	if imageID != "img" {
+		return nil, fmt.Errorf("%w: %s", entity.ErrImageNotFound, imageID)
+	}
+	return &entity.Image{ID: "img", OriginalPath: "originals/img/photo.png", Bucket: "images", Format: entity.FormatPNG}, nil
+}
+
+type fakeRelay struct {
+	notified int
+}
+
+func (r *fakeRelay) Notify() {
+	r.notified++
+}
+
+func newTestService(letters ...*entity.DeadLetter) (*DeadLetterService, *fakeDeadLetterRepo, *fakeRelay) {
+	repo := &fakeDeadLetterRepo{letters: make(map[string]*entity.DeadLetter)}
+	for _, letter := range letters {
+		repo.letters[letter.ID] = letter
+	}
+	relay := &fakeRelay{}
+	return NewDeadLetterService(repo, fakeImageRepo{}, relay, zap.NewNop()), repo, relay
+}
+
+func taskPayload(t *testing.T, task entity.ProcessingTask) []byte {
+	t.Helper()
+	payload, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestReplayDeadLetter_UsesOriginalOperations(t *testing.T) {
+	payload := taskPayload(t, entity.ProcessingTask{
+		ID:            "old",
+		ImageID:       "img",
+		Operations:    []entity.OperationParams{{Type: entity.OpThumbnail}},
+		PresetName:    "avatar",
+		PresetVersion: 3,
+	})
+	service, repo, relay := newTestService(&entity.DeadLetter{ID: "dl", Payload: payload, Status: entity.DeadLetterPending})
+
+	task, err := service.ReplayDeadLetter(context.Background(), "dl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.ID == "old" || task.ImageID != "img" || task.OriginalPath != "originals/img/photo.png" {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+	if len(task.Operations) != 1 || task.PresetName != "avatar" || task.PresetVersion != 3 {
+		t.Fatalf("expected original operations and preset, got %+v", task)
+	}
+	if len(repo.replayed) != 1 || relay.notified != 1 {
+		t.Fatalf("expected task to be queued and relay notified")
+	}
+
+	// Повторный replay того же сообщения запрещен
+	if _, err := service.ReplayDeadLetter(context.Background(), "dl", nil); !errors.Is(err, entity.ErrDeadLetterReplayed) {
+		t.Fatalf("expected ErrDeadLetterReplayed, got %v", err)
+	}
+}
+
+func TestReplayDeadLetter_EditedOperations(t *testing.T) {
+	payload := taskPayload(t, entity.ProcessingTask{
+		ImageID:    "img",
+		Operations: []entity.OperationParams{{Type: entity.OpThumbnail}},
+		PresetName: "avatar",
+	})
+	service, _, _ := newTestService(&entity.DeadLetter{ID: "dl", Payload: payload, Status: entity.DeadLetterPending})
+
+	operations := []entity.OperationParams{{Type: entity.OpResize}, {Type: entity.OpWatermark}}
+	task, err := service.ReplayDeadLetter(context.Background(), "dl", operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Operations) != 2 || task.PresetName != "" {
+		t.Fatalf("expected edited operations without preset, got %+v", task)
+	}
+}
+
+func TestReplayDeadLetter_PoisonMessage(t *testing.T) {
+	poison := &entity.DeadLetter{ID: "dl", ImageID: "img", Payload: []byte("{not json"), Status: entity.DeadLetterPending}
+	unknown := &entity.DeadLetter{ID: "unknown", Payload: []byte("{not json"), Status: entity.DeadLetterPending}
+	service, _, _ := newTestService(poison, unknown)
+
+	if _, err := service.ReplayDeadLetter(context.Background(), "dl", nil); !errors.Is(err, entity.ErrDeadLetterNotReplayable) {
+		t.Fatalf("expected ErrDeadLetterNotReplayable without operations, got %v", err)
+	}
+
+	// Изображение известно из заголовков, поэтому достаточно передать операции
+	operations := []entity.OperationParams{{Type: entity.OpThumbnail}}
+	if _, err := service.ReplayDeadLetter(context.Background(), "dl", operations); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := service.ReplayDeadLetter(context.Background(), "unknown", operations); !errors.Is(err, entity.ErrDeadLetterNotReplayable) {
+		t.Fatalf("expected ErrDeadLetterNotReplayable for unknown image, got %v", err)
+	}
+}
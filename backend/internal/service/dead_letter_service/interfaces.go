@@ -0,0 +1,26 @@
+package deadletterservice
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+// DeadLetterRepositoryInterface определяет интерфейс репозитория dead-letter очереди
+type DeadLetterRepositoryInterface interface {
+	GetDeadLetter(ctx context.Context, id string) (*entity.DeadLetter, error)
+	ListDeadLetters(ctx context.Context, status entity.DeadLetterStatus, limit, offset int) ([]entity.DeadLetter, error)
+	// ReplayDeadLetter атомарно отмечает сообщение и создает задачу с записью outbox
+	ReplayDeadLetter(ctx context.Context, id string, task *entity.ProcessingTask) error
+}
+
+// ImageRepositoryInterface определяет методы репозитория изображений,
+// нужные для восстановления задачи
+type ImageRepositoryInterface interface {
+	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
+}
+
+// TaskRelayInterface публикует задачи из outbox в брокер
+type TaskRelayInterface interface {
+	// Notify сообщает о новой записи в outbox, чтобы не ждать следующего опроса
+	Notify()
+}
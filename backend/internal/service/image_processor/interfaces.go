@@ -1,13 +1,17 @@
 package image_processor
 
 import (
+	"context"
+	"image"
 	"imageprocessor/backend/internal/domain/entity"
 )
 
 // Operation определяет интерфейс для операции обработки изображения
 type Operation interface {
-	// Execute выполняет операцию над изображением
-	Execute(imageData []byte, params map[string]interface{}) ([]byte, error)
+	// Execute выполняет операцию над декодированным изображением.
+	// Кодирование результата выполняет процессор, а не операция.
+	// Операция прерывается с ошибкой контекста, если ctx отменен
+	Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error)
 
 	// GetOperationType возвращает тип операции
 	GetOperationType() entity.OperationType
@@ -15,3 +19,8 @@ type Operation interface {
 	// Validate проверяет параметры операции
 	Validate(params map[string]interface{}) error
 }
+
+// OverlayLoader загружает изображения для наложения водяных знаков
+type OverlayLoader interface {
+	LoadOverlay(ctx context.Context, source entity.OverlaySource) (image.Image, error)
+}
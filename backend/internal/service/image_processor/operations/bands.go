@@ -0,0 +1,42 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"image/draw"
+)
+
+// bandHeight - сколько строк обрабатывается между проверками отмены контекста
+const bandHeight = 64
+
+// Попиксельные циклы операций (копирование, оттенки серого, отражение,
+// наложение слоя) идут полосами строк и проверяют отмену перед каждой полосой.
+// Вызовы imaging (Resize, Fit, Fill, Crop, Rotate) не прерываются: отмена
+// проверяется до и после них, а их время ограничено лимитами размеров
+// изображения и сроком попытки worker.processingTimeout
+
+// forEachBand вызывает fn для полос строк [y0, y1) в диапазоне [minY, maxY),
+// проверяя отмену контекста перед каждой полосой
+func forEachBand(ctx context.Context, minY, maxY int, fn func(y0, y1 int)) error {
+	for y0 := minY; y0 < maxY; y0 += bandHeight {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fn(y0, min(y0+bandHeight, maxY))
+	}
+	return nil
+}
+
+// toNRGBA копирует изображение в новый NRGBA с началом координат в (0, 0)
+func toNRGBA(ctx context.Context, img image.Image) (*image.NRGBA, error) {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	err := forEachBand(ctx, 0, bounds.Dy(), func(y0, y1 int) {
+		band := image.Rect(0, y0, bounds.Dx(), y1)
+		draw.Draw(dst, band, img, bounds.Min.Add(image.Pt(0, y0)), draw.Src)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
@@ -0,0 +1,135 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// checksContext отменяется после заданного числа проверок Err: так проверяется,
+// что операция замечает отмену посередине изображения, а не только на входе
+type checksContext struct {
+	context.Context
+	checks int
+	left   int
+}
+
+func newChecksContext(left int) *checksContext {
+	return &checksContext{Context: context.Background(), left: left}
+}
+
+func (c *checksContext) Err() error {
+	c.checks++
+	if c.checks > c.left {
+		return context.Canceled
+	}
+	return nil
+}
+
+// noiseImage возвращает изображение со случайными пикселями и ненулевым началом координат
+func noiseImage(width, height int) image.Image {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width+3, height+2))
+	rng.Read(img.Pix)
+	return img.SubImage(image.Rect(3, 2, width+3, height+2))
+}
+
+func TestBandedOperations_MatchImaging(t *testing.T) {
+	src := noiseImage(37, 3*bandHeight+5)
+
+	tests := []struct {
+		name string
+		op   func() (image.Image, error)
+		want *image.NRGBA
+	}{
+		{"grayscale", func() (image.Image, error) {
+			return NewGrayscaleOperation().Execute(context.Background(), src, nil)
+		}, imaging.Grayscale(src)},
+		{"flip horizontal", func() (image.Image, error) {
+			return NewFlipOperation().Execute(context.Background(), src, map[string]interface{}{"mode": "horizontal"})
+		}, imaging.FlipH(src)},
+		{"flip vertical", func() (image.Image, error) {
+			return NewFlipOperation().Execute(context.Background(), src, map[string]interface{}{"mode": "vertical"})
+		}, imaging.FlipV(src)},
+		{"flip both", func() (image.Image, error) {
+			return NewFlipOperation().Execute(context.Background(), src, map[string]interface{}{"mode": "both"})
+		}, imaging.Rotate180(src)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if err != nil {
+				t.Fatal(err)
+			}
+			nrgba, ok := got.(*image.NRGBA)
+			if !ok || nrgba.Bounds() != tt.want.Bounds() || !bytes.Equal(nrgba.Pix, tt.want.Pix) {
+				t.Fatal("result differs from imaging")
+			}
+		})
+	}
+}
+
+func TestBandedOperations_StopOnCancel(t *testing.T) {
+	src := noiseImage(16, 4*bandHeight)
+	layer := imaging.New(16, 4*bandHeight, color.White)
+	layout := overlayLayout{position: "center", opacity: 1}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"grayscale", func(ctx context.Context) error {
+			_, err := NewGrayscaleOperation().Execute(ctx, src, nil)
+			return err
+		}},
+		{"flip", func(ctx context.Context) error {
+			_, err := NewFlipOperation().Execute(ctx, src, map[string]interface{}{"mode": "both"})
+			return err
+		}},
+		{"composite", func(ctx context.Context) error {
+			_, err := compositeLayer(ctx, src, layer, layout)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Первые две полосы копирования проходят, отмена видна на третьей
+			ctx := newChecksContext(2)
+			if err := tt.run(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			if ctx.checks != 3 {
+				t.Fatalf("expected to stop at the first cancelled check, got %d checks", ctx.checks)
+			}
+		})
+	}
+}
+
+func TestCompositeLayer_SingleLayerInBands(t *testing.T) {
+	base := imaging.New(10, 3*bandHeight, color.Black)
+	layer := imaging.New(4, 2*bandHeight+7, color.White)
+
+	dst, err := compositeLayer(context.Background(), base, layer, overlayLayout{position: "top-left", opacity: 1, margin: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			inside := x >= 1 && x < 5 && y >= 1 && y < 2*bandHeight+8
+			want := color.NRGBA{A: 255}
+			if inside {
+				want = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			if got := dst.NRGBAAt(x, y); got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+)
+
+// DecodeImage декодирует изображение из байтов
+func DecodeImage(data []byte) (image.Image, entity.ImageFormat, error) {
+	reader := bytes.NewReader(data)
+
+	// Сначала определяем формат
+	_, formatStr, err := image.DecodeConfig(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image config: %w", err)
+	}
+
+	// Сбрасываем reader
+	_, err = reader.Seek(0, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to reset reader: %w", err)
+	}
+
+	// Декодируем изображение
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var format entity.ImageFormat
+	switch formatStr {
+	case "jpeg":
+		format = entity.FormatJPEG
+	case "png":
+		format = entity.FormatPNG
+	case "gif":
+		format = entity.FormatGIF
+	case "webp":
+		format = entity.FormatWebP
+	default:
+		format = entity.ImageFormat(formatStr)
+	}
+
+	return img, format, nil
+}
+
+// EncodeImage кодирует изображение в байты
+func EncodeImage(img image.Image, format entity.ImageFormat, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case entity.FormatJPEG, entity.FormatJPG:
+		if quality <= 0 || quality > 100 {
+			quality = entity.DefaultJPEGQuality
+		}
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+	case entity.FormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.DefaultCompression}
+		err := encoder.Encode(&buf, img)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PNG: %w", err)
+		}
+	case entity.FormatGIF:
+		err := gif.Encode(&buf, img, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode GIF: %w", err)
+		}
+	case entity.FormatWebP:
+		return nil, fmt.Errorf("encoding to %s is not supported", format)
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// IsEncodableFormat проверяет, умеет ли процессор кодировать изображение в формат
+func IsEncodableFormat(format entity.ImageFormat) bool {
+	switch format {
+	case entity.FormatJPEG, entity.FormatJPG, entity.FormatPNG, entity.FormatGIF:
+		return true
+	default:
+		return false
+	}
+}
+
+// OutputFormatParam - служебный параметр, через который процессор сообщает операции
+// формат результата до ее выполнения. Из запроса не принимается
+const OutputFormatParam = "_output_format"
+
+// OutputFormat определяет формат результата: явно заданный в параметрах
+// или формат исходника, если его можно закодировать
+func OutputFormat(params map[string]interface{}, sourceFormat entity.ImageFormat) entity.ImageFormat {
+	if format := getStringParam(params, entity.ParamFormat, ""); format != "" {
+		return normalizeFormat(entity.ImageFormat(strings.ToLower(format)))
+	}
+	if IsEncodableFormat(sourceFormat) {
+		return normalizeFormat(sourceFormat)
+	}
+	// Форматы без кодировщика (например, WebP) сохраняем без потерь
+	return entity.FormatPNG
+}
+
+// ValidateOutputParams проверяет общие параметры вывода: format и quality
+func ValidateOutputParams(params map[string]interface{}) error {
+	if format, exists := params[entity.ParamFormat]; exists {
+		f, ok := format.(string)
+		if !ok {
+			return fmt.Errorf("format must be a string")
+		}
+		if !IsEncodableFormat(entity.ImageFormat(strings.ToLower(f))) {
+			return fmt.Errorf("unsupported output format: %s", f)
+		}
+	}
+
+	if quality, exists := params[entity.ParamQuality]; exists {
+		q, ok := getNumber(quality)
+		if !ok {
+			return fmt.Errorf("quality must be a number")
+		}
+		if q < 1 || q > 100 {
+			return fmt.Errorf("quality must be between 1 and 100")
+		}
+	}
+
+	return nil
+}
+
+func normalizeFormat(format entity.ImageFormat) entity.ImageFormat {
+	if format == entity.FormatJPG {
+		return entity.FormatJPEG
+	}
+	return format
+}
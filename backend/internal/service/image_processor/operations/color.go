@@ -0,0 +1,83 @@
+package operations
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// parseColor разбирает цвет в формате #RGB, #RRGGBB, #RRGGBBAA, rgb(r, g, b),
+// rgba(r, g, b, a) с альфой 0-1 или "transparent"
+func parseColor(s string) (color.NRGBA, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	if value == "transparent" {
+		return color.NRGBA{}, nil
+	}
+
+	if strings.HasPrefix(value, "rgb") {
+		return parseRGBFunc(s, value)
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+
+	return color.NRGBA{
+		R: uint8(n >> 24),
+		G: uint8(n >> 16),
+		B: uint8(n >> 8),
+		A: uint8(n),
+	}, nil
+}
+
+// parseRGBFunc разбирает rgb(r, g, b) и rgba(r, g, b, a)
+func parseRGBFunc(original, value string) (color.NRGBA, error) {
+	var args string
+	var withAlpha bool
+	switch {
+	case strings.HasPrefix(value, "rgba(") && strings.HasSuffix(value, ")"):
+		args, withAlpha = value[len("rgba("):len(value)-1], true
+	case strings.HasPrefix(value, "rgb(") && strings.HasSuffix(value, ")"):
+		args = value[len("rgb(") : len(value)-1]
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", original)
+	}
+
+	parts := strings.Split(args, ",")
+	if (withAlpha && len(parts) != 4) || (!withAlpha && len(parts) != 3) {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", original)
+	}
+
+	var channels [3]uint8
+	for i := 0; i < 3; i++ {
+		n, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+		if err != nil || n < 0 || n > 255 {
+			return color.NRGBA{}, fmt.Errorf("invalid color: %s", original)
+		}
+		channels[i] = uint8(n)
+	}
+
+	alpha := uint8(255)
+	if withAlpha {
+		a, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
+		if err != nil || a < 0 || a > 1 {
+			return color.NRGBA{}, fmt.Errorf("invalid color: %s", original)
+		}
+		alpha = uint8(a*255 + 0.5)
+	}
+
+	return color.NRGBA{R: channels[0], G: channels[1], B: channels[2], A: alpha}, nil
+}
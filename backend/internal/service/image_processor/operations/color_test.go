@@ -0,0 +1,56 @@
+package operations
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{value: "#f80", want: color.NRGBA{R: 0xff, G: 0x88, B: 0x00, A: 0xff}},
+		{value: "#FFF", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{value: "#1a2b3c", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{value: "1A2B3C", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{value: "#1a2b3c80", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0x80}},
+		{value: "#00000000", want: color.NRGBA{}},
+		{value: "  #ffffff  ", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{value: "transparent", want: color.NRGBA{}},
+		{value: "rgb(10, 20, 30)", want: color.NRGBA{R: 10, G: 20, B: 30, A: 0xff}},
+		{value: "RGBA(10,20,30,0.5)", want: color.NRGBA{R: 10, G: 20, B: 30, A: 128}},
+		{value: "", wantErr: true},
+		{value: "#", wantErr: true},
+		{value: "#ff", wantErr: true},
+		{value: "#ffff", wantErr: true},
+		{value: "#fffff", wantErr: true},
+		{value: "#fffffff", wantErr: true},
+		{value: "#fffffffff", wantErr: true},
+		{value: "#ggg", wantErr: true},
+		{value: "#12345z", wantErr: true},
+		{value: "white", wantErr: true},
+		{value: "rgb(256, 0, 0)", wantErr: true},
+		{value: "rgb(1, 2)", wantErr: true},
+		{value: "rgba(1, 2, 3)", wantErr: true},
+		{value: "rgba(1, 2, 3, 1.5)", wantErr: true},
+		{value: "rgb(1, 2, 3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseColor(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseColor(%q) unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseColor(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
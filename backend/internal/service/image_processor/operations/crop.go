@@ -0,0 +1,150 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+
+	"github.com/disintegration/imaging"
+)
+
+type CropOperation struct {
+	cfg config.ProcessingConfig
+}
+
+func NewCropOperation(cfg config.ProcessingConfig) *CropOperation {
+	return &CropOperation{cfg: cfg}
+}
+
+func (o *CropOperation) GetOperationType() entity.OperationType {
+	return entity.OpCrop
+}
+
+func (o *CropOperation) Validate(params map[string]interface{}) error {
+	limits := map[string]int{
+		entity.ParamWidth:  o.cfg.MaxImageWidth,
+		entity.ParamHeight: o.cfg.MaxImageHeight,
+	}
+	for _, key := range []string{entity.ParamWidth, entity.ParamHeight} {
+		val, exists := params[key]
+		if !exists {
+			return fmt.Errorf("%s parameter is required", key)
+		}
+		n, ok := getNumber(val)
+		if !ok {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		if n <= 0 {
+			return fmt.Errorf("%s must be positive", key)
+		}
+		if n > float64(limits[key]) {
+			return fmt.Errorf("%s must not exceed %d pixels", key, limits[key])
+		}
+	}
+
+	mode, err := cropMode(params)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case entity.CropModeRectangle:
+		for _, key := range []string{entity.ParamX, entity.ParamY} {
+			val, exists := params[key]
+			if !exists {
+				continue
+			}
+			n, ok := getNumber(val)
+			if !ok {
+				return fmt.Errorf("%s must be a number", key)
+			}
+			if n < 0 {
+				return fmt.Errorf("%s must not be negative", key)
+			}
+		}
+	case entity.CropModeGravity:
+		if gravity, exists := params[entity.ParamGravity]; exists {
+			g, ok := gravity.(string)
+			if !ok {
+				return fmt.Errorf("gravity must be a string")
+			}
+			if _, ok := cropAnchors[entity.CropGravity(g)]; !ok {
+				return fmt.Errorf("invalid gravity: %s", g)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *CropOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Получаем параметры
+	width := getIntParam(params, entity.ParamWidth, 0)
+	height := getIntParam(params, entity.ParamHeight, 0)
+	mode, err := cropMode(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var cropped *image.NRGBA
+
+	if mode == entity.CropModeRectangle {
+		// Вырезаем прямоугольник с заданным смещением
+		bounds := img.Bounds()
+		x := getIntParam(params, entity.ParamX, 0)
+		y := getIntParam(params, entity.ParamY, 0)
+		rect := image.Rect(x, y, x+width, y+height).Add(bounds.Min)
+		if !rect.Overlaps(bounds) {
+			return nil, fmt.Errorf("crop rectangle is outside of image bounds %dx%d", bounds.Dx(), bounds.Dy())
+		}
+		cropped = imaging.Crop(img, rect)
+	} else {
+		// Вырезаем область заданного размера относительно точки привязки
+		gravity := getStringParam(params, entity.ParamGravity, string(entity.GravityCenter))
+		cropped = imaging.CropAnchor(img, width, height, cropAnchors[entity.CropGravity(gravity)])
+	}
+
+	return cropped, nil
+}
+
+// cropAnchors сопоставляет gravity с точками привязки imaging
+var cropAnchors = map[entity.CropGravity]imaging.Anchor{
+	entity.GravityTopLeft:     imaging.TopLeft,
+	entity.GravityTop:         imaging.Top,
+	entity.GravityTopRight:    imaging.TopRight,
+	entity.GravityLeft:        imaging.Left,
+	entity.GravityCenter:      imaging.Center,
+	entity.GravityRight:       imaging.Right,
+	entity.GravityBottomLeft:  imaging.BottomLeft,
+	entity.GravityBottom:      imaging.Bottom,
+	entity.GravityBottomRight: imaging.BottomRight,
+}
+
+// cropMode определяет режим обрезки: явно заданный или по наличию координат
+func cropMode(params map[string]interface{}) (entity.CropMode, error) {
+	if mode, exists := params[entity.ParamMode]; exists {
+		m, ok := mode.(string)
+		if !ok {
+			return "", fmt.Errorf("mode must be a string")
+		}
+		switch entity.CropMode(m) {
+		case entity.CropModeRectangle, entity.CropModeGravity:
+			return entity.CropMode(m), nil
+		default:
+			return "", fmt.Errorf("invalid crop mode: %s", m)
+		}
+	}
+
+	_, hasX := params[entity.ParamX]
+	_, hasY := params[entity.ParamY]
+	if hasX || hasY {
+		return entity.CropModeRectangle, nil
+	}
+	return entity.CropModeGravity, nil
+}
@@ -0,0 +1,135 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"imageprocessor/backend/internal/config"
+	"strings"
+	"testing"
+)
+
+// gradientImage возвращает изображение, в котором R пикселя равен x, а G - y,
+// чтобы по цвету можно было определить, откуда пиксель взят
+func gradientImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func TestCropOperation_Validate(t *testing.T) {
+	op := NewCropOperation(config.ProcessingConfig{MaxImageWidth: 1000, MaxImageHeight: 500})
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"gravity by default", map[string]interface{}{"width": 100, "height": 50}, ""},
+		{"rectangle by coordinates", map[string]interface{}{"width": 100.0, "height": 50.0, "x": 10.0, "y": 0}, ""},
+		{"explicit gravity", map[string]interface{}{"width": 100, "height": 50, "mode": "gravity", "gravity": "bottom-right"}, ""},
+		{"missing height", map[string]interface{}{"width": 100}, "height parameter is required"},
+		{"width not a number", map[string]interface{}{"width": "100", "height": 50}, "width must be a number"},
+		{"zero width", map[string]interface{}{"width": 0, "height": 50}, "width must be positive"},
+		{"width over limit", map[string]interface{}{"width": 1001, "height": 50}, "width must not exceed 1000 pixels"},
+		{"height over limit", map[string]interface{}{"width": 100, "height": 501}, "height must not exceed 500 pixels"},
+		{"negative x", map[string]interface{}{"width": 100, "height": 50, "x": -1}, "x must not be negative"},
+		{"y not a number", map[string]interface{}{"width": 100, "height": 50, "mode": "rectangle", "y": "top"}, "y must be a number"},
+		{"unknown mode", map[string]interface{}{"width": 100, "height": 50, "mode": "smart"}, "invalid crop mode: smart"},
+		{"unknown gravity", map[string]interface{}{"width": 100, "height": 50, "gravity": "middle"}, "invalid gravity: middle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.Validate(tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCropOperation_Execute(t *testing.T) {
+	op := NewCropOperation(config.ProcessingConfig{MaxImageWidth: 1000, MaxImageHeight: 1000})
+	src := gradientImage(40, 30)
+
+	tests := []struct {
+		name       string
+		src        image.Image
+		params     map[string]interface{}
+		wantSize   image.Point
+		wantOrigin color.NRGBA
+		wantErr    bool
+	}{
+		{
+			name:       "rectangle inside image",
+			src:        src,
+			params:     map[string]interface{}{"width": 10, "height": 5, "x": 3, "y": 4},
+			wantSize:   image.Pt(10, 5),
+			wantOrigin: color.NRGBA{R: 3, G: 4, B: 100, A: 255},
+		},
+		{
+			name:       "rectangle clipped by image bounds",
+			src:        src,
+			params:     map[string]interface{}{"width": 100, "height": 100, "x": 30, "y": 25},
+			wantSize:   image.Pt(10, 5),
+			wantOrigin: color.NRGBA{R: 30, G: 25, B: 100, A: 255},
+		},
+		{
+			name:       "coordinates relative to image bounds",
+			src:        src.SubImage(image.Rect(5, 5, 40, 30)),
+			params:     map[string]interface{}{"width": 2, "height": 2, "x": 1, "y": 1},
+			wantSize:   image.Pt(2, 2),
+			wantOrigin: color.NRGBA{R: 6, G: 6, B: 100, A: 255},
+		},
+		{
+			name:    "rectangle outside image",
+			src:     src,
+			params:  map[string]interface{}{"width": 10, "height": 10, "x": 40, "y": 0},
+			wantErr: true,
+		},
+		{
+			name:    "rectangle below image",
+			src:     src,
+			params:  map[string]interface{}{"width": 10, "height": 10, "mode": "rectangle", "y": 500},
+			wantErr: true,
+		},
+		{
+			name:       "gravity bottom-right",
+			src:        src,
+			params:     map[string]interface{}{"width": 10, "height": 10, "gravity": "bottom-right"},
+			wantSize:   image.Pt(10, 10),
+			wantOrigin: color.NRGBA{R: 30, G: 20, B: 100, A: 255},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := op.Execute(context.Background(), tt.src, tt.params)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "outside of image bounds") {
+					t.Fatalf("expected out of bounds error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := result.Bounds().Size(); size != tt.wantSize {
+				t.Fatalf("expected size %v, got %v", tt.wantSize, size)
+			}
+			bounds := result.Bounds()
+			if got := color.NRGBAModel.Convert(result.At(bounds.Min.X, bounds.Min.Y)); got != tt.wantOrigin {
+				t.Fatalf("expected origin pixel %v, got %v", tt.wantOrigin, got)
+			}
+		})
+	}
+}
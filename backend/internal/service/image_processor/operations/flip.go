@@ -0,0 +1,85 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+type FlipOperation struct{}
+
+func NewFlipOperation() *FlipOperation {
+	return &FlipOperation{}
+}
+
+func (o *FlipOperation) GetOperationType() entity.OperationType {
+	return entity.OpFlip
+}
+
+func (o *FlipOperation) Validate(params map[string]interface{}) error {
+	if mode, exists := params[entity.ParamMode]; exists {
+		m, ok := mode.(string)
+		if !ok {
+			return fmt.Errorf("mode must be a string")
+		}
+		switch entity.FlipMode(m) {
+		case entity.FlipHorizontal, entity.FlipVertical, entity.FlipBoth:
+		default:
+			return fmt.Errorf("invalid flip mode: %s", m)
+		}
+	}
+	return nil
+}
+
+func (o *FlipOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	mode := entity.FlipMode(getStringParam(params, entity.ParamMode, string(entity.FlipHorizontal)))
+
+	dst, err := toNRGBA(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	width := dst.Bounds().Dx()
+	height := dst.Bounds().Dy()
+	rowSize := width * 4
+
+	// Отражение по обеим осям эквивалентно повороту на 180 градусов
+	if mode != entity.FlipVertical {
+		err := forEachBand(ctx, 0, height, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				reversePixels(dst.Pix[y*dst.Stride : y*dst.Stride+rowSize])
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mode == entity.FlipVertical || mode == entity.FlipBoth {
+		// Строки верхней половины меняются местами с зеркальными строками нижней
+		tmp := make([]byte, rowSize)
+		err := forEachBand(ctx, 0, height/2, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				top := dst.Pix[y*dst.Stride : y*dst.Stride+rowSize]
+				bottom := dst.Pix[(height-1-y)*dst.Stride : (height-1-y)*dst.Stride+rowSize]
+				copy(tmp, top)
+				copy(top, bottom)
+				copy(bottom, tmp)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
+
+// reversePixels меняет порядок 4-байтовых пикселей строки на обратный
+func reversePixels(row []byte) {
+	for i, j := 0, len(row)-4; i < j; i, j = i+4, j-4 {
+		row[i], row[i+1], row[i+2], row[i+3], row[j], row[j+1], row[j+2], row[j+3] =
+			row[j], row[j+1], row[j+2], row[j+3], row[i], row[i+1], row[i+2], row[i+3]
+	}
+}
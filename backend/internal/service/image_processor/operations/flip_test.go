@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"context"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestFlipOperation_Validate(t *testing.T) {
+	op := NewFlipOperation()
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"default mode", map[string]interface{}{}, ""},
+		{"horizontal", map[string]interface{}{"mode": "horizontal"}, ""},
+		{"vertical", map[string]interface{}{"mode": "vertical"}, ""},
+		{"both", map[string]interface{}{"mode": "both"}, ""},
+		{"unknown direction", map[string]interface{}{"mode": "diagonal"}, "invalid flip mode: diagonal"},
+		{"case sensitive", map[string]interface{}{"mode": "Horizontal"}, "invalid flip mode: Horizontal"},
+		{"empty direction", map[string]interface{}{"mode": ""}, "invalid flip mode"},
+		{"mode not a string", map[string]interface{}{"mode": 1}, "mode must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.Validate(tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFlipOperation_Execute(t *testing.T) {
+	op := NewFlipOperation()
+	src := gradientImage(10, 6)
+
+	tests := []struct {
+		mode string
+		// wantOrigin - ожидаемый цвет левого верхнего угла результата
+		wantOrigin color.NRGBA
+	}{
+		{"", color.NRGBA{R: 9, G: 0, B: 100, A: 255}},
+		{"horizontal", color.NRGBA{R: 9, G: 0, B: 100, A: 255}},
+		{"vertical", color.NRGBA{R: 0, G: 5, B: 100, A: 255}},
+		{"both", color.NRGBA{R: 9, G: 5, B: 100, A: 255}},
+	}
+	for _, tt := range tests {
+		params := map[string]interface{}{}
+		if tt.mode != "" {
+			params["mode"] = tt.mode
+		}
+
+		result, err := op.Execute(context.Background(), src, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Bounds() != src.Bounds() {
+			t.Fatalf("mode %q: flip must keep bounds, got %v", tt.mode, result.Bounds())
+		}
+		if got := color.NRGBAModel.Convert(result.At(0, 0)); got != tt.wantOrigin {
+			t.Errorf("mode %q: expected origin pixel %v, got %v", tt.mode, tt.wantOrigin, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// DefaultFontName - шрифт водяного знака по умолчанию
+const DefaultFontName = "goregular"
+
+// FontRegistry хранит шрифты, доступные водяному знаку по имени
+type FontRegistry struct {
+	fonts map[string]*opentype.Font
+}
+
+// LoadFonts регистрирует встроенные шрифты Go и файлы .ttf/.otf из каталога dir.
+// Имя шрифта - имя файла без расширения в нижнем регистре. Ошибки отдельных файлов
+// возвращаются вместе с реестром, в котором остаются все успешно загруженные шрифты
+func LoadFonts(dir string) (*FontRegistry, error) {
+	registry := &FontRegistry{fonts: make(map[string]*opentype.Font)}
+
+	builtin := map[string][]byte{
+		"goregular": goregular.TTF,
+		"gobold":    gobold.TTF,
+		"goitalic":  goitalic.TTF,
+		"gomono":    gomono.TTF,
+	}
+	for name, data := range builtin {
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse builtin font %s: %w", name, err)
+		}
+		registry.fonts[name] = f
+	}
+
+	if dir == "" {
+		return registry, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return registry, fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".ttf" && ext != ".otf") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read font %s: %w", entry.Name(), err))
+			continue
+		}
+		f, err := opentype.Parse(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse font %s: %w", entry.Name(), err))
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		registry.fonts[name] = f
+	}
+
+	return registry, errors.Join(errs...)
+}
+
+// Has проверяет, зарегистрирован ли шрифт
+func (r *FontRegistry) Has(name string) bool {
+	_, ok := r.fonts[strings.ToLower(name)]
+	return ok
+}
+
+// Names возвращает отсортированные имена шрифтов
+func (r *FontRegistry) Names() []string {
+	names := make([]string, 0, len(r.fonts))
+	for name := range r.fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewFace создает начертание шрифта заданного размера.
+// Начертание не потокобезопасно, поэтому создается на каждую операцию
+func (r *FontRegistry) NewFace(name string, size float64) (font.Face, error) {
+	f, ok := r.fonts[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown font: %s", name)
+	}
+
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
@@ -0,0 +1,73 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+func TestLoadFonts_Builtin(t *testing.T) {
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"gobold", "goitalic", "gomono", "goregular"}
+	if got := fonts.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected builtin fonts %v, got %v", want, got)
+	}
+	if !fonts.Has(DefaultFontName) || !fonts.Has("GoBold") {
+		t.Fatal("font lookup must be case insensitive")
+	}
+}
+
+func TestLoadFonts_Directory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"Custom.TTF": gomono.TTF,
+		"broken.otf": []byte("not a font"),
+		"readme.txt": []byte("ignored"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fonts, err := LoadFonts(dir)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse font broken.otf") {
+		t.Fatalf("expected parse error for broken.otf, got %v", err)
+	}
+	if fonts == nil || !fonts.Has("custom") || !fonts.Has(DefaultFontName) {
+		t.Fatal("registry must keep builtin and successfully loaded fonts")
+	}
+	if fonts.Has("broken") || fonts.Has("readme") {
+		t.Fatalf("unexpected fonts registered: %v", fonts.Names())
+	}
+
+	fonts, err = LoadFonts(filepath.Join(dir, "missing"))
+	if err == nil || fonts == nil || !fonts.Has(DefaultFontName) {
+		t.Fatalf("missing directory must return builtin fonts with an error, got %v", err)
+	}
+}
+
+func TestFontRegistry_NewFace(t *testing.T) {
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	face, err := fonts.NewFace("GoRegular", 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = face.Close()
+
+	if _, err := fonts.NewFace("comic-sans", 24); err == nil || err.Error() != "unknown font: comic-sans" {
+		t.Fatalf("expected unknown font error, got %v", err)
+	}
+}
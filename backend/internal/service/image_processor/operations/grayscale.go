@@ -0,0 +1,46 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+type GrayscaleOperation struct{}
+
+func NewGrayscaleOperation() *GrayscaleOperation {
+	return &GrayscaleOperation{}
+}
+
+func (o *GrayscaleOperation) GetOperationType() entity.OperationType {
+	return entity.OpGrayscale
+}
+
+func (o *GrayscaleOperation) Validate(params map[string]interface{}) error {
+	return nil
+}
+
+func (o *GrayscaleOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	dst, err := toNRGBA(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	// Яркость по весам ITU-R BT.601, как в imaging.Grayscale; альфа сохраняется
+	width := dst.Bounds().Dx()
+	err = forEachBand(ctx, 0, dst.Bounds().Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+			for i := 0; i < len(row); i += 4 {
+				f := 0.299*float64(row[i]) + 0.587*float64(row[i+1]) + 0.114*float64(row[i+2])
+				gray := uint8(f + 0.5)
+				row[i], row[i+1], row[i+2] = gray, gray, gray
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
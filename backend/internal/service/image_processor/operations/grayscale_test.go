@@ -0,0 +1,48 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"testing"
+)
+
+func TestGrayscaleOperation_Execute(t *testing.T) {
+	op := NewGrayscaleOperation()
+	src := gradientImage(8, 8)
+	src.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 10, B: 10, A: 128})
+
+	if err := op.Validate(nil); err != nil {
+		t.Fatalf("grayscale has no parameters to reject: %v", err)
+	}
+
+	result, err := op.Execute(context.Background(), src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Bounds() != src.Bounds() {
+		t.Fatalf("grayscale must keep bounds, got %v", result.Bounds())
+	}
+
+	bounds := result.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(result.At(x, y)).(color.NRGBA)
+			if c.R != c.G || c.G != c.B {
+				t.Fatalf("pixel (%d, %d) is not gray: %v", x, y, c)
+			}
+		}
+	}
+	if alpha := color.NRGBAModel.Convert(result.At(0, 0)).(color.NRGBA).A; alpha != 128 {
+		t.Fatalf("grayscale must keep alpha, got %d", alpha)
+	}
+}
+
+func TestGrayscaleOperation_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewGrayscaleOperation().Execute(ctx, gradientImage(2, 2), nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"imageprocessor/backend/internal/domain/entity"
+
+	"github.com/disintegration/imaging"
+)
+
+// overlayLayout описывает размещение наложения на изображении
+type overlayLayout struct {
+	position string
+	opacity  float64
+	scale    float64 // ширина наложения относительно ширины изображения
+	margin   int
+	spacing  int // расстояние между копиями в режиме tiled
+	tiled    bool
+}
+
+// validateOverlayParams проверяет параметры режима наложения изображения
+func validateOverlayParams(params map[string]interface{}) error {
+	imageID, hasImageID := params[entity.ParamOverlayImageID]
+	asset, hasAsset := params[entity.ParamOverlayAsset]
+	if hasImageID == hasAsset {
+		return fmt.Errorf("exactly one of %s or %s is required", entity.ParamOverlayImageID, entity.ParamOverlayAsset)
+	}
+	if hasImageID {
+		if id, ok := imageID.(string); !ok || id == "" {
+			return fmt.Errorf("%s must be a non-empty string", entity.ParamOverlayImageID)
+		}
+	}
+	if hasAsset {
+		if name, ok := asset.(string); !ok || name == "" {
+			return fmt.Errorf("%s must be a non-empty string", entity.ParamOverlayAsset)
+		}
+	}
+
+	if scale, exists := params[entity.ParamScale]; exists {
+		s, ok := getNumber(scale)
+		if !ok {
+			return fmt.Errorf("scale must be a number")
+		}
+		if s <= 0 || s > 1 {
+			return fmt.Errorf("scale must be greater than 0 and at most 1")
+		}
+	}
+
+	return nil
+}
+
+// addImageWatermark накладывает масштабированное изображение с заданной прозрачностью
+// в одну позицию или замощением по всему изображению
+func addImageWatermark(ctx context.Context, img, overlay image.Image, layout overlayLayout) (image.Image, error) {
+	logo := scaleOverlay(overlay, img.Bounds().Size(), layout)
+	return compositeLayer(ctx, img, logo, layout)
+}
+
+// scaleOverlay приводит ширину наложения к доле ширины изображения,
+// не допуская выхода за его высоту с учетом отступов
+func scaleOverlay(overlay image.Image, base image.Point, layout overlayLayout) *image.NRGBA {
+	src := overlay.Bounds().Size()
+
+	width := float64(base.X) * layout.scale
+	height := width * float64(src.Y) / float64(src.X)
+	if maxHeight := float64(base.Y - 2*layout.margin); maxHeight > 0 && height > maxHeight {
+		width = width * maxHeight / height
+	}
+
+	w := int(width + 0.5)
+	if w < 1 {
+		w = 1
+	}
+	return imaging.Resize(overlay, w, 0, imaging.Lanczos)
+}
+
+// watermarkOrigin вычисляет левый верхний угол водяного знака размера size
+func watermarkOrigin(bounds image.Rectangle, size image.Point, position string, margin int) image.Point {
+	left := bounds.Min.X + margin
+	right := bounds.Max.X - size.X - margin
+	centerX := bounds.Min.X + (bounds.Dx()-size.X)/2
+	top := bounds.Min.Y + margin
+	bottom := bounds.Max.Y - size.Y - margin
+	centerY := bounds.Min.Y + (bounds.Dy()-size.Y)/2
+
+	switch entity.WatermarkPosition(position) {
+	case entity.WatermarkTopLeft:
+		return image.Pt(left, top)
+	case entity.WatermarkTopRight:
+		return image.Pt(right, top)
+	case entity.WatermarkTopCenter:
+		return image.Pt(centerX, top)
+	case entity.WatermarkBottomLeft:
+		return image.Pt(left, bottom)
+	case entity.WatermarkBottomCenter:
+		return image.Pt(centerX, bottom)
+	case entity.WatermarkCenter:
+		return image.Pt(centerX, centerY)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
@@ -1,21 +1,21 @@
 package operations
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 
 	"github.com/disintegration/imaging"
 )
 
-type ResizeOperation struct{}
+type ResizeOperation struct {
+	cfg config.ProcessingConfig
+}
 
-func NewResizeOperation() *ResizeOperation {
-	return &ResizeOperation{}
+func NewResizeOperation(cfg config.ProcessingConfig) *ResizeOperation {
+	return &ResizeOperation{cfg: cfg}
 }
 
 func (o *ResizeOperation) GetOperationType() entity.OperationType {
@@ -42,6 +42,9 @@ func (o *ResizeOperation) Validate(params map[string]interface{}) error {
 		if w <= 0 {
 			return fmt.Errorf("width must be positive")
 		}
+		if w > float64(o.cfg.MaxImageWidth) {
+			return fmt.Errorf("width must not exceed %d pixels", o.cfg.MaxImageWidth)
+		}
 	}
 
 	if hasHeight {
@@ -56,16 +59,17 @@ func (o *ResizeOperation) Validate(params map[string]interface{}) error {
 		if h <= 0 {
 			return fmt.Errorf("height must be positive")
 		}
+		if h > float64(o.cfg.MaxImageHeight) {
+			return fmt.Errorf("height must not exceed %d pixels", o.cfg.MaxImageHeight)
+		}
 	}
 
 	return nil
 }
 
-func (o *ResizeOperation) Execute(imageData []byte, params map[string]interface{}) ([]byte, error) {
-	// Декодируем изображение
-	img, format, err := DecodeImage(imageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+func (o *ResizeOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Получаем параметры
@@ -79,6 +83,17 @@ func (o *ResizeOperation) Execute(imageData []byte, params map[string]interface{
 		return nil, fmt.Errorf("width or height must be specified")
 	}
 
+	// Пропорциональная сторона тоже не должна превышать лимиты
+	if keepAspect && (width == 0 || height == 0) {
+		src := img.Bounds().Size()
+		if width > 0 && src.X > 0 && width*src.Y/src.X > o.cfg.MaxImageHeight {
+			return nil, fmt.Errorf("resulting height exceeds %d pixels", o.cfg.MaxImageHeight)
+		}
+		if height > 0 && src.Y > 0 && height*src.X/src.Y > o.cfg.MaxImageWidth {
+			return nil, fmt.Errorf("resulting width exceeds %d pixels", o.cfg.MaxImageWidth)
+		}
+	}
+
 	if keepAspect {
 		// Сохраняем пропорции
 		if width > 0 && height > 0 {
@@ -102,9 +117,7 @@ func (o *ResizeOperation) Execute(imageData []byte, params map[string]interface{
 		resized = imaging.Resize(img, width, height, imaging.Lanczos)
 	}
 
-	// Кодируем обратно в байты
-	quality := getIntParam(params, "quality", entity.DefaultJPEGQuality)
-	return EncodeImage(resized, format, quality)
+	return resized, nil
 }
 
 // Вспомогательные функции для извлечения параметров
@@ -122,6 +135,21 @@ func getIntParam(params map[string]interface{}, key string, defaultValue int) in
 	return defaultValue
 }
 
+// getNumber приводит числовое значение параметра к float64
+func getNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
 func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
 	if val, exists := params[key]; exists {
 		if b, ok := val.(bool); ok {
@@ -153,73 +181,3 @@ func getFloat64Param(params map[string]interface{}, key string, defaultValue flo
 	}
 	return defaultValue
 }
-
-// DecodeImage декодирует изображение из байтов
-func DecodeImage(data []byte) (image.Image, entity.ImageFormat, error) {
-	reader := bytes.NewReader(data)
-
-	// Сначала определяем формат
-	_, formatStr, err := image.DecodeConfig(reader)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode image config: %w", err)
-	}
-
-	// Сбрасываем reader
-	_, err = reader.Seek(0, 0)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to reset reader: %w", err)
-	}
-
-	// Декодируем изображение
-	img, _, err := image.Decode(reader)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode image: %w", err)
-	}
-
-	var format entity.ImageFormat
-	switch formatStr {
-	case "jpeg":
-		format = entity.FormatJPEG
-	case "png":
-		format = entity.FormatPNG
-	case "gif":
-		format = entity.FormatGIF
-	case "webp":
-		format = entity.FormatWebP
-	default:
-		format = entity.ImageFormat(formatStr)
-	}
-
-	return img, format, nil
-}
-
-// EncodeImage кодирует изображение в байты
-func EncodeImage(img image.Image, format entity.ImageFormat, quality int) ([]byte, error) {
-	var buf bytes.Buffer
-
-	switch format {
-	case entity.FormatJPEG, entity.FormatJPG:
-		if quality <= 0 || quality > 100 {
-			quality = entity.DefaultJPEGQuality
-		}
-		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
-		}
-	case entity.FormatPNG:
-		encoder := png.Encoder{CompressionLevel: png.DefaultCompression}
-		err := encoder.Encode(&buf, img)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode PNG: %w", err)
-		}
-	case entity.FormatGIF:
-		err := gif.Encode(&buf, img, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode GIF: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported output format: %s", format)
-	}
-
-	return buf.Bytes(), nil
-}
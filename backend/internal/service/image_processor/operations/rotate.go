@@ -0,0 +1,73 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"imageprocessor/backend/internal/domain/entity"
+
+	"github.com/disintegration/imaging"
+)
+
+type RotateOperation struct{}
+
+func NewRotateOperation() *RotateOperation {
+	return &RotateOperation{}
+}
+
+func (o *RotateOperation) GetOperationType() entity.OperationType {
+	return entity.OpRotate
+}
+
+func (o *RotateOperation) Validate(params map[string]interface{}) error {
+	angle, exists := params[entity.ParamAngle]
+	if !exists {
+		return fmt.Errorf("angle parameter is required")
+	}
+	a, ok := getNumber(angle)
+	if !ok {
+		return fmt.Errorf("angle must be a number")
+	}
+	if a < -360 || a > 360 {
+		return fmt.Errorf("angle must be between -360 and 360")
+	}
+
+	if background, exists := params[entity.ParamBackground]; exists {
+		bg, ok := background.(string)
+		if !ok {
+			return fmt.Errorf("background must be a string")
+		}
+		if _, err := parseColor(bg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *RotateOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Получаем параметры
+	angle := getFloat64Param(params, entity.ParamAngle, 0)
+	background, err := parseColor(getStringParam(params, entity.ParamBackground, defaultRotateBackground(params)))
+	if err != nil {
+		return nil, err
+	}
+
+	// imaging поворачивает против часовой стрелки, API принимает угол по часовой
+	rotated := imaging.Rotate(img, -angle, background)
+
+	return rotated, nil
+}
+
+// defaultRotateBackground возвращает заливку углов по умолчанию: прозрачную,
+// если формат результата ее сохраняет, иначе белую, чтобы в JPEG не было черных углов
+func defaultRotateBackground(params map[string]interface{}) string {
+	if format, ok := params[OutputFormatParam].(entity.ImageFormat); ok && !format.SupportsAlpha() {
+		return entity.DefaultOpaqueRotateBackground
+	}
+	return entity.DefaultRotateBackground
+}
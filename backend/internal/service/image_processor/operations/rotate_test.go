@@ -0,0 +1,122 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+	"testing"
+)
+
+func TestRotateOperation_Validate(t *testing.T) {
+	op := NewRotateOperation()
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"right angle", map[string]interface{}{"angle": 90}, ""},
+		{"arbitrary angle", map[string]interface{}{"angle": 37.5}, ""},
+		{"negative angle", map[string]interface{}{"angle": -45.0}, ""},
+		{"full turn", map[string]interface{}{"angle": 360}, ""},
+		{"background color", map[string]interface{}{"angle": 30, "background": "#fff"}, ""},
+		{"missing angle", map[string]interface{}{}, "angle parameter is required"},
+		{"angle not a number", map[string]interface{}{"angle": "90"}, "angle must be a number"},
+		{"angle above range", map[string]interface{}{"angle": 361}, "angle must be between -360 and 360"},
+		{"angle below range", map[string]interface{}{"angle": -360.5}, "angle must be between -360 and 360"},
+		{"background not a string", map[string]interface{}{"angle": 30, "background": 0}, "background must be a string"},
+		{"invalid background", map[string]interface{}{"angle": 30, "background": "white"}, "invalid color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.Validate(tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRotateOperation_Execute(t *testing.T) {
+	op := NewRotateOperation()
+	src := gradientImage(40, 20)
+
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		wantSize image.Point
+		// wantPixel - ожидаемый цвет левого верхнего угла результата
+		wantPixel color.NRGBA
+	}{
+		{
+			name:      "clockwise right angle",
+			params:    map[string]interface{}{"angle": 90},
+			wantSize:  image.Pt(20, 40),
+			wantPixel: color.NRGBA{R: 0, G: 19, B: 100, A: 255},
+		},
+		{
+			name:      "negative angle turns counterclockwise",
+			params:    map[string]interface{}{"angle": -90},
+			wantSize:  image.Pt(20, 40),
+			wantPixel: color.NRGBA{R: 39, G: 0, B: 100, A: 255},
+		},
+		{
+			name:      "non-right angle expands canvas with transparent background",
+			params:    map[string]interface{}{"angle": 45},
+			wantSize:  image.Pt(42, 42),
+			wantPixel: color.NRGBA{},
+		},
+		{
+			name:      "transparent background kept for png output",
+			params:    map[string]interface{}{"angle": 45, OutputFormatParam: entity.FormatPNG},
+			wantSize:  image.Pt(42, 42),
+			wantPixel: color.NRGBA{},
+		},
+		{
+			name:      "white background by default for jpeg output",
+			params:    map[string]interface{}{"angle": 45, OutputFormatParam: entity.FormatJPEG},
+			wantSize:  image.Pt(42, 42),
+			wantPixel: color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+		},
+		{
+			name:      "white background by default for gif output",
+			params:    map[string]interface{}{"angle": 45, OutputFormatParam: entity.FormatGIF},
+			wantSize:  image.Pt(42, 42),
+			wantPixel: color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+		},
+		{
+			name:      "explicit background for jpeg output",
+			params:    map[string]interface{}{"angle": 30, "background": "#ff0000", OutputFormatParam: entity.FormatJPEG},
+			wantSize:  image.Pt(45, 37),
+			wantPixel: color.NRGBA{R: 255, A: 255},
+		},
+		{
+			name:      "non-right angle with background color",
+			params:    map[string]interface{}{"angle": 30, "background": "#ff0000"},
+			wantSize:  image.Pt(45, 37),
+			wantPixel: color.NRGBA{R: 255, A: 255},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := op.Execute(context.Background(), src, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := result.Bounds().Size(); size != tt.wantSize {
+				t.Fatalf("expected size %v, got %v", tt.wantSize, size)
+			}
+			if got := color.NRGBAModel.Convert(result.At(0, 0)); got != tt.wantPixel {
+				t.Fatalf("expected corner pixel %v, got %v", tt.wantPixel, got)
+			}
+		})
+	}
+}
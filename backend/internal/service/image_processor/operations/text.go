@@ -0,0 +1,203 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+type textAlign int
+
+const (
+	alignLeft textAlign = iota
+	alignCenter
+	alignRight
+)
+
+// textStyle описывает оформление текста водяного знака
+type textStyle struct {
+	color        color.NRGBA
+	strokeColor  color.NRGBA
+	strokeWidth  int
+	shadowColor  color.NRGBA
+	shadowOffset int
+	hasShadow    bool
+	align        textAlign
+}
+
+// wrapText разбивает текст на строки не шире maxWidth по измеренной ширине глифов.
+// Явные переводы строк сохраняются; слово шире maxWidth разбивается по символам
+func wrapText(face font.Face, text string, maxWidth int) []string {
+	limit := fixed.I(maxWidth)
+	var lines []string
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.FieldsFunc(paragraph, unicode.IsSpace)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		current := ""
+		for _, word := range words {
+			candidate := word
+			if current != "" {
+				candidate = current + " " + word
+			}
+			if maxWidth <= 0 || font.MeasureString(face, candidate) <= limit {
+				current = candidate
+				continue
+			}
+
+			if current != "" {
+				lines = append(lines, current)
+			}
+
+			// Слово не помещается целиком - переносим по символам
+			current = ""
+			for _, r := range word {
+				next := current + string(r)
+				if current != "" && font.MeasureString(face, next) > limit {
+					lines = append(lines, current)
+					next = string(r)
+				}
+				current = next
+			}
+		}
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+// renderText рисует строки на прозрачном слое размером по метрикам шрифта
+// с учетом обводки и тени. Отмена контекста проверяется перед каждым проходом
+// обводки: их число растет квадратично с ее шириной
+func renderText(ctx context.Context, face font.Face, lines []string, style textStyle) (*image.NRGBA, error) {
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	ascent := metrics.Ascent.Ceil()
+
+	widths := make([]int, len(lines))
+	blockWidth := 0
+	for i, line := range lines {
+		widths[i] = font.MeasureString(face, line).Ceil()
+		if widths[i] > blockWidth {
+			blockWidth = widths[i]
+		}
+	}
+
+	pad := style.strokeWidth
+	shadow := 0
+	if style.hasShadow {
+		shadow = style.shadowOffset
+	}
+	width := blockWidth + 2*pad + shadow
+	height := lineHeight*len(lines) + 2*pad + shadow
+	if width < 1 || height < 1 {
+		return image.NewNRGBA(image.Rect(0, 0, 1, 1)), nil
+	}
+
+	layer := image.NewNRGBA(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{Dst: layer, Face: face}
+
+	drawLines := func(c color.NRGBA, dx, dy int) {
+		drawer.Src = image.NewUniform(c)
+		for i, line := range lines {
+			x := pad
+			switch style.align {
+			case alignCenter:
+				x += (blockWidth - widths[i]) / 2
+			case alignRight:
+				x += blockWidth - widths[i]
+			}
+			drawer.Dot = fixed.P(x+dx, pad+ascent+i*lineHeight+dy)
+			drawer.DrawString(line)
+		}
+	}
+
+	if style.hasShadow {
+		drawLines(style.shadowColor, style.shadowOffset, style.shadowOffset)
+	}
+
+	// Обводка - текст, нарисованный со смещениями в пределах радиуса
+	if style.strokeWidth > 0 {
+		w := style.strokeWidth
+		for dy := -w; dy <= w; dy++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for dx := -w; dx <= w; dx++ {
+				if (dx != 0 || dy != 0) && dx*dx+dy*dy <= w*w {
+					drawLines(style.strokeColor, dx, dy)
+				}
+			}
+		}
+	}
+
+	drawLines(style.color, 0, 0)
+
+	return layer, nil
+}
+
+// alignForPosition выравнивает строки к краю, у которого стоит водяной знак
+func alignForPosition(position string) textAlign {
+	switch {
+	case strings.HasSuffix(position, "left"):
+		return alignLeft
+	case strings.HasSuffix(position, "right"):
+		return alignRight
+	default:
+		return alignCenter
+	}
+}
+
+// compositeLayer накладывает готовый слой с прозрачностью в позицию или замощением.
+// Отмена контекста проверяется перед каждой полосой строк, а при замощении -
+// перед каждым рядом
+func compositeLayer(ctx context.Context, img image.Image, layer image.Image, layout overlayLayout) (*image.NRGBA, error) {
+	dst, err := toNRGBA(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	size := layer.Bounds().Size()
+	origin := layer.Bounds().Min
+	mask := image.NewUniform(color.Alpha{A: uint8(layout.opacity*255 + 0.5)})
+
+	drawAt := func(pt image.Point) {
+		draw.DrawMask(dst, image.Rectangle{Min: pt, Max: pt.Add(size)}, layer, origin, mask, image.Point{}, draw.Over)
+	}
+
+	if layout.tiled {
+		stepX := size.X + layout.spacing
+		stepY := size.Y + layout.spacing
+		for y := layout.margin; y < dst.Bounds().Dy(); y += stepY {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for x := layout.margin; x < dst.Bounds().Dx(); x += stepX {
+				drawAt(image.Pt(x, y))
+			}
+		}
+		return dst, nil
+	}
+
+	// Одиночный слой накладывается полосами строк
+	target := image.Rectangle{Min: watermarkOrigin(dst.Bounds(), size, layout.position, layout.margin)}
+	target.Max = target.Min.Add(size)
+	err = forEachBand(ctx, target.Min.Y, target.Max.Y, func(y0, y1 int) {
+		band := image.Rect(target.Min.X, y0, target.Max.X, y1)
+		draw.DrawMask(dst, band, layer, origin.Add(image.Pt(0, y0-target.Min.Y)), mask, image.Point{}, draw.Over)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
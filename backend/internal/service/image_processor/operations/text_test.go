@@ -0,0 +1,105 @@
+package operations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+// newMonoFace возвращает моноширинное начертание: ширина строки кратна
+// ширине символа, поэтому границы переноса легко посчитать
+func newMonoFace(t *testing.T) (font.Face, int) {
+	t.Helper()
+
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := fonts.NewFace("gomono", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = face.Close() })
+
+	return face, font.MeasureString(face, "m").Ceil()
+}
+
+func TestWrapText(t *testing.T) {
+	face, glyph := newMonoFace(t)
+
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth int
+		want     []string
+	}{
+		{"fits on one line", "hello world", 11 * glyph, []string{"hello world"}},
+		{"wraps by words", "hello big world", 9 * glyph, []string{"hello big", "world"}},
+		{"collapses spaces", "hello   world", 5 * glyph, []string{"hello", "world"}},
+		{"splits long word by runes", "abcdefghij", 4 * glyph, []string{"abcd", "efgh", "ij"}},
+		{"long word after short one", "ab cdefgh", 4 * glyph, []string{"ab", "cdef", "gh"}},
+		{"keeps explicit line breaks", "one\n\ntwo", 10 * glyph, []string{"one", "", "two"}},
+		{"multibyte runes", "привет", 3 * glyph, []string{"при", "вет"}},
+		{"no limit", "hello big world", 0, []string{"hello big world"}},
+		{"narrower than a glyph", "ab", 1, []string{"a", "b"}},
+		{"empty text", "", 10 * glyph, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(face, tt.text, tt.maxWidth); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrapText(%q, %d) = %q, want %q", tt.text, tt.maxWidth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderText_LayerSize(t *testing.T) {
+	face, glyph := newMonoFace(t)
+	lineHeight := face.Metrics().Height.Ceil()
+
+	tests := []struct {
+		name       string
+		lines      []string
+		style      textStyle
+		wantWidth  int
+		wantHeight int
+	}{
+		{"plain", []string{"abc"}, textStyle{}, 3 * glyph, lineHeight},
+		{"widest line", []string{"a", "abcde"}, textStyle{}, 5 * glyph, 2 * lineHeight},
+		{"stroke pads both sides", []string{"abc"}, textStyle{strokeWidth: 2}, 3*glyph + 4, lineHeight + 4},
+		{"shadow ignored when disabled", []string{"abc"}, textStyle{shadowOffset: 5}, 3 * glyph, lineHeight},
+		{"shadow adds offset", []string{"abc"}, textStyle{strokeWidth: 1, shadowOffset: 3, hasShadow: true}, 3*glyph + 5, lineHeight + 5},
+		{"empty text", nil, textStyle{}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layer, err := renderText(context.Background(), face, tt.lines, tt.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := layer.Bounds().Size()
+			if size.X != tt.wantWidth || size.Y != tt.wantHeight {
+				t.Fatalf("expected %dx%d layer, got %dx%d", tt.wantWidth, tt.wantHeight, size.X, size.Y)
+			}
+		})
+	}
+}
+
+func TestAlignForPosition(t *testing.T) {
+	tests := map[string]textAlign{
+		"top-left":      alignLeft,
+		"bottom-left":   alignLeft,
+		"top-right":     alignRight,
+		"bottom-right":  alignRight,
+		"top-center":    alignCenter,
+		"center":        alignCenter,
+		"bottom-center": alignCenter,
+	}
+	for position, want := range tests {
+		if got := alignForPosition(position); got != want {
+			t.Errorf("alignForPosition(%q) = %d, want %d", position, got, want)
+		}
+	}
+}
@@ -1,17 +1,21 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"image"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 
 	"github.com/disintegration/imaging"
 )
 
-type ThumbnailOperation struct{}
+type ThumbnailOperation struct {
+	cfg config.ProcessingConfig
+}
 
-func NewThumbnailOperation() *ThumbnailOperation {
-	return &ThumbnailOperation{}
+func NewThumbnailOperation(cfg config.ProcessingConfig) *ThumbnailOperation {
+	return &ThumbnailOperation{cfg: cfg}
 }
 
 func (o *ThumbnailOperation) GetOperationType() entity.OperationType {
@@ -32,22 +36,20 @@ func (o *ThumbnailOperation) Validate(params map[string]interface{}) error {
 		if s <= 0 {
 			return fmt.Errorf("size must be positive")
 		}
-		if s > 1000 {
-			return fmt.Errorf("size must not exceed 1000 pixels")
+		if s > float64(o.cfg.MaxThumbnailSize) {
+			return fmt.Errorf("size must not exceed %d pixels", o.cfg.MaxThumbnailSize)
 		}
 	}
 	return nil
 }
 
-func (o *ThumbnailOperation) Execute(imageData []byte, params map[string]interface{}) ([]byte, error) {
-	// Декодируем изображение
-	img, format, err := DecodeImage(imageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+func (o *ThumbnailOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Получаем параметры
-	size := getIntParam(params, entity.ParamSize, entity.DefaultThumbnailSize)
+	size := getIntParam(params, entity.ParamSize, o.cfg.DefaultThumbnailSize)
 	cropToFit := getBoolParam(params, entity.ParamCropToFit, false)
 
 	var thumbnail *image.NRGBA
@@ -60,7 +62,5 @@ func (o *ThumbnailOperation) Execute(imageData []byte, params map[string]interfa
 		thumbnail = imaging.Fit(img, size, size, imaging.Lanczos)
 	}
 
-	// Кодируем обратно в байты с высоким качеством
-	quality := getIntParam(params, "quality", entity.DefaultJPEGQuality)
-	return EncodeImage(thumbnail, format, quality)
+	return thumbnail, nil
 }
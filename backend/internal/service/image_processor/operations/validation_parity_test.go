@@ -0,0 +1,101 @@
+package operations_test
+
+import (
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/http-server/handler/dto"
+	"imageprocessor/backend/internal/service/image_processor/operations"
+	"testing"
+)
+
+// validator - общая часть операций, проверяющая параметры в воркере
+type validator interface {
+	Validate(params map[string]interface{}) error
+}
+
+// TestValidationParity проверяет, что запрос, принятый API (dto.OperationRequest),
+// принимается и операцией в воркере, и наоборот: иначе задача с принятыми
+// параметрами упадет уже после загрузки
+func TestValidationParity(t *testing.T) {
+	cfg := config.ProcessingConfig{MaxImageWidth: 1000, MaxImageHeight: 500}.WithDefaults()
+
+	ops := map[entity.OperationType]validator{
+		entity.OpCrop:      operations.NewCropOperation(cfg),
+		entity.OpRotate:    operations.NewRotateOperation(),
+		entity.OpFlip:      operations.NewFlipOperation(),
+		entity.OpGrayscale: operations.NewGrayscaleOperation(),
+	}
+
+	tests := []struct {
+		op     entity.OperationType
+		params map[string]interface{}
+	}{
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "x": 10.0, "y": 20.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "mode": "gravity", "gravity": "top-left"}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "mode": "gravity", "x": "ignored"}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0}},
+		{entity.OpCrop, map[string]interface{}{"width": "100", "height": 50.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 0.0, "height": 50.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 1000.0, "height": 500.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 1001.0, "height": 50.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 501.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "x": -1.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "y": "top"}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "mode": "smart"}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "mode": 1.0}},
+		{entity.OpCrop, map[string]interface{}{"width": 100.0, "height": 50.0, "gravity": "middle"}},
+
+		{entity.OpRotate, map[string]interface{}{"angle": 90.0}},
+		{entity.OpRotate, map[string]interface{}{"angle": 37.5}},
+		{entity.OpRotate, map[string]interface{}{"angle": -360.0}},
+		{entity.OpRotate, map[string]interface{}{"angle": 360.5}},
+		{entity.OpRotate, map[string]interface{}{"angle": "90"}},
+		{entity.OpRotate, map[string]interface{}{}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "#fff"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "#ff000080"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "rgba(255, 0, 0, 0.5)"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "transparent"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "white"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "#ggg"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": "rgb(256, 0, 0)"}},
+		{entity.OpRotate, map[string]interface{}{"angle": 30.0, "background": 0.0}},
+
+		{entity.OpFlip, map[string]interface{}{}},
+		{entity.OpFlip, map[string]interface{}{"mode": "horizontal"}},
+		{entity.OpFlip, map[string]interface{}{"mode": "vertical"}},
+		{entity.OpFlip, map[string]interface{}{"mode": "both"}},
+		{entity.OpFlip, map[string]interface{}{"mode": "diagonal"}},
+		{entity.OpFlip, map[string]interface{}{"mode": ""}},
+		{entity.OpFlip, map[string]interface{}{"mode": true}},
+
+		{entity.OpGrayscale, map[string]interface{}{}},
+		{entity.OpGrayscale, map[string]interface{}{"unused": 1.0}},
+	}
+	for _, tt := range tests {
+		// DTO дополняет параметры значениями по умолчанию, поэтому каждая
+		// проверка получает свою копию
+		request := dto.OperationRequest{Type: string(tt.op), Parameters: copyParams(tt.params)}
+		dtoErr := request.Validate(cfg)
+		opErr := ops[tt.op].Validate(copyParams(tt.params))
+
+		if (dtoErr == nil) != (opErr == nil) {
+			t.Errorf("%s %v: API and worker disagree: dto error %v, operation error %v", tt.op, tt.params, dtoErr, opErr)
+		}
+
+		// Параметры после дополнения значениями по умолчанию тоже должны проходить
+		if dtoErr == nil {
+			if err := ops[tt.op].Validate(request.Parameters); err != nil {
+				t.Errorf("%s %v: operation rejects parameters accepted by API: %v", tt.op, request.Parameters, err)
+			}
+		}
+	}
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		copied[k] = v
+	}
+	return copied
+}
@@ -1,33 +1,29 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
-	"image/draw"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 
-	"github.com/golang/freetype"
-	"github.com/golang/freetype/truetype"
-	"golang.org/x/image/font/gofont/goregular"
-	"golang.org/x/image/math/fixed"
+	"github.com/disintegration/imaging"
 )
 
+// OverlayImageParam - служебный параметр, через который процессор передает
+// декодированное изображение для наложения. Из запроса не принимается
+const OverlayImageParam = "_overlay_image"
+
 type WatermarkOperation struct {
-	font *truetype.Font
+	cfg   config.ProcessingConfig
+	fonts *FontRegistry
 }
 
-func NewWatermarkOperation() *WatermarkOperation {
-	// Загружаем встроенный шрифт
-	font, err := truetype.Parse(goregular.TTF)
-	if err != nil {
-		// Если не удалось загрузить шрифт, используем nil
-		// В Execute будем проверять
-		font = nil
-	}
-
+func NewWatermarkOperation(cfg config.ProcessingConfig, fonts *FontRegistry) *WatermarkOperation {
 	return &WatermarkOperation{
-		font: font,
+		cfg:   cfg,
+		fonts: fonts,
 	}
 }
 
@@ -36,8 +32,22 @@ func (o *WatermarkOperation) GetOperationType() entity.OperationType {
 }
 
 func (o *WatermarkOperation) Validate(params map[string]interface{}) error {
+	mode := getStringParam(params, entity.ParamMode, string(entity.WatermarkModeText))
+	switch entity.WatermarkMode(mode) {
+	case entity.WatermarkModeText:
+		if err := o.validateTextParams(params); err != nil {
+			return err
+		}
+	case entity.WatermarkModeImage:
+		if err := validateOverlayParams(params); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid watermark mode: %s", mode)
+	}
+
 	if opacity, exists := params[entity.ParamOpacity]; exists {
-		op, ok := opacity.(float64)
+		op, ok := getNumber(opacity)
 		if !ok {
 			return fmt.Errorf("opacity must be a number")
 		}
@@ -65,85 +75,164 @@ func (o *WatermarkOperation) Validate(params map[string]interface{}) error {
 		}
 	}
 
+	for _, key := range []string{entity.ParamMargin, entity.ParamSpacing} {
+		if value, exists := params[key]; exists {
+			v, ok := getNumber(value)
+			if !ok {
+				return fmt.Errorf("%s must be a number", key)
+			}
+			if v < 0 {
+				return fmt.Errorf("%s must not be negative", key)
+			}
+		}
+	}
+
+	if tiled, exists := params[entity.ParamTiled]; exists {
+		if _, ok := tiled.(bool); !ok {
+			return fmt.Errorf("tiled must be a boolean")
+		}
+	}
+
 	return nil
 }
 
-func (o *WatermarkOperation) Execute(imageData []byte, params map[string]interface{}) ([]byte, error) {
-	// Декодируем изображение
-	img, format, err := DecodeImage(imageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+// validateTextParams проверяет параметры текстового водяного знака
+func (o *WatermarkOperation) validateTextParams(params map[string]interface{}) error {
+	if text, exists := params[entity.ParamText]; exists {
+		s, ok := text.(string)
+		if !ok {
+			return fmt.Errorf("text must be a string")
+		}
+		if s == "" {
+			return fmt.Errorf("text must not be empty")
+		}
+	}
+
+	if name, exists := params[entity.ParamFont]; exists {
+		fontName, ok := name.(string)
+		if !ok {
+			return fmt.Errorf("font must be a string")
+		}
+		if !o.fonts.Has(fontName) {
+			return fmt.Errorf("unknown font: %s", fontName)
+		}
+	}
+
+	for _, key := range []string{entity.ParamFontColor, entity.ParamStrokeColor, entity.ParamShadowColor} {
+		if value, exists := params[key]; exists {
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string", key)
+			}
+			if _, err := parseColor(s); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+
+	numbers := []struct {
+		key      string
+		min, max float64
+	}{
+		{entity.ParamFontSize, 1, entity.MaxWatermarkFontSize},
+		{entity.ParamStrokeWidth, 0, entity.MaxWatermarkStrokeWidth},
+		{entity.ParamShadowOffset, 0, entity.MaxWatermarkShadowOffset},
+		{entity.ParamMaxWidth, 1, 1 << 16},
+		{entity.ParamAngle, -360, 360},
+	}
+	for _, n := range numbers {
+		if value, exists := params[n.key]; exists {
+			v, ok := getNumber(value)
+			if !ok {
+				return fmt.Errorf("%s must be a number", n.key)
+			}
+			if v < n.min || v > n.max {
+				return fmt.Errorf("%s must be between %g and %g", n.key, n.min, n.max)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *WatermarkOperation) Execute(ctx context.Context, img image.Image, params map[string]interface{}) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Получаем параметры
-	text := getStringParam(params, entity.ParamText, entity.DefaultWatermarkText)
-	opacity := getFloat64Param(params, entity.ParamOpacity, entity.DefaultWatermarkOpacity)
 	position := getStringParam(params, entity.ParamPosition, string(entity.WatermarkBottomRight))
-	fontSize := getIntParam(params, entity.ParamFontSize, 24)
+	layout := overlayLayout{
+		position: position,
+		opacity:  getFloat64Param(params, entity.ParamOpacity, o.cfg.WatermarkOpacity),
+		scale:    getFloat64Param(params, entity.ParamScale, entity.DefaultWatermarkScale),
+		margin:   getIntParam(params, entity.ParamMargin, entity.DefaultWatermarkMargin),
+		spacing:  getIntParam(params, entity.ParamSpacing, entity.DefaultWatermarkMargin),
+		tiled:    getBoolParam(params, entity.ParamTiled, false),
+	}
+
+	if getStringParam(params, entity.ParamMode, string(entity.WatermarkModeText)) == string(entity.WatermarkModeImage) {
+		overlay, ok := params[OverlayImageParam].(image.Image)
+		if !ok {
+			return nil, fmt.Errorf("overlay image is not loaded")
+		}
+		return addImageWatermark(ctx, img, overlay, layout)
+	}
 
-	// Добавляем водяной знак
-	watermarked, err := o.addTextWatermark(img, text, position, opacity, fontSize)
+	// Добавляем текстовый водяной знак
+	watermarked, err := o.addTextWatermark(ctx, img, params, layout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add watermark: %w", err)
 	}
 
-	// Кодируем обратно в байты
-	quality := getIntParam(params, "quality", entity.DefaultJPEGQuality)
-	return EncodeImage(watermarked, format, quality)
+	return watermarked, nil
 }
 
-func (o *WatermarkOperation) addTextWatermark(img image.Image, text, position string, opacity float64, fontSize int) (image.Image, error) {
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-
-	if o.font == nil {
-		return nil, fmt.Errorf("font not available")
-	}
-
-	// Создаем контекст для рисования текста
-	c := freetype.NewContext()
-	c.SetDPI(72)
-	c.SetFont(o.font)
-	c.SetFontSize(float64(fontSize))
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
-
-	// Цвет текста с прозрачностью
-	alpha := uint8(opacity * 255)
-	textColor := color.RGBA{R: 255, G: 255, B: 255, A: alpha}
-	c.SetSrc(image.NewUniform(textColor))
-
-	// Вычисляем размер текста
-	textWidth := len(text) * fontSize / 2 // Приблизительная ширина
-	textHeight := fontSize
-
-	// Вычисляем позицию
-	var pt fixed.Point26_6
-	switch position {
-	case "top-left":
-		pt = freetype.Pt(10, 10+textHeight)
-	case "top-right":
-		pt = freetype.Pt(bounds.Dx()-textWidth-10, 10+textHeight)
-	case "top-center":
-		pt = freetype.Pt((bounds.Dx()-textWidth)/2, 10+textHeight)
-	case "bottom-left":
-		pt = freetype.Pt(10, bounds.Dy()-10)
-	case "bottom-right":
-		pt = freetype.Pt(bounds.Dx()-textWidth-10, bounds.Dy()-10)
-	case "bottom-center":
-		pt = freetype.Pt((bounds.Dx()-textWidth)/2, bounds.Dy()-10)
-	case "center":
-		pt = freetype.Pt((bounds.Dx()-textWidth)/2, (bounds.Dy()+textHeight)/2)
-	default:
-		pt = freetype.Pt(bounds.Dx()-textWidth-10, bounds.Dy()-10)
+// addTextWatermark измеряет текст по метрикам шрифта, переносит строки по ширине,
+// рисует слой с обводкой и тенью, поворачивает его и накладывает на изображение
+func (o *WatermarkOperation) addTextWatermark(ctx context.Context, img image.Image, params map[string]interface{}, layout overlayLayout) (image.Image, error) {
+	text := getStringParam(params, entity.ParamText, o.cfg.WatermarkText)
+	fontName := getStringParam(params, entity.ParamFont, DefaultFontName)
+	fontSize := getFloat64Param(params, entity.ParamFontSize, float64(o.cfg.WatermarkFontSize))
+	angle := getFloat64Param(params, entity.ParamAngle, 0)
+
+	face, err := o.fonts.NewFace(fontName, fontSize)
+	if err != nil {
+		return nil, err
 	}
+	defer face.Close()
 
-	// Рисуем текст
-	_, err := c.DrawString(text, pt)
+	style := textStyle{
+		strokeWidth:  getIntParam(params, entity.ParamStrokeWidth, 0),
+		shadowOffset: getIntParam(params, entity.ParamShadowOffset, entity.DefaultWatermarkShadowOffset),
+		align:        alignForPosition(layout.position),
+	}
+	if style.color, err = parseColor(getStringParam(params, entity.ParamFontColor, entity.DefaultWatermarkFontColor)); err != nil {
+		return nil, err
+	}
+	if style.strokeColor, err = parseColor(getStringParam(params, entity.ParamStrokeColor, entity.DefaultWatermarkStrokeColor)); err != nil {
+		return nil, err
+	}
+	if shadowColor := getStringParam(params, entity.ParamShadowColor, ""); shadowColor != "" {
+		if style.shadowColor, err = parseColor(shadowColor); err != nil {
+			return nil, err
+		}
+		style.hasShadow = true
+	}
+
+	// По умолчанию строки переносятся по ширине изображения за вычетом отступов
+	maxWidth := getIntParam(params, entity.ParamMaxWidth, img.Bounds().Dx()-2*layout.margin-2*style.strokeWidth)
+	lines := wrapText(face, text, maxWidth)
+
+	rendered, err := renderText(ctx, face, lines, style)
 	if err != nil {
-		return nil, fmt.Errorf("failed to draw text: %w", err)
+		return nil, err
+	}
+	layer := image.Image(rendered)
+	if angle != 0 {
+		// Угол по часовой стрелке, как в операции rotate
+		layer = imaging.Rotate(layer, -angle, color.Transparent)
 	}
 
-	return rgba, nil
+	return compositeLayer(ctx, img, layer, layout)
 }
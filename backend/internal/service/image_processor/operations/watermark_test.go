@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"imageprocessor/backend/internal/config"
+	"strings"
+	"testing"
+)
+
+func newTestWatermark(t *testing.T) *WatermarkOperation {
+	t.Helper()
+
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewWatermarkOperation(config.ProcessingConfig{}.WithDefaults(), fonts)
+}
+
+func TestWatermarkOperation_Validate(t *testing.T) {
+	op := newTestWatermark(t)
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"defaults", map[string]interface{}{}, ""},
+		{"styled text", map[string]interface{}{"text": "© me", "font": "GoBold", "font_color": "#ffffff80", "stroke_width": 2, "angle": -30}, ""},
+		{"empty text", map[string]interface{}{"text": ""}, "text must not be empty"},
+		{"text not a string", map[string]interface{}{"text": 42}, "text must be a string"},
+		{"unknown font", map[string]interface{}{"text": "hi", "font": "comic-sans"}, "unknown font: comic-sans"},
+		{"empty font", map[string]interface{}{"text": "hi", "font": ""}, "unknown font: "},
+		{"font not a string", map[string]interface{}{"text": "hi", "font": 1}, "font must be a string"},
+		{"invalid font color", map[string]interface{}{"font_color": "#12345"}, "font_color: invalid color"},
+		{"font size out of range", map[string]interface{}{"font_size": 0}, "font_size must be between"},
+		{"unknown mode", map[string]interface{}{"mode": "video"}, "invalid watermark mode: video"},
+		{"image without source", map[string]interface{}{"mode": "image"}, "exactly one of"},
+		{"invalid position", map[string]interface{}{"position": "middle"}, "invalid position: middle"},
+		{"opacity out of range", map[string]interface{}{"opacity": 1.5}, "opacity must be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.Validate(tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWatermarkOperation_ExecuteText(t *testing.T) {
+	op := newTestWatermark(t)
+	src := image.NewNRGBA(image.Rect(0, 0, 120, 60))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+
+	result, err := op.Execute(context.Background(), src, map[string]interface{}{
+		"text":       "mark",
+		"font_color": "#000000",
+		"opacity":    1.0,
+		"position":   "top-left",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Bounds().Size() != src.Bounds().Size() {
+		t.Fatalf("watermark must keep image size, got %v", result.Bounds())
+	}
+
+	dark := 0
+	bounds := result.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if c := color.NRGBAModel.Convert(result.At(x, y)).(color.NRGBA); c.R < 0x80 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Fatal("expected text pixels on the image")
+	}
+}
+
+func TestWatermarkOperation_ExecuteErrors(t *testing.T) {
+	op := newTestWatermark(t)
+	src := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+
+	// Execute не полагается на Validate: неизвестный шрифт возвращает ошибку
+	if _, err := op.Execute(context.Background(), src, map[string]interface{}{"font": "missing"}); err == nil || !strings.Contains(err.Error(), "unknown font: missing") {
+		t.Fatalf("expected unknown font error, got %v", err)
+	}
+
+	if _, err := op.Execute(context.Background(), src, map[string]interface{}{"mode": "image", "overlay_asset": "logo"}); err == nil || err.Error() != "overlay image is not loaded" {
+		t.Fatalf("expected missing overlay error, got %v", err)
+	}
+}
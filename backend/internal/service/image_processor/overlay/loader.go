@@ -0,0 +1,106 @@
+package overlay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/repository/cloud"
+	imageOperations "imageprocessor/backend/internal/service/image_processor/operations"
+
+	"go.uber.org/zap"
+)
+
+// ImageRepositoryInterface определяет интерфейс репозитория изображений для наложений
+type ImageRepositoryInterface interface {
+	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
+}
+
+// Loader находит и загружает изображения для водяных знаков:
+// оригиналы ранее загруженных изображений или ассеты из конфигурации
+type Loader struct {
+	imageRepo    ImageRepositoryInterface
+	cloudStorage cloud.CloudStorageInterface
+	assets       map[string]string // имя ассета -> ключ объекта в хранилище
+	logger       *zap.Logger
+}
+
+func NewLoader(
+	imageRepo ImageRepositoryInterface,
+	cloudStorage cloud.CloudStorageInterface,
+	assets map[string]string,
+	logger *zap.Logger,
+) *Loader {
+	return &Loader{
+		imageRepo:    imageRepo,
+		cloudStorage: cloudStorage,
+		assets:       assets,
+		logger:       logger,
+	}
+}
+
+// ValidateOverlay проверяет, что источник наложения существует в БД и хранилище
+func (l *Loader) ValidateOverlay(ctx context.Context, source entity.OverlaySource) error {
+	path, err := l.resolvePath(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	exists, err := l.cloudStorage.FileExists(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to check overlay: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", entity.ErrOverlayNotFound, path)
+	}
+
+	return nil
+}
+
+// LoadOverlay скачивает и декодирует изображение для наложения
+func (l *Loader) LoadOverlay(ctx context.Context, source entity.OverlaySource) (image.Image, error) {
+	path, err := l.resolvePath(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := l.cloudStorage.DownloadFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download overlay %s: %w", path, err)
+	}
+
+	img, _, err := imageOperations.DecodeImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode overlay %s: %w", path, err)
+	}
+
+	l.logger.Debug("Overlay loaded",
+		zap.String("path", path),
+		zap.Int("width", img.Bounds().Dx()),
+		zap.Int("height", img.Bounds().Dy()),
+	)
+
+	return img, nil
+}
+
+// resolvePath определяет ключ объекта в хранилище для источника наложения
+func (l *Loader) resolvePath(ctx context.Context, source entity.OverlaySource) (string, error) {
+	if source.Asset != "" {
+		path, ok := l.assets[source.Asset]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown asset %s", entity.ErrOverlayNotFound, source.Asset)
+		}
+		return path, nil
+	}
+
+	img, err := l.imageRepo.GetImageByID(ctx, source.ImageID)
+	if err != nil {
+		if errors.Is(err, entity.ErrImageNotFound) {
+			return "", fmt.Errorf("%w: image %s", entity.ErrOverlayNotFound, source.ImageID)
+		}
+		return "", fmt.Errorf("failed to get overlay image: %w", err)
+	}
+
+	return img.OriginalPath, nil
+}
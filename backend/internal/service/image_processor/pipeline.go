@@ -0,0 +1,93 @@
+package image_processor
+
+import (
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+)
+
+// PipelineStep описывает шаг конвейера: индекс операции в задаче и имя входного варианта
+type PipelineStep struct {
+	Index int
+	Name  string
+	Input string
+}
+
+// BuildPipeline строит порядок выполнения операций по их входам.
+// Операции образуют граф: вход каждой - оригинал или результат другой операции.
+// Возвращает ошибку при повторяющихся именах, ссылках на неизвестные варианты и циклах
+func BuildPipeline(operations []entity.OperationParams) ([]PipelineStep, error) {
+	indexByName := make(map[string]int, len(operations))
+	for i, op := range operations {
+		name := op.VariantName()
+		if name == entity.OriginalVariant {
+			return nil, fmt.Errorf("operation name %q is reserved", name)
+		}
+		if _, exists := indexByName[name]; exists {
+			return nil, fmt.Errorf("duplicate operation name: %s", name)
+		}
+		indexByName[name] = i
+	}
+
+	// Определяем входы и строим список зависимых операций
+	steps := make([]PipelineStep, len(operations))
+	dependents := make([][]int, len(operations))
+	pending := make([]int, len(operations))
+
+	for i, op := range operations {
+		input := op.Input
+		if input == "" {
+			// По умолчанию операция применяется к результату предыдущей
+			if i == 0 {
+				input = entity.OriginalVariant
+			} else {
+				input = operations[i-1].VariantName()
+			}
+		}
+
+		steps[i] = PipelineStep{Index: i, Name: op.VariantName(), Input: input}
+
+		if input == entity.OriginalVariant {
+			continue
+		}
+		parent, exists := indexByName[input]
+		if !exists {
+			return nil, fmt.Errorf("operation %s references unknown input: %s", op.VariantName(), input)
+		}
+		if parent == i {
+			return nil, fmt.Errorf("operation %s uses itself as input", op.VariantName())
+		}
+		dependents[parent] = append(dependents[parent], i)
+		pending[i] = 1
+	}
+
+	// Топологическая сортировка с сохранением порядка из запроса
+	order := make([]PipelineStep, 0, len(operations))
+	done := make([]bool, len(operations))
+	for len(order) < len(operations) {
+		next := -1
+		for i := range operations {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			cycle := make([]string, 0)
+			for i, op := range operations {
+				if !done[i] {
+					cycle = append(cycle, op.VariantName())
+				}
+			}
+			return nil, fmt.Errorf("operations form a cycle or depend on one: %s", strings.Join(cycle, ", "))
+		}
+
+		done[next] = true
+		order = append(order, steps[next])
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+
+	return order, nil
+}
@@ -5,30 +5,53 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"imageprocessor/backend/internal/config"
 	"imageprocessor/backend/internal/domain/entity"
 	imageProcessor "imageprocessor/backend/internal/service/image_processor"
-	"imageprocessor/backend/internal/service/image_processor/operations"
+	imageOperations "imageprocessor/backend/internal/service/image_processor/operations"
 
 	"go.uber.org/zap"
-	"golang.org/x/image/webp"
+	_ "golang.org/x/image/webp"
 )
 
 type ImageProcessorImpl struct {
-	operations map[entity.OperationType]imageProcessor.Operation
-	logger     *zap.Logger
+	cfg           config.ProcessingConfig
+	limits        imageLimits
+	operations    map[entity.OperationType]imageProcessor.Operation
+	overlayLoader imageProcessor.OverlayLoader
+	fonts         *imageOperations.FontRegistry
+	logger        *zap.Logger
 }
 
-// NewImageProcessor создает новый процессор изображений
-func NewImageProcessor(logger *zap.Logger) *ImageProcessorImpl {
+// NewImageProcessor создает новый процессор изображений.
+// Незаданные лимиты и значения по умолчанию берутся из entity.
+// overlayLoader может быть nil, тогда водяные знаки-изображения недоступны
+func NewImageProcessor(logger *zap.Logger, cfg config.ProcessingConfig, overlayLoader imageProcessor.OverlayLoader) *ImageProcessorImpl {
+	cfg = cfg.WithDefaults()
 	processor := &ImageProcessorImpl{
-		operations: make(map[entity.OperationType]imageProcessor.Operation),
-		logger:     logger,
+		cfg:           cfg,
+		limits:        newImageLimits(cfg),
+		operations:    make(map[entity.OperationType]imageProcessor.Operation),
+		overlayLoader: overlayLoader,
+		logger:        logger,
 	}
 
+	// Встроенные шрифты доступны всегда, ошибки файлов из каталога не фатальны
+	fonts, err := imageOperations.LoadFonts(cfg.FontsDir)
+	if err != nil {
+		logger.Warn("Failed to load some watermark fonts", zap.Error(err), zap.String("dir", cfg.FontsDir))
+	}
+	logger.Info("Watermark fonts loaded", zap.Strings("fonts", fonts.Names()))
+	processor.fonts = fonts
+
 	// Регистрируем все операции
-	processor.registerOperation(operations.NewResizeOperation())
-	processor.registerOperation(operations.NewThumbnailOperation())
-	processor.registerOperation(operations.NewWatermarkOperation())
+	processor.registerOperation(imageOperations.NewResizeOperation(cfg))
+	processor.registerOperation(imageOperations.NewThumbnailOperation(cfg))
+	processor.registerOperation(imageOperations.NewWatermarkOperation(cfg, fonts))
+	processor.registerOperation(imageOperations.NewCropOperation(cfg))
+	processor.registerOperation(imageOperations.NewRotateOperation())
+	processor.registerOperation(imageOperations.NewFlipOperation())
+	processor.registerOperation(imageOperations.NewGrayscaleOperation())
 
 	logger.Info("Image processor initialized with operations",
 		zap.Int("operationCount", len(processor.operations)),
@@ -37,14 +60,29 @@ func NewImageProcessor(logger *zap.Logger) *ImageProcessorImpl {
 	return processor
 }
 
+// Fonts возвращает шрифты, доступные водяному знаку
+func (p *ImageProcessorImpl) Fonts() *imageOperations.FontRegistry {
+	return p.fonts
+}
+
 // registerOperation регистрирует операцию в процессоре
 func (p *ImageProcessorImpl) registerOperation(op imageProcessor.Operation) {
 	p.operations[op.GetOperationType()] = op
 	p.logger.Debug("Registered operation", zap.String("type", string(op.GetOperationType())))
 }
 
-// ProcessImage обрабатывает изображение согласно списку операций
-func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte, operations []entity.OperationParams) (map[string][]byte, error) {
+// ProcessImage обрабатывает изображение согласно списку операций.
+// Изображение декодируется один раз, операции выполняются в памяти,
+// а кодирование происходит только при выдаче результата каждой операции
+func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte, operations []entity.OperationParams) (map[string]*entity.ProcessedOutput, error) {
+	return p.ProcessImageWithProgress(ctx, imageData, operations, nil)
+}
+
+// ProcessImageWithProgress обрабатывает изображение как ProcessImage и вызывает
+// progress после каждой выполненной операции. progress может быть nil.
+// Отмена ctx проверяется между этапами и внутри операций; в этом случае
+// возвращается ошибка, оборачивающая ctx.Err()
+func (p *ImageProcessorImpl) ProcessImageWithProgress(ctx context.Context, imageData []byte, operations []entity.OperationParams, progress entity.OperationProgressFunc) (map[string]*entity.ProcessedOutput, error) {
 	p.logger.Info("Processing image with operations",
 		zap.Int("dataSize", len(imageData)),
 		zap.Int("operationCount", len(operations)),
@@ -54,19 +92,53 @@ func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte,
 	format, err := p.ValidateImage(imageData)
 	if err != nil {
 		p.logger.Error("Image validation failed", zap.Error(err))
-		return nil, fmt.Errorf("invalid image: %w", err)
+		return nil, err
 	}
 
 	p.logger.Debug("Image validated", zap.String("format", string(format)))
 
-	results := make(map[string][]byte)
-	currentData := imageData
+	// Проверяем размеры по заголовку до полного декодирования
+	if _, err := p.CheckImageLimits(imageData); err != nil {
+		p.logger.Error("Image exceeds decoding limits", zap.Error(err))
+		return nil, err
+	}
+
+	// Строим порядок выполнения по входам операций
+	pipeline, err := imageProcessor.BuildPipeline(operations)
+	if err != nil {
+		p.logger.Error("Invalid operation pipeline", zap.Error(err))
+		return nil, fmt.Errorf("invalid operation pipeline: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("processing interrupted before decoding: %w", err)
+	}
+
+	// Декодируем изображение один раз на весь конвейер
+	original, _, err := imageOperations.DecodeImage(imageData)
+	if err != nil {
+		p.logger.Error("Image decoding failed", zap.Error(err))
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	// Декодированные результаты по именам вариантов - входы для следующих шагов
+	images := map[string]image.Image{entity.OriginalVariant: original}
+	results := make(map[string]*entity.ProcessedOutput)
+	overlays := make(map[entity.OverlaySource]image.Image)
+
+	// Выполняем операции в порядке зависимостей
+	for _, step := range pipeline {
+		opParams := operations[step.Index]
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("processing interrupted before operation %s: %w", step.Name, err)
+		}
 
-	// Выполняем операции последовательно
-	for idx, opParams := range operations {
 		p.logger.Debug("Executing operation",
-			zap.Int("index", idx),
+			zap.Int("index", step.Index),
 			zap.String("type", string(opParams.Type)),
+			zap.String("variant", step.Name),
+			zap.String("input", step.Input),
 		)
 
 		operation, exists := p.operations[opParams.Type]
@@ -83,9 +155,34 @@ func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte,
 			)
 			return nil, fmt.Errorf("invalid parameters for operation %s: %w", opParams.Type, err)
 		}
+		if err := imageOperations.ValidateOutputParams(opParams.Parameters); err != nil {
+			p.logger.Error("Operation output validation failed",
+				zap.String("type", string(opParams.Type)),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("invalid output parameters for operation %s: %w", opParams.Type, err)
+		}
+
+		// Подгружаем изображение для наложения, если операция его использует
+		params, err := p.withOverlay(ctx, opParams.Parameters, overlays)
+		if err != nil {
+			p.logger.Error("Failed to load overlay",
+				zap.String("type", string(opParams.Type)),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("failed to load overlay for operation %s: %w", opParams.Type, err)
+		}
+
+		// Формат результата известен до выполнения: от него зависят умолчания операции,
+		// например заливка углов при повороте
+		outputFormat := imageOperations.OutputFormat(opParams.Parameters, format)
+		if !p.cfg.IsSupportedFormat(outputFormat) {
+			return nil, fmt.Errorf("output format %s of operation %s is not supported", outputFormat, opParams.Type)
+		}
+		params = withParam(params, imageOperations.OutputFormatParam, outputFormat)
 
 		// Выполняем операцию
-		processedData, err := operation.Execute(currentData, opParams.Parameters)
+		processedImage, err := operation.Execute(ctx, images[step.Input], params)
 		if err != nil {
 			p.logger.Error("Operation execution failed",
 				zap.String("type", string(opParams.Type)),
@@ -93,16 +190,42 @@ func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte,
 			)
 			return nil, fmt.Errorf("failed to execute operation %s: %w", opParams.Type, err)
 		}
+		images[step.Name] = processedImage
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("processing interrupted before encoding %s: %w", step.Name, err)
+		}
+
+		// Кодируем только выдаваемый результат, следующая операция получает декодированное изображение
+		quality := getQuality(opParams.Parameters, p.cfg.DefaultJpegQuality)
+		processedData, err := imageOperations.EncodeImage(processedImage, outputFormat, quality)
+		if err != nil {
+			p.logger.Error("Operation result encoding failed",
+				zap.String("type", string(opParams.Type)),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("failed to encode result of operation %s: %w", opParams.Type, err)
+		}
 
-		// Сохраняем результат
-		operationKey := string(opParams.Type)
-		results[operationKey] = processedData
-		currentData = processedData
+		// Сохраняем результат под именем варианта
+		results[step.Name] = &entity.ProcessedOutput{
+			Operation: opParams.Type,
+			Data:      processedData,
+			Format:    outputFormat,
+			MimeType:  outputFormat.MimeType(),
+			Quality:   quality,
+		}
 
 		p.logger.Debug("Operation completed",
 			zap.String("type", string(opParams.Type)),
+			zap.String("variant", step.Name),
+			zap.String("format", string(outputFormat)),
 			zap.Int("resultSize", len(processedData)),
 		)
+
+		if progress != nil {
+			progress(len(results), len(pipeline), opParams.Type, step.Name)
+		}
 	}
 
 	p.logger.Info("Image processing completed",
@@ -113,39 +236,78 @@ func (p *ImageProcessorImpl) ProcessImage(ctx context.Context, imageData []byte,
 	return results, nil
 }
 
-// ValidateImage проверяет, является ли файл допустимым изображением
+// withOverlay возвращает параметры с декодированным изображением для наложения.
+// Каждый источник загружается один раз за задачу, исходные параметры не изменяются
+func (p *ImageProcessorImpl) withOverlay(ctx context.Context, params map[string]interface{}, cache map[entity.OverlaySource]image.Image) (map[string]interface{}, error) {
+	source, ok := entity.OverlaySourceFromParams(params)
+	if !ok {
+		return params, nil
+	}
+	if p.overlayLoader == nil {
+		return nil, fmt.Errorf("overlay loader is not configured")
+	}
+
+	overlay, cached := cache[source]
+	if !cached {
+		var err error
+		overlay, err = p.overlayLoader.LoadOverlay(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		cache[source] = overlay
+	}
+
+	return withParam(params, imageOperations.OverlayImageParam, overlay), nil
+}
+
+// withParam возвращает копию параметров с добавленным служебным значением
+func withParam(params map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// getQuality возвращает качество кодирования из параметров операции
+func getQuality(params map[string]interface{}, defaultQuality int) int {
+	switch v := params[entity.ParamQuality].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return defaultQuality
+}
+
+// ValidateImage определяет формат по сигнатуре содержимого и проверяет,
+// что файл является полным изображением в разрешенном конфигурацией формате.
+// Возвращает entity.ErrInvalidImage для нечитаемых и обрезанных файлов
+// и entity.ErrUnsupportedFormat для запрещенных форматов
 func (p *ImageProcessorImpl) ValidateImage(imageData []byte) (entity.ImageFormat, error) {
 	if len(imageData) == 0 {
-		return "", fmt.Errorf("empty image data")
+		return "", fmt.Errorf("%w: empty image data", entity.ErrInvalidImage)
 	}
 
-	// Пытаемся декодировать изображение
-	_, format, err := image.DecodeConfig(bytes.NewReader(imageData))
+	// Формат определяется по сигнатуре, WebP регистрируется импортом golang.org/x/image/webp
+	_, formatName, err := image.DecodeConfig(bytes.NewReader(imageData))
 	if err != nil {
-		// Пробуем WebP отдельно, так как он не включен в стандартную библиотеку
-		_, err := webp.DecodeConfig(bytes.NewReader(imageData))
-		if err == nil {
-			return entity.FormatWebP, nil
-		}
-		return "", fmt.Errorf("unsupported image format: %w", err)
+		return "", fmt.Errorf("%w: not a recognized image: %v", entity.ErrInvalidImage, err)
 	}
+	format := formatFromName(formatName)
 
-	// Преобразуем формат в наш тип
-	var imgFormat entity.ImageFormat
-	switch format {
-	case "jpeg":
-		imgFormat = entity.FormatJPEG
-	case "png":
-		imgFormat = entity.FormatPNG
-	case "gif":
-		imgFormat = entity.FormatGIF
-	case "webp":
-		imgFormat = entity.FormatWebP
-	default:
-		return "", fmt.Errorf("unsupported image format: %s", format)
+	if !p.cfg.IsSupportedFormat(format) {
+		return "", fmt.Errorf("%w: %s", entity.ErrUnsupportedFormat, format)
+	}
+
+	if err := checkComplete(format, imageData); err != nil {
+		return "", fmt.Errorf("%w: %v", entity.ErrInvalidImage, err)
 	}
 
-	return imgFormat, nil
+	return format, nil
 }
 
 // GetImageInfo возвращает информацию об изображении
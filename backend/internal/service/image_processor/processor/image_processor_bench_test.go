@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	imageOperations "imageprocessor/backend/internal/service/image_processor/operations"
+	"math"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// benchmarkPipeline - типичная цепочка из нескольких операций
+var benchmarkPipeline = []entity.OperationParams{
+	{Type: entity.OpResize, Parameters: map[string]interface{}{entity.ParamWidth: 1280.0}},
+	{Type: entity.OpRotate, Parameters: map[string]interface{}{entity.ParamAngle: 90.0}},
+	{Type: entity.OpFlip, Parameters: map[string]interface{}{entity.ParamMode: "horizontal"}},
+	{Type: entity.OpWatermark, Parameters: map[string]interface{}{entity.ParamText: "benchmark"}},
+	{Type: entity.OpGrayscale, Parameters: map[string]interface{}{}},
+}
+
+// BenchmarkProcessImage_InMemory измеряет конвейер с однократным декодированием
+func BenchmarkProcessImage_InMemory(b *testing.B) {
+	p := NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+	source := benchmarkSource(b)
+	reference := referenceResult(b, p, source)
+
+	var results map[string]*entity.ProcessedOutput
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		results, err = p.ProcessImage(context.Background(), source, benchmarkPipeline)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	last := benchmarkPipeline[len(benchmarkPipeline)-1].VariantName()
+	b.ReportMetric(psnr(b, reference, results[last].Data), "psnr-dB")
+}
+
+// BenchmarkProcessImage_PerStepCodec воспроизводит прежнее поведение:
+// каждая операция декодирует и кодирует изображение заново
+func BenchmarkProcessImage_PerStepCodec(b *testing.B) {
+	p := NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+	source := benchmarkSource(b)
+	reference := referenceResult(b, p, source)
+
+	var current []byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		current = source
+		for _, opParams := range benchmarkPipeline {
+			img, format, err := imageOperations.DecodeImage(current)
+			if err != nil {
+				b.Fatal(err)
+			}
+			processed, err := p.operations[opParams.Type].Execute(context.Background(), img, opParams.Parameters)
+			if err != nil {
+				b.Fatal(err)
+			}
+			current, err = imageOperations.EncodeImage(processed, format, entity.DefaultJPEGQuality)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(psnr(b, reference, current), "psnr-dB")
+}
+
+// benchmarkSource генерирует JPEG 1920x1080 с градиентами и мелкими деталями
+func benchmarkSource(b *testing.B) []byte {
+	b.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := 0; y < 1080; y++ {
+		for x := 0; x < 1920; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x * 255 / 1920),
+				G: uint8(y * 255 / 1080),
+				B: uint8((x ^ y) & 0xff),
+				A: 0xff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: entity.DefaultJPEGQuality}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// referenceResult выполняет цепочку без промежуточного и финального кодирования
+func referenceResult(b *testing.B, p *ImageProcessorImpl, source []byte) image.Image {
+	b.Helper()
+
+	img, _, err := imageOperations.DecodeImage(source)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, opParams := range benchmarkPipeline {
+		img, err = p.operations[opParams.Type].Execute(context.Background(), img, opParams.Parameters)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	return img
+}
+
+// psnr вычисляет пиковое отношение сигнал/шум между эталоном и закодированным результатом
+func psnr(b *testing.B, reference image.Image, encoded []byte) float64 {
+	b.Helper()
+
+	img, _, err := imageOperations.DecodeImage(encoded)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	bounds := reference.Bounds()
+	if bounds.Size() != img.Bounds().Size() {
+		b.Fatalf("size mismatch: %v vs %v", bounds.Size(), img.Bounds().Size())
+	}
+
+	var sum float64
+	offset := img.Bounds().Min.Sub(bounds.Min)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, _ := reference.At(x, y).RGBA()
+			r2, g2, b2, _ := img.At(x+offset.X, y+offset.Y).RGBA()
+			for _, d := range []float64{
+				float64(r1>>8) - float64(r2>>8),
+				float64(g1>>8) - float64(g2>>8),
+				float64(b1>>8) - float64(b2>>8),
+			} {
+				sum += d * d
+			}
+		}
+	}
+
+	mse := sum / float64(bounds.Dx()*bounds.Dy()*3)
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
@@ -0,0 +1,62 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"go.uber.org/zap"
+)
+
+func TestProcessImage_RotateBackgroundFollowsOutputFormat(t *testing.T) {
+	p := NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, imaging.New(200, 100, color.NRGBA{R: 255, A: 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		// wantCorner - ожидаемый цвет левого верхнего угла после декодирования результата
+		wantCorner func(c color.NRGBA) bool
+	}{
+		{"jpeg gets white corners", map[string]interface{}{"angle": 45, "format": "jpeg"},
+			func(c color.NRGBA) bool { return c.R > 250 && c.G > 250 && c.B > 250 }},
+		{"jpg alias gets white corners", map[string]interface{}{"angle": 45, "format": "jpg"},
+			func(c color.NRGBA) bool { return c.R > 250 && c.G > 250 && c.B > 250 }},
+		{"png keeps transparent corners", map[string]interface{}{"angle": 45},
+			func(c color.NRGBA) bool { return c.A == 0 }},
+		{"explicit background wins for jpeg", map[string]interface{}{"angle": 45, "format": "jpeg", "background": "#0000ff"},
+			func(c color.NRGBA) bool { return c.B > 240 && c.R < 15 && c.G < 15 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := p.ProcessImage(context.Background(), buf.Bytes(), []entity.OperationParams{
+				{Type: entity.OpRotate, Parameters: tt.params},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("expected one result, got %d", len(results))
+			}
+			for _, result := range results {
+				img, _, err := image.Decode(bytes.NewReader(result.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if corner := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); !tt.wantCorner(corner) {
+					t.Fatalf("unexpected corner color %v for %s output", corner, result.Format)
+				}
+			}
+		})
+	}
+}
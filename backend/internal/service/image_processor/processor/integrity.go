@@ -0,0 +1,242 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"io"
+	"sync"
+)
+
+// checkComplete проверяет по структуре файла, что изображение не обрезано.
+// Данные пикселей не распаковываются: проверяется цепочка блоков до
+// завершающего маркера формата
+func checkComplete(format entity.ImageFormat, data []byte) error {
+	_, err := walkStructure(format, bufio.NewReader(bytes.NewReader(data)))
+	return err
+}
+
+// walkStructure проходит по структуре файла из потока и возвращает количество кадров
+func walkStructure(format entity.ImageFormat, r *bufio.Reader) (int, error) {
+	switch format {
+	case entity.FormatPNG:
+		return 1, checkPNGComplete(r)
+	case entity.FormatJPEG:
+		return 1, checkJPEGComplete(r)
+	case entity.FormatGIF:
+		return countGIFFrames(r)
+	case entity.FormatWebP:
+		return 1, checkWebPComplete(r)
+	default:
+		return 1, nil
+	}
+}
+
+// streamValidator проверяет структуру изображения по мере записи данных.
+// Разбор выполняется в отдельной горутине, читающей из канала io.Pipe
+type streamValidator struct {
+	writer    *io.PipeWriter
+	done      chan error
+	closeOnce sync.Once
+	err       error
+}
+
+// NewStreamValidator возвращает io.WriteCloser, который проверяет, что записанные
+// данные образуют полное изображение заданного формата. Close возвращает
+// entity.ErrInvalidImage для обрезанных файлов и entity.ErrImageTooLarge,
+// если количество кадров превышает лимит. Ошибка структуры, найденная
+// до конца потока, возвращается из Write
+func (p *ImageProcessorImpl) NewStreamValidator(format entity.ImageFormat) io.WriteCloser {
+	reader, writer := io.Pipe()
+	v := &streamValidator{
+		writer: writer,
+		done:   make(chan error, 1),
+	}
+
+	go func() {
+		frames, err := walkStructure(format, bufio.NewReader(reader))
+		if err == nil && frames > p.limits.maxFrames {
+			err = fmt.Errorf("%w: %d frames exceeds limit %d", entity.ErrImageTooLarge, frames, p.limits.maxFrames)
+		} else if err != nil && !errors.Is(err, entity.ErrImageTooLarge) {
+			err = fmt.Errorf("%w: %v", entity.ErrInvalidImage, err)
+		}
+
+		if err != nil {
+			// Прерываем запись, чтобы загрузка остановилась на первой ошибке
+			reader.CloseWithError(err)
+		} else {
+			// Данные после завершающего маркера допустимы и не проверяются
+			_, _ = io.Copy(io.Discard, reader)
+		}
+		v.done <- err
+	}()
+
+	return v
+}
+
+func (v *streamValidator) Write(p []byte) (int, error) {
+	return v.writer.Write(p)
+}
+
+// Close завершает поток и возвращает результат проверки. Повторные вызовы безопасны
+func (v *streamValidator) Close() error {
+	v.closeOnce.Do(func() {
+		_ = v.writer.Close()
+		v.err = <-v.done
+	})
+	return v.err
+}
+
+// checkPNGComplete проходит по чанкам PNG до IEND
+func checkPNGComplete(r *bufio.Reader) error {
+	const signatureSize = 8
+	// Контрольная сумма после данных чанка
+	const crcSize = 4
+
+	if _, err := r.Discard(signatureSize); err != nil {
+		return fmt.Errorf("png: truncated signature")
+	}
+
+	chunkHeader := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunkHeader); err != nil {
+			return fmt.Errorf("png: truncated file, IEND chunk not found")
+		}
+		length := int64(binary.BigEndian.Uint32(chunkHeader[0:4]))
+		if err := discard(r, length+crcSize); err != nil {
+			return fmt.Errorf("png: truncated %s chunk", chunkHeader[4:8])
+		}
+		if string(chunkHeader[4:8]) == "IEND" {
+			return nil
+		}
+	}
+}
+
+// checkJPEGComplete проходит по сегментам JPEG и сканам до маркера EOI.
+// Сегменты пропускаются по длине, поэтому EOI встроенных миниатюр EXIF не учитывается
+func checkJPEGComplete(r *bufio.Reader) error {
+	truncated := fmt.Errorf("jpeg: truncated file, EOI marker not found")
+
+	// SOI
+	if _, err := r.Discard(2); err != nil {
+		return truncated
+	}
+
+	marker, err := readJPEGMarker(r)
+	for {
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return truncated
+			}
+			return err
+		}
+
+		switch {
+		case marker == jpegMarkerEOI:
+			return nil
+		case isStandaloneJPEGMarker(marker):
+			marker, err = readJPEGMarker(r)
+			continue
+		}
+
+		lengthBytes := make([]byte, 2)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return truncated
+		}
+		length := int64(binary.BigEndian.Uint16(lengthBytes))
+		if length < 2 {
+			return fmt.Errorf("jpeg: invalid segment length %d", length)
+		}
+		if err := discard(r, length-2); err != nil {
+			return truncated
+		}
+
+		if marker == jpegMarkerSOS {
+			marker, err = skipEntropyData(r)
+		} else {
+			marker, err = readJPEGMarker(r)
+		}
+	}
+}
+
+const (
+	jpegMarkerSOS = 0xDA
+	jpegMarkerEOI = 0xD9
+	jpegMarkerRST = 0xD0
+	jpegMarkerTEM = 0x01
+)
+
+// isStandaloneJPEGMarker проверяет, что маркер не имеет сегмента данных
+func isStandaloneJPEGMarker(marker byte) bool {
+	return marker == jpegMarkerTEM || (marker >= jpegMarkerRST && marker <= jpegMarkerRST+7)
+}
+
+// readJPEGMarker читает маркер, пропуская заполняющие байты 0xFF
+func readJPEGMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, fmt.Errorf("jpeg: invalid marker 0x%02x", b)
+	}
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// skipEntropyData пропускает данные скана и возвращает следующий маркер:
+// внутри скана 0xFF всегда экранируется нулем или является маркером RSTn
+func skipEntropyData(r *bufio.Reader) (byte, error) {
+	for {
+		if _, err := r.ReadSlice(0xFF); err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				continue
+			}
+			return 0, err
+		}
+
+		next, err := r.ReadByte()
+		for err == nil && next == 0xFF {
+			next, err = r.ReadByte()
+		}
+		if err != nil {
+			return 0, err
+		}
+		if next == 0x00 || (next >= jpegMarkerRST && next <= jpegMarkerRST+7) {
+			continue
+		}
+		return next, nil
+	}
+}
+
+// checkWebPComplete сверяет размер из заголовка RIFF с длиной данных
+func checkWebPComplete(r *bufio.Reader) error {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("RIFF")) {
+		return fmt.Errorf("webp: invalid RIFF header")
+	}
+	size := int64(binary.LittleEndian.Uint32(header[4:8]))
+	if err := discard(r, size); err != nil {
+		return fmt.Errorf("webp: truncated file, expected %d bytes of RIFF data", size)
+	}
+	return nil
+}
+
+// discard пропускает n байт, проверяя, что данные не закончились
+func discard(r *bufio.Reader, n int64) error {
+	skipped, err := io.CopyN(io.Discard, r, n)
+	if skipped < n {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+)
+
+func encodedSamples(t *testing.T) map[entity.ImageFormat][]byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 5), B: 128, A: 255})
+		}
+	}
+
+	samples := make(map[entity.ImageFormat][]byte)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	samples[entity.FormatPNG] = append([]byte(nil), buf.Bytes()...)
+
+	buf.Reset()
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	samples[entity.FormatJPEG] = append([]byte(nil), buf.Bytes()...)
+
+	buf.Reset()
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	samples[entity.FormatGIF] = append([]byte(nil), buf.Bytes()...)
+
+	return samples
+}
+
+func TestValidateImage_DetectsFormatFromContent(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	for want, data := range encodedSamples(t) {
+		got, err := p.ValidateImage(data)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", want, err)
+		}
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestValidateImage_RejectsTruncated(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	for format, data := range encodedSamples(t) {
+		for _, cut := range []int{len(data) / 2, len(data) - 2} {
+			_, err := p.ValidateImage(data[:cut])
+			if !errors.Is(err, entity.ErrInvalidImage) {
+				t.Fatalf("%s cut at %d of %d: expected ErrInvalidImage, got %v", format, cut, len(data), err)
+			}
+		}
+	}
+}
+
+func TestValidateImage_JPEGWithEmbeddedEOI(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+	data := encodedSamples(t)[entity.FormatJPEG]
+
+	// Сегмент APP1 с маркером EOI внутри, как у встроенной миниатюры EXIF
+	app1 := []byte{0xFF, 0xE1, 0x00, 0x06, 0xFF, 0xD9, 0x00, 0x00}
+	withApp1 := append(append(append([]byte(nil), data[:2]...), app1...), data[2:]...)
+
+	if _, err := p.ValidateImage(withApp1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.ValidateImage(withApp1[:len(withApp1)/2]); !errors.Is(err, entity.ErrInvalidImage) {
+		t.Fatalf("expected ErrInvalidImage, got %v", err)
+	}
+}
+
+func TestValidateImage_RejectsNonImagesAndUnsupported(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{SupportedFormats: []string{"png", "jpeg"}})
+
+	if _, err := p.ValidateImage([]byte("<html>not an image</html>")); !errors.Is(err, entity.ErrInvalidImage) {
+		t.Fatalf("expected ErrInvalidImage, got %v", err)
+	}
+	if _, err := p.ValidateImage(encodedSamples(t)[entity.FormatGIF]); !errors.Is(err, entity.ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
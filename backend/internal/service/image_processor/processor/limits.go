@@ -0,0 +1,272 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"io"
+)
+
+// workingCopyBytesPerPixel - операции imaging работают с копией в NRGBA
+const workingCopyBytesPerPixel = 4
+
+// imageLimits - лимиты исходного изображения, проверяемые по заголовку.
+// Заголовок читается без декодирования пикселей, поэтому проверка дешевая
+// даже для файла, объявляющего гигантские размеры
+type imageLimits struct {
+	maxWidth       int
+	maxHeight      int
+	maxPixels      int64
+	maxFrames      int
+	maxMemoryBytes int64
+}
+
+func newImageLimits(cfg config.ProcessingConfig) imageLimits {
+	return imageLimits{
+		maxWidth:       cfg.MaxSourceWidth,
+		maxHeight:      cfg.MaxSourceHeight,
+		maxPixels:      int64(cfg.MaxMegapixels * 1e6),
+		maxFrames:      cfg.MaxGIFFrames,
+		maxMemoryBytes: int64(cfg.MaxDecodeMemoryMB) << 20,
+	}
+}
+
+// imageHeader - сведения из заголовка, достаточные для оценки стоимости декодирования
+type imageHeader struct {
+	info          entity.ImageInfo
+	bytesPerPixel int
+}
+
+// readImageHeader читает размеры, формат и модель цвета из заголовка.
+// Достаточно начала файла, кадры GIF здесь не считаются
+func readImageHeader(data []byte) (*imageHeader, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty image data", entity.ErrInvalidImage)
+	}
+
+	cfg, formatStr, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read image header: %v", entity.ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("%w: invalid dimensions %dx%d", entity.ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	header := &imageHeader{
+		info: entity.ImageInfo{
+			Width:  cfg.Width,
+			Height: cfg.Height,
+			Format: formatFromName(formatStr),
+			Size:   int64(len(data)),
+			Frames: 1,
+		},
+		bytesPerPixel: bytesPerPixel(cfg.ColorModel),
+	}
+
+	return header, nil
+}
+
+// check сравнивает заголовок с лимитами. Оценка памяти учитывает декодированный
+// оригинал и рабочую копию, которую создают операции
+func (l imageLimits) check(header *imageHeader) error {
+	info := header.info
+	if info.Width > l.maxWidth {
+		return fmt.Errorf("%w: width %d exceeds limit %d", entity.ErrImageTooLarge, info.Width, l.maxWidth)
+	}
+	if info.Height > l.maxHeight {
+		return fmt.Errorf("%w: height %d exceeds limit %d", entity.ErrImageTooLarge, info.Height, l.maxHeight)
+	}
+
+	pixels := int64(info.Width) * int64(info.Height)
+	if pixels > l.maxPixels {
+		return fmt.Errorf("%w: %.1f megapixels exceeds limit %.1f",
+			entity.ErrImageTooLarge, float64(pixels)/1e6, float64(l.maxPixels)/1e6)
+	}
+
+	if info.Frames > l.maxFrames {
+		return fmt.Errorf("%w: %d frames exceeds limit %d", entity.ErrImageTooLarge, info.Frames, l.maxFrames)
+	}
+
+	memory := pixels * int64(header.bytesPerPixel+workingCopyBytesPerPixel)
+	if memory > l.maxMemoryBytes {
+		return fmt.Errorf("%w: estimated decoding memory %d MB exceeds budget %d MB",
+			entity.ErrImageTooLarge, memory>>20, l.maxMemoryBytes>>20)
+	}
+
+	return nil
+}
+
+// CheckImageLimits проверяет заголовок изображения по лимитам конфигурации
+// до полного декодирования. Возвращает entity.ErrInvalidImage, если заголовок
+// не читается, и entity.ErrImageTooLarge при превышении лимитов
+func (p *ImageProcessorImpl) CheckImageLimits(imageData []byte) (*entity.ImageInfo, error) {
+	header, err := readImageHeader(imageData)
+	if err != nil {
+		return nil, err
+	}
+	if header.info.Format == entity.FormatGIF {
+		frames, err := countGIFFrames(bufio.NewReader(bytes.NewReader(imageData)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrInvalidImage, err)
+		}
+		header.info.Frames = frames
+	}
+	if err := p.limits.check(header); err != nil {
+		return nil, err
+	}
+	return &header.info, nil
+}
+
+// InspectImageHeader определяет формат по началу файла и проверяет размеры
+// по лимитам конфигурации. Используется при потоковой загрузке, когда файл
+// целиком недоступен: полноту файла и количество кадров GIF проверяет
+// NewStreamValidator
+func (p *ImageProcessorImpl) InspectImageHeader(prefix []byte) (*entity.ImageInfo, error) {
+	header, err := readImageHeader(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if !p.cfg.IsSupportedFormat(header.info.Format) {
+		return nil, fmt.Errorf("%w: %s", entity.ErrUnsupportedFormat, header.info.Format)
+	}
+	if err := p.limits.check(header); err != nil {
+		return nil, err
+	}
+	return &header.info, nil
+}
+
+// bytesPerPixel оценивает размер пикселя декодированного изображения по модели цвета
+func bytesPerPixel(model color.Model) int {
+	// Палитра - срез, поэтому проверяется отдельно до сравнения моделей
+	if _, ok := model.(color.Palette); ok {
+		return 1
+	}
+
+	switch model {
+	case color.GrayModel, color.AlphaModel:
+		return 1
+	case color.Gray16Model, color.Alpha16Model:
+		return 2
+	case color.YCbCrModel:
+		return 3
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// formatFromName преобразует имя формата из пакета image в entity.ImageFormat
+func formatFromName(name string) entity.ImageFormat {
+	switch name {
+	case "jpeg":
+		return entity.FormatJPEG
+	case "png":
+		return entity.FormatPNG
+	case "gif":
+		return entity.FormatGIF
+	case "webp":
+		return entity.FormatWebP
+	default:
+		return entity.ImageFormat(name)
+	}
+}
+
+// countGIFFrames считает кадры GIF, пропуская блоки данных без распаковки LZW
+func countGIFFrames(r *bufio.Reader) (int, error) {
+	const (
+		headerSize           = 6
+		screenDescriptorSize = 7
+		imageDescriptorSize  = 9
+
+		blockExtension  = 0x21
+		blockImage      = 0x2C
+		blockTrailer    = 0x3B
+		flagColorTable  = 0x80
+		colorTableShift = 0x07
+	)
+
+	if _, err := r.Discard(headerSize); err != nil {
+		return 0, fmt.Errorf("gif: truncated header")
+	}
+
+	screen := make([]byte, screenDescriptorSize)
+	if _, err := io.ReadFull(r, screen); err != nil {
+		return 0, fmt.Errorf("gif: truncated screen descriptor")
+	}
+	if screen[4]&flagColorTable != 0 {
+		if err := discard(r, colorTableSize(screen[4]&colorTableShift)); err != nil {
+			return 0, fmt.Errorf("gif: truncated global color table")
+		}
+	}
+
+	frames := 0
+	for {
+		blockType, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			// Файлы без завершающего блока встречаются и читаются декодером
+			return frames, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch blockType {
+		case blockTrailer:
+			return frames, nil
+		case blockExtension:
+			if _, err := r.ReadByte(); err != nil {
+				return 0, fmt.Errorf("gif: truncated extension")
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return 0, err
+			}
+		case blockImage:
+			descriptor := make([]byte, imageDescriptorSize)
+			if _, err := io.ReadFull(r, descriptor); err != nil {
+				return 0, fmt.Errorf("gif: truncated image descriptor")
+			}
+			if descriptor[8]&flagColorTable != 0 {
+				if err := discard(r, colorTableSize(descriptor[8]&colorTableShift)); err != nil {
+					return 0, fmt.Errorf("gif: truncated local color table")
+				}
+			}
+			// Минимальный размер кода LZW
+			if _, err := r.ReadByte(); err != nil {
+				return 0, fmt.Errorf("gif: truncated image data")
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return 0, err
+			}
+			frames++
+		default:
+			return 0, fmt.Errorf("gif: unknown block type 0x%02x", blockType)
+		}
+	}
+}
+
+// colorTableSize возвращает размер таблицы цветов в байтах по полю флагов
+func colorTableSize(sizeBits byte) int64 {
+	return 3 * (1 << (sizeBits + 1))
+}
+
+// skipSubBlocks пропускает последовательность подблоков до нулевого терминатора
+func skipSubBlocks(r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("gif: truncated data sub-block")
+		}
+		if size == 0 {
+			return nil
+		}
+		if err := discard(r, int64(size)); err != nil {
+			return fmt.Errorf("gif: truncated data sub-block")
+		}
+	}
+}
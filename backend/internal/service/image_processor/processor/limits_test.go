@@ -0,0 +1,251 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// craftedPNG возвращает PNG, заголовок которого объявляет заданные размеры.
+// Пиксельных данных нет: структура файла полная, но полное декодирование
+// невозможно, для проверки лимитов достаточно заголовка
+func craftedPNG(width, height uint32, colorType byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	ihdr[8] = 8 // глубина цвета
+	ihdr[9] = colorType
+
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func writePNGChunk(buf *bytes.Buffer, chunkType string, data []byte) {
+	chunk := append([]byte(chunkType), data...)
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(chunk)
+	_ = binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+}
+
+// craftedJPEG возвращает начало JPEG с маркером SOF0 и заданными размерами.
+// Маркер SOS завершает чтение заголовка, данные скана отсутствуют
+func craftedJPEG(width, height uint16) []byte {
+	return []byte{
+		0xFF, 0xD8, // SOI
+		0xFF, 0xC0, // SOF0
+		0x00, 0x0B, // длина сегмента
+		0x08, // точность
+		byte(height >> 8), byte(height),
+		byte(width >> 8), byte(width),
+		0x01,             // один компонент
+		0x01, 0x11, 0x00, // id, субдискретизация, таблица квантования
+		0xFF, 0xDA, 0x00, 0x08, // SOS и длина сегмента
+	}
+}
+
+// craftedGIF возвращает GIF с заданными логическими размерами и количеством кадров 1x1
+func craftedGIF(width, height uint16, frames int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	_ = binary.Write(&buf, binary.LittleEndian, width)
+	_ = binary.Write(&buf, binary.LittleEndian, height)
+	// Глобальная палитра из двух цветов
+	buf.Write([]byte{0x80, 0x00, 0x00})
+	buf.Write([]byte{0, 0, 0, 255, 255, 255})
+
+	for i := 0; i < frames; i++ {
+		// Graphic Control Extension
+		buf.Write([]byte{0x21, 0xF9, 0x04, 0x00, 0x0A, 0x00, 0x00, 0x00})
+		// Дескриптор кадра 1x1 в точке 0,0 без локальной палитры
+		buf.Write([]byte{0x2C, 0, 0, 0, 0, 1, 0, 1, 0, 0x00})
+		// LZW: минимальный размер кода и один подблок данных
+		buf.Write([]byte{0x02, 0x02, 0x44, 0x01, 0x00})
+	}
+	buf.WriteByte(0x3B)
+	return buf.Bytes()
+}
+
+func newLimitedProcessor(cfg config.ProcessingConfig) *ImageProcessorImpl {
+	return NewImageProcessor(zap.NewNop(), cfg, nil)
+}
+
+func TestCheckImageLimits(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{
+		MaxSourceWidth:    20000,
+		MaxSourceHeight:   20000,
+		MaxMegapixels:     50,
+		MaxGIFFrames:      10,
+		MaxDecodeMemoryMB: 256,
+	})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "png within limits", data: craftedPNG(1920, 1080, 6)},
+		{name: "jpeg within limits", data: craftedJPEG(4000, 3000)},
+		{name: "gif within limits", data: craftedGIF(100, 100, 3)},
+		{name: "png bomb width", data: craftedPNG(60000, 10, 6), wantErr: entity.ErrImageTooLarge},
+		{name: "png bomb height", data: craftedPNG(10, 60000, 6), wantErr: entity.ErrImageTooLarge},
+		{name: "jpeg megapixels", data: craftedJPEG(10000, 10000), wantErr: entity.ErrImageTooLarge},
+		{name: "png memory budget", data: craftedPNG(7000, 7000, 6), wantErr: entity.ErrImageTooLarge},
+		{name: "gif frame count", data: craftedGIF(10, 10, 11), wantErr: entity.ErrImageTooLarge},
+		{name: "truncated header", data: craftedPNG(100, 100, 6)[:20], wantErr: entity.ErrInvalidImage},
+		{name: "truncated gif frames", data: craftedGIF(10, 10, 2)[:40], wantErr: entity.ErrInvalidImage},
+		{name: "not an image", data: []byte("definitely not an image"), wantErr: entity.ErrInvalidImage},
+		{name: "empty", data: nil, wantErr: entity.ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := p.CheckImageLimits(tt.data)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if info.Width == 0 || info.Height == 0 {
+					t.Fatalf("dimensions are not reported: %+v", info)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckImageLimits_GIFFrames(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	info, err := p.CheckImageLimits(craftedGIF(10, 10, 7))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Frames != 7 {
+		t.Fatalf("expected 7 frames, got %d", info.Frames)
+	}
+}
+
+func TestBytesPerPixel(t *testing.T) {
+	tests := []struct {
+		model color.Model
+		want  int
+	}{
+		{color.GrayModel, 1},
+		{color.Palette{color.Black, color.White}, 1},
+		{color.Gray16Model, 2},
+		{color.YCbCrModel, 3},
+		{color.NRGBAModel, 4},
+		{color.RGBA64Model, 8},
+	}
+	for _, tt := range tests {
+		if got := bytesPerPixel(tt.model); got != tt.want {
+			t.Errorf("bytesPerPixel(%T) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestProcessImage_RejectsBombBeforeDecode(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	// Без проверки лимитов декодер попытался бы выделить память под 3.6 гигапикселя
+	_, err := p.ProcessImage(context.Background(), craftedPNG(60000, 60000, 6), []entity.OperationParams{
+		{Type: entity.OpGrayscale, Parameters: map[string]interface{}{}},
+	})
+	if !errors.Is(err, entity.ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+}
+
+func TestProcessImage_AcceptsImageWithinLimits(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := p.ProcessImage(context.Background(), buf.Bytes(), []entity.OperationParams{
+		{Type: entity.OpGrayscale, Parameters: map[string]interface{}{}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+}
+
+func TestProcessImageWithProgress_ReportsEachOperation(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported []string
+	_, err := p.ProcessImageWithProgress(context.Background(), buf.Bytes(), []entity.OperationParams{
+		{Type: entity.OpGrayscale, Parameters: map[string]interface{}{}},
+		{Type: entity.OpFlip, Name: "mirror", Parameters: map[string]interface{}{entity.ParamMode: string(entity.FlipHorizontal)}},
+	}, func(completed, total int, operation entity.OperationType, variant string) {
+		if total != 2 {
+			t.Errorf("total = %d, want 2", total)
+		}
+		reported = append(reported, fmt.Sprintf("%d:%s:%s", completed, operation, variant))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"1:grayscale:grayscale", "2:flip:mirror"}
+	if strings.Join(reported, ",") != strings.Join(want, ",") {
+		t.Fatalf("reported %v, want %v", reported, want)
+	}
+}
+
+func TestProcessImageWithProgress_StopsWhenContextCancelled(t *testing.T) {
+	p := newLimitedProcessor(config.ProcessingConfig{})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Отмена после первой операции: вторая операция не должна выполняться
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reported := 0
+	_, err := p.ProcessImageWithProgress(ctx, buf.Bytes(), []entity.OperationParams{
+		{Type: entity.OpGrayscale, Parameters: map[string]interface{}{}},
+		{Type: entity.OpFlip, Parameters: map[string]interface{}{entity.ParamMode: string(entity.FlipHorizontal)}},
+	}, func(completed, total int, operation entity.OperationType, variant string) {
+		reported++
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if reported != 1 {
+		t.Fatalf("expected one completed operation, got %d", reported)
+	}
+}
@@ -1,12 +1,18 @@
 package imageservice
 
 import (
-	"bytes"
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"imageprocessor/backend/internal/broker"
 	"imageprocessor/backend/internal/domain/entity"
 	"imageprocessor/backend/internal/repository/cloud"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,177 +20,318 @@ import (
 )
 
 type ImageService struct {
-	imageRepo             ImageRepositoryInterface
-	cloudStorage          cloud.CloudStorageInterface
-	producerMessageBroker broker.ProducerMessageBrokerInterface
-	logger                *zap.Logger
-	bucket                string
+	imageRepo      ImageRepositoryInterface
+	cloudStorage   cloud.CloudStorageInterface
+	taskRelay      TaskRelayInterface
+	imageValidator ImageValidatorInterface
+	logger         *zap.Logger
+	bucket         string
+	uploadConfig   UploadConfig
+}
+
+// UploadConfig - параметры приема загружаемых файлов
+type UploadConfig struct {
+	// MaxSize - максимальный размер оригинала в байтах
+	MaxSize int64
+	// AllowContentMismatch разрешает загрузку, если заявленный тип не совпадает с содержимым
+	AllowContentMismatch bool
 }
 
 func NewImageService(
 	imageRepo ImageRepositoryInterface,
 	cloudStorage cloud.CloudStorageInterface,
-	producerMessageBroker broker.ProducerMessageBrokerInterface,
+	taskRelay TaskRelayInterface,
+	imageValidator ImageValidatorInterface,
 	logger *zap.Logger,
 	bucket string,
+	uploadConfig UploadConfig,
 ) *ImageService {
+	if uploadConfig.MaxSize <= 0 {
+		uploadConfig.MaxSize = entity.DefaultMaxUploadSize
+	}
 	return &ImageService{
-		imageRepo:             imageRepo,
-		cloudStorage:          cloudStorage,
-		producerMessageBroker: producerMessageBroker,
-		logger:                logger,
-		bucket:                bucket,
+		imageRepo:      imageRepo,
+		cloudStorage:   cloudStorage,
+		taskRelay:      taskRelay,
+		imageValidator: imageValidator,
+		logger:         logger,
+		bucket:         bucket,
+		uploadConfig:   uploadConfig,
 	}
 }
 
-// UploadImage загружает изображение, сохраняет в S3 и БД, публикует задачу в Kafka
-func (s *ImageService) UploadImage(ctx context.Context, imageData []byte, filename string, mimeType string, operations []entity.OperationParams) (*entity.Image, error) {
-	s.logger.Info("Uploading image",
-		zap.String("filename", filename),
-		zap.Int("size", len(imageData)),
-		zap.Int("operationsCount", len(operations)),
+// StoreOriginal потоково загружает оригинал в S3, не удерживая файл в памяти.
+// Формат и размеры определяются по началу потока до записи в хранилище,
+// размер, контрольная сумма и полнота файла - по мере передачи. Если проверка
+// не пройдена, загрузка прерывается и объект не сохраняется.
+// Запись в БД не создается: для этого вызывается CompleteUpload
+func (s *ImageService) StoreOriginal(ctx context.Context, data io.Reader, filename string, mimeType string) (*entity.Image, error) {
+	s.logger.Info("Storing original image", zap.String("filename", filename))
+
+	// Определяем формат по содержимому: заголовкам клиента не доверяем
+	source := bufio.NewReaderSize(data, headerPeekSize)
+	prefix, err := source.Peek(headerPeekSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	// Отклоняем неподдерживаемые форматы и изображения, декодирование которых превысит лимиты
+	info, err := s.imageValidator.InspectImageHeader(prefix)
+	if err != nil {
+		s.logger.Warn("Image rejected by header validation", zap.Error(err), zap.String("filename", filename))
+		return nil, err
+	}
+	format := info.Format
+	s.logger.Debug("Image header checked",
+		zap.String("format", string(format)),
+		zap.Int("width", info.Width),
+		zap.Int("height", info.Height),
 	)
 
+	if mismatch := contentMismatch(filename, mimeType, format); mismatch != "" {
+		if !s.uploadConfig.AllowContentMismatch {
+			s.logger.Warn("Declared type does not match image content",
+				zap.String("filename", filename),
+				zap.String("mimeType", mimeType),
+				zap.String("detectedFormat", string(format)),
+			)
+			return nil, fmt.Errorf("%w: %s", entity.ErrContentMismatch, mismatch)
+		}
+		s.logger.Info("Declared type ignored, using detected format",
+			zap.String("filename", filename),
+			zap.String("reason", mismatch),
+		)
+	}
+	mimeType = format.MimeType()
+
 	// Генерируем уникальный ID
 	imageID := uuid.New().String()
 
 	// Определяем путь для оригинала в S3
 	originalPath := fmt.Sprintf("originals/%s/%s", imageID, filename)
 
-	// Загружаем оригинал в S3
-	err := s.cloudStorage.UploadFile(ctx, originalPath, bytes.NewReader(imageData), int64(len(imageData)), mimeType)
+	// Загружаем оригинал в S3, проверяя структуру файла по мере передачи
+	validator := s.imageValidator.NewStreamValidator(format)
+	upload := newUploadReader(source, validator, s.uploadConfig.MaxSize)
+	defer func() {
+		_ = validator.Close()
+	}()
+
+	err = s.cloudStorage.UploadFile(ctx, originalPath, upload, -1, mimeType)
 	if err != nil {
+		// Ошибка проверки потока точнее ошибки хранилища, которое ее обернуло
+		if streamErr := upload.Err(); streamErr != nil {
+			s.logger.Warn("Image rejected during upload", zap.Error(streamErr), zap.String("imageId", imageID))
+			return nil, streamErr
+		}
 		s.logger.Error("Failed to upload to S3", zap.Error(err), zap.String("imageId", imageID))
 		return nil, fmt.Errorf("failed to upload to S3: %w", err)
 	}
 
-	s.logger.Info("Image uploaded to S3", zap.String("imageId", imageID), zap.String("path", originalPath))
+	// Хранилище могло не дочитать поток - тогда файл считается обрезанным
+	if err := validator.Close(); err != nil {
+		s.logger.Warn("Stored image failed validation", zap.Error(err), zap.String("imageId", imageID))
+		_ = s.cloudStorage.DeleteFile(ctx, originalPath)
+		return nil, err
+	}
+
+	s.logger.Info("Image uploaded to S3",
+		zap.String("imageId", imageID),
+		zap.String("path", originalPath),
+		zap.Int64("size", upload.Size()),
+	)
 
-	// Создаем запись в БД
-	image := &entity.Image{
+	return &entity.Image{
 		ID:               imageID,
 		OriginalFilename: filename,
-		OriginalSize:     int64(len(imageData)),
+		OriginalSize:     upload.Size(),
 		MimeType:         mimeType,
+		Format:           format,
+		Checksum:         upload.Checksum(),
 		Status:           entity.StatusUploaded,
 		OriginalPath:     originalPath,
 		Bucket:           s.bucket,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
+	}, nil
+}
+
+// CompleteUpload сохраняет запись об оригинале, загруженном StoreOriginal.
+// Если есть операции, в той же транзакции создаются задача на обработку
+// и запись outbox, из которой relay публикует задачу в брокер. preset
+// указывается, если операции взяты из пресета, и сохраняется в задаче
+func (s *ImageService) CompleteUpload(ctx context.Context, image *entity.Image, operations []entity.OperationParams, preset *entity.Preset) (*entity.Image, error) {
+	imageID := image.ID
+	s.logger.Info("Completing image upload",
+		zap.String("imageId", imageID),
+		zap.Int("operationsCount", len(operations)),
+	)
+
+	var task *entity.ProcessingTask
+	if len(operations) > 0 {
+		// Создаем задачу на обработку
+		task = s.newProcessingTask(image, operations, preset)
 	}
 
-	err = s.imageRepo.CreateImage(ctx, image)
+	var err error
+	if task != nil {
+		err = s.imageRepo.CreateImageWithTask(ctx, image, task)
+	} else {
+		err = s.imageRepo.CreateImage(ctx, image)
+	}
 	if err != nil {
 		s.logger.Error("Failed to create image in DB", zap.Error(err), zap.String("imageId", imageID))
 		// Пытаемся откатить загрузку в S3
-		_ = s.cloudStorage.DeleteFile(ctx, originalPath)
+		_ = s.cloudStorage.DeleteFile(ctx, image.OriginalPath)
 		return nil, fmt.Errorf("failed to create image in DB: %w", err)
 	}
 
 	s.logger.Info("Image record created in DB", zap.String("imageId", imageID))
 
-	// Если есть операции, создаем задачу на обработку
-	if len(operations) > 0 {
-		// Определяем формат изображения
-		format := s.detectFormat(filename, mimeType)
+	if task != nil {
+		// Задача уже сохранена в outbox; relay опубликует ее, даже если брокер сейчас недоступен
+		s.taskRelay.Notify()
+		s.logger.Info("Processing task queued in outbox", zap.String("taskId", task.ID), zap.String("imageId", imageID))
+	}
 
-		// Создаем задачу на обработку
-		task := &entity.ProcessingTask{
-			ID:           uuid.New().String(),
-			ImageID:      imageID,
-			OriginalPath: originalPath,
-			Bucket:       s.bucket,
-			Operations:   operations,
-			Format:       format,
-		}
+	return image, nil
+}
 
-		// Создаем запись о задаче в БД
-		err = s.imageRepo.CreateProcessingJob(ctx, task)
-		if err != nil {
-			s.logger.Error("Failed to create processing job", zap.Error(err), zap.String("imageId", imageID))
-			return image, fmt.Errorf("failed to create processing job: %w", err)
-		}
+// ReprocessImage создает новую задачу для уже загруженного изображения:
+// оригинал обрабатывается заново с другими операциями. С supersede варианты
+// предыдущих задач удаляются после успешного выполнения новой, иначе остаются
+// доступны, а по имени варианта выдается самый новый
+func (s *ImageService) ReprocessImage(ctx context.Context, imageID string, operations []entity.OperationParams, preset *entity.Preset, supersede bool) (*entity.ProcessingTask, error) {
+	image, err := s.imageRepo.GetImageByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
 
-		// Публикуем задачу в Kafka
-		err = s.producerMessageBroker.PublishProcessingTask(ctx, task)
-		if err != nil {
-			s.logger.Error("Failed to publish task to Kafka", zap.Error(err), zap.String("imageId", imageID))
-			// Не возвращаем ошибку, так как изображение уже загружено
-			// Можно добавить механизм повторной отправки
-		} else {
-			// Обновляем статус на "processing"
-			_ = s.imageRepo.UpdateImageStatus(ctx, imageID, entity.StatusProcessing)
-			s.logger.Info("Processing task published to Kafka", zap.String("taskId", task.ID), zap.String("imageId", imageID))
-		}
+	task := s.newProcessingTask(image, operations, preset)
+	task.Supersede = supersede
+
+	if err := s.imageRepo.CreateReprocessTask(ctx, task); err != nil {
+		return nil, err
 	}
 
-	return image, nil
+	s.taskRelay.Notify()
+	s.logger.Info("Reprocessing task queued in outbox",
+		zap.String("taskId", task.ID),
+		zap.String("imageId", imageID),
+		zap.Int("operationsCount", len(operations)),
+		zap.Bool("supersede", supersede),
+	)
+
+	return task, nil
 }
 
-// GetImage получает изображение по ID и операции
-func (s *ImageService) GetImage(ctx context.Context, imageID string, operation entity.OperationType) ([]byte, string, error) {
-	s.logger.Debug("Getting image", zap.String("imageId", imageID), zap.String("operation", string(operation)))
+// newProcessingTask создает задачу на обработку оригинала изображения;
+// preset указывается, если операции взяты из пресета
+func (s *ImageService) newProcessingTask(image *entity.Image, operations []entity.OperationParams, preset *entity.Preset) *entity.ProcessingTask {
+	task := &entity.ProcessingTask{
+		ID:           uuid.New().String(),
+		ImageID:      image.ID,
+		OriginalPath: image.OriginalPath,
+		Bucket:       s.bucket,
+		Operations:   operations,
+		Format:       image.Format,
+	}
+	if preset != nil {
+		task.PresetName = preset.Name
+		task.PresetVersion = preset.Version
+	}
+	return task
+}
+
+// DiscardOriginal удаляет оригинал, загруженный StoreOriginal, если загрузка
+// не была завершена, например из-за ошибки в параметрах запроса
+func (s *ImageService) DiscardOriginal(ctx context.Context, image *entity.Image) error {
+	if err := s.cloudStorage.DeleteFile(ctx, image.OriginalPath); err != nil {
+		s.logger.Error("Failed to discard original", zap.Error(err), zap.String("imageId", image.ID))
+		return fmt.Errorf("failed to discard original: %w", err)
+	}
+	s.logger.Info("Original discarded", zap.String("imageId", image.ID))
+	return nil
+}
+
+// ImageObject - сохраненная версия изображения: исходный файл или вариант
+type ImageObject struct {
+	Path     string
+	MimeType string
+	// Original - исходный файл. Он не перезаписывается, а варианты повторная
+	// обработка заменяет новыми объектами под тем же именем варианта
+	Original bool
+}
+
+// ETag возвращает тег версии объекта. Каждый сохраненный вариант получает
+// новый путь, поэтому тег меняется вместе с содержимым
+func (o *ImageObject) ETag() string {
+	sum := sha256.Sum256([]byte(o.Path))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ResolveImage находит версию изображения по операции или имени варианта,
+// не загружая ее. Имя варианта имеет приоритет над типом операции
+func (s *ImageService) ResolveImage(ctx context.Context, imageID string, operation entity.OperationType, variant string) (*ImageObject, error) {
+	s.logger.Debug("Resolving image",
+		zap.String("imageId", imageID),
+		zap.String("operation", string(operation)),
+		zap.String("variant", variant),
+	)
 
 	// Получаем метаданные из БД
 	image, err := s.imageRepo.GetImageByID(ctx, imageID)
 	if err != nil {
 		s.logger.Error("Image not found", zap.Error(err), zap.String("imageId", imageID))
-		return nil, "", fmt.Errorf("image not found: %w", err)
+		return nil, fmt.Errorf("image not found: %w", err)
 	}
 
-	var objectPath string
-
-	// Если запрашивают оригинал
-	if operation == "" || operation == "original" {
-		objectPath = image.OriginalPath
-	} else {
-		// Получаем обработанное изображение
-		processed, err := s.imageRepo.GetProcessedImageByOperation(ctx, imageID, operation)
-		if err != nil {
-			s.logger.Error("Processed image not found",
-				zap.Error(err),
-				zap.String("imageId", imageID),
-				zap.String("operation", string(operation)),
-			)
-			return nil, "", fmt.Errorf("processed image not found: %w", err)
-		}
-		objectPath = processed.Path
+	objectPath, mimeType, err := s.resolveObject(ctx, image, operation, variant)
+	if err != nil {
+		s.logger.Error("Processed image not found",
+			zap.Error(err),
+			zap.String("imageId", imageID),
+			zap.String("operation", string(operation)),
+			zap.String("variant", variant),
+		)
+		return nil, err
 	}
 
-	// Загружаем файл из S3
-	data, err := s.cloudStorage.DownloadFile(ctx, objectPath)
+	return &ImageObject{
+		Path:     objectPath,
+		MimeType: mimeType,
+		Original: objectPath == image.OriginalPath,
+	}, nil
+}
+
+// DownloadImage загружает найденную версию изображения из хранилища
+func (s *ImageService) DownloadImage(ctx context.Context, object *ImageObject) ([]byte, error) {
+	data, err := s.cloudStorage.DownloadFile(ctx, object.Path)
 	if err != nil {
-		s.logger.Error("Failed to download from S3", zap.Error(err), zap.String("path", objectPath))
-		return nil, "", fmt.Errorf("failed to download from S3: %w", err)
+		s.logger.Error("Failed to download from S3", zap.Error(err), zap.String("path", object.Path))
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
 	}
 
-	s.logger.Info("Image downloaded successfully", zap.String("imageId", imageID), zap.Int("size", len(data)))
+	s.logger.Info("Image downloaded successfully", zap.String("path", object.Path), zap.Int("size", len(data)))
 
-	return data, image.MimeType, nil
+	return data, nil
 }
 
 // GetImagePresignedURL генерирует временную ссылку на изображение
-func (s *ImageService) GetImagePresignedURL(ctx context.Context, imageID string, operation entity.OperationType, expiry time.Duration) (string, error) {
+func (s *ImageService) GetImagePresignedURL(ctx context.Context, imageID string, operation entity.OperationType, variant string, expiry time.Duration) (string, error) {
 	// Получаем метаданные из БД
 	image, err := s.imageRepo.GetImageByID(ctx, imageID)
 	if err != nil {
 		return "", fmt.Errorf("image not found: %w", err)
 	}
 
-	var objectPath string
-
-	if operation == "" || operation == "original" {
-		objectPath = image.OriginalPath
-	} else {
-		processed, err := s.imageRepo.GetProcessedImageByOperation(ctx, imageID, operation)
-		if err != nil {
-			return "", fmt.Errorf("processed image not found: %w", err)
-		}
-		objectPath = processed.Path
+	objectPath, mimeType, err := s.resolveObject(ctx, image, operation, variant)
+	if err != nil {
+		return "", err
 	}
 
-	// Генерируем presigned URL
-	url, err := s.cloudStorage.GetPresignedURL(ctx, objectPath, expiry)
+	// Генерируем presigned URL с сохраненным MIME типом
+	url, err := s.cloudStorage.GetPresignedURL(ctx, objectPath, expiry, mimeType)
 	if err != nil {
 		s.logger.Error("Failed to generate presigned URL", zap.Error(err))
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
@@ -193,6 +340,29 @@ func (s *ImageService) GetImagePresignedURL(ctx context.Context, imageID string,
 	return url, nil
 }
 
+// resolveObject определяет путь и MIME тип запрошенной версии изображения
+func (s *ImageService) resolveObject(ctx context.Context, image *entity.Image, operation entity.OperationType, variant string) (string, string, error) {
+	var (
+		processed *entity.ProcessedImage
+		err       error
+	)
+
+	switch {
+	case variant != "" && variant != entity.OriginalVariant:
+		processed, err = s.imageRepo.GetProcessedImageByVariant(ctx, image.ID, variant)
+	case variant == "" && operation != "" && operation != entity.OriginalVariant:
+		processed, err = s.imageRepo.GetProcessedImageByOperation(ctx, image.ID, operation)
+	default:
+		return image.OriginalPath, image.MimeType, nil
+	}
+
+	if err != nil {
+		return "", "", fmt.Errorf("processed image not found: %w", err)
+	}
+
+	return processed.Path, processedMimeType(processed), nil
+}
+
 // DeleteImage удаляет изображение и все его версии
 func (s *ImageService) DeleteImage(ctx context.Context, imageID string) error {
 	s.logger.Info("Deleting image", zap.String("imageId", imageID))
@@ -215,6 +385,13 @@ func (s *ImageService) DeleteImage(ctx context.Context, imageID string) error {
 		pathsToDelete = append(pathsToDelete, processed.Path)
 	}
 
+	// Результаты трансформаций по URL кэшируются под отдельным префиксом
+	transformed, err := s.cloudStorage.ListFiles(ctx, fmt.Sprintf("transformed/%s/", imageID))
+	if err != nil {
+		s.logger.Warn("Failed to list transformed images", zap.Error(err))
+	}
+	pathsToDelete = append(pathsToDelete, transformed...)
+
 	// Удаляем файлы из S3
 	if err := s.cloudStorage.DeleteFiles(ctx, pathsToDelete); err != nil {
 		s.logger.Error("Failed to delete files from S3", zap.Error(err))
@@ -232,7 +409,8 @@ func (s *ImageService) DeleteImage(ctx context.Context, imageID string) error {
 	return nil
 }
 
-// GetImageStatus получает статус обработки изображения
+// GetImageStatus получает статус обработки изображения. Ход обработки
+// считается по последней задаче изображения
 func (s *ImageService) GetImageStatus(ctx context.Context, imageID string) (*ImageStatus, error) {
 	// Получаем изображение
 	image, err := s.imageRepo.GetImageByID(ctx, imageID)
@@ -263,7 +441,17 @@ func (s *ImageService) GetImageStatus(ctx context.Context, imageID string) (*Ima
 	}
 
 	if job != nil {
+		status.JobID = job.ID
+		status.JobStatus = job.Status
 		status.TotalOperations = len(job.Operations)
+
+		// Варианты предыдущих задач не входят в ход обработки последней
+		status.ProcessedOperations = 0
+		for _, processed := range processedImages {
+			if processed.JobID == job.ID {
+				status.ProcessedOperations++
+			}
+		}
 	}
 
 	// Вычисляем прогресс
@@ -274,27 +462,82 @@ func (s *ImageService) GetImageStatus(ctx context.Context, imageID string) (*Ima
 	return status, nil
 }
 
+// CancelImageProcessing отменяет ожидающие и выполняющиеся задачи изображения.
+// Воркер, выполняющий задачу, прерывает ее и удаляет сохраненные варианты
+func (s *ImageService) CancelImageProcessing(ctx context.Context, imageID string) ([]entity.ProcessingJob, error) {
+	jobs, err := s.imageRepo.CancelImageProcessingJobs(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		s.logger.Info("Processing job cancelled",
+			zap.String("jobId", job.ID),
+			zap.String("imageId", imageID),
+		)
+	}
+	return jobs, nil
+}
+
+// CancelJob отменяет задачу по ID
+func (s *ImageService) CancelJob(ctx context.Context, jobID string) (*entity.ProcessingJob, error) {
+	job, err := s.imageRepo.CancelProcessingJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Processing job cancelled",
+		zap.String("jobId", job.ID),
+		zap.String("imageId", job.ImageID),
+	)
+	return job, nil
+}
+
 // ListImages возвращает список изображений
 func (s *ImageService) ListImages(ctx context.Context, limit, offset int) ([]entity.Image, error) {
 	return s.imageRepo.ListImages(ctx, limit, offset)
 }
 
-// detectFormat определяет формат изображения по имени файла или MIME типу
-func (s *ImageService) detectFormat(filename, mimeType string) entity.ImageFormat {
-	// Пытаемся определить по MIME типу
-	switch mimeType {
-	case "image/jpeg":
-		return entity.FormatJPEG
-	case "image/png":
-		return entity.FormatPNG
-	case "image/gif":
-		return entity.FormatGIF
-	case "image/webp":
-		return entity.FormatWebP
-	}
-
-	// По умолчанию JPEG
-	return entity.FormatJPEG
+// contentMismatch сравнивает MIME тип из запроса и расширение файла с форматом,
+// определенным по содержимому. Пустой и общий MIME тип, а также расширения,
+// не относящиеся к изображениям, не проверяются. Возвращает описание расхождения
+func contentMismatch(filename, mimeType string, detected entity.ImageFormat) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mediaType = strings.ToLower(mediaType)
+		if mediaType == "image/jpg" {
+			mediaType = "image/jpeg"
+		}
+		if mediaType != "application/octet-stream" && mediaType != detected.MimeType() {
+			return fmt.Sprintf("content type %s, detected %s", mediaType, detected)
+		}
+	}
+
+	var declared entity.ImageFormat
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		declared = entity.FormatJPEG
+	case ".png":
+		declared = entity.FormatPNG
+	case ".gif":
+		declared = entity.FormatGIF
+	case ".webp":
+		declared = entity.FormatWebP
+	default:
+		return ""
+	}
+	if declared != detected {
+		return fmt.Sprintf("file extension %s, detected %s", filepath.Ext(filename), detected)
+	}
+
+	return ""
+}
+
+// processedMimeType возвращает сохраненный MIME тип обработанной версии
+func processedMimeType(processed *entity.ProcessedImage) string {
+	if processed.MimeType != "" {
+		return processed.MimeType
+	}
+	return processed.Format.MimeType()
 }
 
 // ImageStatus представляет статус обработки изображения
@@ -305,6 +548,8 @@ type ImageStatus struct {
 	ProcessedOperations int                `json:"processed_operations"`
 	TotalOperations     int                `json:"total_operations"`
 	Progress            int                `json:"progress"`
+	JobID               string             `json:"job_id,omitempty"`
+	JobStatus           string             `json:"job_status,omitempty"`
 	CreatedAt           time.Time          `json:"created_at"`
 	UpdatedAt           time.Time          `json:"updated_at"`
 }
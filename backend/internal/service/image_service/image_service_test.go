@@ -0,0 +1,451 @@
+package imageservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/repository/cloud"
+	"imageprocessor/backend/internal/service/image_processor/processor"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeStorage читает поток, как хранилище при загрузке, и сохраняет объект,
+// только если поток прочитан без ошибок; остальные методы в тесте не вызываются
+type fakeStorage struct {
+	cloud.CloudStorageInterface
+	attempts int
+	objects  map[string][]byte
+}
+
+func (s *fakeStorage) UploadFile(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	s.attempts++
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+	s.objects[path] = data
+	return nil
+}
+
+func (s *fakeStorage) DeleteFile(ctx context.Context, path string) error {
+	delete(s.objects, path)
+	return nil
+}
+
+type fakeImageRepo struct {
+	ImageRepositoryInterface
+	created int
+	last    *entity.Image
+	tasks   []*entity.ProcessingTask
+
+	// activeJob имитирует ожидающую задачу изображения
+	activeJob bool
+	job       *entity.ProcessingJob
+	processed []entity.ProcessedImage
+}
+
+func (r *fakeImageRepo) CreateImage(ctx context.Context, image *entity.Image) error {
+	r.created++
+	r.last = image
+	return nil
+}
+
+func (r *fakeImageRepo) CreateImageWithTask(ctx context.Context, image *entity.Image, task *entity.ProcessingTask) error {
+	r.tasks = append(r.tasks, task)
+	return r.CreateImage(ctx, image)
+}
+
+func (r *fakeImageRepo) GetImageByID(ctx context.Context, imageID string) (*entity.Image, error) {
+	if r.last == nil || r.last.ID != imageID {
+		return nil, entity.ErrImageNotFound
+	}
+	return r.last, nil
+}
+
+func (r *fakeImageRepo) CreateReprocessTask(ctx context.Context, task *entity.ProcessingTask) error {
+	if r.activeJob {
+		return entity.ErrJobInProgress
+	}
+	r.tasks = append(r.tasks, task)
+	r.activeJob = true
+	return nil
+}
+
+func (r *fakeImageRepo) GetProcessedImagesByImageID(ctx context.Context, imageID string) ([]entity.ProcessedImage, error) {
+	return r.processed, nil
+}
+
+func (r *fakeImageRepo) GetProcessedImageByVariant(ctx context.Context, imageID, variant string) (*entity.ProcessedImage, error) {
+	for i := len(r.processed) - 1; i >= 0; i-- {
+		if r.processed[i].VariantName == variant {
+			return &r.processed[i], nil
+		}
+	}
+	return nil, entity.ErrImageNotFound
+}
+
+func (r *fakeImageRepo) GetProcessingJobByImageID(ctx context.Context, imageID string) (*entity.ProcessingJob, error) {
+	if r.job == nil {
+		return nil, entity.ErrJobNotFound
+	}
+	return r.job, nil
+}
+
+type fakeRelay struct {
+	notified int
+}
+
+func (r *fakeRelay) Notify() {
+	r.notified++
+}
+
+// bombPNG возвращает PNG-заголовок, объявляющий 60000x60000 пикселей
+func bombPNG() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 60000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 60000)
+	ihdr[8] = 8
+	ihdr[9] = 6
+
+	for _, chunk := range []struct {
+		chunkType string
+		data      []byte
+	}{{"IHDR", ihdr}, {"IEND", nil}} {
+		raw := append([]byte(chunk.chunkType), chunk.data...)
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(chunk.data)))
+		buf.Write(raw)
+		_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(raw))
+	}
+	return buf.Bytes()
+}
+
+func newTestImageService(storage *fakeStorage, repo *fakeImageRepo, uploadConfig UploadConfig) *ImageService {
+	validator := processor.NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+	return NewImageService(repo, storage, &fakeRelay{}, validator, zap.NewNop(), "images", uploadConfig)
+}
+
+// upload выполняет обе фазы загрузки, как хэндлер
+func upload(service *ImageService, data []byte, filename, mimeType string) (*entity.Image, error) {
+	image, err := service.StoreOriginal(context.Background(), bytes.NewReader(data), filename, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	return service.CompleteUpload(context.Background(), image, nil, nil)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func smallPNG(t *testing.T) []byte {
+	return encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 16, 16)))
+}
+
+// noisePNG возвращает PNG больше буфера заголовка, чтобы поток шел дальше подсмотренного начала
+func noisePNG(t *testing.T) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	rng := rand.New(rand.NewSource(1))
+	rng.Read(img.Pix)
+	data := encodePNG(t, img)
+	if len(data) <= headerPeekSize {
+		t.Fatalf("sample must exceed header peek size, got %d bytes", len(data))
+	}
+	return data
+}
+
+func TestUploadImage_RejectsBeforeStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "decompression bomb", data: bombPNG(), wantErr: entity.ErrImageTooLarge},
+		{name: "truncated header", data: bombPNG()[:16], wantErr: entity.ErrInvalidImage},
+		{name: "not an image", data: []byte("<html>not an image</html>"), wantErr: entity.ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeStorage{}
+			repo := &fakeImageRepo{}
+			service := newTestImageService(storage, repo, UploadConfig{})
+
+			_, err := upload(service, tt.data, "bomb.png", "image/png")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if storage.attempts != 0 || repo.created != 0 {
+				t.Fatalf("rejected image must not reach storage: attempts=%d, created=%d", storage.attempts, repo.created)
+			}
+		})
+	}
+}
+
+func TestUploadImage_AbortsInvalidStream(t *testing.T) {
+	data := noisePNG(t)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		config  UploadConfig
+		wantErr error
+	}{
+		// Заголовок корректен, но файл обрывается после буфера заголовка
+		{name: "truncated body", data: data[:len(data)-100], wantErr: entity.ErrInvalidImage},
+		{name: "exceeds upload size", data: data, config: UploadConfig{MaxSize: int64(len(data)) - 1}, wantErr: entity.ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeStorage{}
+			repo := &fakeImageRepo{}
+			service := newTestImageService(storage, repo, tt.config)
+
+			_, err := upload(service, tt.data, "noise.png", "image/png")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if len(storage.objects) != 0 || repo.created != 0 {
+				t.Fatalf("aborted upload must not be stored: objects=%d, created=%d", len(storage.objects), repo.created)
+			}
+		})
+	}
+}
+
+func TestUploadImage_StreamsSizeAndChecksum(t *testing.T) {
+	storage := &fakeStorage{}
+	repo := &fakeImageRepo{}
+	service := newTestImageService(storage, repo, UploadConfig{})
+	data := noisePNG(t)
+
+	image, err := upload(service, data, "noise.png", "image/png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if image.OriginalSize != int64(len(data)) || image.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected size or checksum: %d %s", image.OriginalSize, image.Checksum)
+	}
+	if !bytes.Equal(storage.objects[image.OriginalPath], data) {
+		t.Fatalf("stored object differs from upload")
+	}
+	if repo.created != 1 {
+		t.Fatalf("expected one image record, got %d", repo.created)
+	}
+}
+
+func TestUploadImage_ContentMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		mimeType string
+		allow    bool
+		wantErr  error
+	}{
+		{name: "mime disagrees", filename: "photo.png", mimeType: "image/jpeg", wantErr: entity.ErrContentMismatch},
+		{name: "extension disagrees", filename: "photo.jpg", mimeType: "image/png", wantErr: entity.ErrContentMismatch},
+		{name: "non-image mime", filename: "photo.png", mimeType: "text/plain", wantErr: entity.ErrContentMismatch},
+		{name: "generic mime", filename: "upload", mimeType: "application/octet-stream"},
+		{name: "mismatch allowed", filename: "photo.jpg", mimeType: "image/jpeg", allow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeStorage{}
+			repo := &fakeImageRepo{}
+			service := newTestImageService(storage, repo, UploadConfig{AllowContentMismatch: tt.allow})
+
+			_, err := upload(service, smallPNG(t), tt.filename, tt.mimeType)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if storage.attempts != 0 {
+					t.Fatalf("rejected image must not reach storage")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Сохраняется формат, определенный по содержимому
+			if repo.last.Format != entity.FormatPNG || repo.last.MimeType != "image/png" {
+				t.Fatalf("expected detected png, got %s (%s)", repo.last.Format, repo.last.MimeType)
+			}
+		})
+	}
+}
+
+func TestCompleteUpload_QueuesTaskInOutbox(t *testing.T) {
+	storage := &fakeStorage{}
+	repo := &fakeImageRepo{}
+	relay := &fakeRelay{}
+	validator := processor.NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+	service := NewImageService(repo, storage, relay, validator, zap.NewNop(), "images", UploadConfig{})
+
+	image, err := service.StoreOriginal(context.Background(), bytes.NewReader(smallPNG(t)), "photo.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []entity.OperationParams{{Type: entity.OpThumbnail}}
+	preset := &entity.Preset{Name: "avatar", Version: 2}
+	if _, err := service.CompleteUpload(context.Background(), image, operations, preset); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.tasks) != 1 || repo.created != 1 {
+		t.Fatalf("expected image and task created together, got %d images and %d tasks", repo.created, len(repo.tasks))
+	}
+	task := repo.tasks[0]
+	if task.ImageID != image.ID || task.PresetName != "avatar" || task.PresetVersion != 2 {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+	if relay.notified != 1 {
+		t.Fatalf("expected relay to be notified once, got %d", relay.notified)
+	}
+
+	// Без операций задача не создается
+	image, err = service.StoreOriginal(context.Background(), bytes.NewReader(smallPNG(t)), "photo.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CompleteUpload(context.Background(), image, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.tasks) != 1 || relay.notified != 1 {
+		t.Fatalf("upload without operations must not queue a task")
+	}
+}
+
+func TestReprocessImage_QueuesTaskForExistingOriginal(t *testing.T) {
+	storage := &fakeStorage{}
+	repo := &fakeImageRepo{}
+	relay := &fakeRelay{}
+	validator := processor.NewImageProcessor(zap.NewNop(), config.ProcessingConfig{}, nil)
+	service := NewImageService(repo, storage, relay, validator, zap.NewNop(), "images", UploadConfig{})
+
+	image, err := upload(service, smallPNG(t), "photo.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads := storage.attempts
+
+	operations := []entity.OperationParams{{Type: entity.OpGrayscale}}
+	task, err := service.ReprocessImage(context.Background(), image.ID, operations, nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.OriginalPath != image.OriginalPath || !task.Supersede || len(task.Operations) != 1 {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+	if storage.attempts != uploads {
+		t.Fatal("reprocessing must not upload the original again")
+	}
+	if len(repo.tasks) != 1 || relay.notified != 1 {
+		t.Fatalf("expected one queued task and relay notification, got %d tasks, %d notifications", len(repo.tasks), relay.notified)
+	}
+
+	// Пока задача не завершена, новая не создается
+	if _, err := service.ReprocessImage(context.Background(), image.ID, operations, nil, false); !errors.Is(err, entity.ErrJobInProgress) {
+		t.Fatalf("expected ErrJobInProgress, got %v", err)
+	}
+	if _, err := service.ReprocessImage(context.Background(), "missing", operations, nil, false); !errors.Is(err, entity.ErrImageNotFound) {
+		t.Fatalf("expected ErrImageNotFound, got %v", err)
+	}
+}
+
+func TestGetImageStatus_ReflectsLatestJob(t *testing.T) {
+	repo := &fakeImageRepo{
+		last: &entity.Image{ID: "img", Status: entity.StatusUploaded},
+		job: &entity.ProcessingJob{
+			ID:         "second",
+			Status:     entity.JobStatusProcessing,
+			Operations: []entity.OperationParams{{Type: entity.OpThumbnail}, {Type: entity.OpGrayscale}},
+		},
+		processed: []entity.ProcessedImage{
+			{JobID: "first", VariantName: "thumbnail"},
+			{JobID: "first", VariantName: "resize"},
+			{JobID: "second", VariantName: "thumbnail"},
+		},
+	}
+	service := newTestImageService(&fakeStorage{}, repo, UploadConfig{})
+
+	status, err := service.GetImageStatus(context.Background(), "img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.JobID != "second" || status.JobStatus != entity.JobStatusProcessing {
+		t.Fatalf("expected latest job, got %s (%s)", status.JobID, status.JobStatus)
+	}
+	if status.ProcessedOperations != 1 || status.TotalOperations != 2 || status.Progress != 50 {
+		t.Fatalf("expected 1 of 2 operations (50%%), got %d of %d (%d%%)", status.ProcessedOperations, status.TotalOperations, status.Progress)
+	}
+}
+
+func TestResolveImage_VariantETagChangesOnReprocess(t *testing.T) {
+	repo := &fakeImageRepo{
+		last:      &entity.Image{ID: "img", OriginalPath: "originals/img.png", MimeType: "image/png"},
+		processed: []entity.ProcessedImage{{VariantName: "thumb", Path: "processed/img/thumb/first.jpg", MimeType: "image/jpeg"}},
+	}
+	service := newTestImageService(&fakeStorage{}, repo, UploadConfig{})
+
+	original, err := service.ResolveImage(context.Background(), "img", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !original.Original || original.Path != "originals/img.png" {
+		t.Fatalf("expected original object, got %+v", original)
+	}
+
+	first, err := service.ResolveImage(context.Background(), "img", "", "thumb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Original || first.MimeType != "image/jpeg" {
+		t.Fatalf("expected variant object, got %+v", first)
+	}
+
+	// Повторная обработка сохраняет вариант под новым путем
+	repo.processed = append(repo.processed, entity.ProcessedImage{VariantName: "thumb", Path: "processed/img/thumb/second.jpg", MimeType: "image/jpeg"})
+	second, err := service.ResolveImage(context.Background(), "img", "", "thumb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ETag() == first.ETag() {
+		t.Fatal("reprocessed variant must get a new ETag")
+	}
+	if first.ETag() == original.ETag() || !strings.HasPrefix(first.ETag(), `"`) {
+		t.Fatalf("unexpected ETag %s", first.ETag())
+	}
+
+	if _, err := service.ResolveImage(context.Background(), "img", "", "missing"); err == nil {
+		t.Fatal("expected error for unknown variant")
+	}
+}
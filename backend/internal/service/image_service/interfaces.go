@@ -3,11 +3,14 @@ package imageservice
 import (
 	"context"
 	"imageprocessor/backend/internal/domain/entity"
+	"io"
 )
 
 // ImageRepository определяет интерфейс репозитория изображений
 type ImageRepositoryInterface interface {
 	CreateImage(ctx context.Context, image *entity.Image) error
+	// CreateImageWithTask атомарно создает изображение, задачу и запись outbox
+	CreateImageWithTask(ctx context.Context, image *entity.Image, task *entity.ProcessingTask) error
 	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
 	UpdateImageStatus(ctx context.Context, imageID string, status entity.ImageStatus) error
 	DeleteImage(ctx context.Context, imageID string) error
@@ -16,8 +19,27 @@ type ImageRepositoryInterface interface {
 	CreateProcessedImage(ctx context.Context, processed *entity.ProcessedImage) error
 	GetProcessedImagesByImageID(ctx context.Context, imageID string) ([]entity.ProcessedImage, error)
 	GetProcessedImageByOperation(ctx context.Context, imageID string, operation entity.OperationType) (*entity.ProcessedImage, error)
+	GetProcessedImageByVariant(ctx context.Context, imageID string, variantName string) (*entity.ProcessedImage, error)
 
 	CreateProcessingJob(ctx context.Context, job *entity.ProcessingTask) error
-	UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) error
-	GetProcessingJobByImageID(ctx context.Context, imageID string) (*entity.ProcessingTask, error)
+	UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) (bool, error)
+	GetProcessingJobByImageID(ctx context.Context, imageID string) (*entity.ProcessingJob, error)
+	// CreateReprocessTask атомарно создает задачу для загруженного изображения и запись outbox
+	CreateReprocessTask(ctx context.Context, task *entity.ProcessingTask) error
+	CancelProcessingJob(ctx context.Context, jobID string) (*entity.ProcessingJob, error)
+	CancelImageProcessingJobs(ctx context.Context, imageID string) ([]entity.ProcessingJob, error)
+}
+
+// ImageValidatorInterface проверяет изображение до сохранения оригинала
+type ImageValidatorInterface interface {
+	// InspectImageHeader определяет формат по началу файла и проверяет лимиты размеров
+	InspectImageHeader(prefix []byte) (*entity.ImageInfo, error)
+	// NewStreamValidator проверяет полноту файла по мере передачи
+	NewStreamValidator(format entity.ImageFormat) io.WriteCloser
+}
+
+// TaskRelayInterface публикует задачи из outbox в брокер
+type TaskRelayInterface interface {
+	// Notify сообщает о новой записи в outbox, чтобы не ждать следующего опроса
+	Notify()
 }
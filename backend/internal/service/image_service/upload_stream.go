@@ -0,0 +1,87 @@
+package imageservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"imageprocessor/backend/internal/domain/entity"
+	"io"
+)
+
+// headerPeekSize - объем начала файла, по которому определяются формат и размеры.
+// Заголовок JPEG может идти после крупных сегментов EXIF и ICC, поэтому запас большой
+const headerPeekSize = 1 << 20
+
+// uploadReader передает поток в хранилище, по ходу чтения считая размер
+// и контрольную сумму SHA-256 и передавая данные на проверку структуры.
+// Превышение размера или ошибка проверки прерывают чтение, поэтому
+// хранилище не завершает загрузку такого файла
+type uploadReader struct {
+	source    io.Reader
+	validator io.WriteCloser
+	hash      hash.Hash
+	size      int64
+	maxSize   int64
+	err       error
+}
+
+func newUploadReader(source io.Reader, validator io.WriteCloser, maxSize int64) *uploadReader {
+	return &uploadReader{
+		source:    source,
+		validator: validator,
+		hash:      sha256.New(),
+		maxSize:   maxSize,
+	}
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.source.Read(p)
+	if n > 0 {
+		r.size += int64(n)
+		if r.size > r.maxSize {
+			r.err = fmt.Errorf("%w: limit is %d bytes", entity.ErrFileTooLarge, r.maxSize)
+			return 0, r.err
+		}
+		r.hash.Write(p[:n])
+		if _, werr := r.validator.Write(p[:n]); werr != nil {
+			r.err = werr
+			return 0, r.err
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		// Поток закончился: файл принимается, только если его структура полная
+		if cerr := r.validator.Close(); cerr != nil {
+			r.err = cerr
+			return 0, r.err
+		}
+	}
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// Err возвращает ошибку, прервавшую чтение потока; конец потока ошибкой не считается
+func (r *uploadReader) Err() error {
+	if errors.Is(r.err, io.EOF) {
+		return nil
+	}
+	return r.err
+}
+
+// Size возвращает количество прочитанных байт
+func (r *uploadReader) Size() int64 {
+	return r.size
+}
+
+// Checksum возвращает SHA-256 прочитанных данных в шестнадцатеричном виде
+func (r *uploadReader) Checksum() string {
+	return hex.EncodeToString(r.hash.Sum(nil))
+}
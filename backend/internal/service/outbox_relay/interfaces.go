@@ -0,0 +1,14 @@
+package outboxrelay
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// OutboxRepositoryInterface определяет интерфейс репозитория outbox
+type OutboxRepositoryInterface interface {
+	ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error)
+	MarkOutboxMessageSent(ctx context.Context, id int64) error
+	RescheduleOutboxMessage(ctx context.Context, id int64, errorMsg string, delay time.Duration) error
+}
@@ -0,0 +1,150 @@
+package outboxrelay
+
+import (
+	"context"
+	"fmt"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для незаданных параметров OutboxConfig
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultLeaseTimeout    = 30 * time.Second
+	defaultRetryBackoff    = time.Second
+	defaultMaxRetryBackoff = time.Minute
+)
+
+// Relay публикует задачи из outbox в брокер. Запись outbox создается в одной
+// транзакции с изображением и задачей, поэтому задача не теряется, даже если
+// брокер недоступен в момент загрузки: relay повторяет публикацию с растущей
+// задержкой, пока она не удастся. Доставка - как минимум один раз
+type Relay struct {
+	repo     OutboxRepositoryInterface
+	producer broker.ProducerMessageBrokerInterface
+	cfg      config.OutboxConfig
+	logger   *zap.Logger
+	wake     chan struct{}
+}
+
+func NewRelay(repo OutboxRepositoryInterface, producer broker.ProducerMessageBrokerInterface, cfg config.OutboxConfig, logger *zap.Logger) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = defaultLeaseTimeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.MaxRetryBackoff < cfg.RetryBackoff {
+		cfg.MaxRetryBackoff = max(defaultMaxRetryBackoff, cfg.RetryBackoff)
+	}
+
+	return &Relay{
+		repo:     repo,
+		producer: producer,
+		cfg:      cfg,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает о новых записях в outbox, чтобы relay не ждал следующего опроса
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run публикует записи outbox, пока не отменен контекст
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	r.logger.Info("Outbox relay started", zap.Duration("pollInterval", r.cfg.PollInterval))
+
+	for {
+		// Пока записи арендуются полными пачками, в outbox могут оставаться готовые к отправке
+		for {
+			claimed, err := r.RelayBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("Failed to relay outbox messages", zap.Error(err))
+				}
+				break
+			}
+			if claimed < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RelayBatch арендует пачку записей и публикует их. Неудачная публикация
+// откладывается с задержкой. Возвращает количество арендованных записей
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	messages, err := r.repo.ClaimOutboxMessages(ctx, r.cfg.BatchSize, r.cfg.LeaseTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	for _, message := range messages {
+		if err := r.producer.PublishProcessingTask(ctx, message.Task); err != nil {
+			delay := r.backoff(message.Attempts)
+			r.logger.Warn("Failed to publish task from outbox, will retry",
+				zap.Error(err),
+				zap.String("taskId", message.Task.ID),
+				zap.Int("attempt", message.Attempts),
+				zap.Duration("backoff", delay),
+			)
+			if err := r.repo.RescheduleOutboxMessage(ctx, message.ID, err.Error(), delay); err != nil {
+				// Запись станет доступна после истечения аренды
+				r.logger.Error("Failed to reschedule outbox message", zap.Error(err), zap.Int64("id", message.ID))
+			}
+			continue
+		}
+
+		if err := r.repo.MarkOutboxMessageSent(ctx, message.ID); err != nil {
+			// Задача уже в брокере; после истечения аренды она будет опубликована повторно
+			r.logger.Error("Failed to mark outbox message sent", zap.Error(err), zap.Int64("id", message.ID))
+			continue
+		}
+
+		r.logger.Info("Processing task published from outbox",
+			zap.String("taskId", message.Task.ID),
+			zap.String("imageId", message.Task.ImageID),
+		)
+	}
+
+	return len(messages), nil
+}
+
+// backoff возвращает задержку перед следующей публикацией: RetryBackoff,
+// удваиваемая с каждой попыткой, но не больше MaxRetryBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= r.cfg.MaxRetryBackoff {
+			return r.cfg.MaxRetryBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,145 @@
+package outboxrelay
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/broker"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeOutboxRepo хранит записи в памяти; отложенные записи не арендуются
+type fakeOutboxRepo struct {
+	pending     []entity.OutboxMessage
+	sent        []int64
+	rescheduled map[int64]time.Duration
+}
+
+func (r *fakeOutboxRepo) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error) {
+	var claimed []entity.OutboxMessage
+	for i := range r.pending {
+		if len(claimed) == limit {
+			break
+		}
+		if _, delayed := r.rescheduled[r.pending[i].ID]; delayed {
+			continue
+		}
+		r.pending[i].Attempts++
+		claimed = append(claimed, r.pending[i])
+	}
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepo) MarkOutboxMessageSent(ctx context.Context, id int64) error {
+	r.sent = append(r.sent, id)
+	for i, message := range r.pending {
+		if message.ID == id {
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *fakeOutboxRepo) RescheduleOutboxMessage(ctx context.Context, id int64, errorMsg string, delay time.Duration) error {
+	if r.rescheduled == nil {
+		r.rescheduled = make(map[int64]time.Duration)
+	}
+	r.rescheduled[id] = delay
+	return nil
+}
+
+// fakeProducer отклоняет задачи из failing
+type fakeProducer struct {
+	broker.ProducerMessageBrokerInterface
+	failing   map[string]bool
+	published []string
+}
+
+func (p *fakeProducer) PublishProcessingTask(ctx context.Context, task *entity.ProcessingTask) error {
+	if p.failing[task.ID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, task.ID)
+	return nil
+}
+
+func newMessages(ids ...string) []entity.OutboxMessage {
+	messages := make([]entity.OutboxMessage, 0, len(ids))
+	for i, id := range ids {
+		messages = append(messages, entity.OutboxMessage{ID: int64(i + 1), Task: &entity.ProcessingTask{ID: id}})
+	}
+	return messages
+}
+
+func TestRelay_PublishesAndMarksSent(t *testing.T) {
+	repo := &fakeOutboxRepo{pending: newMessages("a", "b", "c")}
+	producer := &fakeProducer{failing: map[string]bool{"b": true}}
+	relay := NewRelay(repo, producer, config.OutboxConfig{BatchSize: 10, RetryBackoff: time.Second}, zap.NewNop())
+
+	claimed, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed != 3 {
+		t.Fatalf("expected 3 claimed messages, got %d", claimed)
+	}
+	if len(producer.published) != 2 || len(repo.sent) != 2 {
+		t.Fatalf("expected 2 published and sent, got %v and %v", producer.published, repo.sent)
+	}
+	if delay, ok := repo.rescheduled[2]; !ok || delay != time.Second {
+		t.Fatalf("expected failed message to be rescheduled after 1s, got %v", repo.rescheduled)
+	}
+
+	// Отложенная запись не публикуется до наступления срока
+	claimed, err = relay.RelayBatch(context.Background())
+	if err != nil || claimed != 0 {
+		t.Fatalf("expected nothing to relay, got %d, %v", claimed, err)
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(&fakeOutboxRepo{}, &fakeProducer{}, config.OutboxConfig{
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 5 * time.Second,
+	}, zap.NewNop())
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := relay.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRelay_RunStopsOnCancel(t *testing.T) {
+	repo := &fakeOutboxRepo{pending: newMessages("a")}
+	producer := &fakeProducer{}
+	relay := NewRelay(repo, producer, config.OutboxConfig{PollInterval: time.Hour}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("relay did not stop")
+	}
+}
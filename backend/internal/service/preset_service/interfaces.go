@@ -0,0 +1,15 @@
+package presetservice
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+// PresetRepositoryInterface определяет интерфейс репозитория пресетов
+type PresetRepositoryInterface interface {
+	CreatePresetVersion(ctx context.Context, preset *entity.Preset) error
+	GetPreset(ctx context.Context, name string, version int) (*entity.Preset, error)
+	ListPresets(ctx context.Context) ([]entity.Preset, error)
+	ListPresetVersions(ctx context.Context, name string) ([]entity.Preset, error)
+	DeletePreset(ctx context.Context, name string) error
+}
@@ -0,0 +1,98 @@
+package presetservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type PresetService struct {
+	presetRepo PresetRepositoryInterface
+	logger     *zap.Logger
+}
+
+func NewPresetService(presetRepo PresetRepositoryInterface, logger *zap.Logger) *PresetService {
+	return &PresetService{
+		presetRepo: presetRepo,
+		logger:     logger,
+	}
+}
+
+// CreatePreset создает первую версию пресета
+func (s *PresetService) CreatePreset(ctx context.Context, name, description string, operations []entity.OperationParams) (*entity.Preset, error) {
+	_, err := s.presetRepo.GetPreset(ctx, name, 0)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", entity.ErrPresetAlreadyExists, name)
+	}
+	if !errors.Is(err, entity.ErrPresetNotFound) {
+		return nil, err
+	}
+
+	return s.saveVersion(ctx, name, description, operations)
+}
+
+// UpdatePreset сохраняет новую версию существующего пресета.
+// Предыдущие версии остаются доступными для воспроизводимости
+func (s *PresetService) UpdatePreset(ctx context.Context, name, description string, operations []entity.OperationParams) (*entity.Preset, error) {
+	if _, err := s.presetRepo.GetPreset(ctx, name, 0); err != nil {
+		return nil, err
+	}
+
+	return s.saveVersion(ctx, name, description, operations)
+}
+
+// GetPreset возвращает версию пресета; version = 0 означает последнюю
+func (s *PresetService) GetPreset(ctx context.Context, name string, version int) (*entity.Preset, error) {
+	return s.presetRepo.GetPreset(ctx, name, version)
+}
+
+// ListPresets возвращает последние версии всех пресетов
+func (s *PresetService) ListPresets(ctx context.Context) ([]entity.Preset, error) {
+	return s.presetRepo.ListPresets(ctx)
+}
+
+// ListPresetVersions возвращает историю версий пресета
+func (s *PresetService) ListPresetVersions(ctx context.Context, name string) ([]entity.Preset, error) {
+	versions, err := s.presetRepo.ListPresetVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", entity.ErrPresetNotFound, name)
+	}
+	return versions, nil
+}
+
+// DeletePreset удаляет пресет со всеми версиями
+func (s *PresetService) DeletePreset(ctx context.Context, name string) error {
+	s.logger.Info("Deleting preset", zap.String("preset", name))
+	return s.presetRepo.DeletePreset(ctx, name)
+}
+
+func (s *PresetService) saveVersion(ctx context.Context, name, description string, operations []entity.OperationParams) (*entity.Preset, error) {
+	preset := &entity.Preset{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: description,
+		Operations:  operations,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.presetRepo.CreatePresetVersion(ctx, preset); err != nil {
+		s.logger.Error("Failed to save preset", zap.Error(err), zap.String("preset", name))
+		return nil, err
+	}
+
+	s.logger.Info("Preset saved",
+		zap.String("preset", preset.Name),
+		zap.Int("version", preset.Version),
+		zap.Int("operationsCount", len(preset.Operations)),
+	)
+
+	return preset, nil
+}
@@ -11,6 +11,7 @@ type StatsRepositoryInterface interface {
 	IncrementImageUploaded(ctx context.Context, size int64) error
 	IncrementImageProcessed(ctx context.Context, processingTimeMs float64) error
 	IncrementImageFailed(ctx context.Context) error
+	IncrementImageTimedOut(ctx context.Context) error
 	GetOperationStatistics(ctx context.Context) ([]entity.OperationStat, error)
 	UpdateOperationStatistics(ctx context.Context, operation entity.OperationType, success bool, processingTimeMs float64) error
 	GetMostUsedOperation(ctx context.Context) (string, error)
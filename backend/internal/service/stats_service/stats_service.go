@@ -147,6 +147,22 @@ func (s *StatsService) RecordImageFailed(ctx context.Context, operation entity.O
 	return nil
 }
 
+// RecordImageTimedOut записывает обработку, прерванную по таймауту.
+// Такие попытки не учитываются как неудачные
+func (s *StatsService) RecordImageTimedOut(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger.Debug("Recording image processing timed out")
+
+	if err := s.statsRepo.IncrementImageTimedOut(ctx); err != nil {
+		s.logger.Error("Failed to record image timed out", zap.Error(err))
+		return fmt.Errorf("failed to record image timed out: %w", err)
+	}
+
+	return nil
+}
+
 // RecordOperationsProcessed записывает факт обработки нескольких операций
 func (s *StatsService) RecordOperationsProcessed(ctx context.Context, operations []entity.OperationType, processingTimes map[entity.OperationType]float64) error {
 	s.mu.Lock()
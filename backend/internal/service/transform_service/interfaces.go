@@ -0,0 +1,16 @@
+package transformservice
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+)
+
+// ImageRepositoryInterface определяет интерфейс репозитория изображений для трансформаций
+type ImageRepositoryInterface interface {
+	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
+}
+
+// ImageProcessorInterface определяет интерфейс процессора изображений
+type ImageProcessorInterface interface {
+	ProcessImage(ctx context.Context, imageData []byte, operations []entity.OperationParams) (map[string]*entity.ProcessedOutput, error)
+}
@@ -0,0 +1,183 @@
+package transformservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/repository/cloud"
+	"net/http"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// transformedPrefix - префикс ключей кэша трансформаций в хранилище
+const transformedPrefix = "transformed"
+
+var (
+	ErrTransformDisabled = errors.New("transformations are disabled: signing key is not configured")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrTooManyOperations = errors.New("too many operations")
+)
+
+// TransformService выполняет трансформации по подписанным URL синхронно в API
+// и кэширует результаты в хранилище
+type TransformService struct {
+	imageRepo    ImageRepositoryInterface
+	cloudStorage cloud.CloudStorageInterface
+	processor    ImageProcessorInterface
+	logger       *zap.Logger
+	signingKey   []byte
+	cfg          config.TransformConfig
+	inflight     singleflight.Group
+}
+
+func NewTransformService(
+	imageRepo ImageRepositoryInterface,
+	cloudStorage cloud.CloudStorageInterface,
+	processor ImageProcessorInterface,
+	logger *zap.Logger,
+	cfg config.TransformConfig,
+) *TransformService {
+	return &TransformService{
+		imageRepo:    imageRepo,
+		cloudStorage: cloudStorage,
+		processor:    processor,
+		logger:       logger,
+		signingKey:   []byte(cfg.SigningKey),
+		cfg:          cfg,
+	}
+}
+
+// Sign вычисляет подпись для строки опций и ID изображения:
+// base64url(HMAC-SHA256(key, options + "/" + imageID)) без выравнивания
+func (s *TransformService) Sign(options, imageID string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(options + "/" + imageID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверяет подпись URL трансформации
+func (s *TransformService) VerifySignature(signature, options, imageID string) error {
+	if len(s.signingKey) == 0 {
+		return ErrTransformDisabled
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(options + "/" + imageID))
+	if !hmac.Equal(expected, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Transform возвращает результат цепочки операций над оригиналом.
+// Результат кэшируется под детерминированным ключом и при повторных
+// запросах отдается из хранилища без обработки
+func (s *TransformService) Transform(ctx context.Context, imageID string, operations []entity.OperationParams) ([]byte, string, error) {
+	if len(operations) == 0 {
+		return nil, "", fmt.Errorf("no operations specified")
+	}
+	if s.cfg.MaxOperations > 0 && len(operations) > s.cfg.MaxOperations {
+		return nil, "", fmt.Errorf("%w: %d, max %d", ErrTooManyOperations, len(operations), s.cfg.MaxOperations)
+	}
+
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	cacheKey, err := s.cacheKey(imageID, operations)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Одинаковые параллельные запросы выполняют обработку один раз
+	result, err, _ := s.inflight.Do(cacheKey, func() (interface{}, error) {
+		return s.transform(ctx, imageID, cacheKey, operations)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	output := result.(*entity.ProcessedOutput)
+	return output.Data, output.MimeType, nil
+}
+
+func (s *TransformService) transform(ctx context.Context, imageID, cacheKey string, operations []entity.OperationParams) (*entity.ProcessedOutput, error) {
+	// Проверяем кэш
+	exists, err := s.cloudStorage.FileExists(ctx, cacheKey)
+	if err != nil {
+		s.logger.Warn("Failed to check transform cache", zap.Error(err), zap.String("key", cacheKey))
+	}
+	if exists {
+		data, err := s.cloudStorage.DownloadFile(ctx, cacheKey)
+		if err == nil {
+			s.logger.Debug("Transform cache hit", zap.String("imageId", imageID), zap.String("key", cacheKey))
+			return &entity.ProcessedOutput{Data: data, MimeType: http.DetectContentType(data)}, nil
+		}
+		s.logger.Warn("Failed to read transform cache", zap.Error(err), zap.String("key", cacheKey))
+	}
+
+	image, err := s.imageRepo.GetImageByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.cloudStorage.DownloadFile(ctx, image.OriginalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download original: %w", err)
+	}
+
+	outputs, err := s.processor.ProcessImage(ctx, original, operations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process image: %w", err)
+	}
+
+	// Трансформация - линейная цепочка, результатом является последняя операция
+	output, ok := outputs[operations[len(operations)-1].VariantName()]
+	if !ok {
+		return nil, fmt.Errorf("transform produced no result")
+	}
+
+	// Кэш не критичен: при ошибке записи отдаем результат без сохранения
+	err = s.cloudStorage.UploadFile(ctx, cacheKey, bytes.NewReader(output.Data), int64(len(output.Data)), output.MimeType)
+	if err != nil {
+		s.logger.Warn("Failed to store transform result", zap.Error(err), zap.String("key", cacheKey))
+	}
+
+	s.logger.Info("Image transformed",
+		zap.String("imageId", imageID),
+		zap.String("key", cacheKey),
+		zap.Int("size", len(output.Data)),
+	)
+
+	return output, nil
+}
+
+// cacheKey строит ключ из нормализованного списка операций,
+// поэтому эквивалентные опции попадают в один и тот же объект
+func (s *TransformService) cacheKey(imageID string, operations []entity.OperationParams) (string, error) {
+	canonical, err := json.Marshal(operations)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal operations: %w", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+	return fmt.Sprintf("%s/%s/%s", transformedPrefix, imageID, hex.EncodeToString(sum[:])), nil
+}
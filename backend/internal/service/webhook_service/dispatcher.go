@@ -0,0 +1,348 @@
+package webhookservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для незаданных параметров WebhookConfig
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 20
+	defaultTimeout         = 10 * time.Second
+	defaultMaxAttempts     = 8
+	defaultRetryBackoff    = 10 * time.Second
+	defaultMaxRetryBackoff = time.Hour
+)
+
+// leaseMargin - запас аренды доставки сверх таймаута запроса на запись результата
+const leaseMargin = 30 * time.Second
+
+// maxResponseSnippet - сколько байт ответа получателя сохраняется в тексте ошибки
+const maxResponseSnippet = 256
+
+// Заголовки запроса webhook
+const (
+	HeaderEventID    = "X-Webhook-Id"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderAttempt    = "X-Webhook-Attempt"
+	// HeaderSignature содержит подпись в формате t=<unix time>,v1=<hex HMAC-SHA256>
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Dispatcher отправляет сохраненные доставки. Каждая попытка записывается
+// в журнал; неудачная доставка повторяется с растущей задержкой, пока
+// не исчерпано MaxAttempts попыток. Доставка - как минимум один раз:
+// получатель должен различать повторы по X-Webhook-Id
+type Dispatcher struct {
+	repo   DeliveryRepositoryInterface
+	client *http.Client
+	cfg    config.WebhookConfig
+	logger *zap.Logger
+	wake   chan struct{}
+}
+
+func NewDispatcher(repo DeliveryRepositoryInterface, cfg config.WebhookConfig, logger *zap.Logger) *Dispatcher {
+	cfg = withDefaults(cfg)
+
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: newTransport(cfg.Timeout),
+			// Перенаправление не считается доставкой: получатель должен указать итоговый URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:    cfg,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// newTransport создает транспорт, который не подключается к внутренним адресам
+// (см. dialControl). Прокси не используется: через него проверка адреса обходится
+func newTransport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// withDefaults заменяет незаданные параметры значениями по умолчанию
+func withDefaults(cfg config.WebhookConfig) config.WebhookConfig {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.MaxRetryBackoff < cfg.RetryBackoff {
+		cfg.MaxRetryBackoff = max(defaultMaxRetryBackoff, cfg.RetryBackoff)
+	}
+	return cfg
+}
+
+// Notify сообщает о новых доставках, чтобы dispatcher не ждал следующего опроса
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет доставки, пока не отменен контекст
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	d.logger.Info("Webhook dispatcher started", zap.Duration("pollInterval", d.cfg.PollInterval))
+
+	for {
+		// Пока доставки арендуются полными пачками, могут оставаться готовые к отправке
+		for {
+			claimed, err := d.DispatchBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					d.logger.Error("Failed to dispatch webhook deliveries", zap.Error(err))
+				}
+				break
+			}
+			if claimed < d.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DispatchBatch арендует пачку доставок и отправляет их параллельно.
+// Возвращает количество арендованных доставок
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.repo.ClaimWebhookDeliveries(ctx, d.cfg.BatchSize, d.cfg.Timeout+leaseMargin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *entity.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver выполняет одну попытку доставки и записывает ее результат
+func (d *Dispatcher) deliver(ctx context.Context, delivery *entity.WebhookDelivery) {
+	startTime := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	duration := time.Since(startTime)
+
+	if err != nil && ctx.Err() != nil {
+		// Попытка прервана остановкой; доставка повторится после истечения аренды
+		return
+	}
+
+	attempt := &entity.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		StatusCode: statusCode,
+		DurationMs: duration.Milliseconds(),
+	}
+
+	status := entity.WebhookDeliveryDelivered
+	var retryDelay time.Duration
+	if err != nil {
+		attempt.Error = err.Error()
+		status = entity.WebhookDeliveryFailed
+		if delivery.Attempts < delivery.MaxAttempts {
+			status = entity.WebhookDeliveryPending
+			retryDelay = d.backoff(delivery.Attempts)
+		}
+	}
+
+	if recordErr := d.repo.RecordWebhookAttempt(ctx, attempt, status, retryDelay); recordErr != nil {
+		// Доставка снова станет доступна после истечения аренды
+		d.logger.Error("Failed to record webhook attempt", zap.Error(recordErr), zap.String("deliveryId", delivery.ID))
+		return
+	}
+
+	switch status {
+	case entity.WebhookDeliveryDelivered:
+		d.logger.Info("Webhook delivered",
+			zap.String("deliveryId", delivery.ID),
+			zap.String("eventId", delivery.EventID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempt", delivery.Attempts),
+			zap.Duration("duration", duration),
+		)
+	case entity.WebhookDeliveryPending:
+		d.logger.Warn("Webhook delivery failed, will retry",
+			zap.Error(err),
+			zap.String("deliveryId", delivery.ID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempt", delivery.Attempts),
+			zap.Duration("backoff", retryDelay),
+		)
+	default:
+		d.logger.Error("Webhook delivery failed after all attempts",
+			zap.Error(err),
+			zap.String("deliveryId", delivery.ID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempts", delivery.Attempts),
+		)
+	}
+}
+
+// send отправляет подписанный запрос. Возвращает HTTP код ответа (0, если
+// ответ не получен) и ошибку, если получатель не ответил кодом 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery *entity.WebhookDelivery) (int, error) {
+	// Доставки на callback_url подписываются общим ключом, endpoint - своим
+	secret := delivery.Secret
+	if delivery.EndpointID == "" {
+		secret = d.cfg.Secret
+	}
+	if secret == "" {
+		return 0, errors.New("webhook secret is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "imageprocessor-webhooks/1.0")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, string(delivery.EventType))
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts))
+	req.Header.Set(HeaderSignature, fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(secret, timestamp, delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	// Остаток тела дочитывается, чтобы соединение можно было переиспользовать
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		if body := strings.TrimSpace(string(snippet)); body != "" {
+			message += ": " + body
+		}
+		return resp.StatusCode, errors.New(message)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает задержку перед следующей попыткой: RetryBackoff,
+// удваиваемая с каждой попыткой, но не больше MaxRetryBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= d.cfg.MaxRetryBackoff {
+			return d.cfg.MaxRetryBackoff
+		}
+	}
+	return backoff
+}
+
+// Sign вычисляет подпись тела запроса: hex HMAC-SHA256 от "<timestamp>.<body>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверяет заголовок X-Webhook-Signature. Подпись старше
+// tolerance отклоняется, чтобы перехваченный запрос нельзя было повторить
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+			}
+			timestamp = parsed
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == 0 || signature == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package webhookservice
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recordedAttempt - попытка, записанная dispatcher
+type recordedAttempt struct {
+	attempt    entity.WebhookAttempt
+	status     entity.WebhookDeliveryStatus
+	retryDelay time.Duration
+}
+
+// fakeDeliveryRepo выдает доставки один раз и запоминает записанные попытки
+type fakeDeliveryRepo struct {
+	mu         sync.Mutex
+	deliveries []entity.WebhookDelivery
+	recorded   []recordedAttempt
+}
+
+func (r *fakeDeliveryRepo) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	claimed := r.deliveries
+	r.deliveries = nil
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return claimed, nil
+}
+
+func (r *fakeDeliveryRepo) RecordWebhookAttempt(ctx context.Context, attempt *entity.WebhookAttempt, status entity.WebhookDeliveryStatus, retryDelay time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorded = append(r.recorded, recordedAttempt{attempt: *attempt, status: status, retryDelay: retryDelay})
+	return nil
+}
+
+// receivedRequest - запрос, полученный тестовым получателем
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver запускает получателя, отвечающего кодом status
+func newReceiver(t *testing.T, status int) (*httptest.Server, func() []receivedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("receiver says hi"))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), received...)
+	}
+}
+
+func newTestDispatcher(repo *fakeDeliveryRepo) *Dispatcher {
+	d := NewDispatcher(repo, config.WebhookConfig{
+		Secret:          "callback-secret",
+		Timeout:         time.Second,
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 4 * time.Second,
+	}, zap.NewNop())
+	// Тестовый получатель слушает 127.0.0.1, который dispatcher не разрешает
+	d.client.Transport = http.DefaultTransport
+	return d
+}
+
+func TestDispatcher_RefusesPrivateAddressAtDialTime(t *testing.T) {
+	server, received := newReceiver(t, http.StatusNoContent)
+	repo := &fakeDeliveryRepo{deliveries: []entity.WebhookDelivery{
+		{ID: "internal", URL: server.URL, Payload: []byte(`{}`), Attempts: 1, MaxAttempts: 3},
+	}}
+
+	d := NewDispatcher(repo, config.WebhookConfig{Secret: "callback-secret", Timeout: time.Second}, zap.NewNop())
+	if _, err := d.DispatchBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received()) != 0 {
+		t.Fatal("dispatcher must not connect to a loopback address")
+	}
+	if len(repo.recorded) != 1 || !strings.Contains(repo.recorded[0].attempt.Error, "private or loopback address") {
+		t.Fatalf("expected refused attempt, got %+v", repo.recorded)
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+		{"127.0.0.1:80", true},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", true},
+		{"169.254.169.254:80", true},
+		{"0.0.0.0:80", true},
+		{"[::1]:80", true},
+		{"[fd00::1]:80", true},
+		{"0.0.0.1:80", true},
+		{"100.64.1.1:443", true},
+		{"198.18.5.5:443", true},
+		{"100.128.0.1:443", false},
+	}
+	for _, tt := range tests {
+		err := dialControl("tcp", tt.address, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("dialControl(%q) = %v, wantErr %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	server, received := newReceiver(t, http.StatusNoContent)
+	repo := &fakeDeliveryRepo{deliveries: []entity.WebhookDelivery{
+		{ID: "callback", EventID: "evt", EventType: entity.WebhookEventImageProcessed, URL: server.URL, Payload: []byte(`{"id":"evt"}`), MaxAttempts: 3},
+		{ID: "endpoint", EventID: "evt", EventType: entity.WebhookEventImageProcessed, EndpointID: "ep", Secret: "endpoint-secret", URL: server.URL, Payload: []byte(`{"id":"evt"}`), MaxAttempts: 3},
+	}}
+
+	claimed, err := newTestDispatcher(repo).DispatchBatch(context.Background())
+	if err != nil || claimed != 2 {
+		t.Fatalf("DispatchBatch() = %d, %v", claimed, err)
+	}
+
+	requests := received()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for _, req := range requests {
+		secret := "callback-secret"
+		if req.header.Get(HeaderDeliveryID) == "endpoint" {
+			secret = "endpoint-secret"
+		}
+		if err := VerifySignature(secret, req.header.Get(HeaderSignature), req.body, time.Minute); err != nil {
+			t.Errorf("delivery %s: %v", req.header.Get(HeaderDeliveryID), err)
+		}
+		if req.header.Get(HeaderEventID) != "evt" || req.header.Get(HeaderEventType) != "image.processed" {
+			t.Errorf("unexpected headers: %v", req.header)
+		}
+	}
+	if err := VerifySignature("wrong", requests[0].header.Get(HeaderSignature), requests[0].body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+
+	for _, recorded := range repo.recorded {
+		if recorded.status != entity.WebhookDeliveryDelivered || recorded.attempt.StatusCode != http.StatusNoContent {
+			t.Fatalf("unexpected recorded attempt: %+v", recorded)
+		}
+	}
+}
+
+func TestDispatcher_RetriesWithBackoffThenFails(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusInternalServerError)
+	repo := &fakeDeliveryRepo{deliveries: []entity.WebhookDelivery{
+		{ID: "retry", URL: server.URL, Payload: []byte(`{}`), Attempts: 1, MaxAttempts: 3},
+		{ID: "last", URL: server.URL, Payload: []byte(`{}`), Attempts: 2, MaxAttempts: 3},
+	}}
+
+	if _, err := newTestDispatcher(repo).DispatchBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.recorded) != 2 {
+		t.Fatalf("expected 2 recorded attempts, got %d", len(repo.recorded))
+	}
+	for _, recorded := range repo.recorded {
+		if recorded.attempt.StatusCode != http.StatusInternalServerError || recorded.attempt.Error != "unexpected status 500: receiver says hi" {
+			t.Fatalf("attempt must keep status and response: %+v", recorded.attempt)
+		}
+		switch recorded.attempt.DeliveryID {
+		case "retry":
+			if recorded.status != entity.WebhookDeliveryPending || recorded.retryDelay != 2*time.Second {
+				t.Fatalf("expected retry after 2s, got %+v", recorded)
+			}
+		case "last":
+			if recorded.status != entity.WebhookDeliveryFailed {
+				t.Fatalf("expected final failure, got %+v", recorded)
+			}
+		}
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := newTestDispatcher(&fakeDeliveryRepo{})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
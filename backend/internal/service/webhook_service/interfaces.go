@@ -0,0 +1,41 @@
+package webhookservice
+
+import (
+	"context"
+	"imageprocessor/backend/internal/domain/entity"
+	"time"
+)
+
+// WebhookRepositoryInterface определяет интерфейс репозитория получателей и доставок
+type WebhookRepositoryInterface interface {
+	CreateWebhookEndpoint(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	ListWebhookEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id string) error
+
+	// CreateWebhookDeliveries сохраняет доставки одного события атомарно
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter, limit, offset int) ([]entity.WebhookDelivery, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID string) ([]entity.WebhookAttempt, error)
+	RedeliverWebhookDelivery(ctx context.Context, id string) error
+}
+
+// DeliveryRepositoryInterface определяет методы репозитория, нужные dispatcher
+type DeliveryRepositoryInterface interface {
+	// ClaimWebhookDeliveries арендует готовые к отправке доставки и засчитывает им попытку
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error)
+	// RecordWebhookAttempt записывает попытку в журнал и переводит доставку в status
+	RecordWebhookAttempt(ctx context.Context, attempt *entity.WebhookAttempt, status entity.WebhookDeliveryStatus, retryDelay time.Duration) error
+}
+
+// ImageRepositoryInterface определяет методы репозитория изображений,
+// нужные для поиска callback_url
+type ImageRepositoryInterface interface {
+	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
+}
+
+// DispatcherInterface отправляет сохраненные доставки
+type DispatcherInterface interface {
+	// Notify сообщает о новых доставках, чтобы не ждать следующего опроса
+	Notify()
+}
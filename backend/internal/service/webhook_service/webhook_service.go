@@ -0,0 +1,380 @@
+package webhookservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imageprocessor/backend/internal/config"
+	"imageprocessor/backend/internal/domain/entity"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxWebhookURLLength ограничивает длину URL получателя
+const maxWebhookURLLength = 2048
+
+// Event - тело запроса, которое получает webhook
+type Event struct {
+	ID        string                  `json:"id"`
+	Type      entity.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      EventData               `json:"data"`
+}
+
+// EventData - результат обработки изображения
+type EventData struct {
+	ImageID       string         `json:"image_id"`
+	TaskID        string         `json:"task_id"`
+	Status        string         `json:"status"`
+	Preset        string         `json:"preset,omitempty"`
+	PresetVersion int            `json:"preset_version,omitempty"`
+	Variants      []EventVariant `json:"variants"`
+	DurationMs    int64          `json:"duration_ms"`
+	Error         string         `json:"error,omitempty"`
+	CompletedAt   time.Time      `json:"completed_at"`
+}
+
+// EventVariant - результат одной операции задачи
+type EventVariant struct {
+	Name       string `json:"name"`
+	Operation  string `json:"operation"`
+	Path       string `json:"path,omitempty"`
+	Size       int64  `json:"size"`
+	MimeType   string `json:"mime_type"`
+	Format     string `json:"format"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type WebhookService struct {
+	repo       WebhookRepositoryInterface
+	imageRepo  ImageRepositoryInterface
+	dispatcher DispatcherInterface
+	cfg        config.WebhookConfig
+	logger     *zap.Logger
+}
+
+// NewWebhookService создает сервис webhook. dispatcher может быть nil, если
+// доставки отправляет другой процесс: тогда они будут найдены при опросе
+func NewWebhookService(
+	repo WebhookRepositoryInterface,
+	imageRepo ImageRepositoryInterface,
+	dispatcher DispatcherInterface,
+	cfg config.WebhookConfig,
+	logger *zap.Logger,
+) *WebhookService {
+	return &WebhookService{
+		repo:       repo,
+		imageRepo:  imageRepo,
+		dispatcher: dispatcher,
+		cfg:        withDefaults(cfg),
+		logger:     logger,
+	}
+}
+
+// ValidateCallbackURL проверяет callback_url, переданный при загрузке
+func (s *WebhookService) ValidateCallbackURL(rawURL string) error {
+	if s.cfg.Secret == "" {
+		return entity.ErrCallbacksDisabled
+	}
+	return validateWebhookURL(rawURL)
+}
+
+// CreateEndpoint регистрирует получателя событий всех изображений.
+// Если secret не задан, ключ подписи генерируется
+func (s *WebhookService) CreateEndpoint(ctx context.Context, rawURL, secret, description string) (*entity.WebhookEndpoint, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	endpoint := &entity.WebhookEndpoint{
+		ID:          uuid.New().String(),
+		URL:         rawURL,
+		Secret:      secret,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook endpoint created", zap.String("endpointId", endpoint.ID), zap.String("url", endpoint.URL))
+	return endpoint, nil
+}
+
+// ListEndpoints возвращает зарегистрированных получателей
+func (s *WebhookService) ListEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error) {
+	return s.repo.ListWebhookEndpoints(ctx)
+}
+
+// DeleteEndpoint удаляет получателя и журнал его доставок
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id string) error {
+	if err := s.repo.DeleteWebhookEndpoint(ctx, id); err != nil {
+		return err
+	}
+	s.logger.Info("Webhook endpoint deleted", zap.String("endpointId", id))
+	return nil
+}
+
+// ListDeliveries возвращает доставки, новые первыми
+func (s *WebhookService) ListDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter, limit, offset int) ([]entity.WebhookDelivery, error) {
+	return s.repo.ListWebhookDeliveries(ctx, filter, limit, offset)
+}
+
+// GetDelivery возвращает доставку и журнал ее попыток
+func (s *WebhookService) GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	delivery, err := s.repo.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := s.repo.ListWebhookAttempts(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+// Redeliver снова ставит доставленное или неудавшееся событие в очередь отправки
+func (s *WebhookService) Redeliver(ctx context.Context, id string) error {
+	if err := s.repo.RedeliverWebhookDelivery(ctx, id); err != nil {
+		return err
+	}
+
+	s.notify()
+	s.logger.Info("Webhook delivery queued for redelivery", zap.String("deliveryId", id))
+	return nil
+}
+
+// EnqueueResult сохраняет доставки события о завершении обработки: на
+// callback_url изображения и всем зарегистрированным получателям. Отправляет
+// их dispatcher, поэтому недоступность получателя не задерживает воркер
+func (s *WebhookService) EnqueueResult(ctx context.Context, result *entity.ProcessingResult) error {
+	var eventType entity.WebhookEventType
+	switch result.Status {
+	case entity.StatusCompleted:
+		eventType = entity.WebhookEventImageProcessed
+	case entity.StatusFailed:
+		eventType = entity.WebhookEventImageFailed
+	default:
+		return nil
+	}
+
+	image, err := s.imageRepo.GetImageByID(ctx, result.ImageID)
+	if err != nil {
+		if errors.Is(err, entity.ErrImageNotFound) {
+			// Изображение удалено во время обработки - уведомлять не о чем
+			return nil
+		}
+		return fmt.Errorf("failed to get image: %w", err)
+	}
+
+	endpoints, err := s.repo.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	callbackURL := image.CallbackURL
+	if callbackURL != "" && s.cfg.Secret == "" {
+		s.logger.Warn("Webhook secret is not configured, skipping callback",
+			zap.String("imageId", image.ID),
+		)
+		callbackURL = ""
+	}
+	if callbackURL == "" && len(endpoints) == 0 {
+		return nil
+	}
+
+	event := newEvent(eventType, result)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	now := time.Now()
+	newDelivery := func(endpointID, target string) *entity.WebhookDelivery {
+		return &entity.WebhookDelivery{
+			ID:            uuid.New().String(),
+			EventID:       event.ID,
+			EventType:     eventType,
+			ImageID:       result.ImageID,
+			TaskID:        result.ID,
+			EndpointID:    endpointID,
+			URL:           target,
+			Payload:       payload,
+			Status:        entity.WebhookDeliveryPending,
+			MaxAttempts:   s.cfg.MaxAttempts,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+	}
+
+	deliveries := make([]*entity.WebhookDelivery, 0, len(endpoints)+1)
+	if callbackURL != "" {
+		deliveries = append(deliveries, newDelivery("", callbackURL))
+	}
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, newDelivery(endpoint.ID, endpoint.URL))
+	}
+
+	if err := s.repo.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %w", err)
+	}
+
+	s.notify()
+	s.logger.Info("Webhook deliveries queued",
+		zap.String("eventId", event.ID),
+		zap.String("eventType", string(eventType)),
+		zap.String("imageId", result.ImageID),
+		zap.Int("deliveries", len(deliveries)),
+	)
+	return nil
+}
+
+func (s *WebhookService) notify() {
+	if s.dispatcher != nil {
+		s.dispatcher.Notify()
+	}
+}
+
+// newEvent формирует событие из результата обработки
+func newEvent(eventType entity.WebhookEventType, result *entity.ProcessingResult) *Event {
+	variants := make([]EventVariant, 0, len(result.Variants))
+	for _, variant := range result.Variants {
+		variants = append(variants, EventVariant{
+			Name:       variant.Name,
+			Operation:  string(variant.Operation),
+			Path:       variant.Path,
+			Size:       variant.Size,
+			MimeType:   variant.MimeType,
+			Format:     string(variant.Format),
+			DurationMs: variant.DurationMs,
+			Error:      variant.Error,
+		})
+	}
+
+	return &Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data: EventData{
+			ImageID:       result.ImageID,
+			TaskID:        result.ID,
+			Status:        string(result.Status),
+			Preset:        result.PresetName,
+			PresetVersion: result.PresetVersion,
+			Variants:      variants,
+			DurationMs:    result.DurationMs,
+			Error:         result.Error,
+			CompletedAt:   result.CompletedAt.UTC(),
+		},
+	}
+}
+
+// validateWebhookURL проверяет, что URL получателя - абсолютный http(s) URL
+// без учетных данных, не указывающий на localhost или внутренний адрес.
+// Имена хостов проверяются еще раз при подключении (см. dialControl)
+func validateWebhookURL(rawURL string) error {
+	if len(rawURL) > maxWebhookURLLength {
+		return fmt.Errorf("%w: longer than %d characters", entity.ErrInvalidWebhookURL, maxWebhookURLLength)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrInvalidWebhookURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", entity.ErrInvalidWebhookURL)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("%w: host is required", entity.ErrInvalidWebhookURL)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: credentials in url are not allowed", entity.ErrInvalidWebhookURL)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: localhost is not allowed", entity.ErrInvalidWebhookURL)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && isBlockedAddr(addr) {
+		return fmt.Errorf("%w: private or loopback address is not allowed", entity.ErrInvalidWebhookURL)
+	}
+
+	return nil
+}
+
+// blockedPrefixes - внутренние диапазоны, которые не покрывают методы netip.Addr
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "эта сеть"
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT, часто внутренняя сеть облака
+	netip.MustParsePrefix("198.18.0.0/15"), // сети для тестирования оборудования
+}
+
+// isBlockedAddr сообщает, что адрес внутренний: loopback, частная сеть,
+// link-local (в том числе метаданные облака 169.254.169.254), multicast,
+// неопределенный адрес или один из blockedPrefixes
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialControl запрещает подключение к внутренним адресам. Проверяется адрес,
+// к которому фактически подключается dialer, поэтому имя хоста, разрешенное
+// после проверки URL во внутренний адрес (DNS rebinding), тоже отклоняется
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", entity.ErrInvalidWebhookURL, err)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected address %q", entity.ErrInvalidWebhookURL, host)
+	}
+	if isBlockedAddr(addr) {
+		return fmt.Errorf("%w: private or loopback address %s is not allowed", entity.ErrInvalidWebhookURL, addr)
+	}
+	return nil
+}
+
+// generateSecret создает случайный ключ подписи получателя
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
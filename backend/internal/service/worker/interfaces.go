@@ -8,22 +8,30 @@ import (
 // ImageRepository определяет интерфейс репозитория для worker
 type ImageRepositoryInterface interface {
 	GetImageByID(ctx context.Context, imageID string) (*entity.Image, error)
-	UpdateImageStatus(ctx context.Context, imageID string, status entity.ImageStatus) error
+	// UpdateImageResultStatus записывает итог обработки, если изображение не отменено
+	UpdateImageResultStatus(ctx context.Context, imageID string, status entity.ImageStatus) (bool, error)
 	CreateProcessedImage(ctx context.Context, processed *entity.ProcessedImage) error
-	UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) error
+	// UpdateProcessingJobStatus меняет статус задачи, если она не отменена
+	UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) (bool, error)
+	GetProcessingJobStatus(ctx context.Context, jobID string) (string, error)
+	DeleteProcessedImage(ctx context.Context, id string) error
+	// DeleteSupersededImages удаляет варианты других задач и возвращает пути их файлов
+	DeleteSupersededImages(ctx context.Context, imageID, jobID string) ([]string, error)
 }
 
 // StatsService определяет интерфейс сервиса статистики
 type StatsServiceInterface interface {
 	RecordImageProcessed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error
 	RecordImageFailed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error
+	RecordImageTimedOut(ctx context.Context) error
 	RecordOperationsProcessed(ctx context.Context, operations []entity.OperationType, processingTimes map[entity.OperationType]float64) error
 }
 
 // ImageProcessor определяет интерфейс для обработки изображений
 type ImageProcessorInterface interface {
-	// ProcessImage обрабатывает изображение согласно списку операций
-	ProcessImage(ctx context.Context, imageData []byte, operations []entity.OperationParams) (map[string][]byte, error)
+	// ProcessImageWithProgress обрабатывает изображение согласно списку операций,
+	// сообщая о каждой выполненной операции
+	ProcessImageWithProgress(ctx context.Context, imageData []byte, operations []entity.OperationParams, progress entity.OperationProgressFunc) (map[string]*entity.ProcessedOutput, error)
 
 	// ValidateImage проверяет, является ли файл допустимым изображением
 	ValidateImage(imageData []byte) (entity.ImageFormat, error)
@@ -31,3 +39,18 @@ type ImageProcessorInterface interface {
 	// GetImageInfo возвращает информацию об изображении (размер, формат)
 	GetImageInfo(imageData []byte) (*entity.ImageInfo, error)
 }
+
+// ResultPublisherInterface публикует результаты обработки задач
+type ResultPublisherInterface interface {
+	PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error
+}
+
+// WebhookServiceInterface сохраняет доставки webhook о завершении обработки
+type WebhookServiceInterface interface {
+	EnqueueResult(ctx context.Context, result *entity.ProcessingResult) error
+}
+
+// ProgressPublisherInterface публикует события хода обработки для подписчиков API
+type ProgressPublisherInterface interface {
+	PublishProgress(ctx context.Context, event *entity.ProgressEvent) error
+}
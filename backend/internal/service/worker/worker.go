@@ -3,6 +3,7 @@ package workerservice
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"imageprocessor/backend/internal/domain/entity"
 	"imageprocessor/backend/internal/repository/cloud"
@@ -12,63 +13,107 @@ import (
 	"go.uber.org/zap"
 )
 
+// bookkeepingTimeout ограничивает запись итогов задачи после истечения ее срока
+const bookkeepingTimeout = 10 * time.Second
+
+// defaultRetryBackoff умножается на номер попытки в задержке перед повтором
+const defaultRetryBackoff = 2 * time.Second
+
 type WorkerService struct {
-	processor    ImageProcessorInterface
-	cloudStorage cloud.CloudStorageInterface
-	imageRepo    ImageRepositoryInterface
-	statsService StatsServiceInterface
-	logger       *zap.Logger
-	bucket       string
+	processor         ImageProcessorInterface
+	cloudStorage      cloud.CloudStorageInterface
+	imageRepo         ImageRepositoryInterface
+	statsService      StatsServiceInterface
+	results           ResultPublisherInterface
+	webhooks          WebhookServiceInterface
+	progress          ProgressPublisherInterface
+	logger            *zap.Logger
+	bucket            string
+	processingTimeout time.Duration
+	retryBackoff      time.Duration
 }
 
+// NewWorkerService создает сервис обработки задач. processingTimeout
+// ограничивает одну попытку обработки; 0 отключает ограничение
 func NewWorkerService(
 	processor ImageProcessorInterface,
 	cloudStorage cloud.CloudStorageInterface,
 	imageRepo ImageRepositoryInterface,
 	statsService StatsServiceInterface,
+	results ResultPublisherInterface,
+	webhooks WebhookServiceInterface,
+	progress ProgressPublisherInterface,
 	logger *zap.Logger,
 	bucket string,
+	processingTimeout time.Duration,
 ) *WorkerService {
 	return &WorkerService{
-		processor:    processor,
-		cloudStorage: cloudStorage,
-		imageRepo:    imageRepo,
-		statsService: statsService,
-		logger:       logger,
-		bucket:       bucket,
+		processor:         processor,
+		cloudStorage:      cloudStorage,
+		imageRepo:         imageRepo,
+		statsService:      statsService,
+		results:           results,
+		webhooks:          webhooks,
+		progress:          progress,
+		logger:            logger,
+		bucket:            bucket,
+		processingTimeout: processingTimeout,
+		retryBackoff:      defaultRetryBackoff,
 	}
 }
 
-// ProcessTask обрабатывает задачу из Kafka
+// ProcessTask обрабатывает задачу из брокера одной попыткой. Попытка, не уложившаяся
+// в processingTimeout, прерывается и завершается ошибкой ErrProcessingTimeout.
+// Задача, отмененная через API, прерывается без ошибки. Если задачу повторяет
+// очередь, итог неудачи записывается только после ее последней попытки
 func (w *WorkerService) ProcessTask(ctx context.Context, task *entity.ProcessingTask) error {
+	startTime := time.Now()
+	tracker := newProgressTracker(task)
+
+	err := w.processAttempt(ctx, task, tracker, startTime)
+	if err == nil {
+		return nil
+	}
+
+	if task.QueueManaged() && task.Attempt < task.MaxAttempts && !errors.Is(err, entity.ErrProcessingTimeout) {
+		w.recordAttemptFailure(ctx, task, task.Attempt, err)
+		return err
+	}
+	return w.failTask(ctx, task, tracker, startTime, err)
+}
+
+// processAttempt выполняет одну попытку обработки. Успешная и отмененная задачи
+// завершаются здесь же; неудачная попытка только возвращает ошибку, а итог
+// неудачи записывает вызывающий, когда повторов больше не будет
+func (w *WorkerService) processAttempt(ctx context.Context, task *entity.ProcessingTask, tracker *progressTracker, startTime time.Time) error {
 	w.logger.Info("Processing task",
 		zap.String("taskId", task.ID),
 		zap.String("imageId", task.ImageID),
 		zap.Int("operationsCount", len(task.Operations)),
 	)
 
-	startTime := time.Now()
+	if w.jobCancelled(ctx, task.ID) {
+		return w.cancelTask(ctx, task, tracker, startTime, nil)
+	}
+	w.publishProgress(ctx, tracker.event(entity.ProgressStarted))
+
+	// Скачивание, обработка и сохранение выполняются в контексте со сроком задачи;
+	// отмена через API прерывает его с причиной ErrJobCancelled
+	cancelCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	taskCtx, cancel := w.withProcessingTimeout(cancelCtx)
+	defer cancel()
 
 	// Скачиваем оригинальное изображение из S3
-	imageData, err := w.cloudStorage.DownloadFile(ctx, task.OriginalPath)
+	imageData, err := w.cloudStorage.DownloadFile(taskCtx, task.OriginalPath)
 	if err != nil {
 		w.logger.Error("Failed to download original image",
 			zap.Error(err),
 			zap.String("taskId", task.ID),
 			zap.String("path", task.OriginalPath),
 		)
-		err = w.updateJobStatus(ctx, task.ID, "failed", err.Error())
-		if err != nil {
-			w.logger.Error("Failed to update job status", zap.Error(err))
-		}
-		err = w.updateImageStatus(ctx, task.ImageID, entity.StatusFailed)
-		if err != nil {
-			w.logger.Error("Failed to update image status", zap.Error(err))
-		}
-		for _, op := range task.Operations {
-			_ = w.statsService.RecordImageFailed(ctx, op.Type, 0)
-		}
-		return fmt.Errorf("failed to download original image: %w", err)
+		return w.interruptTask(ctx, taskCtx, task, tracker, startTime, nil,
+			fmt.Errorf("failed to download original image: %w", err))
 	}
 
 	w.logger.Debug("Original image downloaded",
@@ -77,26 +122,25 @@ func (w *WorkerService) ProcessTask(ctx context.Context, task *entity.Processing
 	)
 
 	// Обрабатываем изображение
-	processedImages, err := w.processor.ProcessImage(ctx, imageData, task.Operations)
+	processedImages, err := w.processor.ProcessImageWithProgress(taskCtx, imageData, task.Operations,
+		func(completed, total int, operation entity.OperationType, variant string) {
+			tracker.completed = completed
+			event := tracker.event(entity.ProgressOperationCompleted)
+			event.Operation = operation
+			event.Variant = variant
+			w.publishProgress(ctx, event)
+
+			if w.jobCancelled(ctx, task.ID) {
+				abort(entity.ErrJobCancelled)
+			}
+		})
 	if err != nil {
 		w.logger.Error("Failed to process image",
 			zap.Error(err),
 			zap.String("taskId", task.ID),
 		)
-		err = w.updateJobStatus(ctx, task.ID, "failed", err.Error())
-		if err != nil {
-			w.logger.Error("Failed to update job status", zap.Error(err))
-		}
-		err = w.updateImageStatus(ctx, task.ImageID, entity.StatusFailed)
-		if err != nil {
-			w.logger.Error("Failed to update image status", zap.Error(err))
-		}
-		// Записываем в статистику неудачную обработку
-		for _, op := range task.Operations {
-			_ = w.statsService.RecordImageFailed(ctx, op.Type, 0)
-		}
-
-		return fmt.Errorf("failed to process image: %w", err)
+		return w.interruptTask(ctx, taskCtx, task, tracker, startTime, nil,
+			fmt.Errorf("failed to process image: %w", err))
 	}
 
 	w.logger.Info("Image processed successfully",
@@ -105,87 +149,128 @@ func (w *WorkerService) ProcessTask(ctx context.Context, task *entity.Processing
 	)
 
 	// Сохраняем обработанные изображения в S3 и БД
-	processingTimes := make(map[entity.OperationType]float64)
+	processingTimes := make(map[string]float64)
+	variants := make([]entity.VariantResult, 0, len(processedImages))
+	// saved - сохраненные в этой попытке варианты, удаляемые, если попытка прервана
+	saved := make([]entity.ProcessedImage, 0, len(processedImages))
+
+	for variantName, output := range processedImages {
+		// После истечения срока или отмены оставшиеся варианты не сохраняются
+		if err := taskCtx.Err(); err != nil {
+			return w.interruptTask(ctx, taskCtx, task, tracker, startTime, saved,
+				fmt.Errorf("saving interrupted before variant %s: %w", variantName, err))
+		}
 
-	for operationType, processedData := range processedImages {
 		opStartTime := time.Now()
 
-		// Формируем путь для обработанного изображения
-		processedPath := fmt.Sprintf("processed/%s/%s/%s.jpg",
+		// Формируем путь для обработанного изображения с расширением реального формата
+		processedPath := fmt.Sprintf("processed/%s/%s/%s%s",
 			task.ImageID,
-			operationType,
+			variantName,
 			uuid.New().String(),
+			output.Format.Extension(),
 		)
 
 		// Загружаем в S3
-		err = w.cloudStorage.UploadFile(ctx, processedPath,
-			bytes.NewReader(processedData),
-			int64(len(processedData)),
-			"image/jpeg")
+		err = w.cloudStorage.UploadFile(taskCtx, processedPath,
+			bytes.NewReader(output.Data),
+			int64(len(output.Data)),
+			output.MimeType)
 		if err != nil {
 			w.logger.Error("Failed to upload processed image",
 				zap.Error(err),
-				zap.String("operation", operationType),
+				zap.String("variant", variantName),
 			)
+			if taskCtx.Err() != nil {
+				return w.interruptTask(ctx, taskCtx, task, tracker, startTime, saved,
+					fmt.Errorf("failed to upload variant %s: %w", variantName, err))
+			}
+			variants = append(variants, newVariantResult(variantName, output, "", opStartTime, err))
 			continue
 		}
 
 		w.logger.Debug("Processed image uploaded",
 			zap.String("taskId", task.ID),
-			zap.String("operation", operationType),
+			zap.String("variant", variantName),
 			zap.String("path", processedPath),
+			zap.String("mimeType", output.MimeType),
 		)
 
 		// Создаем запись в БД
 		processedImage := &entity.ProcessedImage{
-			ID:         uuid.New().String(),
-			ImageID:    task.ImageID,
-			Operation:  entity.OperationType(operationType),
-			Parameters: getOperationParams(task.Operations, entity.OperationType(operationType)),
-			Path:       processedPath,
-			Size:       int64(len(processedData)),
-			MimeType:   "image/jpeg",
-			Format:     task.Format,
-			Status:     "completed",
-			CreatedAt:  time.Now(),
+			ID:          uuid.New().String(),
+			ImageID:     task.ImageID,
+			JobID:       task.ID,
+			Operation:   output.Operation,
+			VariantName: variantName,
+			Parameters:  getOperationParams(task.Operations, variantName),
+			Path:        processedPath,
+			Size:        int64(len(output.Data)),
+			MimeType:    output.MimeType,
+			Format:      output.Format,
+			Status:      entity.JobStatusCompleted,
+			CreatedAt:   time.Now(),
 		}
 
 		err = w.imageRepo.CreateProcessedImage(ctx, processedImage)
 		if err != nil {
 			w.logger.Error("Failed to create processed image record",
 				zap.Error(err),
-				zap.String("operation", operationType),
+				zap.String("variant", variantName),
 			)
+			variants = append(variants, newVariantResult(variantName, output, processedPath, opStartTime, err))
 			continue
 		}
+		saved = append(saved, *processedImage)
 
 		// Записываем время обработки
 		opDuration := time.Since(opStartTime)
-		processingTimes[entity.OperationType(operationType)] = float64(opDuration.Milliseconds())
+		processingTimes[variantName] = float64(opDuration.Milliseconds())
+		variants = append(variants, newVariantResult(variantName, output, processedPath, opStartTime, nil))
+
+		tracker.uploaded++
+		event := tracker.event(entity.ProgressVariantUploaded)
+		event.Operation = output.Operation
+		event.Variant = variantName
+		event.Path = processedPath
+		w.publishProgress(ctx, event)
 
 		w.logger.Info("Processed image saved",
-			zap.String("operation", operationType),
+			zap.String("variant", variantName),
 			zap.Duration("processingTime", opDuration),
 		)
 	}
 
-	// Обновляем статус задачи
-	err = w.updateJobStatus(ctx, task.ID, "completed", "")
-	if err != nil {
-		w.logger.Error("Failed to update job status", zap.Error(err))
+	// Задача могла быть отменена во время сохранения вариантов
+	if w.jobCancelled(ctx, task.ID) {
+		return w.cancelTask(ctx, task, tracker, startTime, saved)
 	}
 
-	// Обновляем статус изображения
-	err = w.updateImageStatus(ctx, task.ImageID, entity.StatusCompleted)
-	if err != nil {
-		w.logger.Error("Failed to update image status", zap.Error(err))
+	// Отмена могла прийти и после проверки: тогда итог не записывается
+	// и задача завершается как отмененная
+	if !w.completeTask(ctx, task) {
+		return w.cancelTask(ctx, task, tracker, startTime, saved)
+	}
+
+	// Варианты предыдущих задач заменяются, только если все новые сохранены
+	if task.Supersede {
+		if len(saved) == len(processedImages) {
+			w.removeSuperseded(ctx, task)
+		} else {
+			w.logger.Warn("Keeping previous variants: not all variants were saved",
+				zap.String("taskId", task.ID),
+				zap.Int("saved", len(saved)),
+				zap.Int("total", len(processedImages)),
+			)
+		}
 	}
 
 	// Записываем статистику
 	totalDuration := time.Since(startTime)
 
 	// Записываем статистику по каждой операции
-	for opType, procTime := range processingTimes {
+	for variantName, procTime := range processingTimes {
+		opType := processedImages[variantName].Operation
 		err = w.statsService.RecordImageProcessed(ctx, opType, procTime)
 		if err != nil {
 			w.logger.Error("Failed to record stats",
@@ -202,11 +287,28 @@ func (w *WorkerService) ProcessTask(ctx context.Context, task *entity.Processing
 		zap.Int("operationsProcessed", len(processedImages)),
 	)
 
+	w.publishProgress(ctx, tracker.event(entity.ProgressCompleted))
+
+	result := newProcessingResult(task, entity.StatusCompleted, startTime, nil)
+	result.Variants = variants
+	for _, variant := range variants {
+		if variant.Error == "" {
+			result.ProcessedPaths[variant.Name] = variant.Path
+		}
+	}
+	w.publishResult(ctx, result)
+
 	return nil
 }
 
-// ProcessTaskWithRetry обрабатывает задачу с повторными попытками
+// ProcessTaskWithRetry обрабатывает задачу с повторными попытками. Неудачная
+// попытка записывает только свою ошибку; статусы, статистика, результат
+// и webhook о неудаче записываются один раз, когда попытки исчерпаны.
+// Попытка, прерванная по таймауту, не повторяется: повтор упрется в тот же срок.
+// Количество выполненных попыток доступно через entity.ProcessingAttempts(err)
 func (w *WorkerService) ProcessTaskWithRetry(ctx context.Context, task *entity.ProcessingTask, maxRetries int) error {
+	startTime := time.Now()
+	var tracker *progressTracker
 	var lastErr error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -216,25 +318,42 @@ func (w *WorkerService) ProcessTaskWithRetry(ctx context.Context, task *entity.P
 			zap.Int("maxRetries", maxRetries),
 		)
 
-		err := w.ProcessTask(ctx, task)
+		tracker = newProgressTracker(task)
+		err := w.processAttempt(ctx, task, tracker, startTime)
 		if err == nil {
 			return nil
 		}
 
 		lastErr = err
-		w.logger.Warn("Task processing failed, will retry",
-			zap.Error(err),
-			zap.String("taskId", task.ID),
-			zap.Int("attempt", attempt),
-		)
+		if errors.Is(err, entity.ErrProcessingTimeout) {
+			w.logger.Error("Task processing timed out",
+				zap.Error(err),
+				zap.String("taskId", task.ID),
+				zap.Int("attempt", attempt),
+			)
+			return entity.WithAttempts(w.failTask(ctx, task, tracker, startTime, err), attempt)
+		}
+		if attempt == maxRetries {
+			break
+		}
+
+		w.recordAttemptFailure(ctx, task, attempt, err)
 
 		// Экспоненциальная задержка перед повтором
-		if attempt < maxRetries {
-			backoff := time.Duration(attempt*2) * time.Second
-			w.logger.Info("Waiting before retry",
-				zap.Duration("backoff", backoff),
-			)
-			time.Sleep(backoff)
+		backoff := time.Duration(attempt) * w.retryBackoff
+		w.logger.Info("Waiting before retry",
+			zap.Duration("backoff", backoff),
+		)
+		select {
+		case <-ctx.Done():
+			interrupted := fmt.Errorf("task processing interrupted after %d attempts: %w", attempt, lastErr)
+			// Истекший broker.maxProcessingTime завершает задачу; при остановке
+			// воркера задача будет доставлена снова, поэтому итог не записывается
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				interrupted = w.failTask(ctx, task, tracker, startTime, interrupted)
+			}
+			return entity.WithAttempts(interrupted, attempt)
+		case <-time.After(backoff):
 		}
 	}
 
@@ -245,23 +364,328 @@ func (w *WorkerService) ProcessTaskWithRetry(ctx context.Context, task *entity.P
 		zap.Int("maxRetries", maxRetries),
 	)
 
-	return fmt.Errorf("task processing failed after %d retries: %w", maxRetries, lastErr)
+	err := w.failTask(ctx, task, tracker, startTime, fmt.Errorf("task processing failed after %d retries: %w", maxRetries, lastErr))
+	return entity.WithAttempts(err, maxRetries)
+}
+
+// completeTask переводит задачу и изображение в completed. Задачу из очереди
+// завершает сама очередь. Возвращает false, если задачу или изображение уже
+// отменили через API; ошибка записи статуса результат не отменяет
+func (w *WorkerService) completeTask(ctx context.Context, task *entity.ProcessingTask) bool {
+	if !task.QueueManaged() {
+		updated, err := w.updateJobStatus(ctx, task.ID, entity.JobStatusCompleted, "")
+		if err != nil {
+			w.logger.Error("Failed to update job status", zap.Error(err))
+		} else if !updated {
+			return false
+		}
+	}
+
+	updated, err := w.updateImageStatus(ctx, task.ImageID, entity.StatusCompleted)
+	if err != nil {
+		w.logger.Error("Failed to update image status", zap.Error(err))
+	} else if !updated {
+		return false
+	}
+	return true
+}
+
+// recordAttemptFailure записывает ошибку попытки, после которой задача будет
+// повторена. Статус задачи остается processing, итог неудачи не публикуется;
+// ошибку задачи из очереди записывает сама очередь
+func (w *WorkerService) recordAttemptFailure(ctx context.Context, task *entity.ProcessingTask, attempt int, err error) {
+	w.logger.Warn("Task processing failed, will retry",
+		zap.Error(err),
+		zap.String("taskId", task.ID),
+		zap.Int("attempt", attempt),
+	)
+
+	if task.QueueManaged() {
+		return
+	}
+	if _, updateErr := w.updateJobStatus(ctx, task.ID, entity.JobStatusProcessing, err.Error()); updateErr != nil {
+		w.logger.Error("Failed to record attempt error", zap.Error(updateErr), zap.String("taskId", task.ID))
+	}
+}
+
+// withProcessingTimeout возвращает контекст одной попытки обработки
+func (w *WorkerService) withProcessingTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.processingTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, w.processingTimeout)
+}
+
+// timeoutError помечает ошибку как ErrProcessingTimeout, если срок попытки истек
+func (w *WorkerService) timeoutError(taskCtx context.Context, err error) error {
+	if !errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", entity.ErrProcessingTimeout, err)
+}
+
+// interruptTask завершает попытку, прерванную ошибкой: задача, отмененная
+// через API, завершается без ошибки, остальные ошибки возвращаются вызывающему.
+// Варианты, сохраненные прерванной попыткой, удаляются и при таймауте:
+// задача не завершена, и часть ее результатов не должна оставаться доступной
+func (w *WorkerService) interruptTask(ctx, taskCtx context.Context, task *entity.ProcessingTask, tracker *progressTracker, startTime time.Time, saved []entity.ProcessedImage, err error) error {
+	if errors.Is(context.Cause(taskCtx), entity.ErrJobCancelled) {
+		return w.cancelTask(ctx, task, tracker, startTime, saved)
+	}
+
+	if len(saved) > 0 {
+		// Срок мог истечь и у родительского контекста, удаление все равно выполняется
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bookkeepingTimeout)
+		defer cancel()
+		w.removeSaved(cleanupCtx, task, saved)
+	}
+	return w.timeoutError(taskCtx, err)
+}
+
+// jobCancelled проверяет, отменена ли задача через API. Ошибка чтения
+// статуса не прерывает обработку
+func (w *WorkerService) jobCancelled(ctx context.Context, jobID string) bool {
+	status, err := w.imageRepo.GetProcessingJobStatus(ctx, jobID)
+	if err != nil {
+		w.logger.Warn("Failed to check job status", zap.Error(err), zap.String("taskId", jobID))
+		return false
+	}
+	return status == entity.JobStatusCancelled
+}
+
+// cancelTask завершает задачу, отмененную через API: удаляет варианты,
+// сохраненные в этой попытке, и сообщает об отмене. Статусы задачи
+// и изображения уже записаны API, в статистике отмена не учитывается
+func (w *WorkerService) cancelTask(ctx context.Context, task *entity.ProcessingTask, tracker *progressTracker, startTime time.Time, saved []entity.ProcessedImage) error {
+	w.removeSaved(ctx, task, saved)
+
+	w.logger.Info("Task cancelled",
+		zap.String("taskId", task.ID),
+		zap.String("imageId", task.ImageID),
+		zap.Int("removedVariants", len(saved)),
+	)
+
+	w.publishProgress(ctx, tracker.event(entity.ProgressCancelled))
+	w.publishResult(ctx, newProcessingResult(task, entity.StatusCancelled, startTime, entity.ErrJobCancelled))
+	return nil
+}
+
+// removeSaved удаляет записи и файлы вариантов, сохраненных незавершенной попыткой
+func (w *WorkerService) removeSaved(ctx context.Context, task *entity.ProcessingTask, saved []entity.ProcessedImage) {
+	if len(saved) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(saved))
+	for _, processed := range saved {
+		if err := w.imageRepo.DeleteProcessedImage(ctx, processed.ID); err != nil {
+			w.logger.Error("Failed to delete processed image record",
+				zap.Error(err),
+				zap.String("variant", processed.VariantName),
+			)
+		}
+		paths = append(paths, processed.Path)
+	}
+	if err := w.cloudStorage.DeleteFiles(ctx, paths); err != nil {
+		w.logger.Error("Failed to delete processed images", zap.Error(err), zap.String("taskId", task.ID))
+	}
+}
+
+// removeSuperseded удаляет варианты изображения, созданные предыдущими задачами
+func (w *WorkerService) removeSuperseded(ctx context.Context, task *entity.ProcessingTask) {
+	paths, err := w.imageRepo.DeleteSupersededImages(ctx, task.ImageID, task.ID)
+	if err != nil {
+		w.logger.Error("Failed to delete superseded variants", zap.Error(err), zap.String("taskId", task.ID))
+		return
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	// Записи уже удалены; не удаленные файлы не видны через API
+	if err := w.cloudStorage.DeleteFiles(ctx, paths); err != nil {
+		w.logger.Error("Failed to delete superseded files", zap.Error(err), zap.String("taskId", task.ID))
+	}
+
+	w.logger.Info("Superseded variants removed",
+		zap.String("taskId", task.ID),
+		zap.String("imageId", task.ImageID),
+		zap.Int("count", len(paths)),
+	)
+}
+
+// failTask записывает итог задачи, которая больше не будет повторена: статусы
+// задачи и изображения, статистику, событие и результат. Задача, прерванная
+// по таймауту, получает статус timeout и учитывается отдельным счетчиком.
+// Статус задачи из очереди переводит сама очередь
+func (w *WorkerService) failTask(ctx context.Context, task *entity.ProcessingTask, tracker *progressTracker, startTime time.Time, err error) error {
+	jobStatus := entity.JobStatusFailed
+	timedOut := errors.Is(err, entity.ErrProcessingTimeout)
+	if timedOut {
+		jobStatus = entity.JobStatusTimeout
+	}
+	// Срок мог истечь и у родительского контекста, итог все равно записывается
+	if timedOut || ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), bookkeepingTimeout)
+		defer cancel()
+	}
+
+	if !task.QueueManaged() {
+		if _, updateErr := w.updateJobStatus(ctx, task.ID, jobStatus, err.Error()); updateErr != nil {
+			w.logger.Error("Failed to update job status", zap.Error(updateErr))
+		}
+	}
+	if _, updateErr := w.updateImageStatus(ctx, task.ImageID, entity.StatusFailed); updateErr != nil {
+		w.logger.Error("Failed to update image status", zap.Error(updateErr))
+	}
+
+	// Записываем в статистику неудачную обработку
+	if timedOut {
+		if statsErr := w.statsService.RecordImageTimedOut(ctx); statsErr != nil {
+			w.logger.Error("Failed to record timeout stats", zap.Error(statsErr))
+		}
+	} else {
+		for _, op := range task.Operations {
+			_ = w.statsService.RecordImageFailed(ctx, op.Type, 0)
+		}
+	}
+
+	w.publishFailure(ctx, tracker, err)
+	w.publishResult(ctx, newProcessingResult(task, entity.StatusFailed, startTime, err))
+	return err
+}
+
+// publishResult публикует результат обработки и ставит в очередь webhook.
+// Ошибки не влияют на задачу: результат уже сохранен в БД и доступен через API
+func (w *WorkerService) publishResult(ctx context.Context, result *entity.ProcessingResult) {
+	if w.results != nil {
+		if err := w.results.PublishProcessingResult(ctx, result); err != nil {
+			w.logger.Error("Failed to publish processing result",
+				zap.Error(err),
+				zap.String("taskId", result.ID),
+				zap.String("imageId", result.ImageID),
+			)
+		}
+	}
+
+	if w.webhooks != nil {
+		if err := w.webhooks.EnqueueResult(ctx, result); err != nil {
+			w.logger.Error("Failed to queue webhook deliveries",
+				zap.Error(err),
+				zap.String("taskId", result.ID),
+				zap.String("imageId", result.ImageID),
+			)
+		}
+	}
+}
+
+// progressTracker считает выполненные операции и сохраненные результаты задачи
+type progressTracker struct {
+	task      *entity.ProcessingTask
+	total     int
+	completed int
+	uploaded  int
+}
+
+func newProgressTracker(task *entity.ProcessingTask) *progressTracker {
+	return &progressTracker{task: task, total: len(task.Operations)}
+}
+
+// event создает событие с текущим ходом обработки. Выполнение операций
+// и сохранение результатов составляют по половине задачи
+func (t *progressTracker) event(eventType entity.ProgressEventType) *entity.ProgressEvent {
+	progress := 100
+	if eventType != entity.ProgressCompleted {
+		progress = 0
+		if t.total > 0 {
+			progress = (t.completed + t.uploaded) * 100 / (2 * t.total)
+		}
+	}
+
+	return &entity.ProgressEvent{
+		Type:                eventType,
+		ImageID:             t.task.ImageID,
+		TaskID:              t.task.ID,
+		CompletedOperations: t.completed,
+		UploadedVariants:    t.uploaded,
+		TotalOperations:     t.total,
+		Progress:            progress,
+		Timestamp:           time.Now(),
+	}
+}
+
+// publishFailure публикует событие о неудачной попытке обработки
+func (w *WorkerService) publishFailure(ctx context.Context, tracker *progressTracker, err error) {
+	event := tracker.event(entity.ProgressFailed)
+	event.Error = err.Error()
+	w.publishProgress(ctx, event)
+}
+
+// publishProgress публикует событие хода обработки. События нужны только
+// подключенным клиентам, поэтому ошибка публикации не влияет на задачу
+func (w *WorkerService) publishProgress(ctx context.Context, event *entity.ProgressEvent) {
+	if w.progress == nil {
+		return
+	}
+	if err := w.progress.PublishProgress(ctx, event); err != nil {
+		w.logger.Warn("Failed to publish progress event",
+			zap.Error(err),
+			zap.String("taskId", event.TaskID),
+			zap.String("type", string(event.Type)),
+		)
+	}
+}
+
+// newProcessingResult создает результат задачи; err задает ошибку обработки
+func newProcessingResult(task *entity.ProcessingTask, status entity.ImageStatus, startTime time.Time, err error) *entity.ProcessingResult {
+	result := &entity.ProcessingResult{
+		ID:             task.ID,
+		ImageID:        task.ImageID,
+		Status:         status,
+		PresetName:     task.PresetName,
+		PresetVersion:  task.PresetVersion,
+		ProcessedPaths: make(map[string]string),
+		DurationMs:     time.Since(startTime).Milliseconds(),
+		CompletedAt:    time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// newVariantResult описывает сохранение варианта; err задает ошибку сохранения
+func newVariantResult(name string, output *entity.ProcessedOutput, path string, startTime time.Time, err error) entity.VariantResult {
+	variant := entity.VariantResult{
+		Name:       name,
+		Operation:  output.Operation,
+		Path:       path,
+		Size:       int64(len(output.Data)),
+		MimeType:   output.MimeType,
+		Format:     output.Format,
+		DurationMs: time.Since(startTime).Milliseconds(),
+	}
+	if err != nil {
+		variant.Error = err.Error()
+	}
+	return variant
 }
 
-// updateJobStatus обновляет статус задачи в БД
-func (w *WorkerService) updateJobStatus(ctx context.Context, jobID, status, errorMsg string) error {
+// updateJobStatus обновляет статус задачи в БД; false - задача отменена
+func (w *WorkerService) updateJobStatus(ctx context.Context, jobID, status, errorMsg string) (bool, error) {
 	return w.imageRepo.UpdateProcessingJobStatus(ctx, jobID, status, errorMsg)
 }
 
-// updateImageStatus обновляет статус изображения в БД
-func (w *WorkerService) updateImageStatus(ctx context.Context, imageID string, status entity.ImageStatus) error {
-	return w.imageRepo.UpdateImageStatus(ctx, imageID, status)
+// updateImageStatus записывает итог обработки изображения; false - обработка отменена
+func (w *WorkerService) updateImageStatus(ctx context.Context, imageID string, status entity.ImageStatus) (bool, error) {
+	return w.imageRepo.UpdateImageResultStatus(ctx, imageID, status)
 }
 
-// getOperationParams извлекает параметры для конкретной операции
-func getOperationParams(operations []entity.OperationParams, opType entity.OperationType) string {
+// getOperationParams извлекает параметры операции, создавшей вариант
+func getOperationParams(operations []entity.OperationParams, variantName string) string {
 	for _, op := range operations {
-		if op.Type == opType {
+		if op.VariantName() == variantName {
 			// Конвертируем параметры в строку (можно использовать JSON)
 			return fmt.Sprintf("%v", op.Parameters)
 		}
@@ -0,0 +1,475 @@
+package workerservice
+
+import (
+	"context"
+	"errors"
+	"imageprocessor/backend/internal/domain/entity"
+	"imageprocessor/backend/internal/repository/cloud"
+	"io"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит объекты в памяти; остальные методы в тесте не вызываются.
+// С blockAfter > 0 загрузки после blockAfter успешных ждут отмены контекста
+type fakeStorage struct {
+	cloud.CloudStorageInterface
+	objects    map[string][]byte
+	deleted    []string
+	blockAfter int
+}
+
+func (s *fakeStorage) DownloadFile(ctx context.Context, path string) ([]byte, error) {
+	return []byte("original"), nil
+}
+
+func (s *fakeStorage) UploadFile(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	if s.blockAfter > 0 && len(s.objects) >= s.blockAfter {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s.objects[path] = data
+	return nil
+}
+
+func (s *fakeStorage) DeleteFiles(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		delete(s.objects, path)
+	}
+	s.deleted = append(s.deleted, paths...)
+	return nil
+}
+
+// fakeImageRepo возвращает статус cancelled, начиная с проверки cancelAtCheck.
+// С cancelledOnWrite отмена видна только при записи итога
+type fakeImageRepo struct {
+	ImageRepositoryInterface
+	cancelAtCheck    int
+	checks           int
+	cancelledOnWrite bool
+	jobStatuses      []string
+	imageStatuses    []entity.ImageStatus
+	processed        map[string]*entity.ProcessedImage
+}
+
+func (r *fakeImageRepo) GetProcessingJobStatus(ctx context.Context, jobID string) (string, error) {
+	r.checks++
+	if r.cancelAtCheck > 0 && r.checks >= r.cancelAtCheck {
+		return entity.JobStatusCancelled, nil
+	}
+	return entity.JobStatusProcessing, nil
+}
+
+// UpdateProcessingJobStatus не меняет задачу, отмененную к моменту записи итога
+func (r *fakeImageRepo) UpdateProcessingJobStatus(ctx context.Context, jobID string, status string, errorMsg string) (bool, error) {
+	if r.cancelledOnWrite {
+		return false, nil
+	}
+	r.jobStatuses = append(r.jobStatuses, status)
+	return true, nil
+}
+
+func (r *fakeImageRepo) UpdateImageResultStatus(ctx context.Context, imageID string, status entity.ImageStatus) (bool, error) {
+	if r.cancelledOnWrite {
+		return false, nil
+	}
+	r.imageStatuses = append(r.imageStatuses, status)
+	return true, nil
+}
+
+func (r *fakeImageRepo) CreateProcessedImage(ctx context.Context, processed *entity.ProcessedImage) error {
+	r.processed[processed.ID] = processed
+	return nil
+}
+
+func (r *fakeImageRepo) DeleteProcessedImage(ctx context.Context, id string) error {
+	delete(r.processed, id)
+	return nil
+}
+
+func (r *fakeImageRepo) DeleteSupersededImages(ctx context.Context, imageID, jobID string) ([]string, error) {
+	var paths []string
+	for id, processed := range r.processed {
+		if processed.ImageID == imageID && processed.JobID != jobID {
+			paths = append(paths, processed.Path)
+			delete(r.processed, id)
+		}
+	}
+	return paths, nil
+}
+
+type fakeStats struct {
+	StatsServiceInterface
+	processed int
+	failed    int
+	timedOut  int
+}
+
+func (s *fakeStats) RecordImageProcessed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error {
+	s.processed++
+	return nil
+}
+
+func (s *fakeStats) RecordImageFailed(ctx context.Context, operation entity.OperationType, processingTimeMs float64) error {
+	s.failed++
+	return nil
+}
+
+func (s *fakeStats) RecordImageTimedOut(ctx context.Context) error {
+	s.timedOut++
+	return nil
+}
+
+// fakeProcessor возвращает по результату на операцию; с block ждет отмены контекста,
+// первые failures вызовов завершаются ошибкой
+type fakeProcessor struct {
+	ImageProcessorInterface
+	calls    int
+	block    bool
+	failures int
+}
+
+func (p *fakeProcessor) ProcessImageWithProgress(ctx context.Context, imageData []byte, operations []entity.OperationParams, progress entity.OperationProgressFunc) (map[string]*entity.ProcessedOutput, error) {
+	p.calls++
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.calls <= p.failures {
+		return nil, errors.New("decode failed")
+	}
+
+	results := make(map[string]*entity.ProcessedOutput, len(operations))
+	for i, op := range operations {
+		results[op.VariantName()] = &entity.ProcessedOutput{
+			Operation: op.Type,
+			Data:      []byte("processed"),
+			Format:    entity.FormatPNG,
+			MimeType:  "image/png",
+		}
+		progress(i+1, len(operations), op.Type, op.VariantName())
+	}
+	return results, nil
+}
+
+// fakeResults запоминает опубликованные результаты и поставленные в очередь webhook
+type fakeResults struct {
+	published []entity.ImageStatus
+	webhooks  []entity.ImageStatus
+}
+
+func (r *fakeResults) PublishProcessingResult(ctx context.Context, result *entity.ProcessingResult) error {
+	r.published = append(r.published, result.Status)
+	return nil
+}
+
+func (r *fakeResults) EnqueueResult(ctx context.Context, result *entity.ProcessingResult) error {
+	r.webhooks = append(r.webhooks, result.Status)
+	return nil
+}
+
+// fakeProgress запоминает типы событий хода обработки
+type fakeProgress struct {
+	events []entity.ProgressEventType
+}
+
+func (p *fakeProgress) PublishProgress(ctx context.Context, event *entity.ProgressEvent) error {
+	p.events = append(p.events, event.Type)
+	return nil
+}
+
+type workerFixture struct {
+	service   *WorkerService
+	storage   *fakeStorage
+	repo      *fakeImageRepo
+	stats     *fakeStats
+	processor *fakeProcessor
+	results   *fakeResults
+	progress  *fakeProgress
+}
+
+func newWorkerFixture(timeout time.Duration) *workerFixture {
+	f := &workerFixture{
+		storage:   &fakeStorage{objects: make(map[string][]byte)},
+		repo:      &fakeImageRepo{processed: make(map[string]*entity.ProcessedImage)},
+		stats:     &fakeStats{},
+		processor: &fakeProcessor{},
+		results:   &fakeResults{},
+		progress:  &fakeProgress{},
+	}
+	f.service = NewWorkerService(f.processor, f.storage, f.repo, f.stats, f.results, f.results, f.progress, zap.NewNop(), "bucket", timeout)
+	f.service.retryBackoff = time.Millisecond
+	return f
+}
+
+func newTestTask() *entity.ProcessingTask {
+	return &entity.ProcessingTask{
+		ID:           "task",
+		ImageID:      "img",
+		OriginalPath: "originals/img/source.png",
+		Operations: []entity.OperationParams{
+			{Type: entity.OpGrayscale},
+			{Type: entity.OpFlip, Name: "mirror"},
+		},
+	}
+}
+
+func TestProcessTask_SkipsTaskCancelledBeforeStart(t *testing.T) {
+	f := newWorkerFixture(time.Minute)
+	f.repo.cancelAtCheck = 1
+
+	if err := f.service.ProcessTask(context.Background(), newTestTask()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.processor.calls != 0 {
+		t.Fatalf("cancelled task was processed %d times", f.processor.calls)
+	}
+	if len(f.repo.jobStatuses) != 0 || f.stats.failed != 0 {
+		t.Fatalf("cancelled task recorded as %v, failed stats %d", f.repo.jobStatuses, f.stats.failed)
+	}
+}
+
+func TestProcessTask_CancelBetweenOperationsStopsProcessing(t *testing.T) {
+	f := newWorkerFixture(time.Minute)
+	// Первая проверка - перед началом, вторая - после первой операции
+	f.repo.cancelAtCheck = 2
+
+	if err := f.service.ProcessTask(context.Background(), newTestTask()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.storage.objects) != 0 || len(f.repo.processed) != 0 {
+		t.Fatalf("cancelled task saved variants: %d objects, %d records", len(f.storage.objects), len(f.repo.processed))
+	}
+	if f.stats.failed != 0 || f.stats.processed != 0 {
+		t.Fatalf("cancelled task counted in stats: failed %d, processed %d", f.stats.failed, f.stats.processed)
+	}
+}
+
+func TestProcessTask_CancelDuringSavingRemovesPartialVariants(t *testing.T) {
+	f := newWorkerFixture(time.Minute)
+	// Перед началом и после двух операций задача активна, отмена видна перед завершением
+	f.repo.cancelAtCheck = 4
+
+	if err := f.service.ProcessTask(context.Background(), newTestTask()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.storage.deleted) != 2 {
+		t.Fatalf("expected 2 removed variants, got %v", f.storage.deleted)
+	}
+	if len(f.storage.objects) != 0 || len(f.repo.processed) != 0 {
+		t.Fatalf("variants left after cancel: %d objects, %d records", len(f.storage.objects), len(f.repo.processed))
+	}
+	if len(f.repo.jobStatuses) != 0 {
+		t.Fatalf("cancelled job status overwritten with %v", f.repo.jobStatuses)
+	}
+	if f.stats.failed != 0 || f.stats.processed != 0 {
+		t.Fatalf("cancelled task counted in stats: failed %d, processed %d", f.stats.failed, f.stats.processed)
+	}
+}
+
+func TestProcessTask_CancelBeforeCompletionIsNotPublished(t *testing.T) {
+	for _, queueManaged := range []bool{false, true} {
+		f := newWorkerFixture(time.Minute)
+		f.repo.cancelledOnWrite = true
+		task := newTestTask()
+		if queueManaged {
+			task.Attempt = 1
+			task.MaxAttempts = 3
+		}
+
+		if err := f.service.ProcessTask(context.Background(), task); err != nil {
+			t.Fatalf("queueManaged=%v: unexpected error: %v", queueManaged, err)
+		}
+		if len(f.repo.imageStatuses) != 0 {
+			t.Fatalf("queueManaged=%v: cancelled image overwritten with %v", queueManaged, f.repo.imageStatuses)
+		}
+		if len(f.results.published) != 1 || f.results.published[0] != entity.StatusCancelled {
+			t.Fatalf("queueManaged=%v: expected cancelled result only, got %v", queueManaged, f.results.published)
+		}
+		if len(f.results.webhooks) != 1 || f.results.webhooks[0] != entity.StatusCancelled {
+			t.Fatalf("queueManaged=%v: expected no completed webhook, got %v", queueManaged, f.results.webhooks)
+		}
+		if len(f.repo.processed) != 0 || f.stats.processed != 0 {
+			t.Fatalf("queueManaged=%v: cancelled task kept %d variants, stats %d", queueManaged, len(f.repo.processed), f.stats.processed)
+		}
+	}
+}
+
+func TestProcessTaskWithRetry_TimeoutIsNotRetried(t *testing.T) {
+	f := newWorkerFixture(20 * time.Millisecond)
+	f.processor.block = true
+
+	err := f.service.ProcessTaskWithRetry(context.Background(), newTestTask(), 3)
+	if !errors.Is(err, entity.ErrProcessingTimeout) {
+		t.Fatalf("expected ErrProcessingTimeout, got %v", err)
+	}
+	if f.processor.calls != 1 || entity.ProcessingAttempts(err) != 1 {
+		t.Fatalf("timed out task processed %d times, error reports %d attempts", f.processor.calls, entity.ProcessingAttempts(err))
+	}
+	if len(f.repo.jobStatuses) != 1 || f.repo.jobStatuses[0] != entity.JobStatusTimeout {
+		t.Fatalf("expected timeout job status, got %v", f.repo.jobStatuses)
+	}
+	if f.stats.timedOut != 1 || f.stats.failed != 0 {
+		t.Fatalf("expected timeout counter only, got timed out %d, failed %d", f.stats.timedOut, f.stats.failed)
+	}
+}
+
+func TestProcessTask_TimeoutDuringSavingRemovesPartialVariants(t *testing.T) {
+	f := newWorkerFixture(50 * time.Millisecond)
+	f.storage.blockAfter = 1
+
+	err := f.service.ProcessTask(context.Background(), newTestTask())
+	if !errors.Is(err, entity.ErrProcessingTimeout) {
+		t.Fatalf("expected ErrProcessingTimeout, got %v", err)
+	}
+	if len(f.storage.deleted) != 1 {
+		t.Fatalf("expected the saved variant to be removed, got %v", f.storage.deleted)
+	}
+	if len(f.storage.objects) != 0 || len(f.repo.processed) != 0 {
+		t.Fatalf("variants left after timeout: %d objects, %d records", len(f.storage.objects), len(f.repo.processed))
+	}
+	if len(f.repo.jobStatuses) != 1 || f.repo.jobStatuses[0] != entity.JobStatusTimeout {
+		t.Fatalf("expected timeout job status, got %v", f.repo.jobStatuses)
+	}
+}
+
+func TestProcessTaskWithRetry_FailedAttemptIsNotPublished(t *testing.T) {
+	f := newWorkerFixture(time.Minute)
+	f.processor.failures = 1
+
+	if err := f.service.ProcessTaskWithRetry(context.Background(), newTestTask(), 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.processor.calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", f.processor.calls)
+	}
+	if len(f.results.published) != 1 || f.results.published[0] != entity.StatusCompleted {
+		t.Fatalf("expected only completed result, got %v", f.results.published)
+	}
+	if len(f.results.webhooks) != 1 || f.results.webhooks[0] != entity.StatusCompleted {
+		t.Fatalf("expected only completed webhook, got %v", f.results.webhooks)
+	}
+	for _, event := range f.progress.events {
+		if event == entity.ProgressFailed {
+			t.Fatalf("failed progress event published for retried attempt: %v", f.progress.events)
+		}
+	}
+	for _, status := range f.repo.jobStatuses {
+		if status == entity.JobStatusFailed {
+			t.Fatalf("job marked failed before retry: %v", f.repo.jobStatuses)
+		}
+	}
+	if f.stats.failed != 0 {
+		t.Fatalf("retried attempt counted as failed %d times", f.stats.failed)
+	}
+}
+
+func TestProcessTaskWithRetry_PublishesFailureOnceAfterLastAttempt(t *testing.T) {
+	f := newWorkerFixture(time.Minute)
+	f.processor.failures = 3
+
+	err := f.service.ProcessTaskWithRetry(context.Background(), newTestTask(), 3)
+	if err == nil {
+		t.Fatal("expected error after all attempts failed")
+	}
+	if attempts := entity.ProcessingAttempts(err); attempts != 3 {
+		t.Fatalf("expected 3 attempts in error, got %d", attempts)
+	}
+	if f.processor.calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", f.processor.calls)
+	}
+	if len(f.results.published) != 1 || f.results.published[0] != entity.StatusFailed {
+		t.Fatalf("expected one failed result, got %v", f.results.published)
+	}
+	if len(f.results.webhooks) != 1 || f.results.webhooks[0] != entity.StatusFailed {
+		t.Fatalf("expected one failed webhook, got %v", f.results.webhooks)
+	}
+	last := f.repo.jobStatuses[len(f.repo.jobStatuses)-1]
+	if last != entity.JobStatusFailed {
+		t.Fatalf("expected job to end failed, got %v", f.repo.jobStatuses)
+	}
+	// Статистика неудачи записывается по операциям задачи один раз
+	if f.stats.failed != 2 {
+		t.Fatalf("expected failure stats for 2 operations, got %d", f.stats.failed)
+	}
+}
+
+func TestProcessTask_QueueManagedTaskLeavesRetriesToQueue(t *testing.T) {
+	for _, tt := range []struct {
+		attempt int
+		final   bool
+	}{
+		{attempt: 1, final: false},
+		{attempt: 3, final: true},
+	} {
+		f := newWorkerFixture(time.Minute)
+		f.processor.failures = 1
+		task := newTestTask()
+		task.Attempt = tt.attempt
+		task.MaxAttempts = 3
+
+		if err := f.service.ProcessTask(context.Background(), task); err == nil {
+			t.Fatalf("attempt %d: expected error", tt.attempt)
+		}
+		if f.processor.calls != 1 {
+			t.Fatalf("attempt %d: worker retried queue task %d times", tt.attempt, f.processor.calls)
+		}
+		if len(f.repo.jobStatuses) != 0 {
+			t.Fatalf("attempt %d: worker changed queue job status: %v", tt.attempt, f.repo.jobStatuses)
+		}
+		if published := len(f.results.published) == 1; published != tt.final {
+			t.Fatalf("attempt %d: failure published=%v, results %v", tt.attempt, published, f.results.published)
+		}
+	}
+
+	f := newWorkerFixture(time.Minute)
+	task := newTestTask()
+	task.Attempt = 1
+	task.MaxAttempts = 3
+	if err := f.service.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.repo.jobStatuses) != 0 {
+		t.Fatalf("worker completed queue job itself: %v", f.repo.jobStatuses)
+	}
+}
+
+func TestProcessTask_SupersedesPreviousVariants(t *testing.T) {
+	for _, supersede := range []bool{true, false} {
+		f := newWorkerFixture(time.Minute)
+		f.repo.processed["old"] = &entity.ProcessedImage{ID: "old", ImageID: "img", JobID: "previous", Path: "processed/img/grayscale/old.png"}
+		f.storage.objects["processed/img/grayscale/old.png"] = []byte("old")
+
+		task := newTestTask()
+		task.Supersede = supersede
+		if err := f.service.ProcessTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, kept := f.repo.processed["old"]
+		if kept == supersede {
+			t.Fatalf("supersede=%v: previous variant kept=%v", supersede, kept)
+		}
+		if _, exists := f.storage.objects["processed/img/grayscale/old.png"]; exists == supersede {
+			t.Fatalf("supersede=%v: previous file exists=%v", supersede, exists)
+		}
+		want := 2
+		if !supersede {
+			want = 3
+		}
+		if len(f.repo.processed) != want {
+			t.Fatalf("supersede=%v: unexpected variants %v", supersede, f.repo.processed)
+		}
+		for _, processed := range f.repo.processed {
+			if processed.ID != "old" && processed.JobID != task.ID {
+				t.Fatalf("new variant not linked to job: %+v", processed)
+			}
+		}
+	}
+}
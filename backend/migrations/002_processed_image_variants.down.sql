@@ -0,0 +1,5 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_processed_images_image_variant;
+
+-- Drop column
+ALTER TABLE processed_images DROP COLUMN IF EXISTS variant_name;
@@ -0,0 +1,9 @@
+-- Add variant name so one job can produce several outputs of the same operation type
+ALTER TABLE processed_images ADD COLUMN IF NOT EXISTS variant_name VARCHAR(100);
+
+UPDATE processed_images SET variant_name = operation WHERE variant_name IS NULL;
+
+ALTER TABLE processed_images ALTER COLUMN variant_name SET NOT NULL;
+
+-- Create index for variant lookups
+CREATE INDEX IF NOT EXISTS idx_processed_images_image_variant ON processed_images(image_id, variant_name);
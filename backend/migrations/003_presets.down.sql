@@ -0,0 +1,6 @@
+-- Drop preset columns
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS preset_version;
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS preset_name;
+
+-- Drop tables
+DROP TABLE IF EXISTS presets;
@@ -0,0 +1,17 @@
+-- Create presets table (каждое изменение пресета - новая версия)
+CREATE TABLE IF NOT EXISTS presets (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    version INT NOT NULL,
+    description TEXT,
+    operations JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(name, version)
+);
+
+-- Create index for latest version lookups
+CREATE INDEX IF NOT EXISTS idx_presets_name_version ON presets(name, version DESC);
+
+-- Record preset used by processing job
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS preset_name VARCHAR(64);
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS preset_version INT;
@@ -0,0 +1,2 @@
+-- Drop column
+ALTER TABLE images DROP COLUMN IF EXISTS format;
@@ -0,0 +1,15 @@
+-- Store format detected from image content
+ALTER TABLE images ADD COLUMN IF NOT EXISTS format VARCHAR(20);
+
+UPDATE images SET format = CASE mime_type
+    WHEN 'image/jpeg' THEN 'jpeg'
+    WHEN 'image/jpg' THEN 'jpeg'
+    WHEN 'image/png' THEN 'png'
+    WHEN 'image/gif' THEN 'gif'
+    WHEN 'image/webp' THEN 'webp'
+    ELSE ''
+END
+WHERE format IS NULL;
+
+ALTER TABLE images ALTER COLUMN format SET DEFAULT '';
+ALTER TABLE images ALTER COLUMN format SET NOT NULL;
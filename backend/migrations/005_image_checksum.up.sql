@@ -0,0 +1,2 @@
+-- Store SHA-256 of the original computed during upload
+ALTER TABLE images ADD COLUMN IF NOT EXISTS checksum VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_processing_jobs_locked_until;
+DROP INDEX IF EXISTS idx_processing_jobs_available;
+
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS locked_by;
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS available_at;
@@ -0,0 +1,8 @@
+-- Queue state for the Postgres broker: when a job becomes visible and who holds its lease
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS locked_by VARCHAR(36);
+
+-- Create indexes for leasing
+CREATE INDEX IF NOT EXISTS idx_processing_jobs_available ON processing_jobs(available_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_processing_jobs_locked_until ON processing_jobs(locked_until) WHERE status = 'processing';
@@ -0,0 +1,18 @@
+-- Create task_outbox table (задачи, ожидающие публикации в брокер)
+CREATE TABLE IF NOT EXISTS task_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    task_id VARCHAR(36) NOT NULL,
+    image_id VARCHAR(36) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES processing_jobs(id) ON DELETE CASCADE
+);
+
+-- Create index for relay polling
+CREATE INDEX IF NOT EXISTS idx_task_outbox_available ON task_outbox(available_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_task_outbox_task_id ON task_outbox(task_id);
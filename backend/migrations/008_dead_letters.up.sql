@@ -0,0 +1,19 @@
+-- Create dead_letters table (сообщения, которые не удалось обработать или разобрать)
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id VARCHAR(36) PRIMARY KEY,
+    task_id VARCHAR(36),
+    image_id VARCHAR(36),
+    source VARCHAR(20) NOT NULL,
+    payload BYTEA NOT NULL,
+    headers JSONB,
+    error_message TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    replay_task_id VARCHAR(36),
+    replayed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for dead_letters
+CREATE INDEX IF NOT EXISTS idx_dead_letters_status_created_at ON dead_letters(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_image_id ON dead_letters(image_id);
@@ -0,0 +1,7 @@
+-- Drop tables
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+
+-- Drop callback_url column from images
+ALTER TABLE images DROP COLUMN IF EXISTS callback_url;
@@ -0,0 +1,2 @@
+-- Drop column
+ALTER TABLE statistics DROP COLUMN IF EXISTS total_images_timed_out;
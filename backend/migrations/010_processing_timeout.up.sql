@@ -0,0 +1,2 @@
+-- Count tasks interrupted by worker.processingTimeout separately from failures
+ALTER TABLE statistics ADD COLUMN IF NOT EXISTS total_images_timed_out BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Drop columns
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS supersede;
+
+DROP INDEX IF EXISTS idx_processed_images_job_id;
+
+ALTER TABLE processed_images DROP COLUMN IF EXISTS job_id;
@@ -0,0 +1,18 @@
+-- Link variants to the job that produced them so reprocessing can tell old and new results apart
+ALTER TABLE processed_images ADD COLUMN IF NOT EXISTS job_id VARCHAR(36);
+
+-- Existing variants belong to the latest job of their image
+UPDATE processed_images p
+SET job_id = (
+    SELECT j.id FROM processing_jobs j
+    WHERE j.image_id = p.image_id
+    ORDER BY j.created_at DESC
+    LIMIT 1
+)
+WHERE job_id IS NULL;
+
+-- Create index for per-job variant lookups
+CREATE INDEX IF NOT EXISTS idx_processed_images_job_id ON processed_images(job_id);
+
+-- Reprocessing job replaces variants of previous jobs once it completes
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS supersede BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_processing_jobs_backfill_id;
+DROP INDEX IF EXISTS idx_images_created_at_id;
+
+-- Drop backfill_id column from processing_jobs
+ALTER TABLE processing_jobs DROP COLUMN IF EXISTS backfill_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS backfill_errors;
+DROP TABLE IF EXISTS backfills;
@@ -0,0 +1,44 @@
+-- Create backfills table (массовая повторная обработка изображений по фильтру)
+CREATE TABLE IF NOT EXISTS backfills (
+    id VARCHAR(36) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    filter JSONB NOT NULL,
+    operations JSONB NOT NULL,
+    preset_name VARCHAR(100),
+    preset_version INT,
+    supersede BOOLEAN NOT NULL DEFAULT false,
+    batch_size INT NOT NULL,
+    batch_interval_ms BIGINT NOT NULL,
+    cursor_created_at TIMESTAMP,
+    cursor_image_id VARCHAR(36),
+    total_images BIGINT NOT NULL DEFAULT 0,
+    scanned_images BIGINT NOT NULL DEFAULT 0,
+    queued_images BIGINT NOT NULL DEFAULT 0,
+    skipped_images BIGINT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- Create backfill_errors table (журнал неудачных пачек)
+CREATE TABLE IF NOT EXISTS backfill_errors (
+    id BIGSERIAL PRIMARY KEY,
+    backfill_id VARCHAR(36) NOT NULL,
+    attempt INT NOT NULL,
+    batch_size INT NOT NULL,
+    error_message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (backfill_id) REFERENCES backfills(id) ON DELETE CASCADE
+);
+
+-- Link jobs to the backfill that created them to report progress and republish interrupted batches
+ALTER TABLE processing_jobs ADD COLUMN IF NOT EXISTS backfill_id VARCHAR(36);
+
+-- Create indexes for runner polling, keyset paging and per-backfill job counts
+CREATE INDEX IF NOT EXISTS idx_backfills_due ON backfills(next_run_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_backfill_errors_backfill_id ON backfill_errors(backfill_id, id);
+CREATE INDEX IF NOT EXISTS idx_images_created_at_id ON images(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_processing_jobs_backfill_id ON processing_jobs(backfill_id) WHERE backfill_id IS NOT NULL;